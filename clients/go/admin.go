@@ -0,0 +1,175 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Admin groups server administration commands. Obtain one with
+// Client.Admin; it shares the client's connection.
+type Admin struct {
+	c      *Client
+	dryRun *dryRunLog
+}
+
+// Admin returns the administration command set for this connection
+func (c *Client) Admin(opts ...AdminOption) *Admin {
+	a := &Admin{c: c}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// ACLUser is a server user as reported by ACLUsers
+type ACLUser struct {
+	Name    string
+	Enabled bool
+	Roles   []string
+}
+
+// ACLUserSpec describes a user for ACLSetUser
+type ACLUserSpec struct {
+	Name    string
+	Enabled bool
+	Roles   []string
+	// Password replaces the user's password when non-empty; an empty
+	// value leaves it unchanged. Passwords are write-only: the server
+	// reports neither them nor anything derived from them.
+	Password string
+}
+
+// ACLUsers lists all users known to the server
+func (a *Admin) ACLUsers() ([]ACLUser, error) {
+	if err := a.c.sendFrame(OpACLList, nil); err != nil {
+		return nil, err
+	}
+	resp, err := a.c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	records, err := toKeys(resp)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]ACLUser, len(records))
+	for i, rec := range records {
+		u, err := decodeACLUser([]byte(rec))
+		if err != nil {
+			return nil, fmt.Errorf("user record %d: %w", i, err)
+		}
+		users[i] = u
+	}
+	return users, nil
+}
+
+// ACLSetUser creates a user or replaces its roles and enabled state
+func (a *Admin) ACLSetUser(u ACLUserSpec) error {
+	if u.Name == "" {
+		return errors.New("user name is empty")
+	}
+	// Payload: [name][enabled u8][password][role_count u32][role...]
+	payload := appendString(nil, u.Name)
+	if u.Enabled {
+		payload = append(payload, 1)
+	} else {
+		payload = append(payload, 0)
+	}
+	payload = appendString(payload, u.Password)
+	payload = appendStrings(payload, u.Roles)
+
+	detail := fmt.Sprintf("enabled=%t roles=%v", u.Enabled, u.Roles)
+	if u.Password != "" {
+		detail += " password=changed"
+	}
+	return a.mutate(OpACLSetUser, u.Name, detail, payload)
+}
+
+// ACLDelUser deletes a user
+func (a *Admin) ACLDelUser(name string) error {
+	return a.mutate(OpACLDelUser, name, "", appendString(nil, name))
+}
+
+// ConfigGet returns the configuration parameters matching a glob pattern
+func (a *Admin) ConfigGet(pattern string) (map[string]string, error) {
+	if err := a.c.sendFrame(OpConfigGet, appendString(nil, pattern)); err != nil {
+		return nil, err
+	}
+	resp, err := a.c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	// Response: a map, or from older servers an array of alternating names
+	// and values
+	if m, ok := resp.(map[string]interface{}); ok {
+		params := make(map[string]string, len(m))
+		for name, v := range m {
+			params[name] = fmt.Sprint(v)
+		}
+		return params, nil
+	}
+	pairs, err := toKeys(resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("config reply has an odd number of elements")
+	}
+	params := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		params[pairs[i]] = pairs[i+1]
+	}
+	return params, nil
+}
+
+// ConfigSet changes a configuration parameter at runtime
+func (a *Admin) ConfigSet(name, value string) error {
+	payload := appendString(nil, name)
+	payload = appendString(payload, value)
+	return a.mutate(OpConfigSet, name, fmt.Sprintf("value=%q", value), payload)
+}
+
+// FlushDB deletes every key in the connection's current database
+func (a *Admin) FlushDB() error {
+	return a.mutate(OpFlushDB, "", "", nil)
+}
+
+func appendStrings(buf []byte, ss []string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(ss)))
+	for _, s := range ss {
+		buf = appendString(buf, s)
+	}
+	return buf
+}
+
+// decodeACLUser decodes [name][enabled u8][role_count u32][role...]
+func decodeACLUser(b []byte) (ACLUser, error) {
+	var u ACLUser
+	name, n, err := readString(b)
+	if err != nil {
+		return u, err
+	}
+	u.Name = name
+	b = b[n:]
+	if len(b) < 1 {
+		return u, errors.New("incomplete user record")
+	}
+	u.Enabled = b[0] != 0
+	b = b[1:]
+	if len(b) < 4 {
+		return u, errors.New("incomplete role count")
+	}
+	count := int(binary.BigEndian.Uint32(b))
+	b = b[4:]
+	u.Roles = make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		role, n, err := readString(b)
+		if err != nil {
+			return u, err
+		}
+		u.Roles = append(u.Roles, role)
+		b = b[n:]
+	}
+	return u, nil
+}
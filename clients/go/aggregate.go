@@ -0,0 +1,234 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ReducerOp is the function a Reducer applies to each group
+type ReducerOp uint8
+
+// Reducer functions
+const (
+	ReduceCount    ReducerOp = 0x01
+	ReduceAvgScore ReducerOp = 0x02
+	ReduceMinScore ReducerOp = 0x03
+	ReduceMaxScore ReducerOp = 0x04
+	ReduceMin      ReducerOp = 0x05
+	ReduceMax      ReducerOp = 0x06
+)
+
+// Reducer computes one value per group of an aggregation
+type Reducer struct {
+	op    ReducerOp
+	field string
+}
+
+// Count counts the vectors in each group
+func Count() Reducer { return Reducer{op: ReduceCount} }
+
+// AvgScore averages the similarity scores of each group; the aggregation
+// needs a query vector
+func AvgScore() Reducer { return Reducer{op: ReduceAvgScore} }
+
+// MinScore is the lowest similarity score of each group
+func MinScore() Reducer { return Reducer{op: ReduceMinScore} }
+
+// MaxScore is the highest similarity score of each group
+func MaxScore() Reducer { return Reducer{op: ReduceMaxScore} }
+
+// Min is the smallest value of a numeric metadata field in each group
+func Min(field string) Reducer { return Reducer{op: ReduceMin, field: field} }
+
+// Max is the largest value of a numeric metadata field in each group
+func Max(field string) Reducer { return Reducer{op: ReduceMax, field: field} }
+
+// String formats the reducer, e.g. "count" or "max(price)"
+func (r Reducer) String() string {
+	switch r.op {
+	case ReduceCount:
+		return "count"
+	case ReduceAvgScore:
+		return "avg_score"
+	case ReduceMinScore:
+		return "min_score"
+	case ReduceMaxScore:
+		return "max_score"
+	case ReduceMin:
+		return "min(" + r.field + ")"
+	case ReduceMax:
+		return "max(" + r.field + ")"
+	default:
+		return fmt.Sprintf("Reducer(%d)", uint8(r.op))
+	}
+}
+
+func (r Reducer) usesScore() bool {
+	return r.op == ReduceAvgScore || r.op == ReduceMinScore || r.op == ReduceMaxScore
+}
+
+// AggregateGroup is one group of an aggregation result
+type AggregateGroup struct {
+	// Key is the groupBy field value shared by the group's vectors
+	Key MetaValue
+	// Values holds one result per reducer, in the order they were given
+	Values []float64
+}
+
+// AggregateQuery groups the vectors of a collection by a metadata field and
+// reduces each group. Build one with Client.Aggregate:
+//
+//	groups, err := client.Aggregate("docs", "lang", celrix.Count(), celrix.AvgScore()).
+//		Filter(celrix.F[bool]("published").Eq(true)).
+//		Near(v, 1000).
+//		Run()
+//
+// Without Near every vector matching the filter is aggregated; with it only
+// the k nearest to the query vector are, which score reducers require.
+type AggregateQuery struct {
+	c          *Client
+	collection string
+	groupBy    string
+	reducers   []Reducer
+	filter     *Filter
+	vector     []float32
+	k          int
+}
+
+// Aggregate starts an aggregation over a collection
+func (c *Client) Aggregate(collection, groupBy string, reducers ...Reducer) *AggregateQuery {
+	return &AggregateQuery{c: c, collection: collection, groupBy: groupBy, reducers: reducers}
+}
+
+// Filter restricts the aggregation to vectors whose metadata matches f
+func (q *AggregateQuery) Filter(f Filter) *AggregateQuery {
+	q.filter = &f
+	return q
+}
+
+// Near restricts the aggregation to the k vectors nearest to vector and
+// makes their scores available to reducers
+func (q *AggregateQuery) Near(vector []float32, k int) *AggregateQuery {
+	q.vector, q.k = vector, k
+	return q
+}
+
+func (q *AggregateQuery) validate(schema Schema) error {
+	invalid := func(field, reason string) error {
+		return &ValidationError{Collection: q.collection, Field: field, Reason: reason}
+	}
+	if len(q.reducers) == 0 {
+		return invalid("", "aggregation has no reducers")
+	}
+	if len(q.reducers) > math.MaxUint8 {
+		return invalid("", fmt.Sprintf("aggregation has %d reducers, at most %d are allowed", len(q.reducers), math.MaxUint8))
+	}
+	if _, ok := schema.Fields[q.groupBy]; !ok {
+		return invalid(q.groupBy, "field not defined in schema")
+	}
+	for _, r := range q.reducers {
+		switch {
+		case r.usesScore() && q.vector == nil:
+			return invalid("", fmt.Sprintf("reducer %s needs a query vector", r))
+		case r.op == ReduceMin || r.op == ReduceMax:
+			typ, ok := schema.Fields[r.field]
+			if !ok {
+				return invalid(r.field, "field not defined in schema")
+			}
+			if typ != MetaInt && typ != MetaFloat && typ != MetaTime {
+				return invalid(r.field, fmt.Sprintf("reducer %s needs a numeric field, not %s", r, typ))
+			}
+		case r.op < ReduceCount || r.op > ReduceMax:
+			return invalid("", fmt.Sprintf("unknown reducer %s", r))
+		}
+	}
+	if q.vector != nil {
+		if schema.Dims > 0 && len(q.vector) != schema.Dims {
+			return invalid("", fmt.Sprintf("query has %d dims, schema expects %d", len(q.vector), schema.Dims))
+		}
+		if q.k <= 0 {
+			return invalid("", fmt.Sprintf("k must be positive, got %d", q.k))
+		}
+	}
+	if q.filter != nil {
+		if err := q.filter.Validate(schema.Fields); err != nil {
+			return invalid("", err.Error())
+		}
+	}
+	return nil
+}
+
+// Run executes the aggregation. Groups are returned in the server's order,
+// which is by descending count.
+func (q *AggregateQuery) Run() ([]AggregateGroup, error) {
+	schema, err := q.c.Collection(q.collection).Schema()
+	if err != nil {
+		return nil, err
+	}
+	if err := q.validate(schema); err != nil {
+		return nil, err
+	}
+	if err := q.c.checkVector(OpAggregate, "", q.vector); err != nil {
+		return nil, err
+	}
+
+	// Payload: [coll][group_by][reducer_count u8]([op u8][field])...
+	//          [has_filter u8][filter][has_vector u8][count][f32...][k u32]
+	payload := appendString(nil, q.collection)
+	payload = appendString(payload, q.groupBy)
+	payload = append(payload, uint8(len(q.reducers)))
+	for _, r := range q.reducers {
+		payload = appendString(append(payload, uint8(r.op)), r.field)
+	}
+	if q.filter == nil {
+		payload = append(payload, 0)
+	} else if payload, err = q.filter.appendTo(append(payload, 1)); err != nil {
+		return nil, err
+	}
+	if q.vector == nil {
+		payload = append(payload, 0)
+	} else {
+		payload = appendVector(append(payload, 1), q.vector)
+		payload = binary.BigEndian.AppendUint32(payload, uint32(q.k))
+	}
+
+	q.c.nextCollection = q.collection
+	if err := q.c.sendFrame(OpAggregate, payload); err != nil {
+		return nil, err
+	}
+	resp, err := q.c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	// Response: array of [group key value][f64 per reducer]
+	records, err := toKeys(resp)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]AggregateGroup, len(records))
+	for i, rec := range records {
+		if groups[i], err = q.decodeGroup([]byte(rec)); err != nil {
+			return nil, fmt.Errorf("aggregate group %d: %w", i, err)
+		}
+	}
+	return groups, nil
+}
+
+func (q *AggregateQuery) decodeGroup(b []byte) (AggregateGroup, error) {
+	key, n, err := decodeMetaValue(b)
+	if err != nil {
+		return AggregateGroup{}, err
+	}
+	b = b[n:]
+	if len(b) != 8*len(q.reducers) {
+		return AggregateGroup{}, errors.New("group has the wrong number of values")
+	}
+	g := AggregateGroup{Key: key, Values: make([]float64, len(q.reducers))}
+	for i := range g.Values {
+		g.Values[i] = math.Float64frombits(binary.BigEndian.Uint64(b[8*i:]))
+	}
+	return g, nil
+}
@@ -0,0 +1,73 @@
+package celrix
+
+import "errors"
+
+// CreateAlias points alias at an existing collection. Operations through
+// Collection(alias) are resolved by the server to the target collection.
+func (c *Client) CreateAlias(alias, collection string) error {
+	return c.aliasOp(OpCreateAlias, alias, collection)
+}
+
+// SwapAlias atomically repoints alias at a different collection, so readers
+// switch from the old index to the new one without seeing a partial state
+func (c *Client) SwapAlias(alias, collection string) error {
+	return c.aliasOp(OpSwapAlias, alias, collection)
+}
+
+// RefreshAlias drops the schema cached for alias and fetches that of the
+// collection it now points to. The cache follows SwapAlias made by this
+// client, and validation through Collection refetches a schema that
+// rejects a write or search; RefreshAlias picks up a swap made elsewhere
+// eagerly, so that a stale schema cannot accept what the new collection
+// would reject.
+func (c *Client) RefreshAlias(alias string) (Schema, error) {
+	if alias == "" {
+		return Schema{}, errors.New("alias name is empty")
+	}
+	c.schemas.remove(alias)
+	return c.DescribeCollection(alias)
+}
+
+// DropAlias removes an alias without affecting the collection it points to
+func (c *Client) DropAlias(alias string) error {
+	if alias == "" {
+		return errors.New("alias name is empty")
+	}
+	if err := c.sendFrame(OpDropAlias, appendString(nil, alias)); err != nil {
+		return err
+	}
+	if err := c.expectOK(); err != nil {
+		return err
+	}
+	c.schemas.remove(alias)
+	return nil
+}
+
+func (c *Client) aliasOp(opcode uint8, alias, collection string) error {
+	if alias == "" {
+		return errors.New("alias name is empty")
+	}
+	if collection == "" {
+		return errors.New("collection name is empty")
+	}
+
+	// Payload: [alias_len][alias][coll_len][coll]
+	payload := appendString(nil, alias)
+	payload = appendString(payload, collection)
+
+	if err := c.sendFrame(opcode, payload); err != nil {
+		return err
+	}
+	if err := c.expectOK(); err != nil {
+		return err
+	}
+
+	// The alias now resolves to collection; reuse its schema if known so
+	// validation through the alias follows the flip
+	if s, ok := c.schemas.get(collection); ok {
+		c.schemas.put(alias, s)
+	} else {
+		c.schemas.remove(alias)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// WithBatchArena decodes the values of batch reads such as GetOrNilFast
+// out of a single buffer per batch, so a thousand-key read costs a handful
+// of allocations instead of one or more per key. The trade-off is
+// retention: the buffer, holding every value of the batch, stays live for
+// as long as any one of its strings does. Callers that keep only a few
+// values from large batches should copy them, or leave this off.
+//
+// Batches that fall back to one pipelined reply per key still read a
+// frame per key; WithZeroCopyReads removes those allocations too.
+func WithBatchArena() Option {
+	return func(o *options) {
+		o.batchArena = true
+	}
+}
+
+// batchArena accumulates the values of a batch and hands them back as
+// strings sharing one backing buffer
+type batchArena struct {
+	buf []byte
+	// spans holds the [start, end) of each value in buf; nils have start -1
+	spans [][2]int
+}
+
+func newBatchArena(n int) *batchArena {
+	return &batchArena{spans: make([][2]int, 0, n)}
+}
+
+func (a *batchArena) add(b []byte) {
+	a.spans = append(a.spans, [2]int{len(a.buf), len(a.buf) + len(b)})
+	a.buf = append(a.buf, b...)
+}
+
+func (a *batchArena) addNil() {
+	a.spans = append(a.spans, [2]int{-1, -1})
+}
+
+// values converts the batch to strings with one copy of the buffer
+func (a *batchArena) values() []*string {
+	return arenaValues(string(a.buf), a.spans)
+}
+
+// arenaValues slices base into the values described by spans. The strings
+// and the pointers to them come from two allocations in total.
+func arenaValues(base string, spans [][2]int) []*string {
+	strs := make([]string, len(spans))
+	vals := make([]*string, len(spans))
+	for i, sp := range spans {
+		if sp[0] < 0 {
+			continue
+		}
+		strs[i] = base[sp[0]:sp[1]]
+		vals[i] = &strs[i]
+	}
+	return vals
+}
+
+// decodeValuesArena decodes a typed array of value and nil elements, as
+// sent for MGET, referencing the values within payload rather than
+// allocating each. An error element is returned with its index.
+func decodeValuesArena(payload []byte) ([]*string, int, error) {
+	if len(payload) < 4 {
+		return nil, -1, errors.New("incomplete typed array")
+	}
+	count := int(binary.BigEndian.Uint32(payload))
+	if count > (len(payload)-4)/5 {
+		return nil, -1, errors.New("typed array count exceeds payload")
+	}
+	spans := make([][2]int, count)
+	off := 4
+	for i := range spans {
+		if len(payload)-off < 5 {
+			return nil, -1, errors.New("incomplete typed array element")
+		}
+		op, n := payload[off], int(binary.BigEndian.Uint32(payload[off+1:]))
+		off += 5
+		if len(payload)-off < n {
+			return nil, -1, errors.New("incomplete typed array element")
+		}
+		switch op {
+		case OpValue:
+			spans[i] = [2]int{off, off + n}
+		case OpNil:
+			spans[i] = [2]int{-1, -1}
+		case OpError:
+			return nil, i, &ServerError{Message: string(payload[off : off+n])}
+		default:
+			return nil, -1, fmt.Errorf("value %d: unexpected opcode %s", i, Op(op))
+		}
+		off += n
+	}
+	return arenaValues(string(payload), spans), -1, nil
+}
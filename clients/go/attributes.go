@@ -0,0 +1,89 @@
+package celrix
+
+import "time"
+
+// AttrServerDuration is the reply attribute holding the server's execution
+// time for the command, in microseconds
+const AttrServerDuration = "server_us"
+
+// WithServerTiming asks the server to report how long it spent executing
+// each command, exposed per reply by Reply.ServerDuration and in aggregate
+// by Client.ServerLatency. Servers that do not support CapServerTiming
+// ignore the request.
+func WithServerTiming() Option {
+	return func(o *options) {
+		o.serverTiming = true
+	}
+}
+
+// ServerDuration returns the time the server spent executing the command,
+// if it reported it. The difference from the client-observed latency is
+// network and queueing time.
+func (r Reply) ServerDuration() (time.Duration, bool) {
+	return serverDuration(r.attrs)
+}
+
+func serverDuration(attrs map[string]Reply) (time.Duration, bool) {
+	us, ok := attrs[AttrServerDuration].Int()
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(us) * time.Microsecond, true
+}
+
+// LastServerDuration is ServerDuration for the most recent reply read on
+// this connection
+func (c *Client) LastServerDuration() (time.Duration, bool) {
+	return serverDuration(c.lastAttrs)
+}
+
+// ServerLatency returns the p-th percentile (0 < p <= 100) of server
+// execution time reported for op, and the number of samples behind it.
+// Samples are only collected with WithServerTiming.
+func (c *Client) ServerLatency(op Op, p float64) (time.Duration, uint64) {
+	return c.serverLatency.percentile(uint8(op), p)
+}
+
+// Attributes returns the attributes the server sent ahead of this reply,
+// such as timing, cache hits or the serving shard, or nil if it sent none.
+// Attributes never change the reply itself.
+func (r Reply) Attributes() map[string]Reply { return r.attrs }
+
+// LastAttributes returns the attributes sent with the most recent reply
+// read on this connection, for commands that return Go values rather than
+// a Reply
+func (c *Client) LastAttributes() map[string]Reply { return c.lastAttrs }
+
+// withAttributes attaches the attributes of the reply just read
+func (c *Client) withAttributes(r Reply) Reply {
+	r.attrs = c.lastAttrs
+	return r
+}
+
+// absorbAttributes handles an attributes frame, which precedes the reply
+// to the same request and carries a map payload. It reports whether f was
+// one.
+func (c *Client) absorbAttributes(f frame) (bool, error) {
+	if f.opcode != OpAttributes {
+		c.lastAttrs = nil
+		if c.nextAttrs != nil && c.nextAttrsReq == f.reqID {
+			c.lastAttrs = c.nextAttrs
+			if d, ok := serverDuration(c.lastAttrs); ok {
+				c.serverLatency.observe(c.pendingOp, d)
+				if c.otlp != nil {
+					c.otlp.serverLatency.observe(c.pendingOp, d)
+				}
+			}
+		}
+		c.nextAttrs = nil
+		return false, nil
+	}
+	// Copy the payload: decoded values alias it, and fast paths reuse
+	// their read buffer for the reply
+	m, err := decodeMap(append([]byte(nil), f.payload...))
+	if err != nil {
+		return true, err
+	}
+	c.nextAttrs, c.nextAttrsReq = m.m, f.reqID
+	return true, nil
+}
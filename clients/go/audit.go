@@ -0,0 +1,174 @@
+package celrix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// ErrAuditFailed is wrapped by errors from admin operations that completed
+// on the server but could not be recorded in the audit trail
+var ErrAuditFailed = errors.New("celrix: audit record not written")
+
+// AuditRecord describes one administrative change: who made it, what it
+// was, when, and how it ended
+type AuditRecord struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Server string    `json:"server"`
+	Op     string    `json:"op"`
+	// Target is the user, parameter or other object acted on
+	Target string `json:"target,omitempty"`
+	// Detail summarises the change. Secrets such as passwords are never
+	// included.
+	Detail string `json:"detail,omitempty"`
+	// Error is empty if the server accepted the change
+	Error string `json:"error,omitempty"`
+}
+
+// AuditWriter receives audit records. Implementations must be safe for
+// concurrent use.
+type AuditWriter interface {
+	WriteAudit(AuditRecord) error
+}
+
+// AuditOptions configures WithAudit
+type AuditOptions struct {
+	// Writer receives every record. Required.
+	Writer AuditWriter
+	// Actor identifies who is acting. Defaults to user@host of the
+	// current process.
+	Actor string
+	// Server also sends each record to the server's audit stream. Servers
+	// without one reject the first record; server auditing is then
+	// switched off for the client and the local trail is unaffected.
+	Server bool
+}
+
+// WithAudit records every state-changing Admin operation. Records are
+// written after the server replies, whether or not it accepted the change.
+// If the local writer fails, the operation's error wraps ErrAuditFailed.
+func WithAudit(opts AuditOptions) Option {
+	return func(o *options) {
+		if opts.Writer == nil {
+			return
+		}
+		if opts.Actor == "" {
+			opts.Actor = defaultActor()
+		}
+		o.audit = &auditor{opts: opts, server: opts.Server}
+	}
+}
+
+type auditor struct {
+	opts AuditOptions
+
+	mu     sync.Mutex
+	server bool
+}
+
+func defaultActor() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		name += "@" + host
+	}
+	return name
+}
+
+// JSONAuditWriter appends records to w as JSON lines
+type JSONAuditWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONAuditWriter returns an AuditWriter producing one JSON object per
+// line. For an append-only file, open it with os.O_APPEND.
+func NewJSONAuditWriter(w io.Writer) *JSONAuditWriter {
+	return &JSONAuditWriter{enc: json.NewEncoder(w)}
+}
+
+// WriteAudit appends rec
+func (w *JSONAuditWriter) WriteAudit(rec AuditRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(rec)
+}
+
+// record writes the audit record for an operation that finished with opErr
+// and returns opErr, annotated if the record could not be written
+func (a *Admin) record(op uint8, target, detail string, opErr error) error {
+	au := a.c.opts.audit
+	if au == nil {
+		return opErr
+	}
+	rec := AuditRecord{
+		Time:   a.c.opts.now().UTC(),
+		Actor:  au.opts.Actor,
+		Server: a.c.addr,
+		Op:     Op(op).String(),
+		Target: target,
+		Detail: detail,
+	}
+	if opErr != nil {
+		rec.Error = opErr.Error()
+	}
+
+	if err := au.opts.Writer.WriteAudit(rec); err != nil {
+		err = fmt.Errorf("%w: %v", ErrAuditFailed, err)
+		if opErr != nil {
+			return errors.Join(opErr, err)
+		}
+		return err
+	}
+	if au.sendToServer() {
+		a.sendAudit(au, rec)
+	}
+	return opErr
+}
+
+func (au *auditor) sendToServer() bool {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+	return au.server
+}
+
+// sendAudit forwards rec to the server's audit stream. Failures are not
+// returned: the local record is authoritative, and a server that lacks the
+// command is not asked again.
+func (a *Admin) sendAudit(au *auditor, rec AuditRecord) {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	err = a.c.sendFrame(OpAuditLog, body)
+	if err == nil {
+		err = a.c.expectOK()
+	}
+	if isServerError(err) {
+		au.mu.Lock()
+		au.server = false
+		au.mu.Unlock()
+	}
+}
+
+// mutate sends a state-changing admin command, expects OK, and audits it.
+// In dry-run mode the command is simulated instead and not audited, since
+// nothing changes.
+func (a *Admin) mutate(op uint8, target, detail string, payload []byte) error {
+	if a.dryRun != nil {
+		return a.simulate(op, target, payload)
+	}
+	err := a.c.sendFrame(op, payload)
+	if err == nil {
+		err = a.c.expectOK()
+	}
+	return a.record(op, target, detail, err)
+}
@@ -0,0 +1,93 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// BFReserve creates an empty Bloom filter at key sized for capacity items
+// at the given false positive rate, e.g. 0.01 for 1%. Adding to a missing
+// key creates a filter with the server's default sizing instead.
+func (c *Client) BFReserve(key string, errorRate float64, capacity int64) error {
+	if !(errorRate > 0 && errorRate < 1) {
+		return fmt.Errorf("error rate must be between 0 and 1, got %v", errorRate)
+	}
+	if capacity <= 0 {
+		return fmt.Errorf("capacity must be positive, got %d", capacity)
+	}
+	// Payload: [key][error_rate f64][capacity u64]
+	payload := appendString(nil, key)
+	payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(errorRate))
+	payload = binary.BigEndian.AppendUint64(payload, uint64(capacity))
+	return c.write(OpBFReserve, key, payload)
+}
+
+// BFAdd adds item to the Bloom filter at key and reports whether it was
+// new. A false result means the item was, or collides with, one already
+// added.
+func (c *Client) BFAdd(key, item string) (bool, error) {
+	return c.writeBool(OpBFAdd, key, appendString(appendString(nil, key), item))
+}
+
+// BFExists reports whether item may have been added to the Bloom filter at
+// key. False positives occur at the filter's error rate; false negatives
+// never do.
+func (c *Client) BFExists(key, item string) (bool, error) {
+	if err := c.sendKeyed(OpBFExists, key, appendString(appendString(nil, key), item)); err != nil {
+		return false, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return false, err
+	}
+	return c.toBool(resp)
+}
+
+// BFMAdd is BFAdd for several items in one round trip, returning one
+// result per item
+func (c *Client) BFMAdd(key string, items ...string) ([]bool, error) {
+	return c.bfMulti(OpBFMAdd, key, items)
+}
+
+// BFMExists is BFExists for several items in one round trip, returning one
+// result per item
+func (c *Client) BFMExists(key string, items ...string) ([]bool, error) {
+	return c.bfMulti(OpBFMExists, key, items)
+}
+
+func (c *Client) bfMulti(opcode uint8, key string, items []string) ([]bool, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	// Payload: [key][count u32][item...]
+	payload := appendStrings(appendString(nil, key), items)
+	if err := c.sendKeyed(opcode, key, payload); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	return toBools(resp, len(items))
+}
+
+// toBools converts an array of yes/no replies holding n elements
+func toBools(resp interface{}, n int) ([]bool, error) {
+	arr, ok := resp.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected array response, got %T", resp)
+	}
+	if len(arr) != n {
+		return nil, fmt.Errorf("expected %d results, got %d", n, len(arr))
+	}
+	out := make([]bool, n)
+	for i, v := range arr {
+		b, ok := yesNo(v)
+		if !ok {
+			return nil, fmt.Errorf("result %d: unexpected type %T", i, v)
+		}
+		out[i] = b
+	}
+	return out, nil
+}
@@ -0,0 +1,128 @@
+package celrix
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithBudget caps the rate at which the client sends commands and request
+// bytes, so that a runaway loop cannot saturate a shared server. Commands
+// over budget are delayed, not rejected. Either limit may be zero to leave
+// it unbounded. Each limit allows a burst of one second's worth.
+func WithBudget(maxCommandsPerSecond, maxBytesPerSecond float64) Option {
+	return func(o *options) {
+		o.budget = &budget{}
+		if maxCommandsPerSecond > 0 {
+			o.budget.commands = &tokenBucket{rate: maxCommandsPerSecond}
+		}
+		if maxBytesPerSecond > 0 {
+			o.budget.bytes = &tokenBucket{rate: maxBytesPerSecond}
+		}
+	}
+}
+
+// ClientStats is a snapshot of a client's traffic and budget use
+type ClientStats struct {
+	// Commands and BytesSent count requests and their payload bytes
+	Commands  uint64
+	BytesSent uint64
+	// Throttled counts commands delayed by WithBudget, and ThrottledFor
+	// the total delay
+	Throttled    uint64
+	ThrottledFor time.Duration
+	// Desyncs counts connections quarantined after a DesyncError
+	Desyncs uint64
+	// CommandTokens and ByteTokens are the budget left in each bucket; they
+	// are negative while paying off a burst and zero without WithBudget
+	CommandTokens float64
+	ByteTokens    float64
+}
+
+// Stats returns a snapshot of the client's traffic counters. It is safe to
+// call concurrently with commands.
+func (c *Client) Stats() ClientStats {
+	s := ClientStats{Commands: c.sentCommands.Load(), BytesSent: c.sentBytes.Load(), Desyncs: c.desyncs.Load()}
+	if b := c.opts.budget; b != nil {
+		b.mu.Lock()
+		now := c.opts.now()
+		s.CommandTokens = b.commands.available(now)
+		s.ByteTokens = b.bytes.available(now)
+		b.mu.Unlock()
+		s.Throttled = b.throttled.Load()
+		s.ThrottledFor = time.Duration(b.waited.Load())
+	}
+	return s
+}
+
+type budget struct {
+	mu       sync.Mutex
+	commands *tokenBucket
+	bytes    *tokenBucket
+
+	throttled atomic.Uint64
+	waited    atomic.Int64
+}
+
+// tokenBucket refills at rate tokens per second up to one second's worth.
+// Takes may drive it negative; the debt is the wait before the next take.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func (tb *tokenBucket) refill(now time.Time) {
+	if tb.last.IsZero() {
+		tb.tokens, tb.last = tb.rate, now
+		return
+	}
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.rate {
+		tb.tokens = tb.rate
+	}
+	tb.last = now
+}
+
+// take spends n tokens and returns how long to wait until the bucket is
+// out of debt
+func (tb *tokenBucket) take(now time.Time, n float64) time.Duration {
+	if tb == nil {
+		return 0
+	}
+	tb.refill(now)
+	tb.tokens -= n
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+func (tb *tokenBucket) available(now time.Time) float64 {
+	if tb == nil {
+		return 0
+	}
+	tb.refill(now)
+	return tb.tokens
+}
+
+// spend accounts one command of n payload bytes, waiting if it is over
+// budget
+func (c *Client) spend(n int) {
+	b := c.opts.budget
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	now := c.opts.now()
+	wait := b.commands.take(now, 1)
+	if w := b.bytes.take(now, float64(n)); w > wait {
+		wait = w
+	}
+	b.mu.Unlock()
+	if wait > 0 {
+		b.throttled.Add(1)
+		b.waited.Add(int64(wait))
+		<-c.opts.timer(wait).C()
+	}
+}
@@ -0,0 +1,24 @@
+package celrix
+
+// SetBytes is Set for a binary value. The value is encoded straight from
+// the slice, without the copy a string conversion would make.
+func (c *Client) SetBytes(key string, value []byte) error {
+	opcode, payload, err := c.setPayload(key, value, 0)
+	if err != nil {
+		return err
+	}
+	return c.write(opcode, key, payload)
+}
+
+// GetBytes is Get for a binary value. The returned slice is the reply's own
+// buffer, so it is not copied into a string, and belongs to the caller.
+func (c *Client) GetBytes(key string) ([]byte, bool, error) {
+	if err := c.sendKeyed(OpGet, key, appendString(nil, key)); err != nil {
+		return nil, false, err
+	}
+	f, err := c.recvFrame()
+	if err != nil {
+		return nil, false, err
+	}
+	return c.fastValue(f)
+}
@@ -0,0 +1,124 @@
+package celrix
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"time"
+)
+
+// CapturedExchange is a request and the first frame of its reply, as
+// recorded by WithSampledCapture
+type CapturedExchange struct {
+	Request  RecordedFrame
+	Response RecordedFrame
+	// Key is the redacted key of keyed commands
+	Key      string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// FrameSink receives exchanges sampled by WithSampledCapture. Capture is
+// called on the goroutine that read the reply and must not block.
+type FrameSink interface {
+	Capture(CapturedExchange)
+}
+
+// FrameSinkFunc adapts a function to FrameSink
+type FrameSinkFunc func(CapturedExchange)
+
+// Capture calls f
+func (f FrameSinkFunc) Capture(x CapturedExchange) { f(x) }
+
+// Capture records both frames of a sampled exchange, so a Recorder can
+// serve as a FrameSink
+func (r *Recorder) Capture(x CapturedExchange) {
+	r.record(true, x.Request.Version, x.Request.Opcode, x.Request.Flags, x.Request.ReqID, x.Request.Payload)
+	r.record(false, x.Response.Version, x.Response.Opcode, x.Response.Flags, x.Response.ReqID, x.Response.Payload)
+}
+
+// WithSampledCapture records a random fraction rate, between 0 and 1, of
+// commands as full request and reply frames and hands them to sink, for
+// catching intermittent protocol faults in production at a bounded cost.
+// Pipelined requests, such as those of ForEach, are not sampled.
+//
+// Captures are redacted: keys are passed through the function given with
+// WithKeyRedaction, or RedactKey without one, both in Key and where they
+// lead the request payload; the rest of a keyed request's payload and the
+// payload of value replies are masked with zeros. Opcodes, flags, request
+// IDs and every length other than the key's are kept, which is what
+// framing bugs show up in.
+func WithSampledCapture(rate float64, sink FrameSink) Option {
+	return func(o *options) {
+		o.capture = &sampledCapture{rate: rate, sink: sink}
+	}
+}
+
+type sampledCapture struct {
+	rate float64
+	sink FrameSink
+}
+
+func (s *sampledCapture) sample() bool {
+	return s.rate >= 1 || (s.rate > 0 && rand.Float64() < s.rate)
+}
+
+// pendingCapture is a sampled request awaiting its reply
+type pendingCapture struct {
+	req   RecordedFrame
+	key   string
+	start time.Time
+}
+
+// captureRequest samples the request about to be sent
+func (c *Client) captureRequest(opcode uint8, payload []byte) {
+	c.captured = nil
+	if !c.opts.capture.sample() {
+		return
+	}
+	pc := &pendingCapture{
+		req:   RecordedFrame{Outgoing: true, Version: c.layout.Version(), Opcode: opcode, ReqID: c.pendingReqID},
+		start: c.opts.now(),
+	}
+	key := c.pendingKey
+	if key == "" {
+		pc.req.Payload = append([]byte(nil), payload...)
+		c.captured = pc
+		return
+	}
+	redact := c.opts.redactKey
+	if redact == nil {
+		redact = RedactKey
+	}
+	pc.key = redact(key)
+	lead := appendString(nil, key)
+	if bytes.HasPrefix(payload, lead) {
+		pc.req.Payload = appendString(nil, pc.key)
+		pc.req.Payload = append(pc.req.Payload, make([]byte, len(payload)-len(lead))...)
+	} else {
+		pc.req.Payload = make([]byte, len(payload))
+	}
+	c.captured = pc
+}
+
+// captureReply completes the sampled exchange f answers
+func (c *Client) captureReply(f frame) {
+	pc := c.captured
+	if pc.req.ReqID != f.reqID {
+		return
+	}
+	c.captured = nil
+	resp := RecordedFrame{Version: c.layout.Version(), Opcode: f.opcode, Flags: f.flags, ReqID: f.reqID}
+	switch f.opcode {
+	case OpValue, OpTypedString:
+		resp.Payload = make([]byte, len(f.payload))
+	default:
+		resp.Payload = append([]byte(nil), f.payload...)
+	}
+	c.opts.capture.sink.Capture(CapturedExchange{
+		Request:  pc.req,
+		Response: resp,
+		Key:      pc.key,
+		Start:    pc.start,
+		Duration: c.opts.now().Sub(pc.start),
+	})
+}
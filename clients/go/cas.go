@@ -0,0 +1,71 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// SetNX sets key to value only if key does not exist, with an optional TTL
+// (zero for none), and reports whether it was set
+func (c *Client) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	if err := c.checkValue(OpSetNX, key, len(value)); err != nil {
+		return false, err
+	}
+	payload := encodeSet(key, []byte(value), ttl)
+	return c.writeBool(OpSetNX, key, payload)
+}
+
+// CompareAndSwap sets key to value only if it currently holds old, and
+// reports whether it did. The TTL is replaced by ttl, or cleared if zero,
+// so holders of a lease can renew it with old == value.
+func (c *Client) CompareAndSwap(key, old, value string, ttl time.Duration) (bool, error) {
+	if err := c.checkValue(OpCompareAndSwap, key, len(value)); err != nil {
+		return false, err
+	}
+	// Payload: [key][old][val_len][val][ttl]
+	payload := appendString(nil, key)
+	payload = appendString(payload, old)
+	payload = appendString(payload, value)
+	payload = binary.BigEndian.AppendUint64(payload, ttlSeconds(ttl))
+	return c.writeBool(OpCompareAndSwap, key, payload)
+}
+
+// CompareAndDelete deletes key only if it holds old, and reports whether
+// it did
+func (c *Client) CompareAndDelete(key, old string) (bool, error) {
+	payload := appendString(appendString(nil, key), old)
+	return c.writeBool(OpCompareAndDelete, key, payload)
+}
+
+// IncrBy atomically adds delta to the integer stored at key, treating a
+// missing key as 0, and returns the new value
+func (c *Client) IncrBy(key string, delta int64) (int64, error) {
+	// Payload: [key][delta i64]
+	payload := binary.BigEndian.AppendUint64(appendString(nil, key), uint64(delta))
+	if err := c.sendKeyed(OpIncrBy, key, payload); err != nil {
+		return 0, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := resp.(int64)
+	if !ok {
+		return 0, c.unexpectedReply()
+	}
+	return n, nil
+}
+
+// writeBool sends a conditional write and decodes its yes/no reply.
+// Conditional writes are never journaled: replaying them later could
+// succeed against state the caller never saw.
+func (c *Client) writeBool(opcode uint8, key string, payload []byte) (bool, error) {
+	if err := c.sendKeyed(opcode, key, payload); err != nil {
+		return false, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return false, err
+	}
+	return c.toBool(resp)
+}
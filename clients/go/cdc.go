@@ -0,0 +1,173 @@
+package celrix
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ChangeKind identifies the mutation carried by a ChangeEvent
+type ChangeKind uint8
+
+// Change kinds
+const (
+	ChangeSet  ChangeKind = 0x01
+	ChangeDel  ChangeKind = 0x02
+	ChangeVAdd ChangeKind = 0x03
+)
+
+// String returns the change kind name
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeSet:
+		return "set"
+	case ChangeDel:
+		return "del"
+	case ChangeVAdd:
+		return "vadd"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", uint8(k))
+	}
+}
+
+// ChangeEvent is a single mutation read from the server's change stream
+type ChangeEvent struct {
+	Seq       uint64
+	Kind      ChangeKind
+	Timestamp time.Time
+	Key       string
+
+	// Set
+	Value []byte
+	TTL   time.Duration
+
+	// VAdd
+	Vector   []float32
+	Metadata Metadata
+
+	// Err is set on the final event delivered before the channel closes
+	// when the stream ended abnormally
+	Err error
+}
+
+const cdcBufferSize = 256
+
+// CDC subscribes to the server's change stream starting at fromSeq
+// (inclusive; 0 starts from the oldest retained change). Events are read on a
+// dedicated connection so the client stays usable for other commands. The
+// channel is closed when ctx is cancelled or the stream fails, in which case
+// the last event delivered carries Err.
+func (c *Client) CDC(ctx context.Context, fromSeq uint64) (<-chan ChangeEvent, error) {
+	sub, err := c.dialDedicated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn := sub.conn
+
+	payload := binary.BigEndian.AppendUint64(nil, fromSeq)
+	if err := sub.sendFrame(OpCDCSubscribe, payload); err != nil {
+		sub.closeDedicated()
+		return nil, err
+	}
+	if err := sub.expectOK(); err != nil {
+		sub.closeDedicated()
+		return nil, err
+	}
+
+	events := make(chan ChangeEvent, cdcBufferSize)
+	stop := closeOnDone(ctx, conn)
+	go func() {
+		defer close(events)
+		defer stop()
+		defer sub.closeDedicated()
+		err := sub.supervised("cdc reader", func() error {
+			for {
+				ev, err := sub.readChangeEvent()
+				if err != nil {
+					return err
+				}
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			select {
+			case events <- ChangeEvent{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (c *Client) readChangeEvent() (ChangeEvent, error) {
+	f, err := c.recvFrame()
+	if err != nil {
+		return ChangeEvent{}, err
+	}
+	if f.opcode != OpChangeEvent {
+		if _, err := decodeResponse(f.opcode, f.payload); err != nil {
+			return ChangeEvent{}, err
+		}
+		return ChangeEvent{}, fmt.Errorf("unexpected opcode in change stream: %d", f.opcode)
+	}
+	return decodeChangeEvent(f.payload)
+}
+
+// decodeChangeEvent decodes [seq: u64][kind: u8][ts: i64][key_len][key] followed by
+// set:  [val_len][val][ttl: u64]
+// vadd: [count][f32...][metadata]
+func decodeChangeEvent(b []byte) (ChangeEvent, error) {
+	if len(b) < 17 {
+		return ChangeEvent{}, errors.New("incomplete change event")
+	}
+	ev := ChangeEvent{
+		Seq:       binary.BigEndian.Uint64(b[0:]),
+		Kind:      ChangeKind(b[8]),
+		Timestamp: time.Unix(0, int64(binary.BigEndian.Uint64(b[9:]))).UTC(),
+	}
+	offset := 17
+	key, n, err := readString(b[offset:])
+	if err != nil {
+		return ChangeEvent{}, fmt.Errorf("change event key: %w", err)
+	}
+	ev.Key = key
+	offset += n
+
+	switch ev.Kind {
+	case ChangeSet:
+		val, n, err := readString(b[offset:])
+		if err != nil {
+			return ChangeEvent{}, fmt.Errorf("change event value: %w", err)
+		}
+		ev.Value = []byte(val)
+		offset += n
+		if offset+8 > len(b) {
+			return ChangeEvent{}, errors.New("incomplete change event TTL")
+		}
+		ev.TTL = time.Duration(binary.BigEndian.Uint64(b[offset:])) * time.Second
+	case ChangeDel:
+	case ChangeVAdd:
+		vec, n, err := readVector(b[offset:])
+		if err != nil {
+			return ChangeEvent{}, fmt.Errorf("change event vector: %w", err)
+		}
+		ev.Vector = vec
+		offset += n
+		if offset < len(b) {
+			meta, _, err := decodeMetadata(b[offset:])
+			if err != nil {
+				return ChangeEvent{}, fmt.Errorf("change event metadata: %w", err)
+			}
+			ev.Metadata = meta
+		}
+	default:
+		return ChangeEvent{}, fmt.Errorf("unknown change kind: %d", ev.Kind)
+	}
+	return ev, nil
+}
@@ -0,0 +1,388 @@
+package celrixadmin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// Kind is the type of resource a change applies to
+type Kind uint8
+
+// Resource kinds
+const (
+	KindCollection Kind = iota + 1
+	KindUser
+	KindConfig
+)
+
+// String returns the kind name
+func (k Kind) String() string {
+	switch k {
+	case KindCollection:
+		return "collection"
+	case KindUser:
+		return "user"
+	case KindConfig:
+		return "config"
+	default:
+		return fmt.Sprintf("Kind(%d)", uint8(k))
+	}
+}
+
+// Action is what a change does to its resource
+type Action uint8
+
+// Actions
+const (
+	ActionCreate Action = iota + 1
+	ActionUpdate
+	// ActionReplace drops and recreates a resource that cannot be changed
+	// in place, such as a collection whose schema differs. Its data is lost.
+	ActionReplace
+	ActionDelete
+)
+
+// String returns the action name
+func (a Action) String() string {
+	switch a {
+	case ActionCreate:
+		return "create"
+	case ActionUpdate:
+		return "update"
+	case ActionReplace:
+		return "replace"
+	case ActionDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("Action(%d)", uint8(a))
+	}
+}
+
+func (a Action) symbol() string {
+	switch a {
+	case ActionCreate:
+		return "+"
+	case ActionUpdate:
+		return "~"
+	case ActionReplace:
+		return "-/+"
+	default:
+		return "-"
+	}
+}
+
+// Change is one step of a plan
+type Change struct {
+	Kind   Kind
+	Action Action
+	Name   string
+	// Details describes what differs, one item per attribute. Passwords
+	// are never included.
+	Details []string
+
+	apply func(c *celrix.Client) error
+}
+
+// String formats the change as a single plan line
+func (ch Change) String() string {
+	s := fmt.Sprintf("%s %s %s", ch.Action.symbol(), ch.Kind, ch.Name)
+	if len(ch.Details) > 0 {
+		s += ": " + strings.Join(ch.Details, ", ")
+	}
+	return s
+}
+
+// Plan is the ordered set of changes that brings a server to a spec
+type Plan struct {
+	Changes []Change
+}
+
+// Empty reports whether the server already matches the spec
+func (p *Plan) Empty() bool { return len(p.Changes) == 0 }
+
+// Destructive reports whether applying the plan deletes or replaces any
+// resource
+func (p *Plan) Destructive() bool {
+	for _, ch := range p.Changes {
+		if ch.Action == ActionDelete || ch.Action == ActionReplace {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the plan one change per line
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "no changes\n"
+	}
+	var b strings.Builder
+	for _, ch := range p.Changes {
+		b.WriteString(ch.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ApplyError reports the change that failed during Apply. Changes before it
+// were applied; changes after it were not attempted.
+type ApplyError struct {
+	Change  Change
+	Applied int
+	Err     error
+}
+
+func (e *ApplyError) Error() string {
+	return fmt.Sprintf("celrixadmin: %s %s %s: %v", e.Change.Action, e.Change.Kind, e.Change.Name, e.Err)
+}
+
+func (e *ApplyError) Unwrap() error { return e.Err }
+
+// Apply executes the plan's changes in order, stopping at the first
+// failure. The server is not re-read first; compute a fresh plan if it may
+// have changed since.
+func (p *Plan) Apply(c *celrix.Client) error {
+	for i, ch := range p.Changes {
+		if err := ch.apply(c); err != nil {
+			return &ApplyError{Change: ch, Applied: i, Err: err}
+		}
+	}
+	return nil
+}
+
+// Compute compares spec with the server and returns the changes needed to
+// match it. Collections are reconciled first, then users, then config.
+func Compute(c *celrix.Client, spec Spec) (*Plan, error) {
+	p := &Plan{}
+	if err := planCollections(p, c, spec); err != nil {
+		return nil, err
+	}
+	if err := planUsers(p, c, spec); err != nil {
+		return nil, err
+	}
+	if err := planConfig(p, c, spec); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func planCollections(p *Plan, c *celrix.Client, spec Spec) error {
+	names, err := c.ListCollections()
+	if err != nil {
+		return fmt.Errorf("celrixadmin: list collections: %w", err)
+	}
+	existing := make(map[string]bool, len(names))
+	for _, name := range names {
+		existing[name] = true
+	}
+
+	if spec.Prune {
+		for _, name := range sortedKeys(existing) {
+			if _, ok := spec.Collections[name]; ok {
+				continue
+			}
+			p.Changes = append(p.Changes, Change{
+				Kind: KindCollection, Action: ActionDelete, Name: name,
+				apply: func(c *celrix.Client) error { return c.DropCollection(name) },
+			})
+		}
+	}
+
+	for _, name := range sortedKeys(spec.Collections) {
+		want := spec.Collections[name]
+		create := func(c *celrix.Client) error {
+			_, err := c.CreateCollection(name, want)
+			return err
+		}
+		if !existing[name] {
+			p.Changes = append(p.Changes, Change{
+				Kind: KindCollection, Action: ActionCreate, Name: name,
+				Details: []string{schemaSummary(want)}, apply: create,
+			})
+			continue
+		}
+		have, err := c.DescribeCollection(name)
+		if err != nil {
+			return fmt.Errorf("celrixadmin: describe collection %q: %w", name, err)
+		}
+		// Collections have no in-place alter; any schema difference means
+		// drop and recreate
+		if details := schemaDiff(have, want); len(details) > 0 {
+			p.Changes = append(p.Changes, Change{
+				Kind: KindCollection, Action: ActionReplace, Name: name, Details: details,
+				apply: func(c *celrix.Client) error {
+					if err := c.DropCollection(name); err != nil {
+						return err
+					}
+					return create(c)
+				},
+			})
+		}
+	}
+	return nil
+}
+
+func schemaSummary(s celrix.Schema) string {
+	out := fmt.Sprintf("dims %d, metric %s", s.Dims, s.Metric)
+	if len(s.Fields) > 0 {
+		out += ", fields " + fieldsString(s.Fields)
+	}
+	if s.DefaultTTL > 0 {
+		out += fmt.Sprintf(", default ttl %s", s.DefaultTTL)
+	}
+	return out
+}
+
+func schemaDiff(have, want celrix.Schema) []string {
+	have, want = stored(have), stored(want)
+	var d []string
+	if have.Dims != want.Dims {
+		d = append(d, fmt.Sprintf("dims %d -> %d", have.Dims, want.Dims))
+	}
+	if have.Metric != want.Metric {
+		d = append(d, fmt.Sprintf("metric %s -> %s", have.Metric, want.Metric))
+	}
+	if hf, wf := fieldsString(have.Fields), fieldsString(want.Fields); hf != wf {
+		d = append(d, fmt.Sprintf("fields %s -> %s", hf, wf))
+	}
+	if have.DefaultTTL != want.DefaultTTL {
+		d = append(d, fmt.Sprintf("default ttl %s -> %s", have.DefaultTTL, want.DefaultTTL))
+	}
+	return d
+}
+
+// stored returns s as the server keeps it, so that equivalent schemas do
+// not plan a replace: MetricDefault creates a cosine index, and the
+// default TTL is sent in whole seconds, rounded up
+func stored(s celrix.Schema) celrix.Schema {
+	if s.Metric == celrix.MetricDefault {
+		s.Metric = celrix.MetricCosine
+	}
+	if s.DefaultTTL > 0 {
+		s.DefaultTTL = (s.DefaultTTL + time.Second - 1).Truncate(time.Second)
+	}
+	return s
+}
+
+func fieldsString(fields map[string]celrix.MetaType) string {
+	parts := make([]string, 0, len(fields))
+	for _, name := range sortedKeys(fields) {
+		parts = append(parts, name+":"+fields[name].String())
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+func planUsers(p *Plan, c *celrix.Client, spec Spec) error {
+	if len(spec.Users) == 0 && !spec.Prune {
+		return nil
+	}
+	users, err := c.Admin().ACLUsers()
+	if err != nil {
+		return fmt.Errorf("celrixadmin: list users: %w", err)
+	}
+	existing := make(map[string]celrix.ACLUser, len(users))
+	for _, u := range users {
+		existing[u.Name] = u
+	}
+
+	if spec.Prune {
+		for _, name := range sortedKeys(existing) {
+			if _, ok := spec.Users[name]; ok {
+				continue
+			}
+			p.Changes = append(p.Changes, Change{
+				Kind: KindUser, Action: ActionDelete, Name: name,
+				apply: func(c *celrix.Client) error { return c.Admin().ACLDelUser(name) },
+			})
+		}
+	}
+
+	for _, name := range sortedKeys(spec.Users) {
+		want := spec.Users[name]
+		set := celrix.ACLUserSpec{Name: name, Enabled: !want.Disabled, Roles: sortedStrings(want.Roles)}
+		have, ok := existing[name]
+		if !ok {
+			set.Password = want.Password
+			p.Changes = append(p.Changes, Change{
+				Kind: KindUser, Action: ActionCreate, Name: name,
+				Details: []string{"roles " + rolesString(set.Roles)},
+				apply:   setUser(set),
+			})
+			continue
+		}
+
+		var details []string
+		if have.Enabled != set.Enabled {
+			details = append(details, fmt.Sprintf("enabled %t -> %t", have.Enabled, set.Enabled))
+		}
+		if hr, wr := rolesString(sortedStrings(have.Roles)), rolesString(set.Roles); hr != wr {
+			details = append(details, fmt.Sprintf("roles %s -> %s", hr, wr))
+		}
+		if want.Password != "" {
+			// The server cannot be asked whether the password matches,
+			// so it is set on every apply
+			set.Password = want.Password
+			details = append(details, "password set")
+		}
+		if len(details) > 0 {
+			p.Changes = append(p.Changes, Change{
+				Kind: KindUser, Action: ActionUpdate, Name: name, Details: details,
+				apply: setUser(set),
+			})
+		}
+	}
+	return nil
+}
+
+func setUser(u celrix.ACLUserSpec) func(c *celrix.Client) error {
+	return func(c *celrix.Client) error { return c.Admin().ACLSetUser(u) }
+}
+
+func rolesString(roles []string) string {
+	return "[" + strings.Join(roles, " ") + "]"
+}
+
+func planConfig(p *Plan, c *celrix.Client, spec Spec) error {
+	if len(spec.Config) == 0 {
+		return nil
+	}
+	have, err := c.Admin().ConfigGet("*")
+	if err != nil {
+		return fmt.Errorf("celrixadmin: read config: %w", err)
+	}
+	for _, name := range sortedKeys(spec.Config) {
+		want := spec.Config[name]
+		cur, ok := have[name]
+		if ok && cur == want {
+			continue
+		}
+		detail := fmt.Sprintf("%q", want)
+		if ok {
+			detail = fmt.Sprintf("%q -> %q", cur, want)
+		}
+		p.Changes = append(p.Changes, Change{
+			Kind: KindConfig, Action: ActionUpdate, Name: name, Details: []string{detail},
+			apply: func(c *celrix.Client) error { return c.Admin().ConfigSet(name, want) },
+		})
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedStrings(ss []string) []string {
+	out := append([]string(nil), ss...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,20 @@
+package celrixadmin
+
+import (
+	"testing"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+func TestSchemaDiffMatchesStoredForm(t *testing.T) {
+	have := celrix.Schema{Dims: 4, Metric: celrix.MetricCosine, DefaultTTL: 2 * time.Second}
+	want := celrix.Schema{Dims: 4, DefaultTTL: 1500 * time.Millisecond}
+	if d := schemaDiff(have, want); len(d) != 0 {
+		t.Errorf("default metric and sub-second TTL: got diff %v, want none", d)
+	}
+	want.Metric = celrix.MetricL2
+	if d := schemaDiff(have, want); len(d) != 1 {
+		t.Errorf("metric change: got diff %v, want one entry", d)
+	}
+}
@@ -0,0 +1,140 @@
+// Package celrixadmin reconciles a CELRIX server against a declarative spec.
+//
+// A Spec lists the collections, ACL users and configuration parameters the
+// server should have. Compute compares it with the live server and returns a
+// Plan of create, update, replace and delete changes; Plan.Apply executes
+// them. The split lets automation show or gate a plan before applying it:
+//
+//	spec, err := celrixadmin.LoadSpec(f)
+//	plan, err := celrixadmin.Compute(client, spec)
+//	fmt.Print(plan)
+//	if plan.Destructive() && !approved {
+//		return
+//	}
+//	err = plan.Apply(client)
+package celrixadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// Spec is the desired server state
+type Spec struct {
+	Collections map[string]celrix.Schema
+	Users       map[string]User
+	// Config lists parameters to enforce. Parameters not listed are left
+	// alone regardless of Prune.
+	Config map[string]string
+	// Prune deletes collections and users that exist on the server but not
+	// in the spec. Without it, unlisted resources are left alone.
+	Prune bool
+}
+
+// User is the desired state of an ACL user
+type User struct {
+	Roles    []string
+	Disabled bool
+	// Password is write-only on the server, so a plan sets it on every
+	// apply. Empty leaves an existing user's password unchanged.
+	Password string
+}
+
+// specFile is the JSON form of a Spec
+type specFile struct {
+	Collections map[string]struct {
+		Dims       int               `json:"dims"`
+		Metric     string            `json:"metric"`
+		Fields     map[string]string `json:"fields"`
+		DefaultTTL string            `json:"default_ttl"`
+	} `json:"collections"`
+	Users map[string]struct {
+		Roles    []string `json:"roles"`
+		Disabled bool     `json:"disabled"`
+		Password string   `json:"password"`
+	} `json:"users"`
+	Config map[string]string `json:"config"`
+	Prune  bool              `json:"prune"`
+}
+
+// LoadSpec reads a spec from JSON:
+//
+//	{
+//	  "collections": {
+//	    "docs": {"dims": 384, "metric": "cosine", "fields": {"lang": "string"}, "default_ttl": "720h"}
+//	  },
+//	  "users": {"app": {"roles": ["readwrite"], "password": "..."}},
+//	  "config": {"maxmemory": "4gb"},
+//	  "prune": false
+//	}
+func LoadSpec(r io.Reader) (Spec, error) {
+	var f specFile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return Spec{}, fmt.Errorf("celrixadmin: decode spec: %w", err)
+	}
+
+	spec := Spec{
+		Collections: make(map[string]celrix.Schema, len(f.Collections)),
+		Users:       make(map[string]User, len(f.Users)),
+		Config:      f.Config,
+		Prune:       f.Prune,
+	}
+	for name, c := range f.Collections {
+		// Collections are created with cosine unless told otherwise, so
+		// that is what the server describes them with
+		schema := celrix.Schema{Dims: c.Dims, Metric: celrix.MetricCosine}
+		if c.Metric != "" {
+			m, ok := parseMetric(c.Metric)
+			if !ok {
+				return Spec{}, fmt.Errorf("celrixadmin: collection %q: unknown metric %q", name, c.Metric)
+			}
+			schema.Metric = m
+		}
+		if len(c.Fields) > 0 {
+			schema.Fields = make(map[string]celrix.MetaType, len(c.Fields))
+			for field, typ := range c.Fields {
+				t, ok := parseMetaType(typ)
+				if !ok {
+					return Spec{}, fmt.Errorf("celrixadmin: collection %q field %q: unknown type %q", name, field, typ)
+				}
+				schema.Fields[field] = t
+			}
+		}
+		if c.DefaultTTL != "" {
+			ttl, err := time.ParseDuration(c.DefaultTTL)
+			if err != nil {
+				return Spec{}, fmt.Errorf("celrixadmin: collection %q: default_ttl: %w", name, err)
+			}
+			schema.DefaultTTL = ttl
+		}
+		spec.Collections[name] = schema
+	}
+	for name, u := range f.Users {
+		spec.Users[name] = User{Roles: u.Roles, Disabled: u.Disabled, Password: u.Password}
+	}
+	return spec, nil
+}
+
+func parseMetric(s string) (celrix.Metric, bool) {
+	for _, m := range []celrix.Metric{celrix.MetricCosine, celrix.MetricL2, celrix.MetricDot} {
+		if m.String() == s {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+func parseMetaType(s string) (celrix.MetaType, bool) {
+	for _, t := range []celrix.MetaType{celrix.MetaString, celrix.MetaInt, celrix.MetaFloat, celrix.MetaBool, celrix.MetaTime} {
+		if t.String() == s {
+			return t, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,121 @@
+// Package celrixcache is a small cache helper over a CELRIX client that
+// keeps its entries under a key prefix and reports entries the server drops.
+package celrixcache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// Options configures a Cache
+type Options struct {
+	// Prefix is prepended to every key, and scopes the keyspace
+	// notifications the cache subscribes to
+	Prefix string
+	// OnEvict is called with each entry the server evicts under memory
+	// pressure, while its value can still be persisted elsewhere
+	OnEvict func(key, value string)
+	// OnExpire is called with each entry whose TTL ran out
+	OnExpire func(key, value string)
+	// OnError is called if the notification stream fails; no further
+	// callbacks are made after it. If nil the error is dropped.
+	OnError func(error)
+}
+
+// Cache stores string values on a CELRIX server. It is safe for concurrent
+// use; commands are serialised on the underlying client, which must not be
+// used directly while the cache is open.
+type Cache struct {
+	mu   sync.Mutex
+	c    *celrix.Client
+	opts Options
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New returns a cache over c. If OnEvict or OnExpire is set, it subscribes
+// to the server's keyspace notifications on a separate connection and calls
+// them from a background goroutine, one event at a time, until ctx is
+// cancelled or Close is called. Callbacks should return promptly, since
+// events queue up behind a slow one.
+func New(ctx context.Context, c *celrix.Client, opts Options) (*Cache, error) {
+	cache := &Cache{c: c, opts: opts}
+	if opts.OnEvict == nil && opts.OnExpire == nil {
+		return cache, nil
+	}
+
+	var kinds []celrix.KeyEventKind
+	if opts.OnEvict != nil {
+		kinds = append(kinds, celrix.KeyEvicted)
+	}
+	if opts.OnExpire != nil {
+		kinds = append(kinds, celrix.KeyExpired)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	events, err := c.KeyEvents(ctx, opts.Prefix, kinds...)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("celrixcache: subscribe to key events: %w", err)
+	}
+	cache.cancel, cache.done = cancel, make(chan struct{})
+	go cache.dispatch(events)
+	return cache, nil
+}
+
+func (c *Cache) dispatch(events <-chan celrix.KeyEvent) {
+	defer close(c.done)
+	for ev := range events {
+		if ev.Err != nil {
+			if c.opts.OnError != nil {
+				c.opts.OnError(ev.Err)
+			}
+			return
+		}
+		key := strings.TrimPrefix(ev.Key, c.opts.Prefix)
+		switch {
+		case ev.Kind == celrix.KeyEvicted && c.opts.OnEvict != nil:
+			c.opts.OnEvict(key, string(ev.Value))
+		case ev.Kind == celrix.KeyExpired && c.opts.OnExpire != nil:
+			c.opts.OnExpire(key, string(ev.Value))
+		}
+	}
+}
+
+// Get returns the cached value of key
+func (c *Cache) Get(key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.c.Get(c.opts.Prefix + key)
+}
+
+// Set caches value under key
+func (c *Cache) Set(key, value string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.c.Set(c.opts.Prefix+key, value)
+}
+
+// Delete removes key from the cache. Deleted entries are not reported to
+// OnEvict or OnExpire.
+func (c *Cache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.c.Del(c.opts.Prefix + key)
+	return err
+}
+
+// Close stops the notification subscription and waits for the callback in
+// progress, if any, to return. The underlying client is left open.
+func (c *Cache) Close() error {
+	if c.cancel == nil {
+		return nil
+	}
+	c.cancel()
+	<-c.done
+	return nil
+}
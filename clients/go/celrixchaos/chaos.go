@@ -0,0 +1,351 @@
+// Package celrixchaos injects faults into CELRIX traffic for resilience
+// testing of applications built on the client.
+//
+// A Client decorates any celrix.Cmdable and, before each command, draws a
+// Fault from a Schedule. Latency and dropped connections can be injected in
+// front of any Cmdable; corrupted frames and partial writes need access to
+// the byte stream and are only applied to clients created with Dial, which
+// installs a fault-injecting transport on every connection the client
+// dials, including the replacements it dials after a fault.
+package celrixchaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// Fault is a single injected failure
+type Fault int
+
+// Faults
+const (
+	None Fault = iota
+	// Latency delays the command by the configured latency
+	Latency
+	// Drop closes the connection as the command is sent
+	Drop
+	// Corrupt flips a byte in the command's outgoing frame, picked by a
+	// RandomSchedule's source or, for other schedules, the middle one
+	Corrupt
+	// PartialWrite sends only part of the frame, then closes the connection
+	PartialWrite
+	// Warp jumps the configured clock forward by Options.Warp, so expiry
+	// and timer-driven code see time pass suddenly
+	Warp
+)
+
+// String returns the fault name
+func (f Fault) String() string {
+	switch f {
+	case None:
+		return "none"
+	case Latency:
+		return "latency"
+	case Drop:
+		return "drop"
+	case Corrupt:
+		return "corrupt"
+	case PartialWrite:
+		return "partial-write"
+	case Warp:
+		return "warp"
+	default:
+		return fmt.Sprintf("Fault(%d)", int(f))
+	}
+}
+
+// ErrInjected is wrapped by errors produced by an injected fault when no
+// real transport is available to fail
+var ErrInjected = errors.New("celrixchaos: injected fault")
+
+// Schedule decides which fault, if any, to inject into the next command
+type Schedule interface {
+	Next() Fault
+}
+
+// Probabilities are per-command chances of each fault, between 0 and 1
+type Probabilities struct {
+	Latency      float64
+	Drop         float64
+	Corrupt      float64
+	PartialWrite float64
+	Warp         float64
+}
+
+// RandomSchedule draws faults with the given probabilities from a source
+// seeded with seed, so a failing run can be reproduced exactly
+func RandomSchedule(seed uint64, p Probabilities) Schedule {
+	return &randomSchedule{rng: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)), p: p}
+}
+
+type randomSchedule struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+	p   Probabilities
+}
+
+// positioner is implemented by schedules that also place Corrupt faults,
+// as a fraction of the frame length, so that a whole run follows from
+// their seed
+type positioner interface {
+	position() float64
+}
+
+func (s *randomSchedule) position() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rng.Float64()
+}
+
+func (s *randomSchedule) Next() Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	x := s.rng.Float64()
+	for _, c := range []struct {
+		p float64
+		f Fault
+	}{
+		{s.p.Drop, Drop},
+		{s.p.PartialWrite, PartialWrite},
+		{s.p.Corrupt, Corrupt},
+		{s.p.Latency, Latency},
+		{s.p.Warp, Warp},
+	} {
+		if x < c.p {
+			return c.f
+		}
+		x -= c.p
+	}
+	return None
+}
+
+// Script returns a schedule that injects faults in the given order, then
+// repeats. Use None entries for commands that should pass untouched.
+func Script(faults ...Fault) Schedule {
+	return &scriptSchedule{faults: faults}
+}
+
+type scriptSchedule struct {
+	mu     sync.Mutex
+	faults []Fault
+	next   int
+}
+
+func (s *scriptSchedule) Next() Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.faults) == 0 {
+		return None
+	}
+	f := s.faults[s.next%len(s.faults)]
+	s.next++
+	return f
+}
+
+// Options configures fault behaviour
+type Options struct {
+	// Latency is the delay added by a Latency fault
+	Latency time.Duration
+	// OnFault, if set, is called with every fault before it is applied
+	OnFault func(op string, f Fault)
+	// Clock times Latency faults. Defaults to celrix.SystemClock; pass the
+	// same FakeClock given to the wrapped client with celrix.WithClock to
+	// keep injected latency virtual.
+	Clock celrix.Clock
+	// Warp is the jump applied by a Warp fault. Warp faults need a Clock
+	// that can be advanced, such as *celrix.FakeClock, and are ignored
+	// otherwise.
+	Warp time.Duration
+}
+
+// Client is a celrix.Cmdable that injects faults before delegating
+type Client struct {
+	next      celrix.Cmdable
+	schedule  Schedule
+	opts      Options
+	transport *transport
+	closer    interface{ Close() error }
+}
+
+var _ celrix.Cmdable = (*Client)(nil)
+
+// Wrap decorates next with faults drawn from schedule. Without access to
+// the transport, Drop, Corrupt and PartialWrite faults fail the command
+// with ErrInjected instead.
+func Wrap(next celrix.Cmdable, schedule Schedule, opts Options) *Client {
+	return &Client{next: next, schedule: schedule, opts: opts}
+}
+
+// Dial connects to addr through a fault-injecting transport and returns a
+// decorated client on which every fault kind acts on the real byte stream
+func Dial(addr string, schedule Schedule, opts Options, clientOpts ...celrix.Option) (*Client, error) {
+	t := &transport{}
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		return &faultConn{Conn: conn, t: t}, nil
+	}
+	c, err := celrix.Connect(addr, append(clientOpts, celrix.WithDialer(dial))...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{next: c, schedule: schedule, opts: opts, transport: t, closer: c}, nil
+}
+
+// Close closes the underlying client when it was created by Dial
+func (c *Client) Close() error {
+	if c.closer != nil {
+		return c.closer.Close()
+	}
+	return nil
+}
+
+func (c *Client) clock() celrix.Clock {
+	if c.opts.Clock != nil {
+		return c.opts.Clock
+	}
+	return celrix.SystemClock
+}
+
+// inject applies the next fault and returns an error if the command must
+// not be delegated
+func (c *Client) inject(op string) error {
+	f := c.schedule.Next()
+	if f == None {
+		return nil
+	}
+	if c.opts.OnFault != nil {
+		c.opts.OnFault(op, f)
+	}
+	switch f {
+	case Latency:
+		celrix.Sleep(c.clock(), c.opts.Latency)
+	case Warp:
+		if w, ok := c.opts.Clock.(interface{ Advance(time.Duration) }); ok {
+			w.Advance(c.opts.Warp)
+		}
+	case Drop, Corrupt, PartialWrite:
+		if c.transport == nil {
+			return fmt.Errorf("%w: %s %s", ErrInjected, op, f)
+		}
+		pos := 0.5
+		if p, ok := c.schedule.(positioner); ok && f == Corrupt {
+			pos = p.position()
+		}
+		c.transport.arm(f, pos)
+	}
+	return nil
+}
+
+// Ping injects a fault, then delegates
+func (c *Client) Ping() error {
+	if err := c.inject("PING"); err != nil {
+		return err
+	}
+	return c.next.Ping()
+}
+
+// Get injects a fault, then delegates
+func (c *Client) Get(key string) (string, bool, error) {
+	if err := c.inject("GET"); err != nil {
+		return "", false, err
+	}
+	return c.next.Get(key)
+}
+
+// Set injects a fault, then delegates
+func (c *Client) Set(key, value string) error {
+	if err := c.inject("SET"); err != nil {
+		return err
+	}
+	return c.next.Set(key, value)
+}
+
+// Del injects a fault, then delegates
+func (c *Client) Del(key string) (bool, error) {
+	if err := c.inject("DEL"); err != nil {
+		return false, err
+	}
+	return c.next.Del(key)
+}
+
+// VAdd injects a fault, then delegates
+func (c *Client) VAdd(key string, vector []float32) error {
+	if err := c.inject("VADD"); err != nil {
+		return err
+	}
+	return c.next.VAdd(key, vector)
+}
+
+// VSearch injects a fault, then delegates
+func (c *Client) VSearch(vector []float32, k int, opts *celrix.VSearchOptions) ([]celrix.VectorMatch, error) {
+	if err := c.inject("VSEARCH"); err != nil {
+		return nil, err
+	}
+	return c.next.VSearch(vector, k, opts)
+}
+
+// transport holds the byte-level fault armed for the next write on any
+// connection Dial opened, so faults follow the client across reconnects
+type transport struct {
+	mu    sync.Mutex
+	armed Fault
+	// pos places a Corrupt fault, as a fraction of the frame length
+	pos float64
+}
+
+func (t *transport) arm(f Fault, pos float64) {
+	t.mu.Lock()
+	t.armed, t.pos = f, pos
+	t.mu.Unlock()
+}
+
+func (t *transport) take() (Fault, float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f := t.armed
+	t.armed = None
+	return f, t.pos
+}
+
+// faultConn applies the transport's armed fault to its next write
+type faultConn struct {
+	net.Conn
+	t *transport
+}
+
+func (fc *faultConn) Write(p []byte) (int, error) {
+	f, pos := fc.t.take()
+	switch f {
+	case Drop:
+		fc.Conn.Close()
+		return 0, fmt.Errorf("%w: dropped connection", ErrInjected)
+	case Corrupt:
+		if len(p) > 0 {
+			// Damage a copy so the caller's buffer is left intact
+			q := append([]byte(nil), p...)
+			q[min(int(pos*float64(len(q))), len(q)-1)] ^= 0xFF
+			return fc.Conn.Write(q)
+		}
+	case PartialWrite:
+		n := len(p) / 2
+		if n > 0 {
+			if _, err := fc.Conn.Write(p[:n]); err != nil {
+				return 0, err
+			}
+		}
+		fc.Conn.Close()
+		return n, fmt.Errorf("%w: partial write of %d/%d bytes", ErrInjected, n, len(p))
+	}
+	return fc.Conn.Write(p)
+}
@@ -0,0 +1,111 @@
+package celrixchaos
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// pingServer answers PING on a local port and counts the connections
+// accepted and the frames received
+type pingServer struct {
+	ln net.Listener
+
+	mu     sync.Mutex
+	conns  int
+	frames int
+}
+
+// listen starts a pingServer
+func listen(t *testing.T) *pingServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &pingServer{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *pingServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		f, err := wire.ReadFrame(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.frames++
+		s.mu.Unlock()
+		if _, err := conn.Write(wire.Encode(wire.Frame{Opcode: celrix.OpPong, RequestID: f.RequestID})); err != nil {
+			return
+		}
+	}
+}
+
+func (s *pingServer) counts() (conns, frames int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns, s.frames
+}
+
+func TestDropFollowsReconnects(t *testing.T) {
+	s := listen(t)
+	retry := celrix.WithRetry(celrix.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	c, err := Dial(s.ln.Addr().String(), Script(Drop, None), Options{}, retry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 6; i++ {
+		if err := c.Ping(); err != nil {
+			t.Fatalf("ping %d: %v", i, err)
+		}
+	}
+	// Every drop, including those after a reconnect, costs a connection
+	if conns, frames := s.counts(); conns != 4 || frames != 6 {
+		t.Errorf("%d connections and %d frames, want 4 and 6", conns, frames)
+	}
+}
+
+func TestCorruptPositionFollowsSeed(t *testing.T) {
+	positions := func() []float64 {
+		s := RandomSchedule(42, Probabilities{Corrupt: 0.5})
+		var out []float64
+		for i := 0; i < 8; i++ {
+			if s.Next() == Corrupt {
+				out = append(out, s.(positioner).position())
+			}
+		}
+		return out
+	}
+	a, b := positions(), positions()
+	if len(a) == 0 || len(a) != len(b) {
+		t.Fatalf("runs drew %d and %d corrupt faults", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("fault %d: positions %v and %v from the same seed", i, a[i], b[i])
+		}
+	}
+}
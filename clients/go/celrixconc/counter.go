@@ -0,0 +1,75 @@
+package celrixconc
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// BoundedCounter is an integer kept within [min, max] across every process
+// using the same key, for quotas and capacity accounting where a plain
+// counter could overshoot. A missing key counts as min. The key holds the
+// value in decimal.
+type BoundedCounter struct {
+	mu       sync.Mutex
+	c        *celrix.Client
+	key      string
+	min, max int64
+}
+
+// NewBoundedCounter returns the counter at key
+func NewBoundedCounter(c *celrix.Client, key string, min, max int64) *BoundedCounter {
+	return &BoundedCounter{c: c, key: key, min: min, max: max}
+}
+
+// Add adds delta unless the result would leave the bounds, and returns the
+// resulting value and whether delta was applied. A rejected delta leaves
+// the counter unchanged and returns its current value.
+func (b *BoundedCounter) Add(delta int64) (int64, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := 0; i < maxRetries; i++ {
+		v, old, exists, err := b.get()
+		if err != nil {
+			return 0, false, err
+		}
+		n := v + delta
+		overflow := (delta > 0 && n < v) || (delta < 0 && n > v)
+		if overflow || n < b.min || n > b.max {
+			return v, false, nil
+		}
+		ok, err := swap(b.c, b.key, old, exists, strconv.FormatInt(n, 10))
+		if err != nil {
+			return 0, false, fmt.Errorf("celrixconc: counter %s: %w", b.key, err)
+		}
+		if ok {
+			return n, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("%w on counter %s", ErrContended, b.key)
+}
+
+// Value returns the current value
+func (b *BoundedCounter) Value() (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, _, _, err := b.get()
+	return v, err
+}
+
+func (b *BoundedCounter) get() (v int64, raw string, exists bool, err error) {
+	raw, exists, err = b.c.Get(b.key)
+	if err != nil {
+		return 0, "", false, fmt.Errorf("celrixconc: counter %s: %w", b.key, err)
+	}
+	if !exists {
+		return b.min, "", false, nil
+	}
+	v, err = strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, "", false, fmt.Errorf("celrixconc: counter %s holds %q, not an integer", b.key, raw)
+	}
+	return v, raw, true, nil
+}
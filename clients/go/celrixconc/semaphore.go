@@ -0,0 +1,193 @@
+// Package celrixconc provides distributed concurrency primitives on a
+// CELRIX server. Each is a single key updated with compare-and-swap, so
+// every change is atomic without server-side scripting; a change that
+// loses a race is recomputed from the new value and retried.
+//
+// The primitives serialise their own use of the client, but the client
+// must not be used concurrently by anything else.
+package celrixconc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// maxRetries bounds compare-and-swap retries under contention
+const maxRetries = 64
+
+// ErrContended is returned when an update lost maxRetries races in a row
+var ErrContended = errors.New("celrixconc: too much contention")
+
+// Permit is a held semaphore slot
+type Permit string
+
+// Semaphore admits at most limit holders at a time across every process
+// using the same key. Permits are leases: one not refreshed or released
+// within the lease duration is reclaimed, so a crashed holder cannot leak
+// its slot.
+//
+// The key holds the current permits as "id@expiry,..." with expiries in
+// Unix milliseconds.
+type Semaphore struct {
+	mu    sync.Mutex
+	c     *celrix.Client
+	key   string
+	limit int
+	lease time.Duration
+}
+
+// NewSemaphore returns the semaphore at key
+func NewSemaphore(c *celrix.Client, key string, limit int, lease time.Duration) *Semaphore {
+	return &Semaphore{c: c, key: key, limit: limit, lease: lease}
+}
+
+// TryAcquire takes a permit if one is free. The boolean is false if the
+// semaphore is full.
+func (s *Semaphore) TryAcquire() (Permit, bool, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", false, fmt.Errorf("celrixconc: permit id: %w", err)
+	}
+	id := Permit(hex.EncodeToString(b[:]))
+	acquired := false
+	err := s.update(func(held map[Permit]time.Time, now time.Time) bool {
+		if len(held) >= s.limit {
+			return false
+		}
+		held[id] = now.Add(s.lease)
+		acquired = true
+		return true
+	})
+	if err != nil || !acquired {
+		return "", false, err
+	}
+	return id, true, nil
+}
+
+// Acquire waits for a permit, polling every poll, until ctx is cancelled
+func (s *Semaphore) Acquire(ctx context.Context, poll time.Duration) (Permit, error) {
+	for {
+		p, ok, err := s.TryAcquire()
+		if err != nil || ok {
+			return p, err
+		}
+		t := time.NewTimer(poll)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return "", ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Refresh extends p's lease and reports whether p was still held
+func (s *Semaphore) Refresh(p Permit) (bool, error) {
+	held := false
+	err := s.update(func(permits map[Permit]time.Time, now time.Time) bool {
+		if _, held = permits[p]; held {
+			permits[p] = now.Add(s.lease)
+		}
+		return held
+	})
+	return held, err
+}
+
+// Release returns p to the semaphore. Releasing a permit that was already
+// released or reclaimed is a no-op.
+func (s *Semaphore) Release(p Permit) error {
+	return s.update(func(permits map[Permit]time.Time, now time.Time) bool {
+		if _, ok := permits[p]; !ok {
+			return false
+		}
+		delete(permits, p)
+		return true
+	})
+}
+
+// Held returns the number of unexpired permits
+func (s *Semaphore) Held() (int, error) {
+	n := 0
+	err := s.update(func(permits map[Permit]time.Time, now time.Time) bool {
+		n = len(permits)
+		return false
+	})
+	return n, err
+}
+
+// update applies fn to the unexpired permits and writes them back if fn
+// returns true, retrying if another process changed the key meanwhile
+func (s *Semaphore) update(fn func(permits map[Permit]time.Time, now time.Time) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < maxRetries; i++ {
+		old, exists, err := s.c.Get(s.key)
+		if err != nil {
+			return fmt.Errorf("celrixconc: semaphore %s: %w", s.key, err)
+		}
+		now := time.Now()
+		permits, err := decodePermits(old, now)
+		if err != nil {
+			return fmt.Errorf("celrixconc: semaphore %s: %w", s.key, err)
+		}
+		if !fn(permits, now) {
+			return nil
+		}
+		ok, err := swap(s.c, s.key, old, exists, encodePermits(permits))
+		if err != nil {
+			return fmt.Errorf("celrixconc: semaphore %s: %w", s.key, err)
+		}
+		if ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w on semaphore %s", ErrContended, s.key)
+}
+
+// swap replaces old with value, or creates the key if it did not exist
+func swap(c *celrix.Client, key, old string, exists bool, value string) (bool, error) {
+	if !exists {
+		return c.SetNX(key, value, 0)
+	}
+	return c.CompareAndSwap(key, old, value, 0)
+}
+
+func decodePermits(s string, now time.Time) (map[Permit]time.Time, error) {
+	permits := make(map[Permit]time.Time)
+	if s == "" {
+		return permits, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		id, exp, ok := strings.Cut(entry, "@")
+		if !ok {
+			return nil, fmt.Errorf("malformed permit %q", entry)
+		}
+		ms, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed permit %q", entry)
+		}
+		if expires := time.UnixMilli(ms); expires.After(now) {
+			permits[Permit(id)] = expires
+		}
+	}
+	return permits, nil
+}
+
+func encodePermits(permits map[Permit]time.Time) string {
+	entries := make([]string, 0, len(permits))
+	for id, expires := range permits {
+		entries = append(entries, string(id)+"@"+strconv.FormatInt(expires.UnixMilli(), 10))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
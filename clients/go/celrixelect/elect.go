@@ -0,0 +1,196 @@
+// Package celrixelect elects a single leader among processes sharing a
+// CELRIX server, for background workers that must run exactly once.
+//
+// The leader holds a lease: a key set with SetNX and a TTL, and renewed
+// with CompareAndSwap well before it expires. Each win then takes a
+// fencing token from a counter next to the lease, which only ever grows,
+// and writes it into the lease; the win only counts if the lease is still
+// ours by then, so leaders hold tokens in the order they took office.
+// Passing the token along with writes lets downstream systems reject a
+// deposed leader that has not noticed yet, e.g. one paused past its
+// lease by a long GC.
+package celrixelect
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// ErrLost is returned by Leadership.Err once the lease has been lost
+var ErrLost = errors.New("celrixelect: leadership lost")
+
+// Leadership is a won election. It is renewed in the background until it
+// is lost, resigned, or the context passed to Campaign is cancelled.
+type Leadership struct {
+	// Token is the fencing token for this term, greater than every token
+	// handed to earlier leaders of the same key
+	Token int64
+
+	c     *celrix.Client
+	key   string
+	value string
+	ttl   time.Duration
+
+	lost   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// Campaign blocks until this process holds the lease on key, or ctx is
+// cancelled. It retries every ttl/3 while another process leads. The
+// lease is renewed at the same interval once won.
+//
+// c is used from a background goroutine for the life of the leadership
+// and must not be used for anything else meanwhile. The server rounds ttl
+// up to whole seconds.
+func Campaign(ctx context.Context, c *celrix.Client, key string, ttl time.Duration) (*Leadership, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("celrixelect: ttl must be positive, got %v", ttl)
+	}
+	id, err := candidateID()
+	if err != nil {
+		return nil, err
+	}
+	interval := ttl / 3
+	for {
+		won, err := c.SetNX(key, id, ttl)
+		if err != nil {
+			return nil, fmt.Errorf("celrixelect: campaign: %w", err)
+		}
+		if won {
+			l, err := claim(ctx, c, key, id, ttl)
+			if l != nil || err != nil {
+				return l, err
+			}
+		}
+
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// claim takes a fencing token for a lease just won with SetNX and records
+// it in the lease. It returns nil, nil if the lease expired in between, in
+// which case a later leader may already hold a greater token.
+func claim(ctx context.Context, c *celrix.Client, key, id string, ttl time.Duration) (*Leadership, error) {
+	token, err := c.IncrBy(key+":token", 1)
+	if err != nil {
+		c.CompareAndDelete(key, id)
+		return nil, fmt.Errorf("celrixelect: fencing token: %w", err)
+	}
+	value := id + "/" + strconv.FormatInt(token, 10)
+	start := time.Now()
+	ok, err := c.CompareAndSwap(key, id, value, ttl)
+	if err != nil {
+		c.CompareAndDelete(key, id)
+		return nil, fmt.Errorf("celrixelect: campaign: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	rctx, cancel := context.WithCancel(ctx)
+	l := &Leadership{
+		Token:  token,
+		c:      c,
+		key:    key,
+		value:  value,
+		ttl:    ttl,
+		lost:   make(chan struct{}),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go l.renew(ctx, rctx, start)
+	return l, nil
+}
+
+func candidateID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("celrixelect: candidate id: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// renew extends the lease every ttl/3. The lease is presumed lost once a
+// ttl has passed since the start of the last successful renewal, which is
+// never later than the server expires it. Cancelling parent ends the
+// leadership without releasing the lease, which then expires on its own.
+func (l *Leadership) renew(parent, ctx context.Context, held time.Time) {
+	defer close(l.done)
+	t := time.NewTicker(l.ttl / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := parent.Err(); err != nil {
+				l.lose(fmt.Errorf("%w: %v", ErrLost, err))
+			}
+			return
+		case <-t.C:
+		}
+		start := time.Now()
+		ok, err := l.c.CompareAndSwap(l.key, l.value, l.value, l.ttl)
+		switch {
+		case err == nil && ok:
+			held = start
+		case err == nil:
+			l.lose(ErrLost)
+			return
+		case time.Since(held) >= l.ttl:
+			l.lose(fmt.Errorf("%w: renewal failed: %v", ErrLost, err))
+			return
+		}
+	}
+}
+
+func (l *Leadership) lose(err error) {
+	l.mu.Lock()
+	if l.err == nil {
+		l.err = err
+		close(l.lost)
+	}
+	l.mu.Unlock()
+}
+
+// Lost returns a channel that is closed when leadership ends for any
+// reason, including Resign
+func (l *Leadership) Lost() <-chan struct{} { return l.lost }
+
+// Err returns why leadership ended, or nil while it is held
+func (l *Leadership) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Resign stops renewing and releases the lease so another candidate can
+// take over at once. The lease is only deleted if it is still ours.
+func (l *Leadership) Resign() error {
+	l.cancel()
+	<-l.done
+	held := l.Err() == nil
+	l.lose(errors.New("celrixelect: resigned"))
+	if !held {
+		return nil
+	}
+	if _, err := l.c.CompareAndDelete(l.key, l.value); err != nil {
+		return fmt.Errorf("celrixelect: resign: %w", err)
+	}
+	return nil
+}
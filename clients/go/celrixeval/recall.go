@@ -0,0 +1,127 @@
+// Package celrixeval measures search quality against exact results, for
+// tuning index parameters such as ef and M.
+package celrixeval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+	"github.com/YASSERRMD/celrix/clients/go/internal/dataset"
+)
+
+// GroundTruth is the corpus an index was built from. Exact neighbours are
+// found by brute force over Items using Metric, which must match the
+// index's. MetricDefault is taken as cosine, the server's default.
+type GroundTruth struct {
+	Items  []celrix.VectorItem
+	Metric celrix.Metric
+}
+
+// LatencyStats summarises a set of timings
+type LatencyStats struct {
+	Mean, Min, Max time.Duration
+	P50, P90, P99  time.Duration
+}
+
+// Report is the outcome of a Recall run
+type Report struct {
+	Queries int
+	K       int
+	// Recall is the mean recall@k: the fraction of each query's exact top
+	// k that the index also returned
+	Recall float64
+	// MinRecall is the worst single query's recall
+	MinRecall float64
+	// PerQuery holds each query's recall, in query order
+	PerQuery []float64
+	// ANN times the server searches, round trip included; Exact times the
+	// local brute-force searches, for comparison
+	ANN   LatencyStats
+	Exact LatencyStats
+}
+
+// String summarises the report on one line
+func (r Report) String() string {
+	return fmt.Sprintf("recall@%d=%.4f (min %.4f) over %d queries; ann p50=%v p99=%v; exact p50=%v",
+		r.K, r.Recall, r.MinRecall, r.Queries, r.ANN.P50, r.ANN.P99, r.Exact.P50)
+}
+
+// Recall runs every query against the index with VSearch and by brute
+// force over the ground truth, and reports recall@k and the latency of
+// both. It stops early if ctx is cancelled, returning ctx.Err(). c must not
+// be used concurrently while it runs.
+func Recall(ctx context.Context, c *celrix.Client, gt GroundTruth, queries [][]float32, k int) (Report, error) {
+	if k <= 0 {
+		return Report{}, fmt.Errorf("celrixeval: k must be positive, got %d", k)
+	}
+	score, err := dataset.ScorerFor(gt.Metric)
+	if err != nil {
+		return Report{}, fmt.Errorf("celrixeval: %w", err)
+	}
+	rep := Report{Queries: len(queries), K: k, MinRecall: 1, PerQuery: make([]float64, len(queries))}
+	ann := make([]time.Duration, len(queries))
+	exact := make([]time.Duration, len(queries))
+	for i, q := range queries {
+		if err := ctx.Err(); err != nil {
+			return Report{}, err
+		}
+		start := time.Now()
+		truth := dataset.TopK(gt.Items, q, k, score)
+		exact[i] = time.Since(start)
+
+		start = time.Now()
+		got, err := c.VSearch(q, k, nil)
+		ann[i] = time.Since(start)
+		if err != nil {
+			return Report{}, fmt.Errorf("celrixeval: query %d: %w", i, err)
+		}
+
+		r := 1.0
+		if len(truth) > 0 {
+			hits := 0
+			for _, m := range got {
+				if truth[m.Key] {
+					hits++
+				}
+			}
+			r = float64(hits) / float64(len(truth))
+		}
+		rep.PerQuery[i] = r
+		rep.Recall += r
+		rep.MinRecall = math.Min(rep.MinRecall, r)
+	}
+	if len(queries) > 0 {
+		rep.Recall /= float64(len(queries))
+	} else {
+		rep.MinRecall = 0
+	}
+	rep.ANN, rep.Exact = summarize(ann), summarize(exact)
+	return rep, nil
+}
+
+func summarize(ds []time.Duration) LatencyStats {
+	if len(ds) == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	at := func(p float64) time.Duration {
+		return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+	}
+	return LatencyStats{
+		Mean: sum / time.Duration(len(sorted)),
+		Min:  sorted[0],
+		Max:  sorted[len(sorted)-1],
+		P50:  at(0.50),
+		P90:  at(0.90),
+		P99:  at(0.99),
+	}
+}
@@ -0,0 +1,115 @@
+// Package celrixlog adapts popular logging libraries to the client's
+// celrix.Logger interface, for use with celrix.WithLogger.
+//
+// The slog adapter uses the standard library. The zap and zerolog adapters
+// are written against the methods those libraries expose rather than their
+// packages, so depending on celrixlog never pulls them into a build:
+//
+//	celrix.WithLogger(celrixlog.Slog(slog.Default()))
+//	celrix.WithLogger(celrixlog.Zap(zapLogger.Sugar()))
+//	celrix.WithLogger(celrixlog.Zerolog[*zerolog.Event](&zlog))
+package celrixlog
+
+import (
+	"context"
+	"log/slog"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// Slog returns a Logger writing to l
+func Slog(l *slog.Logger) celrix.Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Log(level celrix.LogLevel, msg string, keyvals ...interface{}) {
+	s.l.Log(context.Background(), slogLevel(level), msg, keyvals...)
+}
+
+func slogLevel(level celrix.LogLevel) slog.Level {
+	switch level {
+	case celrix.LevelDebug:
+		return slog.LevelDebug
+	case celrix.LevelInfo:
+		return slog.LevelInfo
+	case celrix.LevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// SugaredLogger is the subset of *zap.SugaredLogger used by Zap
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// Zap returns a Logger writing to a zap SugaredLogger; pass
+// logger.Sugar() for a *zap.Logger
+func Zap(l SugaredLogger) celrix.Logger {
+	return zapLogger{l}
+}
+
+type zapLogger struct {
+	l SugaredLogger
+}
+
+func (z zapLogger) Log(level celrix.LogLevel, msg string, keyvals ...interface{}) {
+	switch level {
+	case celrix.LevelDebug:
+		z.l.Debugw(msg, keyvals...)
+	case celrix.LevelInfo:
+		z.l.Infow(msg, keyvals...)
+	case celrix.LevelWarn:
+		z.l.Warnw(msg, keyvals...)
+	default:
+		z.l.Errorw(msg, keyvals...)
+	}
+}
+
+// ZerologEvent is the subset of *zerolog.Event used by Zerolog
+type ZerologEvent[E any] interface {
+	Fields(fields interface{}) E
+	Msg(msg string)
+}
+
+// ZerologLogger is the subset of *zerolog.Logger used by Zerolog
+type ZerologLogger[E any] interface {
+	Debug() E
+	Info() E
+	Warn() E
+	Error() E
+}
+
+// Zerolog returns a Logger writing to a zerolog logger. The event type
+// cannot be inferred and must be given: Zerolog[*zerolog.Event](&logger).
+func Zerolog[E ZerologEvent[E], L ZerologLogger[E]](l L) celrix.Logger {
+	return zerologLogger[E, L]{l}
+}
+
+type zerologLogger[E ZerologEvent[E], L ZerologLogger[E]] struct {
+	l L
+}
+
+func (z zerologLogger[E, L]) Log(level celrix.LogLevel, msg string, keyvals ...interface{}) {
+	var e E
+	switch level {
+	case celrix.LevelDebug:
+		e = z.l.Debug()
+	case celrix.LevelInfo:
+		e = z.l.Info()
+	case celrix.LevelWarn:
+		e = z.l.Warn()
+	default:
+		e = z.l.Error()
+	}
+	// zerolog accepts fields as an alternating key/value slice
+	e.Fields(keyvals).Msg(msg)
+}
@@ -0,0 +1,383 @@
+// Package celrixqueue implements at-least-once work queues on CELRIX
+// sorted sets.
+//
+// A queue named q keeps its job IDs in the sorted set q:ready, scored by
+// the time each job next becomes visible, and each job's payload and
+// delivery count under q:job:<id>. Reserving a job pushes its score out by
+// the visibility timeout rather than removing it, so a consumer that dies
+// mid-job loses nothing: the job reappears once the timeout passes. Jobs
+// are removed only when acknowledged, or moved to q:dead once they have
+// been delivered MaxAttempts times. Jobs enqueued for later wait in
+// q:scheduled, scored by their due time, until a mover promotes them.
+//
+// Delivery is at least once. A job whose visibility timeout runs out while
+// it is still being worked on is delivered again, so handlers should be
+// idempotent.
+package celrixqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// Defaults for Options
+const (
+	DefaultVisibility  = 30 * time.Second
+	DefaultMaxAttempts = 5
+)
+
+// ErrNotReserved is returned when acknowledging, releasing or extending a
+// job whose reservation has lapsed and been taken by another consumer, or
+// that has already been acknowledged
+var ErrNotReserved = errors.New("celrixqueue: job no longer reserved by this consumer")
+
+// reserveScan is the number of visible jobs Reserve considers per call
+const reserveScan = 16
+
+// Options configures a Queue
+type Options struct {
+	// Visibility is how long a reserved job stays hidden from other
+	// consumers before it is delivered again. Defaults to
+	// DefaultVisibility.
+	Visibility time.Duration
+	// MaxAttempts is the number of deliveries after which a job is
+	// dead-lettered instead. Defaults to DefaultMaxAttempts; negative
+	// retries forever.
+	MaxAttempts int
+}
+
+// Job is a reserved unit of work
+type Job struct {
+	ID      string
+	Payload []byte
+	// Attempts counts deliveries, including this one
+	Attempts int
+	// Deadline is when the reservation lapses and the job becomes visible
+	// to other consumers again
+	Deadline time.Time
+}
+
+// Queue is a named work queue. It is safe for concurrent use; commands are
+// serialised on the underlying client, which must not be used directly
+// while the queue is in use.
+type Queue struct {
+	mu   sync.Mutex
+	c    *celrix.Client
+	name string
+	opts Options
+}
+
+// New returns the queue called name on c
+func New(c *celrix.Client, name string, opts Options) *Queue {
+	if opts.Visibility <= 0 {
+		opts.Visibility = DefaultVisibility
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	return &Queue{c: c, name: name, opts: opts}
+}
+
+func (q *Queue) readyKey() string        { return q.name + ":ready" }
+func (q *Queue) deadKey() string         { return q.name + ":dead" }
+func (q *Queue) scheduledKey() string    { return q.name + ":scheduled" }
+func (q *Queue) jobKey(id string) string { return q.name + ":job:" + id }
+
+// Scores are Unix milliseconds, which float64 holds exactly
+func score(t time.Time) float64     { return float64(t.UnixMilli()) }
+func fromScore(s float64) time.Time { return time.UnixMilli(int64(s)) }
+
+// Job records are [attempts u32][payload]
+func encodeJob(attempts int, p []byte) string {
+	b := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(p)), uint32(attempts))
+	return string(append(b, p...))
+}
+
+func decodeJob(rec string) (int, []byte, error) {
+	if len(rec) < 4 {
+		return 0, nil, errors.New("celrixqueue: truncated job record")
+	}
+	return int(binary.BigEndian.Uint32([]byte(rec[:4]))), []byte(rec[4:]), nil
+}
+
+func newID() (string, error) {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// Enqueue adds a job that is visible immediately and returns its ID
+func (q *Queue) Enqueue(payload []byte) (string, error) {
+	return q.enqueue(q.readyKey(), time.Now(), payload)
+}
+
+// enqueue stores the job record before scheduling its ID, so a consumer
+// never sees an ID without a record
+func (q *Queue) enqueue(set string, at time.Time, payload []byte) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", fmt.Errorf("celrixqueue: job id: %w", err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.c.Set(q.jobKey(id), encodeJob(0, payload)); err != nil {
+		return "", fmt.Errorf("celrixqueue: store job: %w", err)
+	}
+	if _, err := q.c.ZAdd(set, id, score(at), celrix.ZAddNX); err != nil {
+		return "", fmt.Errorf("celrixqueue: schedule job: %w", err)
+	}
+	return id, nil
+}
+
+// Reserve claims the oldest visible job for the visibility timeout, or
+// returns nil if none is visible
+func (q *Queue) Reserve() (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	visible, err := q.c.ZRangeByScore(q.readyKey(), math.Inf(-1), score(now), reserveScan)
+	if err != nil {
+		return nil, fmt.Errorf("celrixqueue: reserve: %w", err)
+	}
+	for _, m := range visible {
+		job, err := q.claim(m.Member, now)
+		if err != nil {
+			return nil, fmt.Errorf("celrixqueue: reserve %s: %w", m.Member, err)
+		}
+		if job != nil {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// claim pushes out the visibility of job id, returning nil if another
+// consumer got there first or the job was dead-lettered
+func (q *Queue) claim(id string, now time.Time) (*Job, error) {
+	deadline := fromScore(score(now.Add(q.opts.Visibility)))
+	ok, err := q.c.ZAdd(q.readyKey(), id, score(deadline), celrix.ZAddXX|celrix.ZAddGT)
+	if err != nil || !ok {
+		return nil, err
+	}
+	// Concurrent claims both succeed when the later one writes a later
+	// deadline; the one whose deadline stuck wins
+	s, ok, err := q.c.ZScore(q.readyKey(), id)
+	if err != nil || !ok || s != score(deadline) {
+		return nil, err
+	}
+
+	rec, ok, err := q.c.Get(q.jobKey(id))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Acknowledged since the range was read
+		_, err := q.c.ZRem(q.readyKey(), id)
+		return nil, err
+	}
+	attempts, payload, err := decodeJob(rec)
+	if err != nil {
+		return nil, err
+	}
+	attempts++
+	if q.opts.MaxAttempts > 0 && attempts > q.opts.MaxAttempts {
+		if _, err := q.c.ZAdd(q.deadKey(), id, score(now), 0); err != nil {
+			return nil, err
+		}
+		_, err := q.c.ZRem(q.readyKey(), id)
+		return nil, err
+	}
+	if err := q.c.Set(q.jobKey(id), encodeJob(attempts, payload)); err != nil {
+		return nil, err
+	}
+	return &Job{ID: id, Payload: payload, Attempts: attempts, Deadline: deadline}, nil
+}
+
+// Ack completes a job, removing it from the queue. It fails with
+// ErrNotReserved if the reservation lapsed and another consumer claimed the
+// job, which then stays queued for that consumer.
+func (q *Queue) Ack(job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.owned(job); err != nil {
+		return fmt.Errorf("celrixqueue: ack %s: %w", job.ID, err)
+	}
+	// A claim rewrites the record with a higher delivery count, so the
+	// record is only deleted if no consumer has claimed the job since
+	ok, err := q.c.CompareAndDelete(q.jobKey(job.ID), encodeJob(job.Attempts, job.Payload))
+	if err != nil {
+		return fmt.Errorf("celrixqueue: ack %s: %w", job.ID, err)
+	}
+	if !ok {
+		return fmt.Errorf("celrixqueue: ack %s: %w", job.ID, ErrNotReserved)
+	}
+	if _, err := q.c.ZRem(q.readyKey(), job.ID); err != nil {
+		return fmt.Errorf("celrixqueue: ack %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// owned checks that job is still reserved under the deadline its consumer
+// holds. Every claim and extension moves the deadline, so another
+// consumer's reservation scores differently.
+func (q *Queue) owned(job *Job) error {
+	s, ok, err := q.c.ZScore(q.readyKey(), job.ID)
+	if err != nil {
+		return err
+	}
+	if !ok || s != score(job.Deadline) {
+		return ErrNotReserved
+	}
+	return nil
+}
+
+// Release gives up a reservation so the job is visible again immediately.
+// Like Ack, it fails with ErrNotReserved once another consumer holds the
+// job.
+func (q *Queue) Release(job *Job) error {
+	return q.reschedule(job, time.Now(), 0)
+}
+
+// Extend pushes the job's deadline to d from now, for work that outlasts
+// the visibility timeout
+func (q *Queue) Extend(job *Job, d time.Duration) error {
+	deadline := time.Now().Add(d)
+	if err := q.reschedule(job, deadline, celrix.ZAddGT); err != nil {
+		return err
+	}
+	job.Deadline = fromScore(score(deadline))
+	return nil
+}
+
+func (q *Queue) reschedule(job *Job, at time.Time, flags celrix.ZAddFlag) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.owned(job); err != nil {
+		return fmt.Errorf("celrixqueue: reschedule %s: %w", job.ID, err)
+	}
+	if _, err := q.c.ZAdd(q.readyKey(), job.ID, score(at), celrix.ZAddXX|flags); err != nil {
+		return fmt.Errorf("celrixqueue: reschedule %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// DeadLetters returns up to limit dead-lettered jobs, oldest first; zero
+// returns them all. Their Deadline is the time they were dead-lettered.
+func (q *Queue) DeadLetters(limit int) ([]*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	dead, err := q.c.ZRangeByScore(q.deadKey(), math.Inf(-1), math.Inf(1), limit)
+	if err != nil {
+		return nil, fmt.Errorf("celrixqueue: dead letters: %w", err)
+	}
+	jobs := make([]*Job, 0, len(dead))
+	for _, m := range dead {
+		rec, ok, err := q.c.Get(q.jobKey(m.Member))
+		if err != nil {
+			return nil, fmt.Errorf("celrixqueue: dead letter %s: %w", m.Member, err)
+		}
+		if !ok {
+			continue
+		}
+		attempts, payload, err := decodeJob(rec)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &Job{ID: m.Member, Payload: payload, Attempts: attempts, Deadline: fromScore(m.Score)})
+	}
+	return jobs, nil
+}
+
+// Requeue moves a dead-lettered job back to the queue with its delivery
+// count reset
+func (q *Queue) Requeue(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	rec, ok, err := q.c.Get(q.jobKey(id))
+	if err != nil {
+		return fmt.Errorf("celrixqueue: requeue %s: %w", id, err)
+	}
+	if !ok {
+		return fmt.Errorf("celrixqueue: requeue %s: no such job", id)
+	}
+	_, payload, err := decodeJob(rec)
+	if err != nil {
+		return err
+	}
+	if err := q.c.Set(q.jobKey(id), encodeJob(0, payload)); err != nil {
+		return fmt.Errorf("celrixqueue: requeue %s: %w", id, err)
+	}
+	if _, err := q.c.ZAdd(q.readyKey(), id, score(time.Now()), 0); err != nil {
+		return fmt.Errorf("celrixqueue: requeue %s: %w", id, err)
+	}
+	if _, err := q.c.ZRem(q.deadKey(), id); err != nil {
+		return fmt.Errorf("celrixqueue: requeue %s: %w", id, err)
+	}
+	return nil
+}
+
+// Handler processes a job. Its context expires at the job's deadline.
+// Returning nil acknowledges the job; an error releases it for another
+// attempt.
+type Handler func(ctx context.Context, job *Job) error
+
+// ConsumeOptions configures Consume
+type ConsumeOptions struct {
+	// PollInterval is how long to wait when the queue is empty. Defaults
+	// to one second.
+	PollInterval time.Duration
+}
+
+// Consume reserves and handles jobs one at a time until ctx is cancelled,
+// when it returns ctx.Err(). Run several Consume loops for parallelism, and
+// a Mover if jobs are enqueued with EnqueueAt.
+// Queue errors stop the loop and are returned; the job being handled, if
+// any, is redelivered after its visibility timeout. A job whose reservation
+// lapsed while it was handled is left to the consumer that claimed it.
+func (q *Queue) Consume(ctx context.Context, h Handler, opts ConsumeOptions) error {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		job, err := q.Reserve()
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			t := time.NewTimer(opts.PollInterval)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+			continue
+		}
+
+		jctx, cancel := context.WithDeadline(ctx, job.Deadline)
+		herr := h(jctx, job)
+		cancel()
+		if herr == nil {
+			err = q.Ack(job)
+		} else {
+			err = q.Release(job)
+		}
+		if err != nil && !errors.Is(err, ErrNotReserved) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package celrixqueue
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// promoteBatch is the number of due jobs Promote moves per round trip
+const promoteBatch = 100
+
+// EnqueueAt adds a job that becomes visible at t and returns its ID. Jobs
+// due now or in the past go straight to the ready queue; later ones are
+// held until a Mover, or a call to Promote, finds them due.
+func (q *Queue) EnqueueAt(t time.Time, payload []byte) (string, error) {
+	if !t.After(time.Now()) {
+		return q.enqueue(q.readyKey(), t, payload)
+	}
+	return q.enqueue(q.scheduledKey(), t, payload)
+}
+
+// Promote moves every scheduled job that is due to the ready queue and
+// returns how many it moved. Jobs are added to the ready queue before they
+// are removed from the schedule, so an interrupted Promote leaves them in
+// both places and the next one finishes the move; none is lost.
+func (q *Queue) Promote() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	moved := 0
+	for {
+		due, err := q.c.ZRangeByScore(q.scheduledKey(), math.Inf(-1), score(time.Now()), promoteBatch)
+		if err != nil {
+			return moved, fmt.Errorf("celrixqueue: promote: %w", err)
+		}
+		for _, m := range due {
+			// Ready jobs are scored by their due time, so late promotion
+			// does not let them jump ahead of older work
+			if _, err := q.c.ZAdd(q.readyKey(), m.Member, m.Score, celrix.ZAddNX); err != nil {
+				return moved, fmt.Errorf("celrixqueue: promote %s: %w", m.Member, err)
+			}
+			removed, err := q.c.ZRem(q.scheduledKey(), m.Member)
+			if err != nil {
+				return moved, fmt.Errorf("celrixqueue: promote %s: %w", m.Member, err)
+			}
+			if removed {
+				moved++
+			}
+		}
+		if len(due) < promoteBatch {
+			return moved, nil
+		}
+	}
+}
+
+// Mover promotes due jobs every interval until ctx is cancelled, when it
+// returns ctx.Err(). Running more than one mover per queue is harmless.
+func (q *Queue) Mover(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("celrixqueue: mover interval must be positive, got %v", interval)
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := q.Promote(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
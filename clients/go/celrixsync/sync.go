@@ -0,0 +1,305 @@
+// Package celrixsync compares two CELRIX instances key by key and
+// reconciles the second with the first, for verifying disaster-recovery
+// sites and repairing drift between them.
+//
+// Instance a is the source of truth. Diff scans both keyspaces and
+// reports keys missing from b, keys only b holds, keys whose version on b
+// is behind a's, and keys whose values differ. Sync then copies a's value
+// over every such key and optionally deletes the extras.
+//
+// Values are compared by their server-computed hashes, so they never
+// cross the network, where both servers support HASH. Otherwise they are
+// fetched and compared directly, which covers string values only: keys
+// that a plain GET cannot read on a, such as vectors, are skipped. Sync
+// repairs string values only. Neither instance is frozen while the
+// compare runs, so keys written concurrently may be reported and are
+// repaired on the next run.
+package celrixsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// DefaultBatchSize is the number of keys compared per round trip when
+// Options.BatchSize is zero
+const DefaultBatchSize = 256
+
+// Kind classifies a difference
+type Kind int
+
+// Difference kinds
+const (
+	// Missing keys exist on a but not on b
+	Missing Kind = iota + 1
+	// Extra keys exist on b but not on a
+	Extra
+	// Stale keys exist on both, with b's version behind a's
+	Stale
+	// Mismatched keys exist on both with different values, and versions
+	// that do not explain it
+	Mismatched
+)
+
+// String returns the kind name
+func (k Kind) String() string {
+	switch k {
+	case Missing:
+		return "missing"
+	case Extra:
+		return "extra"
+	case Stale:
+		return "stale"
+	case Mismatched:
+		return "mismatched"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Difference is one key that differs between the instances
+type Difference struct {
+	Key  string
+	Kind Kind
+	// VersionA and VersionB are the key's versions on each instance, or
+	// zero where the key is absent or the server does not report versions
+	VersionA, VersionB uint64
+}
+
+// Options configures Diff and Sync
+type Options struct {
+	// Match restricts the compare to keys matching a glob pattern; empty
+	// compares every key
+	Match string
+	// BatchSize is the number of keys compared per round trip. Defaults to
+	// DefaultBatchSize.
+	BatchSize int
+	// DeleteExtra makes Sync delete keys that only b holds
+	DeleteExtra bool
+}
+
+func (o Options) batchSize() int {
+	if o.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return o.BatchSize
+}
+
+// Diff returns the differences between a and b, in scan order: first the
+// keys of a that b lacks or holds differently, then the keys only b holds
+func Diff(ctx context.Context, a, b *celrix.Client, opts Options) ([]Difference, error) {
+	d := &differ{a: a, b: b, opts: opts, versions: true, hashes: true}
+	var diffs []Difference
+	err := d.scan(ctx, a, func(keys []string) error {
+		found, err := d.compare(keys)
+		diffs = append(diffs, found...)
+		return err
+	})
+	if err != nil {
+		return diffs, err
+	}
+	err = d.scan(ctx, b, func(keys []string) error {
+		found, err := d.extras(keys)
+		diffs = append(diffs, found...)
+		return err
+	})
+	return diffs, err
+}
+
+// Sync makes b match a for every difference Diff finds and returns the
+// differences it repaired. Extras are only deleted with
+// Options.DeleteExtra. Values are copied without their TTL.
+func Sync(ctx context.Context, a, b *celrix.Client, opts Options) ([]Difference, error) {
+	diffs, err := Diff(ctx, a, b, opts)
+	if err != nil {
+		return nil, err
+	}
+	repaired := make([]Difference, 0, len(diffs))
+	for _, d := range diffs {
+		if err := ctx.Err(); err != nil {
+			return repaired, err
+		}
+		if d.Kind == Extra {
+			if !opts.DeleteExtra {
+				continue
+			}
+			if _, err := b.Del(d.Key); err != nil {
+				return repaired, fmt.Errorf("celrixsync: delete %s: %w", d.Key, err)
+			}
+			repaired = append(repaired, d)
+			continue
+		}
+		v, ok, err := a.Get(d.Key)
+		var se *celrix.ServerError
+		if errors.As(err, &se) {
+			// Not a string value, compared by hash but not copied
+			continue
+		}
+		if err != nil {
+			return repaired, fmt.Errorf("celrixsync: read %s: %w", d.Key, err)
+		}
+		if !ok {
+			// Deleted from a since the compare; the next run reports it
+			// as an extra
+			continue
+		}
+		if err := b.Set(d.Key, v); err != nil {
+			return repaired, fmt.Errorf("celrixsync: copy %s: %w", d.Key, err)
+		}
+		repaired = append(repaired, d)
+	}
+	return repaired, nil
+}
+
+type differ struct {
+	a, b *celrix.Client
+	opts Options
+	// versions and hashes are cleared once either server turns out not
+	// to report them, after which keys are compared by value alone
+	versions, hashes bool
+}
+
+// scan feeds the keys of c matching the options to fn in batches
+func (d *differ) scan(ctx context.Context, c *celrix.Client, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		next, keys, err := c.Scan(cursor, d.opts.Match, d.opts.batchSize())
+		if err != nil {
+			return fmt.Errorf("celrixsync: scan: %w", err)
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// compare checks a batch of a's keys against b
+func (d *differ) compare(keys []string) ([]Difference, error) {
+	va, vb, err := d.keyVersions(keys)
+	if err != nil {
+		return nil, err
+	}
+	sa, err := d.sums(d.a, keys)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := d.sums(d.b, keys)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []Difference
+	for i, key := range keys {
+		diff := Difference{Key: key, VersionA: va[i], VersionB: vb[i]}
+		switch {
+		case !sa[i].ok:
+			// Gone or unreadable on a; the scan of b reports extras
+			continue
+		case sb[i].unreadable:
+			continue
+		case !sb[i].ok:
+			diff.Kind = Missing
+		case vb[i] < va[i]:
+			diff.Kind = Stale
+		case sa[i].sum != sb[i].sum:
+			diff.Kind = Mismatched
+		default:
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// extras reports the keys of a batch of b's that a lacks
+func (d *differ) extras(keys []string) ([]Difference, error) {
+	sa, err := d.sums(d.a, keys)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []Difference
+	for i, key := range keys {
+		if !sa[i].ok && !sa[i].unreadable {
+			diffs = append(diffs, Difference{Key: key, Kind: Extra})
+		}
+	}
+	return diffs, nil
+}
+
+// keyVersions fetches the versions of keys on both instances, or zeros
+// when either server does not report them
+func (d *differ) keyVersions(keys []string) (va, vb []uint64, err error) {
+	if d.versions {
+		if va, err = d.a.KeyVersions(keys...); err == nil {
+			vb, err = d.b.KeyVersions(keys...)
+		}
+		var se *celrix.ServerError
+		switch {
+		case err == nil:
+			return va, vb, nil
+		case errors.As(err, &se):
+			d.versions = false
+		default:
+			return nil, nil, fmt.Errorf("celrixsync: versions: %w", err)
+		}
+	}
+	return make([]uint64, len(keys)), make([]uint64, len(keys)), nil
+}
+
+// sum is a key's value hash as seen on one instance
+type sum struct {
+	sum uint64
+	ok  bool
+	// unreadable marks keys GET refuses, such as vectors, when values
+	// are fetched
+	unreadable bool
+}
+
+// sums hashes keys on c in one round trip, server-side when possible
+func (d *differ) sums(c *celrix.Client, keys []string) ([]sum, error) {
+	if d.hashes {
+		hashes, err := c.Hashes(keys...)
+		var se *celrix.ServerError
+		switch {
+		case err == nil:
+			out := make([]sum, len(hashes))
+			for i, h := range hashes {
+				if h != nil {
+					out[i] = sum{sum: *h, ok: true}
+				}
+			}
+			return out, nil
+		case errors.As(err, &se):
+			d.hashes = false
+		default:
+			return nil, fmt.Errorf("celrixsync: hashes: %w", err)
+		}
+	}
+
+	out := make([]sum, 0, len(keys))
+	err := c.ForEach(keys, func(_ string, r celrix.Reply) error {
+		var s sum
+		if r.Err() != nil {
+			s.unreadable = true
+		} else if b, ok := r.Bytes(); ok {
+			s.sum, s.ok = celrix.HashValue(b), true
+		}
+		out = append(out, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("celrixsync: read: %w", err)
+	}
+	return out, nil
+}
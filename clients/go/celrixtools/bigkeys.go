@@ -0,0 +1,79 @@
+// Package celrixtools holds operational utilities built on the CELRIX
+// client.
+package celrixtools
+
+import (
+	"context"
+	"fmt"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// scanBatch is the COUNT hint passed to SCAN
+const scanBatch = 1000
+
+// BigKey is a key whose memory usage reached the threshold
+type BigKey struct {
+	Key  string
+	Size int64
+}
+
+// BigKeySummary describes a completed or interrupted scan
+type BigKeySummary struct {
+	Scanned int
+	Found   int
+	// Largest is the biggest key seen, whether or not it reached the
+	// threshold
+	Largest BigKey
+	// TotalBytes sums the memory usage of every scanned key
+	TotalBytes int64
+}
+
+// FindBigKeys walks the whole keyspace with SCAN, asks the server for the
+// memory usage of each key, and calls fn for every key of at least
+// threshold bytes as it is found. Keys deleted between SCAN and the size
+// lookup are skipped.
+//
+// The scan stops early if ctx is cancelled or fn returns an error; the
+// summary covers the keys examined so far. c must not be used concurrently
+// while the scan runs.
+func FindBigKeys(ctx context.Context, c *celrix.Client, threshold int64, fn func(BigKey) error) (BigKeySummary, error) {
+	var sum BigKeySummary
+	var cursor uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return sum, err
+		}
+		next, keys, err := c.Scan(cursor, "", scanBatch)
+		if err != nil {
+			return sum, fmt.Errorf("celrixtools: scan: %w", err)
+		}
+		for _, key := range keys {
+			if err := ctx.Err(); err != nil {
+				return sum, err
+			}
+			size, ok, err := c.MemoryUsage(key)
+			if err != nil {
+				return sum, fmt.Errorf("celrixtools: memory usage: %w", err)
+			}
+			if !ok {
+				continue
+			}
+			sum.Scanned++
+			sum.TotalBytes += size
+			if size > sum.Largest.Size {
+				sum.Largest = BigKey{Key: key, Size: size}
+			}
+			if size >= threshold {
+				sum.Found++
+				if err := fn(BigKey{Key: key, Size: size}); err != nil {
+					return sum, err
+				}
+			}
+		}
+		if next == 0 {
+			return sum, nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,126 @@
+// Package celrixversion keeps a bounded history of values for config-style
+// keys.
+//
+// Each write to key k takes the next version number from the counter
+// k:version, stores the value under k:v:<number>, and mirrors it to k
+// itself so plain reads see the latest value. Versions older than the
+// retained count are deleted as new ones are written.
+package celrixversion
+
+import (
+	"fmt"
+	"strconv"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// DefaultKeep is the number of versions retained when New is given zero
+const DefaultKeep = 10
+
+// Version is one stored value of a key
+type Version struct {
+	// Number increases by one with each write, starting at 1
+	Number int64
+	Value  string
+}
+
+// Store reads and writes versioned keys. Like the client, it is not safe
+// for concurrent use; concurrent writers in separate processes each get
+// distinct version numbers, but the mirrored latest value is whichever
+// write landed last.
+type Store struct {
+	c    *celrix.Client
+	keep int
+}
+
+// New returns a store that retains keep versions of each key
+func New(c *celrix.Client, keep int) *Store {
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	return &Store{c: c, keep: keep}
+}
+
+func counterKey(key string) string { return key + ":version" }
+
+func versionKey(key string, n int64) string {
+	return key + ":v:" + strconv.FormatInt(n, 10)
+}
+
+// SetVersioned writes value as the newest version of key and returns its
+// version number
+func (s *Store) SetVersioned(key, value string) (int64, error) {
+	n, err := s.c.IncrBy(counterKey(key), 1)
+	if err != nil {
+		return 0, fmt.Errorf("celrixversion: %s: %w", key, err)
+	}
+	if err := s.c.Set(versionKey(key, n), value); err != nil {
+		return 0, fmt.Errorf("celrixversion: %s version %d: %w", key, n, err)
+	}
+	if err := s.c.Set(key, value); err != nil {
+		return 0, fmt.Errorf("celrixversion: %s: %w", key, err)
+	}
+	if old := n - int64(s.keep); old > 0 {
+		if _, err := s.c.Del(versionKey(key, old)); err != nil {
+			return n, fmt.Errorf("celrixversion: trim %s version %d: %w", key, old, err)
+		}
+	}
+	return n, nil
+}
+
+// GetVersion returns version n of key. The boolean is false if that
+// version was never written or has been trimmed.
+func (s *Store) GetVersion(key string, n int64) (string, bool, error) {
+	if n <= 0 {
+		return "", false, fmt.Errorf("celrixversion: version must be positive, got %d", n)
+	}
+	v, ok, err := s.c.Get(versionKey(key, n))
+	if err != nil {
+		return "", false, fmt.Errorf("celrixversion: %s version %d: %w", key, n, err)
+	}
+	return v, ok, nil
+}
+
+// Latest returns the number of the newest version of key, or 0 if it has
+// none
+func (s *Store) Latest(key string) (int64, error) {
+	// Adding zero reads the counter atomically, treating a missing one as 0
+	n, err := s.c.IncrBy(counterKey(key), 0)
+	if err != nil {
+		return 0, fmt.Errorf("celrixversion: %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// History returns the retained versions of key, newest first, fetched in
+// one pipelined round trip
+func (s *Store) History(key string) ([]Version, error) {
+	latest, err := s.Latest(key)
+	if err != nil {
+		return nil, err
+	}
+	var numbers []int64
+	var keys []string
+	for n := latest; n > 0 && n > latest-int64(s.keep); n-- {
+		numbers = append(numbers, n)
+		keys = append(keys, versionKey(key, n))
+	}
+
+	history := make([]Version, 0, len(keys))
+	i := 0
+	err = s.c.ForEach(keys, func(_ string, r celrix.Reply) error {
+		n := numbers[i]
+		i++
+		if err := r.Err(); err != nil {
+			return fmt.Errorf("celrixversion: %s version %d: %w", key, n, err)
+		}
+		if v, ok := r.Str(); ok {
+			history = append(history, Version{Number: n, Value: v})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
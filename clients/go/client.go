@@ -2,22 +2,30 @@ package celrix
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
 	"math"
 	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
 )
 
 // Constants
 const (
-	Magic      = "CELX"
-	Version    = 1
-	HeaderSize = 22
+	Magic      = wire.Magic
+	Version    = wire.Version
+	HeaderSize = wire.HeaderSize
 )
 
-// OpCodes
+// OpCodes. Codes the server defines in src/protocol/frame.rs keep its
+// meaning; commands it does not have use codes it leaves free, so a server
+// never mistakes one for another command with a compatible payload.
 const (
 	OpPing   = 0x01
 	OpPong   = 0x02
@@ -25,43 +33,412 @@ const (
 	OpSet    = 0x04
 	OpDel    = 0x05
 	OpExists = 0x06
+	OpMGet   = 0x07
+	OpIncrBy = 0x0C
+	OpScan   = 0x0E
 
 	// Response codes
-	OpOk      = 0x10
-	OpError   = 0x11
-	OpValue   = 0x12
-	OpNil     = 0x13
-	OpInteger = 0x14
-	OpArray   = 0x15
+	OpOk          = 0x10
+	OpError       = 0x11
+	OpValue       = 0x12
+	OpNil         = 0x13
+	OpInteger     = 0x14
+	OpArray       = 0x15
+	OpCompressed  = 0x16
+	OpTypedArray  = 0x17
+	OpDouble      = 0x18
+	OpMap         = 0x19
+	OpBool        = 0x1A
+	OpBigInt      = 0x1B
+	OpTypedString = 0x1C
+	OpAttributes  = 0x1D
+	OpValueChunk  = 0x1E
 
 	// Vector ops
-	OpVAdd    = 0x20
-	OpVSearch = 0x21
+	OpVAdd           = 0x20
+	OpVSearch        = 0x21
+	OpVAddMeta       = 0x22
+	OpVSearchFilter  = 0x23
+	OpVAddTTL        = 0x24
+	OpVAddBatch      = 0x25
+	OpVGet           = 0x26
+	OpVSearchMeta    = 0x27
+	OpVDelta         = 0x28
+	OpVSearchExplain = 0x29
+	OpVSearchBatch   = 0x2A
+
+	// Collection ops
+	OpCreateCollection   = 0x30
+	OpDropCollection     = 0x31
+	OpDescribeCollection = 0x32
+	OpCVAdd              = 0x33
+	OpCVSearch           = 0x34
+	OpCreateAlias        = 0x35
+	OpSwapAlias          = 0x36
+	OpDropAlias          = 0x37
+	OpListCollections    = 0x38
+	OpCreateFieldIndex   = 0x39
+	OpDropFieldIndex     = 0x3A
+	OpSearchByField      = 0x3B
+	OpAggregate          = 0x3C
+	OpSnapshotSearch     = 0x3D
+	OpSnapshotVSearch    = 0x3E
+	OpSnapshotRelease    = 0x3F
+
+	// Change data capture
+	OpCDCSubscribe      = 0x40
+	OpChangeEvent       = 0x41
+	OpKeyEventSubscribe = 0x42
+	OpKeyEvent          = 0x43
+
+	// Backup and restore
+	OpExport       = 0x48
+	OpExportChunk  = 0x49
+	OpRestore      = 0x4A
+	OpRestoreChunk = 0x4B
+	OpRestoreEnd   = 0x4C
+	OpExportSince  = 0x4D
+
+	// Connection
+	OpHello        = 0x50
+	OpHealth       = 0x51
+	OpSelect       = 0x52
+	OpDictionaries = 0x53
+	OpCapabilities = 0x54
+
+	// Administration
+	OpACLList         = 0x60
+	OpACLSetUser      = 0x61
+	OpACLDelUser      = 0x62
+	OpConfigGet       = 0x63
+	OpConfigSet       = 0x64
+	OpKeyspaceSample  = 0x65
+	OpHotKeys         = 0x66
+	OpMemoryUsage     = 0x67
+	OpFlushDB         = 0x68
+	OpAuditLog        = 0x69
+	OpDryRun          = 0x6A
+	OpFlushPrefix     = 0x6B
+	OpPreloadIndex    = 0x6C
+	OpPreloadProgress = 0x6D
+	OpClientList      = 0x6E
+
+	// Sorted sets
+	OpZAdd          = 0x70
+	OpZRem          = 0x71
+	OpZScore        = 0x72
+	OpZCard         = 0x73
+	OpZRangeByScore = 0x74
+
+	// Probabilistic structures
+	OpBFReserve   = 0x80
+	OpBFAdd       = 0x81
+	OpBFExists    = 0x82
+	OpBFMAdd      = 0x83
+	OpBFMExists   = 0x84
+	OpCMSInit     = 0x88
+	OpCMSIncr     = 0x89
+	OpCMSQuery    = 0x8A
+	OpTopKReserve = 0x8B
+	OpTopKAdd     = 0x8C
+	OpTopKList    = 0x8D
+
+	// Time series
+	OpTSCreate     = 0x90
+	OpTSAdd        = 0x91
+	OpTSRange      = 0x92
+	OpTSCreateRule = 0x93
+	OpTSDeleteRule = 0x94
+
+	// Batch reads
+	OpMVersion = 0xA1
+	OpHash     = 0xA2
+	OpMHash    = 0xA3
+
+	// Key expiry
+	OpExpire  = 0xB0
+	OpTTL     = 0xB1
+	OpPersist = 0xB2
+
+	// Streamed values
+	OpSetStream    = 0xB8
+	OpSetChunk     = 0xB9
+	OpSetStreamEnd = 0xBA
+	OpGetStream    = 0xBB
+
+	// Conditional writes
+	OpSetNX            = 0xC0
+	OpCompareAndSwap   = 0xC1
+	OpCompareAndDelete = 0xC2
+
+	// Key lifecycle
+	OpRenameBatch = 0xC8
+	OpSoftDel     = 0xC9
+	OpUndelete    = 0xCA
+
+	// Compressed values
+	OpSetCompressed = 0xD0
 )
 
 // Client represents a CELRIX client
 type Client struct {
+	addr string
+	// connMu guards replacing conn against RunContext interrupting it from
+	// another goroutine
+	connMu    sync.Mutex
 	conn      net.Conn
 	rw        *bufio.ReadWriter
 	nextReqID uint64
+	schemas   schemaCache
+	opts      options
+	journal   *writeJournal
+	layout    wire.Layout
+	latency   latencyTracker
+
+	// The request awaiting its first reply frame, for latency tracking and
+	// adaptive deadlines
+	pending      bool
+	pendingStart time.Time
+	pendingState
+	// ctxDeadline and ctxDone are the deadline and done channel of the
+	// context bounding the current command, if any
+	ctxDeadline time.Time
+	ctxDone     <-chan struct{}
+
+	// broken is the failure of the connection under WithRetry, or the
+	// DesyncError, TimeoutError or context error that made it unusable, so
+	// the next command reconnects first; db is the database chosen with Select,
+	// restored on reconnection
+	broken error
+	closed bool
+	db     int
+	// replaying is set while the write journal is replayed after a dial
+	replaying bool
+	// captured is the request sampled by WithSampledCapture, until its
+	// reply arrives
+	captured *pendingCapture
+
+	// lastReply is the frame readResponse last decoded, for
+	// unexpectedReply
+	lastReply frame
+
+	// nextKey is the key of the command about to be sent, set by sendKeyed,
+	// and nextFlags its frame flags
+	nextKey   string
+	nextFlags uint16
+
+	// pendingCollection names the collection of the outstanding command,
+	// and nextCollection that of the command about to be sent; labeled is
+	// set while WithProfilerLabels labels are applied
+	pendingCollection string
+	nextCollection    string
+	labeled           bool
+
+	failure atomic.Pointer[clientFailure]
+
+	serverID string
+
+	// caps are the capabilities accepted by the server, and vectors the
+	// delta bases kept for WithVectorDeltas
+	caps    Capability
+	vectors *vectorCache
+
+	// wbuf holds the frame being written; pbuf and rbuf are the request
+	// payload and reply buffers of the allocation-free fast paths
+	wbuf, pbuf, rbuf []byte
+
+	// lastAttrs are the attributes of the last reply read; nextAttrs those
+	// received for request nextAttrsReq, whose reply is still to come
+	lastAttrs    map[string]Reply
+	nextAttrs    map[string]Reply
+	nextAttrsReq uint64
+
+	// serverLatency holds execution times reported with WithServerTiming
+	serverLatency latencyTracker
+
+	// otlp is the series this client feeds under WithOTLPExporter
+	otlp   *otlpSeries
+	events *eventStream
+
+	// writeDict is the dictionary accepted by the server for compressing
+	// values, if any
+	writeDict *Dictionary
+
+	// counted is set when the client contributes to the expvar gauges
+	counted bool
+	// noMGet is set once the server has refused MGET
+	noMGet bool
+
+	// sentCommands, sentBytes and desyncs are read by Stats from other
+	// goroutines
+	sentCommands atomic.Uint64
+	sentBytes    atomic.Uint64
+	desyncs      atomic.Uint64
+}
+
+// pendingState describes the command in flight
+type pendingState struct {
+	pendingOp      uint8
+	pendingTimeout time.Duration
+	pendingReqID   uint64
+	pendingKey     string
+	// pendingAttempt numbers the tries of the command under WithRetry
+	pendingAttempt int
+	// lastReq is the request WithRetry would resend, until its reply
+	// starts to arrive
+	lastReq *sentRequest
+	// slot is the WithOpConcurrencyLimit semaphore the command holds
+	slot chan struct{}
 }
 
 // Connect connects to the CELRIX server
-func Connect(addr string) (*Client, error) {
-	conn, err := net.Dial("tcp", addr)
+func Connect(addr string, opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	var journal *writeJournal
+	if o.journalDir != "" {
+		j, err := openJournal(o.journalDir)
+		if err != nil {
+			return nil, fmt.Errorf("open write journal: %w", err)
+		}
+		journal = j
+	}
+
+	c := &Client{
+		addr:    addr,
+		opts:    o,
+		journal: journal,
+		events:  newEventStream(),
+	}
+	if o.vectorDeltas > 0 {
+		c.vectors = &vectorCache{capacity: o.vectorDeltas, vectors: make(map[string][]float32)}
+	}
+	err := c.dial(context.Background())
+	if o.retry != nil {
+		for n := 1; err != nil && n < o.retry.attempts(); n++ {
+			c.emit(EventRetry, n, err)
+			c.backoff(o.retry.backoff(n))
+			err = c.dial(context.Background())
+		}
+		c.broken = nil
+	}
 	if err != nil {
+		if journal != nil {
+			journal.close()
+		}
 		return nil, err
 	}
-	return &Client{
-		conn:      conn,
-		rw:        bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)),
-		nextReqID: 1,
-	}, nil
+
+	if err := c.replayPending(); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("replay write journal: %w", err)
+	}
+	c.countOpen()
+	c.startOTLP()
+	return c, nil
+}
+
+// dial opens the connection and negotiates the protocol version,
+// compression dictionaries and capabilities
+func (c *Client) dial(ctx context.Context) error {
+	if err := c.open(ctx); err != nil {
+		return err
+	}
+	if err := c.negotiateDictionaries(); err != nil {
+		c.conn.Close()
+		return err
+	}
+	if err := c.negotiateCapabilities(); err != nil {
+		c.conn.Close()
+		return err
+	}
+	c.emit(EventConnected, 0, nil)
+	return nil
+}
+
+// open opens the connection and negotiates the protocol version
+func (c *Client) open(ctx context.Context) error {
+	conn, err := c.opts.dialer()(ctx, "tcp", c.addr)
+	if err != nil {
+		c.logReconnect("dial failed", "error", err)
+		return err
+	}
+	c.logReconnect("connected")
+	c.setConn(conn)
+	if len(c.opts.pins) > 0 {
+		// A pinned server must complete the handshake; there is no
+		// fallback to an unauthenticated connection
+		if err := c.handshake(); err != nil {
+			c.conn.Close()
+			return err
+		}
+		return nil
+	}
+	if len(c.opts.versions) == 0 && len(c.opts.connLabels) == 0 {
+		return nil
+	}
+
+	if err := c.handshake(); err != nil {
+		if isServerError(err) {
+			// HELLO was refused but the connection is intact
+			c.logReconnect("handshake refused, using protocol version 1", "error", err)
+			c.layout = wire.V1
+			return nil
+		}
+		c.logReconnect("handshake failed, redialing with protocol version 1", "error", err)
+		c.emit(EventRetry, 1, err)
+		// Servers predating the handshake may drop the connection on
+		// HELLO; fall back to version 1 on a fresh one
+		c.conn.Close()
+		if conn, err = c.opts.dialer()(ctx, "tcp", c.addr); err != nil {
+			return err
+		}
+		c.setConn(conn)
+	}
+	return nil
+}
+
+func (c *Client) setConn(conn net.Conn) {
+	if c.pending {
+		c.gauge(&varInflight, -1)
+	}
+	expvarAdd(&varDials, 1)
+	if c.otlp != nil {
+		c.otlp.add(&c.otlp.dials, 1)
+	}
+	c.connMu.Lock()
+	c.conn = conn
+	c.connMu.Unlock()
+	c.rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	c.nextReqID = 1
+	c.layout = wire.V1
+	c.pending = false
 }
 
 // Close closes the connection
 func (c *Client) Close() error {
-	return c.conn.Close()
+	c.closed = true
+	c.releaseSlot()
+	c.stopOTLP()
+	if c.pending {
+		c.pending = false
+		c.gauge(&varInflight, -1)
+	}
+	c.gauge(&varOpen, -1)
+	c.counted = false
+	if c.journal != nil {
+		c.journal.close()
+	}
+	err := c.conn.Close()
+	c.emit(EventDisconnected, 0, nil)
+	c.closeEvents()
+	return err
 }
 
 // Ping checks server health
@@ -76,34 +453,33 @@ func (c *Client) Ping() error {
 	if s, ok := resp.(string); ok && s == "PONG" {
 		return nil
 	}
-	return fmt.Errorf("unexpected response for PING: %v", resp)
+	return c.unexpectedReply()
+}
+
+// Select switches the connection to numbered database db. New connections
+// start on database 0.
+func (c *Client) Select(db int) error {
+	if db < 0 {
+		return fmt.Errorf("invalid database %d", db)
+	}
+	// Payload: [db u32]
+	if err := c.sendFrame(OpSelect, binary.BigEndian.AppendUint32(nil, uint32(db))); err != nil {
+		return err
+	}
+	if err := c.expectOK(); err != nil {
+		return err
+	}
+	c.db = db
+	return nil
 }
 
 // Set sets a key-value pair
 func (c *Client) Set(key, value string) error {
-	// Payload: [key_len][key][val_len][val][ttl]
-	keyBytes := []byte(key)
-	valBytes := []byte(value)
-
-	payload := make([]byte, 4+len(keyBytes)+4+len(valBytes)+8)
-	offset := 0
-
-	binary.BigEndian.PutUint32(payload[offset:], uint32(len(keyBytes)))
-	offset += 4
-	copy(payload[offset:], keyBytes)
-	offset += len(keyBytes)
-
-	binary.BigEndian.PutUint32(payload[offset:], uint32(len(valBytes)))
-	offset += 4
-	copy(payload[offset:], valBytes)
-	offset += len(valBytes)
-
-	binary.BigEndian.PutUint64(payload[offset:], 0) // TTL 0 = None
-
-	if err := c.sendFrame(OpSet, payload); err != nil {
+	opcode, payload, err := c.setPayload(key, []byte(value), 0)
+	if err != nil {
 		return err
 	}
-	return c.expectOK()
+	return c.write(opcode, key, payload)
 }
 
 // Get gets a value by key
@@ -114,7 +490,7 @@ func (c *Client) Get(key string) (string, bool, error) {
 	binary.BigEndian.PutUint32(payload[0:], uint32(len(keyBytes)))
 	copy(payload[4:], keyBytes)
 
-	if err := c.sendFrame(OpGet, payload); err != nil {
+	if err := c.sendKeyed(OpGet, key, payload); err != nil {
 		return "", false, err
 	}
 
@@ -131,7 +507,7 @@ func (c *Client) Get(key string) (string, bool, error) {
 		return s, true, nil
 	}
 
-	return "", false, fmt.Errorf("unexpected response type: %T", resp)
+	return "", false, c.unexpectedReply()
 }
 
 // Del deletes a key
@@ -141,23 +517,36 @@ func (c *Client) Del(key string) (bool, error) {
 	binary.BigEndian.PutUint32(payload[0:], uint32(len(keyBytes)))
 	copy(payload[4:], keyBytes)
 
-	if err := c.sendFrame(OpDel, payload); err != nil {
-		return false, err
+	c.vectors.forget(key)
+	if err := c.sendKeyed(OpDel, key, payload); err != nil {
+		return false, c.journalFailure(OpDel, payload, err)
 	}
 
 	resp, err := c.readResponse()
 	if err != nil {
-		return false, err
+		return false, c.journalFailure(OpDel, payload, err)
 	}
 
-	if n, ok := resp.(int64); ok {
-		return n > 0, nil
+	return c.toBool(resp)
+}
+
+// Exists reports whether key exists
+func (c *Client) Exists(key string) (bool, error) {
+	if err := c.sendKeyed(OpExists, key, appendString(nil, key)); err != nil {
+		return false, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return false, err
 	}
-	return false, fmt.Errorf("unexpected response type: %T", resp)
+	return c.toBool(resp)
 }
 
 // VAdd adds a vector
 func (c *Client) VAdd(key string, vector []float32) error {
+	if err := c.checkVector(OpVAdd, key, vector); err != nil {
+		return err
+	}
 	// Payload: [key_len][key][count][f32...]
 	keyBytes := []byte(key)
 	payloadLen := 4 + len(keyBytes) + 4 + (len(vector) * 4)
@@ -178,31 +567,108 @@ func (c *Client) VAdd(key string, vector []float32) error {
 		offset += 4
 	}
 
-	if err := c.sendFrame(OpVAdd, payload); err != nil {
+	if sent, err := c.vaddDelta(key, vector, payload); sent || err != nil {
+		if err == nil {
+			c.vectors.put(key, vector)
+		}
+		return err
+	}
+	if err := c.write(OpVAdd, key, payload); err != nil {
+		c.vectors.forget(key)
 		return err
 	}
-	return c.expectOK()
+	c.vectors.put(key, vector)
+	return nil
 }
 
-// VSearch searches for similar vectors
-func (c *Client) VSearch(vector []float32, k int) ([]string, error) {
-	// Payload: [count][f32...][k]
-	payloadLen := 4 + (len(vector) * 4) + 4
-	payload := make([]byte, payloadLen)
+// VAddWithTTL adds a vector that the server expires after ttl
+func (c *Client) VAddWithTTL(key string, vector []float32, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.New("TTL must be positive")
+	}
+	if err := c.checkVector(OpVAddTTL, key, vector); err != nil {
+		return err
+	}
 
-	offset := 0
-	binary.BigEndian.PutUint32(payload[offset:], uint32(len(vector)))
-	offset += 4
+	// Payload: [key_len][key][count][f32...][ttl]
+	payload := make([]byte, 0, 4+len(key)+4+len(vector)*4+8)
+	payload = appendString(payload, key)
+	payload = appendVector(payload, vector)
+	payload = binary.BigEndian.AppendUint64(payload, ttlSeconds(ttl))
 
-	for _, f := range vector {
-		bits := math.Float32bits(f)
-		binary.BigEndian.PutUint32(payload[offset:], bits)
-		offset += 4
+	return c.write(OpVAddTTL, key, payload)
+}
+
+// VectorItem is a vector and its metadata for batch insertion
+type VectorItem struct {
+	Key      string
+	Vector   []float32
+	Metadata Metadata
+}
+
+// VAddBatch adds many vectors in a single request. The batch is applied
+// atomically by the server: either every item is added or none is.
+func (c *Client) VAddBatch(items []VectorItem) error {
+	// Payload: [count]([key_len][key][count][f32...][metadata])...
+	size := 4
+	for _, it := range items {
+		if err := c.checkVector(OpVAddBatch, it.Key, it.Vector); err != nil {
+			return err
+		}
+		size += 4 + len(it.Key) + 4 + len(it.Vector)*4 + it.Metadata.encodedLen()
+	}
+	payload := make([]byte, 0, size)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(items)))
+
+	var err error
+	for _, it := range items {
+		payload = appendString(payload, it.Key)
+		payload = appendVector(payload, it.Vector)
+		if payload, err = it.Metadata.appendTo(payload); err != nil {
+			return fmt.Errorf("item %q: %w", it.Key, err)
+		}
+	}
+	return c.write(OpVAddBatch, "", payload)
+}
+
+// VAddWithMetadata adds a vector with typed metadata fields
+func (c *Client) VAddWithMetadata(key string, vector []float32, meta Metadata) error {
+	if err := c.checkVector(OpVAddMeta, key, vector); err != nil {
+		return err
+	}
+	// Payload: [key_len][key][count][f32...][metadata]
+	payload := make([]byte, 0, 4+len(key)+4+len(vector)*4+meta.encodedLen())
+	payload = appendString(payload, key)
+	payload = appendVector(payload, vector)
+
+	payload, err := meta.appendTo(payload)
+	if err != nil {
+		return err
+	}
+
+	return c.write(OpVAddMeta, key, payload)
+}
+
+// VSearchFilter searches for similar vectors whose metadata matches filter
+func (c *Client) VSearchFilter(vector []float32, k int, filter Filter) ([]string, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
 	}
+	if err := c.checkVector(OpVSearchFilter, "", vector); err != nil {
+		return nil, err
+	}
+
+	// Payload: [count][f32...][k][filter]
+	payload := make([]byte, 0, 4+len(vector)*4+4+64)
+	payload = appendVector(payload, vector)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(k))
 
-	binary.BigEndian.PutUint32(payload[offset:], uint32(k))
+	payload, err := filter.appendTo(payload)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := c.sendFrame(OpVSearch, payload); err != nil {
+	if err := c.sendFrame(OpVSearchFilter, payload); err != nil {
 		return nil, err
 	}
 
@@ -210,7 +676,127 @@ func (c *Client) VSearch(vector []float32, k int) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	return toKeys(resp)
+}
+
+// VGet returns the stored vector and metadata for key. The boolean is false
+// if the key holds no vector.
+func (c *Client) VGet(key string) (VectorItem, bool, error) {
+	// Payload: [key_len][key]
+	if err := c.sendKeyed(OpVGet, key, appendString(nil, key)); err != nil {
+		return VectorItem{}, false, err
+	}
 
+	resp, err := c.readResponse()
+	if err != nil {
+		return VectorItem{}, false, err
+	}
+	if resp == nil {
+		return VectorItem{}, false, nil
+	}
+	s, ok := resp.(string)
+	if !ok {
+		return VectorItem{}, false, c.unexpectedReply()
+	}
+
+	// Response: [count][f32...][metadata]
+	b := []byte(s)
+	vec, n, err := readVector(b)
+	if err != nil {
+		return VectorItem{}, false, err
+	}
+	item := VectorItem{Key: key, Vector: vec}
+	if n < len(b) {
+		meta, _, err := decodeMetadata(b[n:])
+		if err != nil {
+			return VectorItem{}, false, err
+		}
+		item.Metadata = meta
+	}
+	return item, true, nil
+}
+
+// Internal helpers
+
+// write sends a write command and expects OK, journaling it if the
+// connection fails before the reply arrives
+func (c *Client) write(opcode uint8, key string, payload []byte) error {
+	if err := c.sendKeyed(opcode, key, payload); err != nil {
+		return c.journalFailure(opcode, payload, err)
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return c.journalFailure(opcode, payload, err)
+	}
+	if s, ok := resp.(string); ok && s == "OK" {
+		return nil
+	}
+	return c.cmdErr(fmt.Errorf("expected OK, got %v", resp))
+}
+
+// appendVector appends [count: u32][f32...]
+func appendVector(buf []byte, vector []float32) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(vector)))
+	for _, f := range vector {
+		buf = binary.BigEndian.AppendUint32(buf, math.Float32bits(f))
+	}
+	return buf
+}
+
+// readVector reads [count: u32][f32...] and returns the bytes consumed
+func readVector(b []byte) ([]float32, int, error) {
+	if len(b) < 4 {
+		return nil, 0, errors.New("incomplete vector length")
+	}
+	count := int(binary.BigEndian.Uint32(b))
+	if len(b) < 4+count*4 {
+		return nil, 0, errors.New("incomplete vector")
+	}
+	vec := make([]float32, count)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.BigEndian.Uint32(b[4+i*4:]))
+	}
+	return vec, 4 + count*4, nil
+}
+
+// encodeSet builds a Set payload: [key_len][key][val_len][val][ttl]
+func encodeSet(key string, value []byte, ttl time.Duration) []byte {
+	payload := make([]byte, 0, 4+len(key)+4+len(value)+8)
+	payload = appendString(payload, key)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(value)))
+	payload = append(payload, value...)
+	return binary.BigEndian.AppendUint64(payload, ttlSeconds(ttl)) // TTL 0 = None
+}
+
+// ttlSeconds converts a TTL to the wire's whole seconds, rounding up so a
+// short positive TTL never becomes 0 (no expiry)
+func ttlSeconds(ttl time.Duration) uint64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return uint64((ttl + time.Second - 1) / time.Second)
+}
+
+// appendString appends [len: u32][bytes]
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
+
+// readString reads [len: u32][bytes] and returns the bytes consumed
+func readString(b []byte) (string, int, error) {
+	if len(b) < 4 {
+		return "", 0, errors.New("incomplete string length")
+	}
+	n := int(binary.BigEndian.Uint32(b))
+	if len(b) < 4+n {
+		return "", 0, errors.New("incomplete string")
+	}
+	return string(b[4 : 4+n]), 4 + n, nil
+}
+
+// toKeys converts an array response into a list of keys
+func toKeys(resp interface{}) ([]string, error) {
 	if arr, ok := resp.([]interface{}); ok {
 		keys := make([]string, len(arr))
 		for i, item := range arr {
@@ -226,7 +812,24 @@ func (c *Client) VSearch(vector []float32, k int) ([]string, error) {
 	return nil, fmt.Errorf("expected array response, got %T", resp)
 }
 
-// Internal helpers
+// toBool converts a yes/no reply: an OpBool, or an integer count from
+// servers predating it
+func (c *Client) toBool(resp interface{}) (bool, error) {
+	if b, ok := yesNo(resp); ok {
+		return b, nil
+	}
+	return false, c.unexpectedReply()
+}
+
+func yesNo(v interface{}) (bool, bool) {
+	switch v := v.(type) {
+	case bool:
+		return v, true
+	case int64:
+		return v > 0, true
+	}
+	return false, false
+}
 
 func (c *Client) expectOK() error {
 	resp, err := c.readResponse()
@@ -236,54 +839,261 @@ func (c *Client) expectOK() error {
 	if s, ok := resp.(string); ok && s == "OK" {
 		return nil
 	}
-	return fmt.Errorf("expected OK, got %v", resp)
+	return c.cmdErr(fmt.Errorf("expected OK, got %v", resp))
+}
+
+// ready runs the checks commands pass before they are sent, alone or
+// pipelined: the client has not failed, the command policy allows each of
+// ops, and a connection an earlier command left broken is replaced. keys,
+// if not nil, holds the key of each op for a policy error.
+func (c *Client) ready(ops []uint8, keys []string) error {
+	if err := c.failedErr(); err != nil {
+		return c.cmdErr(err)
+	}
+	for i, op := range ops {
+		if err := c.opts.policy.check(op); err != nil {
+			c.pendingOp = op
+			if keys != nil {
+				c.pendingKey = keys[i]
+			}
+			return c.cmdErr(err)
+		}
+	}
+	if c.broken != nil {
+		if err := c.reconnect(c.broken); err != nil {
+			return c.cmdErr(unwrapCommand(err))
+		}
+		c.pendingReqID = c.nextReqID
+	}
+	return nil
 }
 
 func (c *Client) sendFrame(opcode uint8, payload []byte) error {
-	header := make([]byte, HeaderSize)
-	copy(header[0:4], []byte(Magic))
-	header[4] = uint8(Version)
-	header[5] = opcode
-	binary.BigEndian.PutUint16(header[6:], 0) // flags
-	binary.BigEndian.PutUint32(header[8:], uint32(len(payload)))
-	binary.BigEndian.PutUint64(header[12:], c.nextReqID)
-	binary.BigEndian.PutUint16(header[20:], 0) // reserved
+	c.pendingKey, c.nextKey = c.nextKey, ""
+	flags := c.nextFlags
+	c.nextFlags = 0
+	c.pendingCollection, c.nextCollection = c.nextCollection, ""
+	c.pendingReqID = c.nextReqID
+	c.pendingOp = opcode
+	c.pendingAttempt = 1
+	if err := c.ready([]uint8{opcode}, nil); err != nil {
+		return err
+	}
+	if err := c.acquireSlot(opcode); err != nil {
+		return c.cmdErr(err)
+	}
+	c.spend(len(payload))
+
+	if !c.pending {
+		c.gauge(&varInflight, 1)
+	}
+	c.pending, c.pendingStart = true, c.opts.now()
+	if err := c.armDeadline(opcode); err != nil {
+		c.releaseSlot()
+		return c.cmdErr(err)
+	}
+
+	if c.opts.capture != nil {
+		c.captureRequest(opcode, payload)
+	}
+	c.labelCommand(opcode)
+	_, err := c.queueFlagged(opcode, flags, payload)
+	c.keepForRetry(opcode, flags, payload)
+	if err == nil {
+		err = c.rw.Flush()
+	}
+	if err != nil {
+		c.unlabel()
+		if err = c.transportFailed(c.cmdErr(c.timeoutErr(err))); err != nil {
+			c.releaseSlot()
+		}
+		return err
+	}
+	return nil
+}
+
+// armDeadline applies the adaptive timeout of opcode to the connection
+func (c *Client) armDeadline(opcode uint8) error {
+	if c.opts.adaptive == nil {
+		return nil
+	}
+	c.pendingTimeout = c.opts.adaptive.timeout(&c.latency, opcode)
+	deadline := time.Now().Add(c.pendingTimeout)
+	if !c.ctxDeadline.IsZero() && c.ctxDeadline.Before(deadline) {
+		deadline = c.ctxDeadline
+	}
+	return c.conn.SetDeadline(deadline)
+}
+
+// queueFrame buffers a request frame without flushing and returns its
+// request ID. Pipelined callers use it directly to put many requests on the
+// wire before reading replies.
+func (c *Client) queueFrame(opcode uint8, payload []byte) (uint64, error) {
+	return c.queueFlagged(opcode, 0, payload)
+}
+
+// queueFlagged is queueFrame for a frame with flags set
+func (c *Client) queueFlagged(opcode uint8, flags uint16, payload []byte) (uint64, error) {
+	c.lastReq = nil
+	reqID := c.nextReqID
+	buf := c.layout.AppendFrame(c.wbuf[:0], wire.Frame{
+		Opcode:    opcode,
+		Flags:     flags,
+		RequestID: reqID,
+		Payload:   payload,
+	})
+	c.wbuf = retain(buf)
+	if c.opts.recorder != nil {
+		c.opts.recorder.record(true, c.layout.Version(), opcode, flags, reqID, payload)
+	}
+	c.logFrame(true, opcode, reqID, len(payload))
+	expvarCommand(opcode)
+	if c.otlp != nil {
+		c.otlp.addOp(c.otlp.commands, opcode)
+		c.otlp.add(&c.otlp.sent, uint64(len(payload)))
+	}
+	c.sentCommands.Add(1)
+	c.sentBytes.Add(uint64(len(payload)))
 	c.nextReqID++
+	_, err := c.rw.Write(buf)
+	return reqID, err
+}
+
+// sendKeyed sends a command that operates on key, so that errors from it
+// name the key
+func (c *Client) sendKeyed(opcode uint8, key string, payload []byte) error {
+	if c.opts.hotKeys != nil && key != "" {
+		c.opts.hotKeys.hit(key)
+	}
+	c.nextKey = key
+	return c.sendFrame(opcode, payload)
+}
 
-	if _, err := c.rw.Write(header); err != nil {
+// timeoutErr converts a deadline expiry caused by WithAdaptiveTimeout into a
+// *TimeoutError, closing the connection
+func (c *Client) timeoutErr(err error) error {
+	if err == nil || c.opts.adaptive == nil || !errors.Is(err, os.ErrDeadlineExceeded) {
 		return err
 	}
-	if len(payload) > 0 {
-		if _, err := c.rw.Write(payload); err != nil {
-			return err
-		}
+	if !c.ctxDeadline.IsZero() && !time.Now().Before(c.ctxDeadline) {
+		// The context's deadline, not the adaptive one, expired
+		return err
+	}
+	c.conn.Close()
+	terr := &TimeoutError{Op: Op(c.pendingOp), Limit: c.pendingTimeout}
+	if !c.closed {
+		// Replace the connection before the next command, as for a
+		// quarantined one
+		c.broken = terr
 	}
-	return c.rw.Flush()
+	c.emit(EventDisconnected, 0, terr)
+	return terr
 }
 
 func (c *Client) readResponse() (interface{}, error) {
-	// Read header
-	header := make([]byte, HeaderSize)
-	if _, err := io.ReadFull(c.rw, header); err != nil {
+	f, err := c.recvFrame()
+	if err != nil {
 		return nil, err
 	}
+	c.lastReply = f
+	resp, err := decodeResponse(f.opcode, f.payload)
+	return resp, c.cmdErr(err)
+}
 
-	magic := string(header[0:4])
-	if magic != Magic {
-		return nil, fmt.Errorf("invalid magic: %s", magic)
-	}
+// recvFrame reads the next frame from the connection
+func (c *Client) recvFrame() (frame, error) {
+	return c.recvFrameInto(nil)
+}
 
-	opcode := header[5]
-	payloadLen := binary.BigEndian.Uint32(header[8:])
+// recvFrameInto reads the next frame, absorbing any attributes frame in
+// front of it. With a non-nil buf the frame is read into *buf, grown as
+// needed, and the payload is only valid until *buf is next reused.
+func (c *Client) recvFrameInto(buf *[]byte) (frame, error) {
+	for {
+		f, err := c.readFrameInto(buf)
+		if err != nil {
+			if err = c.transportFailed(err); err == nil {
+				// Resent on a new connection
+				continue
+			}
+			c.releaseSlot()
+			return frame{}, err
+		}
+		c.lastReq = nil
+		attrs, err := c.absorbAttributes(f)
+		if err != nil {
+			return frame{}, c.cmdErr(err)
+		}
+		if !attrs {
+			if c.captured != nil {
+				c.captureReply(f)
+			}
+			return f, nil
+		}
+	}
+}
 
-	// Read payload
-	payload := make([]byte, payloadLen)
-	if payloadLen > 0 {
-		if _, err := io.ReadFull(c.rw, payload); err != nil {
-			return nil, err
+func (c *Client) readFrameInto(buf *[]byte) (frame, error) {
+	var wf wire.Frame
+	var err error
+	if buf != nil {
+		wf, *buf, err = c.layout.ReadFrameInto(c.rw, *buf)
+	} else {
+		wf, err = c.layout.ReadFrame(c.rw)
+	}
+	if err != nil {
+		c.unlabel()
+		if malformedFrame(err) {
+			return frame{}, c.desync(nil, "malformed frame", err)
+		}
+		return frame{}, c.cmdErr(c.timeoutErr(err))
+	}
+	c.logFrame(false, wf.Opcode, wf.RequestID, len(wf.Payload))
+	if c.pending {
+		c.pending = false
+		c.gauge(&varInflight, -1)
+		if wf.RequestID != c.pendingReqID {
+			c.unlabel()
+			f := frame{opcode: wf.Opcode, flags: wf.Flags, reqID: wf.RequestID, payload: wf.Payload}
+			return frame{}, c.desync(&f, "reply to another request", nil)
+		}
+		elapsed := c.opts.now().Sub(c.pendingStart)
+		c.latency.observe(c.pendingOp, elapsed)
+		if c.otlp != nil {
+			c.otlp.latency.observe(c.pendingOp, elapsed)
+		}
+		c.logSlow(c.pendingOp, c.pendingReqID, elapsed)
+		c.unlabel()
+		c.releaseSlot()
+		// The deadline bounds time to first reply; later frames of a
+		// streamed reply are only limited by the context's
+		if c.opts.adaptive != nil {
+			if err := c.conn.SetDeadline(c.ctxDeadline); err != nil {
+				return frame{}, c.cmdErr(err)
+			}
 		}
 	}
+	f := frame{opcode: wf.Opcode, flags: wf.Flags, reqID: wf.RequestID, payload: wf.Payload}
+	if c.opts.recorder != nil {
+		c.opts.recorder.record(false, wf.Version, f.opcode, f.flags, f.reqID, f.payload)
+	}
+	if f.opcode == OpCompressed {
+		if err := c.inflate(&f); err != nil {
+			return frame{}, c.cmdErr(err)
+		}
+	}
+	return f, nil
+}
+
+// frame is a decoded frame header plus its payload
+type frame struct {
+	opcode  uint8
+	flags   uint16
+	reqID   uint64
+	payload []byte
+}
 
+func decodeResponse(opcode uint8, payload []byte) (interface{}, error) {
 	switch opcode {
 	case OpOk:
 		return "OK", nil
@@ -292,7 +1102,7 @@ func (c *Client) readResponse() (interface{}, error) {
 	case OpNil:
 		return nil, nil
 	case OpError:
-		return nil, errors.New(string(payload))
+		return nil, &ServerError{Message: string(payload)}
 	case OpValue:
 		return string(payload), nil
 	case OpInteger:
@@ -300,6 +1110,26 @@ func (c *Client) readResponse() (interface{}, error) {
 			return nil, errors.New("invalid integer payload")
 		}
 		return int64(binary.BigEndian.Uint64(payload)), nil
+	case OpDouble:
+		// [f64], so scores keep full precision instead of round-tripping
+		// through a string
+		if len(payload) < 8 {
+			return nil, errors.New("invalid double payload")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(payload)), nil
+	case OpBool:
+		if len(payload) < 1 {
+			return nil, errors.New("invalid bool payload")
+		}
+		return payload[0] != 0, nil
+	case OpBigInt:
+		return decodeBigInt(payload)
+	case OpTypedString:
+		// [content_type: u8][bytes]; the hint is only exposed through Reply
+		if len(payload) < 1 {
+			return nil, errors.New("invalid typed string payload")
+		}
+		return string(payload[1:]), nil
 	case OpArray:
 		// Basic array parsing for verify: [count: u32][len: u32][bytes]...
 		// Implements parsing of simple list of strings/values
@@ -325,7 +1155,17 @@ func (c *Client) readResponse() (interface{}, error) {
 		}
 		return res, nil
 
+	case OpTypedArray, OpMap:
+		r, err := decodeReply(opcode, payload)
+		if err != nil {
+			return nil, err
+		}
+		return r.native(), nil
+
 	default:
+		if spec, ok := LookupOpcode(Op(opcode)); ok && spec.Unmarshal != nil {
+			return spec.Unmarshal(payload)
+		}
 		return nil, fmt.Errorf("unknown opcode: %d", opcode)
 	}
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+type benchConfig struct {
+	addr      string
+	workers   int
+	keys      int
+	valueSize int
+	readRatio float64
+	seed      int64
+}
+
+// latencySamples is the reservoir size each bench worker keeps
+const latencySamples = 1 << 16
+
+type benchResult struct {
+	ops     int64
+	errors  int64
+	samples []time.Duration
+	seen    int64
+}
+
+// record adds one latency to the reservoir
+func (r *benchResult) record(rng *rand.Rand, d time.Duration) {
+	r.seen++
+	if len(r.samples) < latencySamples {
+		r.samples = append(r.samples, d)
+	} else if j := rng.Int63n(r.seen); j < latencySamples {
+		r.samples[j] = d
+	}
+}
+
+func bench(ctx context.Context, cfg benchConfig) error {
+	value := strings.Repeat("x", cfg.valueSize)
+	clients := make([]*celrix.Client, cfg.workers)
+	for i := range clients {
+		c, err := celrix.Connect(cfg.addr)
+		if err != nil {
+			return fmt.Errorf("connect: %w", err)
+		}
+		defer c.Close()
+		clients[i] = c
+	}
+
+	results := make([]benchResult, cfg.workers)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		wg.Add(1)
+		go func(c *celrix.Client, r *benchResult, rng *rand.Rand) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				key := fmt.Sprintf("bench-%d", rng.Intn(cfg.keys))
+				t := time.Now()
+				var err error
+				if rng.Float64() < cfg.readRatio {
+					_, _, err = c.Get(key)
+				} else {
+					err = c.Set(key, value)
+				}
+				r.record(rng, time.Since(t))
+				r.ops++
+				if err != nil {
+					r.errors++
+				}
+			}
+		}(c, &results[i], rand.New(rand.NewSource(cfg.seed+int64(i))))
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var total benchResult
+	for _, r := range results {
+		total.ops += r.ops
+		total.errors += r.errors
+		total.samples = append(total.samples, r.samples...)
+	}
+	sort.Slice(total.samples, func(i, j int) bool { return total.samples[i] < total.samples[j] })
+	fmt.Printf("ops:     %d in %s (%.0f/s)\n", total.ops, elapsed.Round(time.Millisecond), float64(total.ops)/elapsed.Seconds())
+	fmt.Printf("errors:  %d\n", total.errors)
+	if len(total.samples) > 0 {
+		fmt.Printf("latency: p50 %s  p99 %s  p99.9 %s  max %s\n",
+			percentile(total.samples, 0.50), percentile(total.samples, 0.99),
+			percentile(total.samples, 0.999), total.samples[len(total.samples)-1])
+	}
+	return nil
+}
+
+// percentile returns the q quantile of sorted
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	return sorted[int(q*float64(len(sorted)-1))]
+}
@@ -0,0 +1,106 @@
+// Command celrix-bench measures CELRIX throughput and soaks it for
+// consistency bugs.
+//
+// The bench mode runs a GET/SET mix from several connections for -duration
+// and reports throughput and latency percentiles:
+//
+//	celrix-bench -addr 127.0.0.1:6380 -workers 8 -duration 30s -read-ratio 0.9
+//
+// The soak mode runs for much longer, mixing writes, deletes, TTLs, vector
+// adds and searches while a shadow in-memory model of every key checks what
+// the server returns against what it was told:
+//
+//	celrix-bench -mode soak -addr 127.0.0.1:6380 -workers 4 -duration 6h
+//
+// Each soak worker owns a disjoint range of keys, so its model is exact and
+// needs no coordination. The invariants checked are:
+//
+//   - a deleted or never-written key is not returned by GET, VGET, EXISTS or
+//     VSEARCH, and DEL reports it absent
+//   - a key past its TTL is not returned either, and one well inside it is
+//   - a live key returns the value or vector last written to it
+//   - SETNX succeeds exactly when the key is absent
+//
+// A TTL is only judged outside a window of -grace around its expiry,
+// bounded by when the write was sent and acknowledged. A write that fails
+// leaves its key unknown until it is next written, so errors are counted
+// but not reported as violations. Every key is checked once more when the
+// run ends, and the soak keys are then deleted.
+//
+// The soak connects with a RetryPolicy, so a dropped connection is
+// survived. Violations are printed as they are found, up to -max-report,
+// and the exit status is 1 if there were any.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6380", "CELRIX server address")
+	mode := flag.String("mode", "bench", "bench or soak")
+	workers := flag.Int("workers", 4, "parallel connections")
+	duration := flag.Duration("duration", 10*time.Second, "how long to run")
+	keys := flag.Int("keys", 1000, "keys per worker")
+	valueSize := flag.Int("value-size", 64, "bytes per value in bench mode")
+	readRatio := flag.Float64("read-ratio", 0.8, "fraction of GETs in bench mode")
+	dims := flag.Int("dims", 8, "vector dimensions in soak mode")
+	ttl := flag.Duration("ttl", 2*time.Second, "longest TTL set in soak mode, in whole seconds")
+	grace := flag.Duration("grace", 250*time.Millisecond, "slack allowed around a TTL's expiry")
+	interval := flag.Duration("report", 10*time.Second, "progress report interval in soak mode (0 disables)")
+	maxReport := flag.Int("max-report", 50, "violations to print before summarising")
+	seed := flag.Int64("seed", 1, "seed for the operation mix")
+	flag.Parse()
+	if *workers < 1 || *keys < 1 || *duration <= 0 || *dims < 1 || *ttl <= 0 ||
+		*readRatio < 0 || *readRatio > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	switch *mode {
+	case "bench":
+		if err := bench(ctx, benchConfig{
+			addr:      *addr,
+			workers:   *workers,
+			keys:      *keys,
+			valueSize: *valueSize,
+			readRatio: *readRatio,
+			seed:      *seed,
+		}); err != nil {
+			log.Fatal(err)
+		}
+	case "soak":
+		violations, err := soak(ctx, soakConfig{
+			addr:      *addr,
+			workers:   *workers,
+			keys:      *keys,
+			dims:      *dims,
+			ttl:       *ttl,
+			grace:     *grace,
+			interval:  *interval,
+			maxReport: *maxReport,
+			seed:      *seed,
+			prefix:    fmt.Sprintf("soak-%d-", time.Now().UnixNano()),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if violations > 0 {
+			os.Exit(1)
+		}
+	default:
+		log.Fatalf("unknown mode %q", *mode)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// keyState is what the shadow model knows about a key
+type keyState uint8
+
+const (
+	// never written in this run
+	absent keyState = iota
+	live
+	deleted
+	// the last write failed, so it may or may not have been applied
+	unknown
+)
+
+// entry is the model of one key. A live key with a TTL expires somewhere
+// in [expiresMin, expiresMax], the TTL counted from when its write was sent
+// and acknowledged.
+type entry struct {
+	state      keyState
+	value      string
+	vector     []float32
+	expiresMin time.Time
+	expiresMax time.Time
+}
+
+// expectation is what a read of a key may return
+type expectation uint8
+
+const (
+	mustExist expectation = iota
+	mustNotExist
+	mayExist
+)
+
+// model is a soak worker's shadow of the keys it owns
+type model struct {
+	grace   time.Duration
+	entries map[string]*entry
+}
+
+func newModel(grace time.Duration) *model {
+	return &model{grace: grace, entries: make(map[string]*entry)}
+}
+
+func (m *model) get(key string) *entry {
+	e, ok := m.entries[key]
+	if !ok {
+		e = &entry{}
+		m.entries[key] = e
+	}
+	return e
+}
+
+// expect returns what a read sent at sent and answered at answered may see
+// of e, and why a key that must not exist is gone
+func (m *model) expect(e *entry, sent, answered time.Time) (expectation, string) {
+	switch e.state {
+	case absent:
+		return mustNotExist, "never written"
+	case deleted:
+		return mustNotExist, "deleted"
+	case unknown:
+		return mayExist, ""
+	}
+	if e.expiresMax.IsZero() {
+		return mustExist, ""
+	}
+	switch {
+	case sent.After(e.expiresMax.Add(m.grace)):
+		return mustNotExist, fmt.Sprintf("expired %s before the read", sent.Sub(e.expiresMax).Round(time.Millisecond))
+	case answered.Before(e.expiresMin.Add(-m.grace)):
+		return mustExist, ""
+	}
+	return mayExist, ""
+}
+
+// check compares whether a read found the key, returning a description of
+// the violation, if any
+func (m *model) check(e *entry, sent, answered time.Time, found bool) string {
+	want, why := m.expect(e, sent, answered)
+	switch {
+	case found && want == mustNotExist:
+		return fmt.Sprintf("present, but %s", why)
+	case !found && want == mustExist:
+		if e.expiresMax.IsZero() {
+			return "absent, but live"
+		}
+		return fmt.Sprintf("absent, but %s from expiry", e.expiresMin.Sub(answered).Round(time.Millisecond))
+	}
+	return ""
+}
+
+// written records a write sent at sent and acknowledged at acked. A zero
+// ttl means the key does not expire.
+func (e *entry) written(value string, vector []float32, ttl time.Duration, sent, acked time.Time) {
+	*e = entry{state: live, value: value, vector: vector}
+	if ttl > 0 {
+		e.expiresMin, e.expiresMax = sent.Add(ttl), acked.Add(ttl)
+	}
+}
+
+// failed records a write or delete whose outcome is not known
+func (e *entry) failed() {
+	*e = entry{state: unknown}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+type soakConfig struct {
+	addr      string
+	workers   int
+	keys      int
+	dims      int
+	ttl       time.Duration
+	grace     time.Duration
+	interval  time.Duration
+	maxReport int
+	seed      int64
+	prefix    string
+}
+
+// soakOps are the soak operations with their relative weights
+var soakOps = []struct {
+	weight int
+	run    func(w *soakWorker, key int)
+}{
+	{20, (*soakWorker).set},
+	{8, (*soakWorker).setNX},
+	{20, (*soakWorker).get},
+	{5, (*soakWorker).exists},
+	{10, (*soakWorker).del},
+	{12, (*soakWorker).vadd},
+	{6, (*soakWorker).vaddTTL},
+	{8, (*soakWorker).vget},
+	{5, (*soakWorker).vdel},
+	{6, (*soakWorker).vsearch},
+}
+
+// soakStats are shared by the workers and the progress reporter
+type soakStats struct {
+	ops        atomic.Int64
+	errors     atomic.Int64
+	reconnects atomic.Int64
+
+	mu         sync.Mutex
+	violations int
+	maxReport  int
+}
+
+func (s *soakStats) violation(worker int, op, key, detail string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.violations++
+	if s.violations <= s.maxReport {
+		fmt.Printf("VIOLATION worker %d %s %s: %s\n", worker, op, key, detail)
+	} else if s.violations == s.maxReport+1 {
+		fmt.Println("further violations not shown")
+	}
+}
+
+// soak runs the soak until ctx is done and returns the number of
+// violations found
+func soak(ctx context.Context, cfg soakConfig) (int, error) {
+	stats := &soakStats{maxReport: cfg.maxReport}
+	workers := make([]*soakWorker, cfg.workers)
+	for i := range workers {
+		c, err := celrix.ConnectWithOptions(cfg.addr, celrix.ConnectOptions{
+			Retry: celrix.RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, Jitter: 0.2},
+			OnReconnect: func(attempt int, cause error) {
+				stats.reconnects.Add(1)
+				fmt.Fprintf(os.Stderr, "worker %d reconnected on attempt %d: %v\n", i, attempt, cause)
+			},
+		})
+		if err != nil {
+			return 0, fmt.Errorf("connect: %w", err)
+		}
+		defer c.Close()
+		workers[i] = &soakWorker{
+			cfg:    &cfg,
+			id:     i,
+			c:      c,
+			rng:    rand.New(rand.NewSource(cfg.seed + int64(i))),
+			model:  newModel(cfg.grace),
+			stats:  stats,
+			prefix: fmt.Sprintf("%sw%d-", cfg.prefix, i),
+		}
+	}
+
+	start := time.Now()
+	stopReport := make(chan struct{})
+	if cfg.interval > 0 {
+		go func() {
+			t := time.NewTicker(cfg.interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					stats.print(os.Stderr, time.Since(start))
+				case <-stopReport:
+					return
+				}
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *soakWorker) {
+			defer wg.Done()
+			w.run(ctx)
+			w.sweep()
+			w.cleanup()
+		}(w)
+	}
+	wg.Wait()
+	close(stopReport)
+
+	stats.print(os.Stdout, time.Since(start))
+	return stats.violations, nil
+}
+
+func (s *soakStats) print(f *os.File, elapsed time.Duration) {
+	s.mu.Lock()
+	violations := s.violations
+	s.mu.Unlock()
+	ops := s.ops.Load()
+	fmt.Fprintf(f, "%s: %d ops (%.0f/s), %d errors, %d reconnects, %d violations\n",
+		elapsed.Round(time.Second), ops, float64(ops)/elapsed.Seconds(),
+		s.errors.Load(), s.reconnects.Load(), violations)
+}
+
+// soakWorker drives one connection over the keys it owns
+type soakWorker struct {
+	cfg    *soakConfig
+	id     int
+	c      *celrix.Client
+	rng    *rand.Rand
+	model  *model
+	stats  *soakStats
+	prefix string
+	seq    int
+}
+
+func (w *soakWorker) run(ctx context.Context) {
+	total := 0
+	for _, op := range soakOps {
+		total += op.weight
+	}
+	for ctx.Err() == nil {
+		n := w.rng.Intn(total)
+		for _, op := range soakOps {
+			if n < op.weight {
+				op.run(w, w.rng.Intn(w.cfg.keys))
+				break
+			}
+			n -= op.weight
+		}
+		w.stats.ops.Add(1)
+	}
+}
+
+func (w *soakWorker) stringKey(n int) string { return fmt.Sprintf("%ss%d", w.prefix, n) }
+func (w *soakWorker) vectorKey(n int) string { return fmt.Sprintf("%sv%d", w.prefix, n) }
+
+func (w *soakWorker) nextValue() string {
+	w.seq++
+	return fmt.Sprintf("%d-%d", w.id, w.seq)
+}
+
+// randomTTL returns a TTL of whole seconds, up to the configured longest
+// and at least one. The wire carries TTLs in seconds, so a finer one would
+// be rounded up by the client and outlive what the model expects.
+func (w *soakWorker) randomTTL() time.Duration {
+	secs := max(1, int64(w.cfg.ttl/time.Second))
+	return time.Duration(1+w.rng.Int63n(secs)) * time.Second
+}
+
+func (w *soakWorker) randomVector() []float32 {
+	v := make([]float32, w.cfg.dims)
+	for i := range v {
+		v[i] = w.rng.Float32()*2 - 1
+	}
+	return v
+}
+
+// failed counts an error and, for a write, forgets the key's state
+func (w *soakWorker) failed(e *entry) {
+	w.stats.errors.Add(1)
+	if e != nil {
+		e.failed()
+	}
+}
+
+func (w *soakWorker) judge(op, key string, e *entry, sent time.Time, found bool) {
+	if v := w.model.check(e, sent, time.Now(), found); v != "" {
+		w.stats.violation(w.id, op, key, v)
+	}
+}
+
+func (w *soakWorker) set(n int) {
+	key, value := w.stringKey(n), w.nextValue()
+	e := w.model.get(key)
+	sent := time.Now()
+	if err := w.c.Set(key, value); err != nil {
+		w.failed(e)
+		return
+	}
+	e.written(value, nil, 0, sent, time.Now())
+}
+
+func (w *soakWorker) setNX(n int) {
+	key, value, ttl := w.stringKey(n), w.nextValue(), w.randomTTL()
+	e := w.model.get(key)
+	sent := time.Now()
+	ok, err := w.c.SetNX(key, value, ttl)
+	if err != nil {
+		w.failed(e)
+		return
+	}
+	acked := time.Now()
+	w.judge("SETNX", key, e, sent, !ok)
+	if ok {
+		e.written(value, nil, ttl, sent, acked)
+	}
+}
+
+func (w *soakWorker) get(n int) {
+	key := w.stringKey(n)
+	e := w.model.get(key)
+	sent := time.Now()
+	value, found, err := w.c.Get(key)
+	if err != nil {
+		w.failed(nil)
+		return
+	}
+	w.judge("GET", key, e, sent, found)
+	if found && e.state == live && value != e.value {
+		w.stats.violation(w.id, "GET", key, fmt.Sprintf("returned %q, but %q was written last", value, e.value))
+	}
+}
+
+func (w *soakWorker) exists(n int) {
+	key := w.stringKey(n)
+	if w.rng.Intn(2) == 0 {
+		key = w.vectorKey(n)
+	}
+	e := w.model.get(key)
+	sent := time.Now()
+	found, err := w.c.Exists(key)
+	if err != nil {
+		w.failed(nil)
+		return
+	}
+	w.judge("EXISTS", key, e, sent, found)
+}
+
+func (w *soakWorker) del(n int)  { w.delete("DEL", w.stringKey(n)) }
+func (w *soakWorker) vdel(n int) { w.delete("DEL", w.vectorKey(n)) }
+
+func (w *soakWorker) delete(op, key string) {
+	e := w.model.get(key)
+	sent := time.Now()
+	existed, err := w.c.Del(key)
+	if err != nil {
+		w.failed(e)
+		return
+	}
+	w.judge(op, key, e, sent, existed)
+	*e = entry{state: deleted}
+}
+
+func (w *soakWorker) vadd(n int) {
+	key, vec := w.vectorKey(n), w.randomVector()
+	e := w.model.get(key)
+	sent := time.Now()
+	if err := w.c.VAdd(key, vec); err != nil {
+		w.failed(e)
+		return
+	}
+	e.written("", vec, 0, sent, time.Now())
+}
+
+func (w *soakWorker) vaddTTL(n int) {
+	key, vec, ttl := w.vectorKey(n), w.randomVector(), w.randomTTL()
+	e := w.model.get(key)
+	sent := time.Now()
+	if err := w.c.VAddWithTTL(key, vec, ttl); err != nil {
+		w.failed(e)
+		return
+	}
+	e.written("", vec, ttl, sent, time.Now())
+}
+
+func (w *soakWorker) vget(n int) {
+	key := w.vectorKey(n)
+	e := w.model.get(key)
+	sent := time.Now()
+	item, found, err := w.c.VGet(key)
+	if err != nil {
+		w.failed(nil)
+		return
+	}
+	w.judge("VGET", key, e, sent, found)
+	if found && e.state == live && !slices.Equal(item.Vector, e.vector) {
+		w.stats.violation(w.id, "VGET", key, "returned a vector other than the one written last")
+	}
+}
+
+// vsearch checks that no key this worker owns is found by a search unless
+// it may exist. Keys of other workers and other clients are ignored.
+func (w *soakWorker) vsearch(int) {
+	sent := time.Now()
+	matches, err := w.c.VSearch(w.randomVector(), 10, nil)
+	if err != nil {
+		w.failed(nil)
+		return
+	}
+	for _, key := range celrix.MatchKeys(matches) {
+		if !strings.HasPrefix(key, w.prefix) {
+			continue
+		}
+		w.judge("VSEARCH", key, w.model.get(key), sent, true)
+	}
+}
+
+// sweep reads back every key the worker touched, checking its final state
+func (w *soakWorker) sweep() {
+	for key, e := range w.model.entries {
+		sent := time.Now()
+		var found bool
+		var err error
+		if strings.HasPrefix(key, w.prefix+"v") {
+			_, found, err = w.c.VGet(key)
+		} else {
+			found, err = w.c.Exists(key)
+		}
+		if err != nil {
+			w.failed(nil)
+			continue
+		}
+		w.judge("sweep", key, e, sent, found)
+	}
+}
+
+// cleanup deletes the worker's keys
+func (w *soakWorker) cleanup() {
+	for key := range w.model.entries {
+		w.c.Del(key)
+	}
+}
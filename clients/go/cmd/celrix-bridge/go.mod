@@ -0,0 +1,15 @@
+module github.com/YASSERRMD/celrix/clients/go/cmd/celrix-bridge
+
+go 1.25.5
+
+require (
+	github.com/YASSERRMD/celrix/clients/go v0.0.0
+	github.com/segmentio/kafka-go v0.4.51
+)
+
+require (
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+)
+
+replace github.com/YASSERRMD/celrix/clients/go => ../..
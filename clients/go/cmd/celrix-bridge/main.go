@@ -0,0 +1,415 @@
+// Command celrix-bridge moves records between Kafka topics and CELRIX.
+//
+// In sink mode it consumes a topic as a member of a consumer group and
+// applies each message to CELRIX as a SET, DEL or VADD. Offsets are
+// committed only for messages that were applied (or journaled, or skipped
+// as malformed), so a bridge that stops on a transport failure resumes from
+// the first message that did not reach the server:
+//
+//	celrix-bridge -mode sink -brokers broker:9092 -topic embeddings -group celrix-sink
+//
+// In source mode it follows the CDC stream and produces one message per
+// change, keyed by the CELRIX key so that changes to a key stay ordered
+// within a partition:
+//
+//	celrix-bridge -mode source -brokers broker:9092 -topic celrix-cdc
+//
+// Without -from-seq, source mode resumes after the highest seq already in
+// the topic, read from the last message of each partition.
+//
+// Message values are JSON, one operation each. Values are binary-safe,
+// carried as base64:
+//
+//	{"op":"set","key":"user:1","value":"SmFuZQ=="}
+//	{"op":"del","key":"user:1"}
+//	{"op":"vadd","key":"doc:1","collection":"docs","vector":[0.1,0.2],"metadata":{"lang":"en","year":2024,"score":1.0}}
+//
+// A sink message without a "key" field takes its key from the Kafka message
+// key. Metadata numbers written with a decimal point or exponent are floats
+// and others ints; source mode writes floats so that they read back as
+// floats.
+//
+// The bridge is its own module so that the Kafka driver is not a dependency
+// of the client.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+	"github.com/segmentio/kafka-go"
+)
+
+// record is the JSON shape shared by both directions
+type record struct {
+	Op         string                     `json:"op"`
+	Seq        uint64                     `json:"seq,omitempty"`
+	Timestamp  *time.Time                 `json:"ts,omitempty"`
+	Key        string                     `json:"key"`
+	Collection string                     `json:"collection,omitempty"`
+	Value      *[]byte                    `json:"value,omitempty"`
+	Vector     []float32                  `json:"vector,omitempty"`
+	Metadata   map[string]json.RawMessage `json:"metadata,omitempty"`
+}
+
+// errMalformed marks records that can never be applied, as opposed to
+// failures that a later attempt may get past
+var errMalformed = errors.New("malformed record")
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6380", "CELRIX server address")
+	mode := flag.String("mode", "sink", "sink (Kafka -> CELRIX) or source (CDC -> Kafka)")
+	brokers := flag.String("brokers", "127.0.0.1:9092", "comma-separated Kafka bootstrap brokers")
+	topic := flag.String("topic", "", "Kafka topic to consume (sink) or produce to (source)")
+	group := flag.String("group", "celrix-bridge", "consumer group for sink mode")
+	fromSeq := flag.Int64("from-seq", -1, "first change sequence to export in source mode (default: resume from the topic)")
+	batch := flag.Int("batch", 100, "changes per produce request in source mode")
+	journal := flag.String("journal", "", "write journal directory for sink mode")
+	strict := flag.Bool("strict", false, "stop on the first malformed record instead of skipping it")
+	flag.Parse()
+
+	if *topic == "" {
+		log.Fatal("-topic is required")
+	}
+	brokerList := strings.Split(*brokers, ",")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var opts []celrix.Option
+	if *journal != "" {
+		opts = append(opts, celrix.WithWriteJournal(*journal))
+	}
+	client, err := celrix.Connect(*addr, opts...)
+	if err != nil {
+		log.Fatal("Failed to connect:", err)
+	}
+	defer client.Close()
+
+	switch *mode {
+	case "sink":
+		r := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:        brokerList,
+			GroupID:        *group,
+			Topic:          *topic,
+			CommitInterval: time.Second,
+			StartOffset:    kafka.FirstOffset,
+		})
+		err = sink(ctx, client, r, *strict)
+		if cerr := r.Close(); err == nil {
+			err = cerr
+		}
+	case "source":
+		w := &kafka.Writer{
+			Addr:         kafka.TCP(brokerList...),
+			Topic:        *topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchSize:    *batch,
+		}
+		var start uint64
+		if *fromSeq >= 0 {
+			start = uint64(*fromSeq)
+		} else {
+			start, err = resumeSeq(ctx, brokerList, *topic)
+		}
+		if err == nil {
+			err = source(ctx, client, w, start, *batch)
+		}
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	default:
+		log.Fatalf("unknown mode %q", *mode)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatal(err)
+	}
+}
+
+// sink applies messages until ctx is done or a message fails for a reason
+// other than its content. The failed message is left uncommitted.
+func sink(ctx context.Context, client *celrix.Client, r *kafka.Reader, strict bool) error {
+	var applied, skipped int
+	defer func() {
+		log.Printf("sink: applied %d records, skipped %d", applied, skipped)
+	}()
+
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		err = applyMessage(client, m)
+		switch {
+		case err == nil, errors.Is(err, celrix.ErrJournaled):
+			applied++
+		case errors.Is(err, errMalformed) && !strict:
+			log.Printf("partition %d offset %d: %v", m.Partition, m.Offset, err)
+			skipped++
+		default:
+			return fmt.Errorf("partition %d offset %d: %w", m.Partition, m.Offset, err)
+		}
+		if err := r.CommitMessages(ctx, m); err != nil {
+			return err
+		}
+	}
+}
+
+// applyMessage decodes and applies one message. Decode failures, records
+// the client rejects and error replies wrap errMalformed; transport failures
+// are returned as they are.
+func applyMessage(client *celrix.Client, m kafka.Message) error {
+	var rec record
+	if err := json.Unmarshal(m.Value, &rec); err != nil {
+		return fmt.Errorf("%w: %v", errMalformed, err)
+	}
+	if rec.Key == "" {
+		rec.Key = string(m.Key)
+	}
+	err := apply(client, rec)
+	var (
+		se *celrix.ServerError
+		ve *celrix.ValidationError
+		le *celrix.VectorTooLargeError
+	)
+	if errors.As(err, &se) || errors.As(err, &ve) || errors.As(err, &le) {
+		return fmt.Errorf("%w: %v", errMalformed, err)
+	}
+	return err
+}
+
+func apply(client *celrix.Client, rec record) error {
+	if rec.Key == "" {
+		return fmt.Errorf("%w: record has no key", errMalformed)
+	}
+	switch rec.Op {
+	case "set":
+		if rec.Value == nil {
+			return fmt.Errorf("%w: set record has no value", errMalformed)
+		}
+		return client.SetBytes(rec.Key, *rec.Value)
+	case "del":
+		_, err := client.Del(rec.Key)
+		return err
+	case "vadd":
+		if len(rec.Vector) == 0 {
+			return fmt.Errorf("%w: vadd record has no vector", errMalformed)
+		}
+		meta, err := decodeMetadata(rec.Metadata)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errMalformed, err)
+		}
+		if rec.Collection != "" {
+			return client.Collection(rec.Collection).VAdd(rec.Key, rec.Vector, meta)
+		}
+		if len(meta) > 0 {
+			return client.VAddWithMetadata(rec.Key, rec.Vector, meta)
+		}
+		return client.VAdd(rec.Key, rec.Vector)
+	default:
+		return fmt.Errorf("%w: unknown op %q", errMalformed, rec.Op)
+	}
+}
+
+// decodeMetadata maps JSON values onto metadata types: strings in RFC 3339
+// form become timestamps, integral numbers become ints
+func decodeMetadata(raw map[string]json.RawMessage) (celrix.Metadata, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	meta := make(celrix.Metadata, len(raw))
+	for k, v := range raw {
+		var x interface{}
+		dec := json.NewDecoder(bytes.NewReader(v))
+		dec.UseNumber()
+		if err := dec.Decode(&x); err != nil {
+			return nil, fmt.Errorf("metadata %q: %w", k, err)
+		}
+		switch x := x.(type) {
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, x); err == nil {
+				meta[k] = celrix.TimeValue(t)
+			} else {
+				meta[k] = celrix.StringValue(x)
+			}
+		case bool:
+			meta[k] = celrix.BoolValue(x)
+		case json.Number:
+			if i, err := x.Int64(); err == nil {
+				meta[k] = celrix.IntValue(i)
+			} else if f, err := x.Float64(); err == nil {
+				meta[k] = celrix.FloatValue(f)
+			} else {
+				return nil, fmt.Errorf("metadata %q: %w", k, err)
+			}
+		default:
+			return nil, fmt.Errorf("metadata %q: unsupported JSON type %T", k, x)
+		}
+	}
+	return meta, nil
+}
+
+// resumeSeq returns the seq after the highest one already produced to topic,
+// or 0 if the topic is empty or does not exist yet
+func resumeSeq(ctx context.Context, brokers []string, topic string) (uint64, error) {
+	conn, err := kafka.DialContext(ctx, "tcp", brokers[0])
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	parts, err := conn.ReadPartitions(topic)
+	if errors.Is(err, kafka.UnknownTopicOrPartition) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var next uint64
+	for _, p := range parts {
+		seq, ok, err := lastSeq(ctx, p)
+		if err != nil {
+			return 0, fmt.Errorf("partition %d: %w", p.ID, err)
+		}
+		if ok && seq+1 > next {
+			next = seq + 1
+		}
+	}
+	log.Printf("source: resuming from seq %d", next)
+	return next, nil
+}
+
+// lastSeq reads the seq of the last message in one partition
+func lastSeq(ctx context.Context, p kafka.Partition) (uint64, bool, error) {
+	conn, err := kafka.DefaultDialer.DialPartition(ctx, "tcp", "", p)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+	first, last, err := conn.ReadOffsets()
+	if err != nil || last <= first {
+		return 0, false, err
+	}
+	if _, err := conn.Seek(last-1, kafka.SeekAbsolute); err != nil {
+		return 0, false, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	} else {
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	}
+	m, err := conn.ReadMessage(10 << 20)
+	if err != nil {
+		return 0, false, err
+	}
+	var rec record
+	if err := json.Unmarshal(m.Value, &rec); err != nil {
+		return 0, false, fmt.Errorf("offset %d: %w", m.Offset, err)
+	}
+	return rec.Seq, true, nil
+}
+
+// source produces the CDC stream from fromSeq on. Changes are gathered into
+// batches of up to batch messages while more are already buffered, and a
+// batch is written before the next one is gathered, so nothing is produced
+// out of seq order.
+func source(ctx context.Context, client *celrix.Client, w *kafka.Writer, fromSeq uint64, batch int) error {
+	events, err := client.CDC(ctx, fromSeq)
+	if err != nil {
+		return err
+	}
+	var produced int
+	defer func() {
+		log.Printf("source: produced %d changes", produced)
+	}()
+
+	msgs := make([]kafka.Message, 0, batch)
+	for ev := range events {
+		if ev.Err != nil {
+			return ev.Err
+		}
+		m, err := changeMessage(ev)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, m)
+		if len(msgs) < batch && len(events) > 0 {
+			continue
+		}
+		if err := w.WriteMessages(ctx, msgs...); err != nil {
+			return err
+		}
+		produced += len(msgs)
+		msgs = msgs[:0]
+	}
+	return ctx.Err()
+}
+
+func changeMessage(ev celrix.ChangeEvent) (kafka.Message, error) {
+	ts := ev.Timestamp
+	rec := record{Op: ev.Kind.String(), Seq: ev.Seq, Timestamp: &ts, Key: ev.Key}
+	switch ev.Kind {
+	case celrix.ChangeSet:
+		v := ev.Value
+		rec.Value = &v
+	case celrix.ChangeVAdd:
+		rec.Vector = ev.Vector
+		rec.Metadata = encodeMetadata(ev.Metadata)
+	}
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	return kafka.Message{Key: []byte(ev.Key), Value: value, Time: ev.Timestamp}, nil
+}
+
+func encodeMetadata(meta celrix.Metadata) map[string]json.RawMessage {
+	if len(meta) == 0 {
+		return nil
+	}
+	out := make(map[string]json.RawMessage, len(meta))
+	for k, v := range meta {
+		var x interface{}
+		switch v.Type() {
+		case celrix.MetaString:
+			x, _ = v.Str()
+		case celrix.MetaInt:
+			x, _ = v.Int()
+		case celrix.MetaFloat:
+			f, _ := v.Float()
+			x = jsonFloat(f)
+		case celrix.MetaBool:
+			x, _ = v.Bool()
+		case celrix.MetaTime:
+			t, _ := v.Time()
+			x = t.Format(time.RFC3339Nano)
+		}
+		b, _ := json.Marshal(x)
+		out[k] = b
+	}
+	return out
+}
+
+// jsonFloat is a float64 that always marshals with a decimal point or
+// exponent, so integral values are not read back as ints
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(float64(f))
+	if err != nil || bytes.ContainsAny(b, ".eE") {
+		return b, err
+	}
+	return append(b, ".0"...), nil
+}
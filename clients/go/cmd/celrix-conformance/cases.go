@@ -0,0 +1,1081 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// testCase checks one request opcode
+type testCase struct {
+	op uint8
+	// run issues the command through the client, after setting up any
+	// state it needs, and checks the decoded result; nil marks an opcode
+	// the suite has no check for
+	run func(ctx context.Context, s *suite) error
+	// want lists the replies accepted byte for byte, for commands whose
+	// reply is fully determined; empty accepts any reply the client
+	// decodes without error
+	want []reply
+	// destructive checks replace server-wide state
+	destructive bool
+}
+
+type reply struct {
+	opcode  uint8
+	payload []byte
+}
+
+// dims is the dimension of every test vector
+const dims = 4
+
+var (
+	vecA = []float32{1, 0, 0, 0}
+	vecB = []float32{0, 1, 0, 0}
+)
+
+var okReply = []reply{{opcode: celrix.OpOk}}
+
+// yes accepts a true result in either encoding toBool decodes
+var yes = []reply{{celrix.OpInteger, u64(1)}, {celrix.OpBool, []byte{1}}}
+
+func integer(n int64) []reply { return []reply{{celrix.OpInteger, u64(uint64(n))}} }
+
+func u64(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+// expect fails a check whose decoded result is not what the command just
+// did implies
+func expect(cond bool, format string, args ...interface{}) error {
+	if cond {
+		return nil
+	}
+	return fmt.Errorf(format, args...)
+}
+
+// cases returns a check for every built-in request opcode, in opcode
+// order. Opcodes without one are listed as skipped, so the matrix always
+// covers the whole protocol.
+func cases() []testCase {
+	var all []testCase
+	for _, group := range [][]testCase{keyCases(), vectorCases(), collectionCases(), streamCases(), adminCases(), structureCases()} {
+		all = append(all, group...)
+	}
+	covered := make(map[uint8]bool, len(all))
+	for _, tc := range all {
+		covered[tc.op] = true
+	}
+	for op := 0; op < 0xE0; op++ {
+		_, isReply := replyNames[uint8(op)]
+		if !covered[uint8(op)] && !isReply && !strings.HasPrefix(celrix.Op(op).String(), "OP(") {
+			all = append(all, testCase{op: uint8(op)})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].op < all[j].op })
+	return all
+}
+
+func keyCases() []testCase {
+	return []testCase{
+		{op: celrix.OpPing, want: []reply{{opcode: celrix.OpPong}}, run: func(_ context.Context, s *suite) error {
+			return s.c.Ping()
+		}},
+		{op: celrix.OpGet, want: []reply{{celrix.OpValue, []byte("v")}}, run: func(_ context.Context, s *suite) error {
+			k := s.key("get")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			v, ok, err := s.c.Get(k)
+			if err != nil {
+				return err
+			}
+			return expect(ok && v == "v", "GET returned %q, %v after SET of \"v\"", v, ok)
+		}},
+		{op: celrix.OpSet, want: okReply, run: func(_ context.Context, s *suite) error {
+			return s.c.Set(s.key("set"), "v")
+		}},
+		{op: celrix.OpDel, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("del")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			existed, err := s.c.Del(k)
+			if err != nil {
+				return err
+			}
+			return expect(existed, "DEL of an existing key reported it missing")
+		}},
+		{op: celrix.OpExists, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("exists")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			ok, err := s.c.Exists(k)
+			if err != nil {
+				return err
+			}
+			return expect(ok, "EXISTS reported a key just set as missing")
+		}},
+		{op: celrix.OpSetCompressed, want: okReply, run: func(_ context.Context, s *suite) error {
+			if s.dict == nil {
+				return skip("needs a dictionary registered on the server; pass it with -dict")
+			}
+			c, err := s.dial(celrix.WithValueCompression(16, *s.dict))
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			k, v := s.key("setcompressed"), strings.Repeat("conformance ", 32)
+			if err := c.Set(k, v); err != nil {
+				return err
+			}
+			got, _, err := c.Get(k)
+			if err != nil {
+				return err
+			}
+			return expect(got == v, "GET after a compressed SET returned %d bytes, want %d", len(got), len(v))
+		}},
+		{op: celrix.OpRenameBatch, run: func(_ context.Context, s *suite) error {
+			from, to := s.key("rename-from"), s.key("rename-to")
+			if err := s.c.Set(from, "v"); err != nil {
+				return err
+			}
+			if err := s.c.RenameBatch(map[string]string{from: to}); err != nil {
+				return err
+			}
+			v, ok, err := s.c.Get(to)
+			if err != nil {
+				return err
+			}
+			return expect(ok && v == "v", "renamed key holds %q, %v", v, ok)
+		}},
+		{op: celrix.OpSetNX, want: yes, run: func(_ context.Context, s *suite) error {
+			set, err := s.c.SetNX(s.key("setnx"), "v", 0)
+			if err != nil {
+				return err
+			}
+			return expect(set, "SETNX of a new key reported it existing")
+		}},
+		{op: celrix.OpCompareAndSwap, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("cas")
+			if err := s.c.Set(k, "a"); err != nil {
+				return err
+			}
+			swapped, err := s.c.CompareAndSwap(k, "a", "b", 0)
+			if err != nil {
+				return err
+			}
+			return expect(swapped, "CAS with the current value did not swap")
+		}},
+		{op: celrix.OpCompareAndDelete, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("cad")
+			if err := s.c.Set(k, "a"); err != nil {
+				return err
+			}
+			deleted, err := s.c.CompareAndDelete(k, "a")
+			if err != nil {
+				return err
+			}
+			return expect(deleted, "CAD with the current value did not delete")
+		}},
+		{op: celrix.OpIncrBy, want: integer(5), run: func(_ context.Context, s *suite) error {
+			n, err := s.c.IncrBy(s.key("incrby"), 5)
+			if err != nil {
+				return err
+			}
+			return expect(n == 5, "INCRBY 5 of a new key returned %d", n)
+		}},
+		{op: celrix.OpSoftDel, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("softdel")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			existed, err := s.c.SoftDel(k)
+			if err != nil {
+				return err
+			}
+			return expect(existed, "SOFTDEL of an existing key reported it missing")
+		}},
+		{op: celrix.OpUndelete, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("undelete")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			if _, err := s.c.SoftDel(k); err != nil {
+				return err
+			}
+			restored, err := s.c.Undelete(k)
+			if err != nil {
+				return err
+			}
+			if err := expect(restored, "UNDELETE of a trashed key reported nothing to restore"); err != nil {
+				return err
+			}
+			v, ok, err := s.c.Get(k)
+			if err != nil {
+				return err
+			}
+			return expect(ok && v == "v", "undeleted key holds %q, %v", v, ok)
+		}},
+		{op: celrix.OpScan, run: func(_ context.Context, s *suite) error {
+			k := s.key("scan")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			var cursor uint64
+			for {
+				next, keys, err := s.c.Scan(cursor, k, 100)
+				if err != nil {
+					return err
+				}
+				for _, got := range keys {
+					if got == k {
+						return nil
+					}
+				}
+				if next == 0 {
+					return fmt.Errorf("SCAN matching %q did not return it", k)
+				}
+				cursor = next
+			}
+		}},
+		{op: celrix.OpSelect, want: okReply, run: func(_ context.Context, s *suite) error {
+			return s.c.Select(0)
+		}},
+		{op: celrix.OpMGet, run: func(_ context.Context, s *suite) error {
+			k := s.key("mget")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			vals, err := s.c.GetOrNilFast(k, s.key("mget-missing"))
+			if err != nil {
+				return err
+			}
+			return expect(len(vals) == 2 && vals[0] != nil && *vals[0] == "v" && vals[1] == nil,
+				"MGET of a set and a missing key returned %d values", len(vals))
+		}},
+		{op: celrix.OpMVersion, run: func(_ context.Context, s *suite) error {
+			k := s.key("mversion")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			versions, err := s.c.KeyVersions(k)
+			if err != nil {
+				return err
+			}
+			return expect(len(versions) == 1 && versions[0] > 0, "MVERSION of a key just set returned %v", versions)
+		}},
+		{op: celrix.OpHash, want: integer(int64(celrix.HashValue([]byte("v")))), run: func(_ context.Context, s *suite) error {
+			k := s.key("hash")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			h, err := s.c.Hash(k)
+			if err != nil {
+				return err
+			}
+			return expect(h == celrix.HashValue([]byte("v")), "HASH returned %016x, want XXH64 %016x", h, celrix.HashValue([]byte("v")))
+		}},
+		{op: celrix.OpMHash, run: func(_ context.Context, s *suite) error {
+			k := s.key("mhash")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			hashes, err := s.c.Hashes(k, s.key("mhash-missing"))
+			if err != nil {
+				return err
+			}
+			return expect(hashes[0] != nil && *hashes[0] == celrix.HashValue([]byte("v")) && hashes[1] == nil,
+				"MHASH of a set and a missing key returned wrong hashes")
+		}},
+		{op: celrix.OpExpire, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("expire")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			ok, err := s.c.Expire(k, time.Minute)
+			if err != nil {
+				return err
+			}
+			return expect(ok, "EXPIRE of an existing key reported it missing")
+		}},
+		{op: celrix.OpTTL, run: func(_ context.Context, s *suite) error {
+			k := s.key("ttl")
+			if err := s.c.SetWithTTL(k, "v", time.Minute); err != nil {
+				return err
+			}
+			ttl, ok, err := s.c.TTL(k)
+			if err != nil {
+				return err
+			}
+			return expect(ok && ttl > 0 && ttl <= time.Minute, "TTL of a key set to expire in 1m returned %s, %v", ttl, ok)
+		}},
+		{op: celrix.OpPersist, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("persist")
+			if err := s.c.SetWithTTL(k, "v", time.Minute); err != nil {
+				return err
+			}
+			ok, err := s.c.Persist(k)
+			if err != nil {
+				return err
+			}
+			return expect(ok, "PERSIST of a key with a TTL reported none removed")
+		}},
+		{op: celrix.OpSetStream, want: okReply, run: setStream},
+		{op: celrix.OpSetChunk, want: okReply, run: setStream},
+		{op: celrix.OpSetStreamEnd, want: okReply, run: setStream},
+		{op: celrix.OpGetStream, run: func(_ context.Context, s *suite) error {
+			k, v := s.key("getstream"), strings.Repeat("conformance ", 32)
+			if err := s.c.Set(k, v); err != nil {
+				return err
+			}
+			var sb strings.Builder
+			n, ok, err := s.c.GetWriter(k, &sb)
+			if err != nil {
+				return err
+			}
+			return expect(ok && n == int64(len(v)) && sb.String() == v, "GETSTREAM returned %d bytes, want %d", n, len(v))
+		}},
+	}
+}
+
+// setStream uploads a value in two chunks and reads it back
+func setStream(_ context.Context, s *suite) error {
+	k, v := s.key("setstream"), strings.Repeat("x", celrix.StreamChunkSize+1)
+	if err := s.c.SetReader(k, strings.NewReader(v), int64(len(v))); err != nil {
+		return err
+	}
+	got, _, err := s.c.Get(k)
+	if err != nil {
+		return err
+	}
+	return expect(got == v, "GET after SETSTREAM returned %d bytes, want %d", len(got), len(v))
+}
+
+func vectorCases() []testCase {
+	meta := celrix.Metadata{"tag": celrix.StringValue("a")}
+	return []testCase{
+		{op: celrix.OpVAdd, want: okReply, run: func(_ context.Context, s *suite) error {
+			return s.c.VAdd(s.key("vadd"), vecA)
+		}},
+		{op: celrix.OpVSearch, run: func(_ context.Context, s *suite) error {
+			if err := s.c.VAdd(s.key("vsearch"), vecA); err != nil {
+				return err
+			}
+			matches, err := s.c.VSearch(vecA, 1, nil)
+			if err != nil {
+				return err
+			}
+			return expect(len(matches) == 1, "VSEARCH k=1 returned %d matches", len(matches))
+		}},
+		{op: celrix.OpVAddMeta, want: okReply, run: func(_ context.Context, s *suite) error {
+			return s.c.VAddWithMetadata(s.key("vaddmeta"), vecA, meta)
+		}},
+		{op: celrix.OpVSearchFilter, run: func(_ context.Context, s *suite) error {
+			k := s.key("vsearchfilter")
+			if err := s.c.VAddWithMetadata(k, vecA, celrix.Metadata{"tag": celrix.StringValue(k)}); err != nil {
+				return err
+			}
+			keys, err := s.c.VSearchFilter(vecA, 10, celrix.Eq("tag", celrix.StringValue(k)))
+			if err != nil {
+				return err
+			}
+			return expect(len(keys) == 1 && keys[0] == k, "filtered VSEARCH returned %v, want [%s]", keys, k)
+		}},
+		{op: celrix.OpVAddTTL, want: okReply, run: func(_ context.Context, s *suite) error {
+			return s.c.VAddWithTTL(s.key("vaddttl"), vecA, time.Minute)
+		}},
+		{op: celrix.OpVAddBatch, run: func(_ context.Context, s *suite) error {
+			return s.c.VAddBatch([]celrix.VectorItem{
+				{Key: s.key("vaddbatch-a"), Vector: vecA},
+				{Key: s.key("vaddbatch-b"), Vector: vecB},
+			})
+		}},
+		{op: celrix.OpVGet, run: func(_ context.Context, s *suite) error {
+			k := s.key("vget")
+			if err := s.c.VAdd(k, vecA); err != nil {
+				return err
+			}
+			item, ok, err := s.c.VGet(k)
+			if err != nil {
+				return err
+			}
+			return expect(ok && len(item.Vector) == dims && item.Vector[0] == 1, "VGET returned %v, %v", item.Vector, ok)
+		}},
+		{op: celrix.OpVSearchMeta, run: func(_ context.Context, s *suite) error {
+			k := s.key("vsearchmeta")
+			if err := s.c.VAddWithMetadata(k, vecA, meta); err != nil {
+				return err
+			}
+			var hits []struct {
+				Key string `celrix:",key"`
+				Tag string `celrix:"tag"`
+			}
+			if err := s.c.VSearchInto(vecA, 1, &hits); err != nil {
+				return err
+			}
+			return expect(len(hits) == 1, "VSEARCH with metadata k=1 returned %d hits", len(hits))
+		}},
+		{op: celrix.OpVDelta, want: okReply, run: func(_ context.Context, s *suite) error {
+			c, err := s.dial(celrix.WithVectorDeltas(16))
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			if !c.HasCapability(celrix.CapVectorDelta) {
+				return skip("server does not accept the vector delta capability")
+			}
+			k := s.key("vdelta")
+			if err := c.VAdd(k, vecA); err != nil {
+				return err
+			}
+			if err := c.VAdd(k, []float32{1, 0, 0, 0.5}); err != nil {
+				return err
+			}
+			item, _, err := c.VGet(k)
+			if err != nil {
+				return err
+			}
+			return expect(len(item.Vector) == dims && item.Vector[3] == 0.5, "vector after a delta is %v", item.Vector)
+		}},
+		{op: celrix.OpVSearchExplain, run: func(_ context.Context, s *suite) error {
+			if err := s.c.VAdd(s.key("vsearchexplain"), vecA); err != nil {
+				return err
+			}
+			_, _, err := s.c.VSearchExplain(vecA, 1, nil)
+			return err
+		}},
+		{op: celrix.OpVSearchBatch, run: func(_ context.Context, s *suite) error {
+			if err := s.c.VAdd(s.key("vsearchbatch"), vecA); err != nil {
+				return err
+			}
+			results, err := s.c.VSearchBatch([][]float32{vecA, vecB}, 1)
+			if err != nil {
+				return err
+			}
+			return expect(len(results) == 2, "VSEARCHBATCH of 2 queries returned %d results", len(results))
+		}},
+	}
+}
+
+func collectionCases() []testCase {
+	meta := celrix.Metadata{"tag": celrix.StringValue("a"), "n": celrix.IntValue(1)}
+	return []testCase{
+		{op: celrix.OpCreateCollection, want: okReply, run: func(_ context.Context, s *suite) error {
+			_, err := s.createCollection("create")
+			return err
+		}},
+		{op: celrix.OpDropCollection, want: okReply, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("drop")
+			if err != nil {
+				return err
+			}
+			return col.Drop()
+		}},
+		{op: celrix.OpDescribeCollection, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("describe")
+			if err != nil {
+				return err
+			}
+			schema, err := s.c.DescribeCollection(col.Name())
+			if err != nil {
+				return err
+			}
+			return expect(schema.Dims == dims && schema.Fields["tag"] == celrix.MetaString,
+				"DESCRIBECOLLECTION returned dims %d, fields %v", schema.Dims, schema.Fields)
+		}},
+		{op: celrix.OpCVAdd, want: okReply, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("cvadd")
+			if err != nil {
+				return err
+			}
+			return col.VAdd("a", vecA, meta)
+		}},
+		{op: celrix.OpCVSearch, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("cvsearch")
+			if err != nil {
+				return err
+			}
+			if err := col.VAdd("a", vecA, meta); err != nil {
+				return err
+			}
+			keys, err := col.VSearch(vecA, 1, nil)
+			if err != nil {
+				return err
+			}
+			return expect(len(keys) == 1 && keys[0] == "a", "collection VSEARCH returned %v, want [a]", keys)
+		}},
+		{op: celrix.OpCreateAlias, want: okReply, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("alias-create")
+			if err != nil {
+				return err
+			}
+			return s.c.CreateAlias(s.alias("create"), col.Name())
+		}},
+		{op: celrix.OpSwapAlias, want: okReply, run: func(_ context.Context, s *suite) error {
+			a, err := s.createCollection("alias-swap-a")
+			if err != nil {
+				return err
+			}
+			b, err := s.createCollection("alias-swap-b")
+			if err != nil {
+				return err
+			}
+			alias := s.alias("swap")
+			if err := s.c.CreateAlias(alias, a.Name()); err != nil {
+				return err
+			}
+			return s.c.SwapAlias(alias, b.Name())
+		}},
+		{op: celrix.OpDropAlias, want: okReply, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("alias-drop")
+			if err != nil {
+				return err
+			}
+			alias := s.alias("drop")
+			if err := s.c.CreateAlias(alias, col.Name()); err != nil {
+				return err
+			}
+			return s.c.DropAlias(alias)
+		}},
+		{op: celrix.OpListCollections, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("list")
+			if err != nil {
+				return err
+			}
+			names, err := s.c.ListCollections()
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				if name == col.Name() {
+					return nil
+				}
+			}
+			return fmt.Errorf("LISTCOLLECTIONS omits %s", col.Name())
+		}},
+		{op: celrix.OpCreateFieldIndex, want: okReply, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("index-create")
+			if err != nil {
+				return err
+			}
+			return col.CreateFieldIndex("tag")
+		}},
+		{op: celrix.OpDropFieldIndex, want: okReply, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("index-drop")
+			if err != nil {
+				return err
+			}
+			if err := col.CreateFieldIndex("tag"); err != nil {
+				return err
+			}
+			return s.c.DropFieldIndex(col.Name(), "tag")
+		}},
+		{op: celrix.OpSearchByField, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("index-search")
+			if err != nil {
+				return err
+			}
+			if err := col.CreateFieldIndex("tag"); err != nil {
+				return err
+			}
+			if err := col.VAdd("a", vecA, meta); err != nil {
+				return err
+			}
+			keys, err := col.SearchByField("tag", celrix.StringValue("a"))
+			if err != nil {
+				return err
+			}
+			return expect(len(keys) == 1 && keys[0] == "a", "SEARCHBYFIELD returned %v, want [a]", keys)
+		}},
+		{op: celrix.OpAggregate, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("aggregate")
+			if err != nil {
+				return err
+			}
+			if err := col.VAdd("a", vecA, meta); err != nil {
+				return err
+			}
+			groups, err := s.c.Aggregate(col.Name(), "tag", celrix.Count()).Run()
+			if err != nil {
+				return err
+			}
+			return expect(len(groups) == 1, "AGGREGATE over one tag returned %d groups", len(groups))
+		}},
+		{op: celrix.OpSnapshotSearch, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("snapshot")
+			if err != nil {
+				return err
+			}
+			h, err := col.SnapshotSearchHandle()
+			if err != nil {
+				return err
+			}
+			return h.Close()
+		}},
+		{op: celrix.OpSnapshotVSearch, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("snapshot-vsearch")
+			if err != nil {
+				return err
+			}
+			if err := col.VAdd("a", vecA, meta); err != nil {
+				return err
+			}
+			h, err := col.SnapshotSearchHandle()
+			if err != nil {
+				return err
+			}
+			defer h.Close()
+			keys, err := h.VSearch(vecA, 1, nil)
+			if err != nil {
+				return err
+			}
+			return expect(len(keys) == 1 && keys[0] == "a", "snapshot VSEARCH returned %v, want [a]", keys)
+		}},
+		{op: celrix.OpSnapshotRelease, want: okReply, run: func(_ context.Context, s *suite) error {
+			col, err := s.createCollection("snapshot-release")
+			if err != nil {
+				return err
+			}
+			h, err := col.SnapshotSearchHandle()
+			if err != nil {
+				return err
+			}
+			return h.Close()
+		}},
+	}
+}
+
+func streamCases() []testCase {
+	return []testCase{
+		{op: celrix.OpCDCSubscribe, want: okReply, run: func(ctx context.Context, s *suite) error {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			events, err := s.c.CDC(ctx, 0)
+			if err != nil {
+				return err
+			}
+			if err := s.c.Set(s.key("cdc"), "v"); err != nil {
+				return err
+			}
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					return fmt.Errorf("change stream closed before any event")
+				}
+				return ev.Err
+			case <-ctx.Done():
+				return fmt.Errorf("no change event after a SET")
+			}
+		}},
+		{op: celrix.OpKeyEventSubscribe, want: okReply, run: func(ctx context.Context, s *suite) error {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			k := s.key("keyevent")
+			events, err := s.c.KeyEvents(ctx, k, celrix.KeyExpired)
+			if err != nil {
+				return err
+			}
+			if _, err := s.c.SetNX(k, "v", time.Second); err != nil {
+				return err
+			}
+			// Servers may expire lazily, so a missing event is not a
+			// failure; the subscription's acknowledgement is what is checked
+			select {
+			case ev, ok := <-events:
+				if ok {
+					return ev.Err
+				}
+				return fmt.Errorf("key event stream closed")
+			case <-time.After(2 * time.Second):
+				return nil
+			}
+		}},
+		{op: celrix.OpExport, run: func(ctx context.Context, s *suite) error {
+			_, err := s.c.Export(ctx, io.Discard)
+			return err
+		}},
+		{op: celrix.OpExportSince, run: func(ctx context.Context, s *suite) error {
+			info, err := s.c.Export(ctx, io.Discard)
+			if err != nil {
+				return err
+			}
+			if err := s.c.Set(s.key("exportsince"), "v"); err != nil {
+				return err
+			}
+			_, err = s.c.ExportSince(ctx, io.Discard, info.Seq)
+			return err
+		}},
+		{op: celrix.OpRestore, want: okReply, destructive: true, run: restore},
+		{op: celrix.OpRestoreChunk, want: okReply, destructive: true, run: restore},
+		{op: celrix.OpRestoreEnd, want: okReply, destructive: true, run: restore},
+		{op: celrix.OpPreloadIndex, run: func(ctx context.Context, s *suite) error {
+			col, err := s.createCollection("preload")
+			if err != nil {
+				return err
+			}
+			if err := col.VAdd("a", vecA, nil); err != nil {
+				return err
+			}
+			_, err = s.c.Admin().PreloadIndex(ctx, col.Name(), nil)
+			return err
+		}},
+	}
+}
+
+// restore exports the dataset and restores it in place, covering all three
+// restore opcodes
+func restore(ctx context.Context, s *suite) error {
+	if err := s.c.Set(s.key("restore"), "v"); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if _, err := s.c.Export(ctx, &buf); err != nil {
+		return err
+	}
+	_, err := s.c.Restore(ctx, &buf)
+	return err
+}
+
+func adminCases() []testCase {
+	return []testCase{
+		{op: celrix.OpHello, run: func(_ context.Context, s *suite) error {
+			c, err := s.dial(celrix.WithProtocolVersions(1))
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			return expect(c.ProtocolVersion() == 1, "HELLO offering only version 1 settled on version %d", c.ProtocolVersion())
+		}},
+		{op: celrix.OpHealth, run: func(_ context.Context, s *suite) error {
+			_, err := s.c.Health()
+			return err
+		}},
+		{op: celrix.OpDictionaries, run: func(_ context.Context, s *suite) error {
+			dict := celrix.FlateDictionary(math.MaxUint32, []byte("celrix-conformance"))
+			if s.dict != nil {
+				dict = *s.dict
+			}
+			c, err := s.dial(celrix.WithValueCompression(16, dict))
+			if err != nil {
+				return err
+			}
+			return c.Close()
+		}},
+		{op: celrix.OpCapabilities, run: func(_ context.Context, s *suite) error {
+			c, err := s.dial(celrix.WithVectorDeltas(16), celrix.WithServerTiming())
+			if err != nil {
+				return err
+			}
+			return c.Close()
+		}},
+		{op: celrix.OpACLList, run: func(_ context.Context, s *suite) error {
+			_, err := s.c.Admin().ACLUsers()
+			return err
+		}},
+		{op: celrix.OpACLSetUser, want: okReply, run: func(_ context.Context, s *suite) error {
+			return s.c.Admin().ACLSetUser(s.userSpec("setuser"))
+		}},
+		{op: celrix.OpACLDelUser, want: okReply, run: func(_ context.Context, s *suite) error {
+			u := s.userSpec("deluser")
+			if err := s.c.Admin().ACLSetUser(u); err != nil {
+				return err
+			}
+			return s.c.Admin().ACLDelUser(u.Name)
+		}},
+		{op: celrix.OpConfigGet, run: func(_ context.Context, s *suite) error {
+			_, err := s.c.Admin().ConfigGet("*")
+			return err
+		}},
+		{op: celrix.OpConfigSet, want: okReply, run: func(_ context.Context, s *suite) error {
+			// Setting a parameter to its current value changes nothing
+			cfg, err := s.c.Admin().ConfigGet("*")
+			if err != nil {
+				return err
+			}
+			for name, v := range cfg {
+				return s.c.Admin().ConfigSet(name, v)
+			}
+			return skip("server reports no configuration parameters")
+		}},
+		{op: celrix.OpKeyspaceSample, run: func(_ context.Context, s *suite) error {
+			if err := s.c.Set(s.key("sample"), "v"); err != nil {
+				return err
+			}
+			_, err := s.c.Admin().KeyspaceSample(1)
+			return err
+		}},
+		{op: celrix.OpHotKeys, run: func(_ context.Context, s *suite) error {
+			_, err := s.c.Admin().HotKeys(1)
+			return err
+		}},
+		{op: celrix.OpMemoryUsage, run: func(_ context.Context, s *suite) error {
+			k := s.key("memory")
+			if err := s.c.Set(k, "v"); err != nil {
+				return err
+			}
+			n, ok, err := s.c.MemoryUsage(k)
+			if err != nil {
+				return err
+			}
+			return expect(ok && n > 0, "MEMORYUSAGE of a key just set returned %d, %v", n, ok)
+		}},
+		{op: celrix.OpFlushDB, want: okReply, destructive: true, run: func(_ context.Context, s *suite) error {
+			return s.c.Admin().FlushDB()
+		}},
+		{op: celrix.OpAuditLog, want: okReply, run: func(_ context.Context, s *suite) error {
+			c, err := s.dial(celrix.WithAudit(celrix.AuditOptions{
+				Writer: celrix.NewJSONAuditWriter(io.Discard),
+				Actor:  "celrix-conformance",
+				Server: true,
+			}))
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			return c.Admin().ACLSetUser(s.userSpec("audit"))
+		}},
+		{op: celrix.OpDryRun, run: func(_ context.Context, s *suite) error {
+			a := s.c.Admin(celrix.WithDryRun())
+			if _, err := a.FlushPrefix(s.prefix + "dryrun:"); err != nil {
+				return err
+			}
+			return expect(len(a.Effects()) == 1, "dry run recorded %d effects", len(a.Effects()))
+		}},
+		{op: celrix.OpFlushPrefix, run: func(_ context.Context, s *suite) error {
+			prefix := s.prefix + "flush:"
+			if err := s.c.Set(s.key("flush:a"), "v"); err != nil {
+				return err
+			}
+			n, err := s.c.Admin().FlushPrefix(prefix)
+			if err != nil {
+				return err
+			}
+			return expect(n == 1, "FLUSHPREFIX over one key removed %d", n)
+		}},
+		{op: celrix.OpClientList, run: func(_ context.Context, s *suite) error {
+			clients, err := s.c.Admin().Clients()
+			if err != nil {
+				return err
+			}
+			return expect(len(clients) > 0, "CLIENTLIST omits the connection asking")
+		}},
+	}
+}
+
+func structureCases() []testCase {
+	return []testCase{
+		{op: celrix.OpZAdd, want: yes, run: func(_ context.Context, s *suite) error {
+			added, err := s.c.ZAdd(s.key("zadd"), "m", 1, 0)
+			if err != nil {
+				return err
+			}
+			return expect(added, "ZADD of a new member reported it existing")
+		}},
+		{op: celrix.OpZRem, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("zrem")
+			if _, err := s.c.ZAdd(k, "m", 1, 0); err != nil {
+				return err
+			}
+			removed, err := s.c.ZRem(k, "m")
+			if err != nil {
+				return err
+			}
+			return expect(removed, "ZREM of a member reported it missing")
+		}},
+		{op: celrix.OpZScore, run: func(_ context.Context, s *suite) error {
+			k := s.key("zscore")
+			if _, err := s.c.ZAdd(k, "m", 1.5, 0); err != nil {
+				return err
+			}
+			score, ok, err := s.c.ZScore(k, "m")
+			if err != nil {
+				return err
+			}
+			return expect(ok && score == 1.5, "ZSCORE returned %v, %v, want 1.5", score, ok)
+		}},
+		{op: celrix.OpZCard, want: integer(1), run: func(_ context.Context, s *suite) error {
+			k := s.key("zcard")
+			if _, err := s.c.ZAdd(k, "m", 1, 0); err != nil {
+				return err
+			}
+			n, err := s.c.ZCard(k)
+			if err != nil {
+				return err
+			}
+			return expect(n == 1, "ZCARD of a one-member set returned %d", n)
+		}},
+		{op: celrix.OpZRangeByScore, run: func(_ context.Context, s *suite) error {
+			k := s.key("zrange")
+			if _, err := s.c.ZAdd(k, "m", 1, 0); err != nil {
+				return err
+			}
+			members, err := s.c.ZRangeByScore(k, 0, 10, 10)
+			if err != nil {
+				return err
+			}
+			return expect(len(members) == 1 && members[0].Member == "m", "ZRANGEBYSCORE returned %v", members)
+		}},
+		{op: celrix.OpBFReserve, want: okReply, run: func(_ context.Context, s *suite) error {
+			return s.c.BFReserve(s.key("bfreserve"), 0.01, 1000)
+		}},
+		{op: celrix.OpBFAdd, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("bfadd")
+			if err := s.c.BFReserve(k, 0.01, 1000); err != nil {
+				return err
+			}
+			added, err := s.c.BFAdd(k, "a")
+			if err != nil {
+				return err
+			}
+			return expect(added, "BFADD to an empty filter reported the item present")
+		}},
+		{op: celrix.OpBFExists, want: yes, run: func(_ context.Context, s *suite) error {
+			k := s.key("bfexists")
+			if err := s.c.BFReserve(k, 0.01, 1000); err != nil {
+				return err
+			}
+			if _, err := s.c.BFAdd(k, "a"); err != nil {
+				return err
+			}
+			ok, err := s.c.BFExists(k, "a")
+			if err != nil {
+				return err
+			}
+			return expect(ok, "BFEXISTS of an added item reported it absent")
+		}},
+		{op: celrix.OpBFMAdd, run: func(_ context.Context, s *suite) error {
+			k := s.key("bfmadd")
+			if err := s.c.BFReserve(k, 0.01, 1000); err != nil {
+				return err
+			}
+			added, err := s.c.BFMAdd(k, "a", "b")
+			if err != nil {
+				return err
+			}
+			return expect(added[0] && added[1], "BFMADD to an empty filter returned %v", added)
+		}},
+		{op: celrix.OpBFMExists, run: func(_ context.Context, s *suite) error {
+			k := s.key("bfmexists")
+			if err := s.c.BFReserve(k, 0.01, 1000); err != nil {
+				return err
+			}
+			if _, err := s.c.BFMAdd(k, "a", "b"); err != nil {
+				return err
+			}
+			ok, err := s.c.BFMExists(k, "a", "b")
+			if err != nil {
+				return err
+			}
+			return expect(ok[0] && ok[1], "BFMEXISTS of added items returned %v", ok)
+		}},
+		{op: celrix.OpCMSInit, want: okReply, run: func(_ context.Context, s *suite) error {
+			return s.c.CMSInit(s.key("cmsinit"), 100, 4)
+		}},
+		{op: celrix.OpCMSIncr, want: integer(3), run: func(_ context.Context, s *suite) error {
+			k := s.key("cmsincr")
+			if err := s.c.CMSInit(k, 100, 4); err != nil {
+				return err
+			}
+			n, err := s.c.CMSIncr(k, "a", 3)
+			if err != nil {
+				return err
+			}
+			return expect(n == 3, "CMSINCR 3 on an empty sketch returned %d", n)
+		}},
+		{op: celrix.OpCMSQuery, run: func(_ context.Context, s *suite) error {
+			k := s.key("cmsquery")
+			if err := s.c.CMSInit(k, 100, 4); err != nil {
+				return err
+			}
+			if _, err := s.c.CMSIncr(k, "a", 3); err != nil {
+				return err
+			}
+			counts, err := s.c.CMSQuery(k, "a")
+			if err != nil {
+				return err
+			}
+			return expect(len(counts) == 1 && counts[0] == 3, "CMSQUERY returned %v, want [3]", counts)
+		}},
+		{op: celrix.OpTopKReserve, want: okReply, run: func(_ context.Context, s *suite) error {
+			return s.c.TopKReserve(s.key("topkreserve"), 3)
+		}},
+		{op: celrix.OpTopKAdd, run: func(_ context.Context, s *suite) error {
+			k := s.key("topkadd")
+			if err := s.c.TopKReserve(k, 3); err != nil {
+				return err
+			}
+			_, err := s.c.TopKAdd(k, "a")
+			return err
+		}},
+		{op: celrix.OpTopKList, run: func(_ context.Context, s *suite) error {
+			k := s.key("topklist")
+			if err := s.c.TopKReserve(k, 3); err != nil {
+				return err
+			}
+			if _, err := s.c.TopKAdd(k, "a"); err != nil {
+				return err
+			}
+			items, err := s.c.TopKList(k)
+			if err != nil {
+				return err
+			}
+			return expect(len(items) == 1 && items[0].Item == "a", "TOPKLIST returned %v", items)
+		}},
+		{op: celrix.OpTSCreate, want: okReply, run: func(_ context.Context, s *suite) error {
+			return s.c.TSCreate(s.key("tscreate"), time.Hour)
+		}},
+		{op: celrix.OpTSAdd, want: okReply, run: func(_ context.Context, s *suite) error {
+			k := s.key("tsadd")
+			if err := s.c.TSCreate(k, time.Hour); err != nil {
+				return err
+			}
+			return s.c.TSAdd(k, time.Now(), 1.5)
+		}},
+		{op: celrix.OpTSRange, run: func(_ context.Context, s *suite) error {
+			k := s.key("tsrange")
+			if err := s.c.TSCreate(k, time.Hour); err != nil {
+				return err
+			}
+			now := time.Now()
+			if err := s.c.TSAdd(k, now, 1.5); err != nil {
+				return err
+			}
+			samples, err := s.c.TSRange(k, now.Add(-time.Minute), now.Add(time.Minute))
+			if err != nil {
+				return err
+			}
+			return expect(len(samples) == 1 && samples[0].Value == 1.5, "TSRANGE returned %v", samples)
+		}},
+		{op: celrix.OpTSCreateRule, want: okReply, run: func(_ context.Context, s *suite) error {
+			src, dst := s.key("tsrule-src"), s.key("tsrule-dst")
+			if err := s.c.TSCreate(src, time.Hour); err != nil {
+				return err
+			}
+			if err := s.c.TSCreate(dst, time.Hour); err != nil {
+				return err
+			}
+			return s.c.TSCreateRule(src, dst, celrix.AggAvg, time.Minute)
+		}},
+		{op: celrix.OpTSDeleteRule, want: okReply, run: func(_ context.Context, s *suite) error {
+			src, dst := s.key("tsrule-del-src"), s.key("tsrule-del-dst")
+			if err := s.c.TSCreate(src, time.Hour); err != nil {
+				return err
+			}
+			if err := s.c.TSCreate(dst, time.Hour); err != nil {
+				return err
+			}
+			if err := s.c.TSCreateRule(src, dst, celrix.AggAvg, time.Minute); err != nil {
+				return err
+			}
+			return s.c.TSDeleteRule(src, dst)
+		}},
+	}
+}
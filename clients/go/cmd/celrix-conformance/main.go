@@ -0,0 +1,112 @@
+// Command celrix-conformance checks a server implementation against the
+// behaviour the Go client expects from it.
+//
+// Each built-in request opcode is exercised through the client API, setting
+// up whatever state the command needs, while every frame exchanged on every
+// connection is captured. A command passes if the client accepts the reply
+// and the server did not answer with an error; commands whose reply is fully
+// determined must also match it byte for byte.
+//
+//	celrix-conformance -addr 127.0.0.1:6380
+//	celrix-conformance -addr 127.0.0.1:6380 -only GET,SET,VSEARCH -v
+//
+// The result is printed as a matrix of opcodes, with the request and reply
+// frames in hex under each failure and, where a reply was expected exactly,
+// both versions aligned at the first differing byte. Reply opcodes the
+// server sent during the run are listed after it.
+//
+// Keys, collections and users are created under a unique prefix and removed
+// afterwards. Commands that replace server-wide state, FLUSHDB and RESTORE,
+// are skipped unless -destructive is given, which is only safe against a
+// scratch server. SETCOMPRESSED is skipped unless the server accepts the
+// dictionary given with -dict.
+//
+// The exit status is 1 if any command fails.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6380", "CELRIX server address")
+	only := flag.String("only", "", "comma-separated opcode names to check (default: all)")
+	destructive := flag.Bool("destructive", false, "also run commands that replace server-wide state")
+	timeout := flag.Duration("timeout", 5*time.Second, "deadline for each command check")
+	dictFile := flag.String("dict", "", "compression dictionary registered on the server, for SETCOMPRESSED")
+	dictID := flag.Uint("dict-id", 1, "ID the server registered -dict under")
+	verbose := flag.Bool("v", false, "print the frames of passing commands too")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := &suite{
+		addr:        *addr,
+		tap:         &tap{},
+		destructive: *destructive,
+		timeout:     *timeout,
+		prefix:      fmt.Sprintf("conformance-%d-", time.Now().UnixNano()),
+	}
+	if *dictFile != "" {
+		dict, err := os.ReadFile(*dictFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		d := celrix.FlateDictionary(uint32(*dictID), dict)
+		s.dict = &d
+	}
+
+	selected, err := selectCases(*only)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := s.connect(); err != nil {
+		log.Fatalf("connect: %v", err)
+	}
+	results := s.run(ctx, selected)
+	s.cleanup()
+	s.c.Close()
+	if err := ctx.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	failed := printMatrix(os.Stdout, results, *verbose)
+	printObserved(os.Stdout, s.tap.observed())
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// selectCases returns the cases for the opcodes named in only, or every
+// case when it is empty
+func selectCases(only string) ([]testCase, error) {
+	all := cases()
+	if only == "" {
+		return all, nil
+	}
+	byName := make(map[string]testCase, len(all))
+	for _, tc := range all {
+		byName[celrix.Op(tc.op).String()] = tc
+	}
+	var out []testCase
+	for _, name := range strings.Split(only, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		tc, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("no check for opcode %q", name)
+		}
+		out = append(out, tc)
+	}
+	return out, nil
+}
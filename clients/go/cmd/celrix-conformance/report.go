@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// replyNames names the reply opcodes, which Op.String leaves to the
+// request side of the protocol
+var replyNames = map[uint8]string{
+	celrix.OpPong:            "PONG",
+	celrix.OpOk:              "OK",
+	celrix.OpError:           "ERROR",
+	celrix.OpValue:           "VALUE",
+	celrix.OpNil:             "NIL",
+	celrix.OpInteger:         "INTEGER",
+	celrix.OpArray:           "ARRAY",
+	celrix.OpCompressed:      "COMPRESSED",
+	celrix.OpTypedArray:      "TYPEDARRAY",
+	celrix.OpDouble:          "DOUBLE",
+	celrix.OpMap:             "MAP",
+	celrix.OpBool:            "BOOL",
+	celrix.OpBigInt:          "BIGINT",
+	celrix.OpTypedString:     "TYPEDSTRING",
+	celrix.OpAttributes:      "ATTRIBUTES",
+	celrix.OpValueChunk:      "VALUECHUNK",
+	celrix.OpChangeEvent:     "CHANGEEVENT",
+	celrix.OpKeyEvent:        "KEYEVENT",
+	celrix.OpExportChunk:     "EXPORTCHUNK",
+	celrix.OpPreloadProgress: "PRELOADPROGRESS",
+}
+
+func replyName(op uint8) string {
+	if name, ok := replyNames[op]; ok {
+		return name
+	}
+	return celrix.Op(op).String()
+}
+
+// printMatrix writes one line per check, followed by the frames of each
+// failure, and reports whether any check failed
+func printMatrix(w io.Writer, results []result, verbose bool) (failed bool) {
+	var counts [3]int
+	for _, r := range results {
+		counts[r.status]++
+		line := fmt.Sprintf("%-4s  0x%02X  %-18s", r.status, r.op, celrix.Op(r.op).String())
+		if r.x != nil && r.x.reply != nil {
+			line += fmt.Sprintf("  -> %-12s", replyName(r.x.reply.Opcode))
+		} else {
+			line += fmt.Sprintf("  %-15s", "")
+		}
+		if r.detail != "" {
+			line += "  " + r.detail
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+		if r.status == fail || (verbose && r.status == pass) {
+			printFrames(w, r)
+		}
+	}
+	fmt.Fprintf(w, "\n%d passed, %d failed, %d skipped\n", counts[pass], counts[fail], counts[skipped])
+	return counts[fail] > 0
+}
+
+// printFrames dumps the checked exchange, aligning the expected and actual
+// reply at their first difference
+func printFrames(w io.Writer, r result) {
+	if r.x == nil {
+		return
+	}
+	fmt.Fprintf(w, "        request   %s\n", frameHex(wire.Encode(r.x.req), 0))
+	if r.x.reply == nil {
+		return
+	}
+	got := wire.Encode(*r.x.reply)
+	if r.want == nil {
+		fmt.Fprintf(w, "        reply     %s\n", frameHex(got, 0))
+		return
+	}
+	want := wire.Encode(*r.want)
+	at := firstDiff(want, got)
+	fmt.Fprintf(w, "        expected  %s\n", frameHex(want, at))
+	fmt.Fprintf(w, "        got       %s\n", frameHex(got, at))
+	if at < wire.HeaderSize {
+		fmt.Fprintf(w, "        first difference at byte %d, in the header\n", at)
+	} else {
+		fmt.Fprintf(w, "        first difference at byte %d, payload byte %d\n", at, at-wire.HeaderSize)
+	}
+}
+
+// hexWindow is the number of frame bytes shown per line
+const hexWindow = 32
+
+// frameHex renders up to hexWindow bytes of a frame starting a little
+// before offset at, so a difference deep in a payload stays visible
+func frameHex(b []byte, at int) string {
+	start := 0
+	if at > hexWindow/2 {
+		start = at - hexWindow/4
+	}
+	if start > len(b) {
+		start = len(b)
+	}
+	end := start + hexWindow
+	if end > len(b) {
+		end = len(b)
+	}
+	var sb strings.Builder
+	if start > 0 {
+		fmt.Fprintf(&sb, "@%d ...", start)
+	}
+	sb.WriteString(hex.EncodeToString(b[start:end]))
+	if end < len(b) {
+		fmt.Fprintf(&sb, "... (%d bytes)", len(b))
+	}
+	return sb.String()
+}
+
+func firstDiff(a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// printObserved lists the reply opcodes seen on the wire, so implementers
+// can tell which reply encodings the run covered
+func printObserved(w io.Writer, ops []uint8) {
+	names := make([]string, len(ops))
+	for i, op := range ops {
+		names[i] = fmt.Sprintf("%s (0x%02X)", replyName(op), op)
+	}
+	fmt.Fprintf(w, "replies observed: %s\n", strings.Join(names, ", "))
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// suite holds the state shared by the checks of one run
+type suite struct {
+	addr        string
+	tap         *tap
+	destructive bool
+	timeout     time.Duration
+	dict        *celrix.Dictionary
+
+	// c is the connection most checks run on
+	c *celrix.Client
+	// prefix namespaces everything the run creates
+	prefix      string
+	keys        []string
+	collections []string
+	aliases     []string
+	users       []string
+}
+
+// connect (re)opens the shared connection
+func (s *suite) connect() error {
+	if s.c != nil {
+		s.c.Close()
+	}
+	c, err := s.dial()
+	if err != nil {
+		return err
+	}
+	s.c = c
+	return nil
+}
+
+// dial opens a tapped connection; checks that need client options of their
+// own use it directly and close the client when done
+func (s *suite) dial(opts ...celrix.Option) (*celrix.Client, error) {
+	opts = append([]celrix.Option{celrix.WithDialer(s.tap.dial)}, opts...)
+	return celrix.Connect(s.addr, opts...)
+}
+
+// key returns a fresh key under the run's prefix, removed by cleanup
+func (s *suite) key(name string) string {
+	k := s.prefix + name
+	s.keys = append(s.keys, k)
+	return k
+}
+
+// collection returns a fresh collection name under the run's prefix
+func (s *suite) collection(name string) string {
+	col := s.prefix + name
+	s.collections = append(s.collections, col)
+	return col
+}
+
+// alias returns a fresh alias name under the run's prefix
+func (s *suite) alias(name string) string {
+	a := s.prefix + "alias-" + name
+	s.aliases = append(s.aliases, a)
+	return a
+}
+
+// userSpec describes a disabled ACL user under the run's prefix
+func (s *suite) userSpec(name string) celrix.ACLUserSpec {
+	u := s.prefix + name
+	s.users = append(s.users, u)
+	return celrix.ACLUserSpec{Name: u, Password: s.prefix}
+}
+
+// createCollection creates a collection of test vectors with a string
+// "tag" field and an integer "n" field
+func (s *suite) createCollection(name string) (*celrix.Collection, error) {
+	return s.c.CreateCollection(s.collection(name), celrix.Schema{
+		Dims:   dims,
+		Metric: celrix.MetricCosine,
+		Fields: map[string]celrix.MetaType{"tag": celrix.MetaString, "n": celrix.MetaInt},
+	})
+}
+
+// cleanup removes what the run created, best effort
+func (s *suite) cleanup() {
+	if s.c == nil || s.c.Err() != nil {
+		if s.connect() != nil {
+			return
+		}
+	}
+	for _, k := range s.keys {
+		s.c.Del(k)
+	}
+	for _, a := range s.aliases {
+		s.c.DropAlias(a)
+	}
+	for _, col := range s.collections {
+		s.c.DropCollection(col)
+	}
+	for _, u := range s.users {
+		s.c.Admin().ACLDelUser(u)
+	}
+}
+
+// skip is returned by a check that cannot run against this server or
+// without -destructive
+type skip string
+
+func (s skip) Error() string { return string(s) }
+
+// status is the outcome of one check
+type status int
+
+const (
+	pass status = iota
+	fail
+	skipped
+)
+
+func (st status) String() string {
+	switch st {
+	case pass:
+		return "PASS"
+	case fail:
+		return "FAIL"
+	default:
+		return "SKIP"
+	}
+}
+
+// result is the outcome of one check, with the exchange it judged
+type result struct {
+	op     uint8
+	status status
+	detail string
+	x      *exchange
+	// want is the expected reply the exchange was compared against, if any
+	want *wire.Frame
+}
+
+func (s *suite) run(ctx context.Context, checks []testCase) []result {
+	results := make([]result, 0, len(checks))
+	for _, tc := range checks {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, s.check(ctx, tc))
+		// A check that timed out or broke the framing leaves the shared
+		// connection unusable
+		if s.c.Err() != nil || s.c.Ping() != nil {
+			if err := s.connect(); err != nil {
+				for _, rest := range checks[len(results):] {
+					results = append(results, result{op: rest.op, status: fail, detail: "reconnect: " + err.Error()})
+				}
+				break
+			}
+		}
+	}
+	return results
+}
+
+func (s *suite) check(ctx context.Context, tc testCase) result {
+	r := result{op: tc.op}
+	if tc.run == nil {
+		r.status, r.detail = skipped, "no check for this opcode"
+		return r
+	}
+	if tc.destructive && !s.destructive {
+		r.status, r.detail = skipped, "replaces server-wide state; run with -destructive"
+		return r
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	m := s.tap.mark()
+	err := s.c.RunContext(ctx, func() error { return tc.run(ctx, s) })
+
+	var sk skip
+	if errors.As(err, &sk) {
+		r.status, r.detail = skipped, sk.Error()
+		return r
+	}
+
+	// The last exchange of the opcode is the one checked; earlier ones
+	// are the check's own setup
+	xs := s.tap.since(m)
+	for i := len(xs) - 1; i >= 0; i-- {
+		if xs[i].req.Opcode == tc.op {
+			r.x = &xs[i]
+			break
+		}
+	}
+
+	r.status = fail
+	switch {
+	case r.x != nil && r.x.reply != nil && r.x.reply.Opcode == celrix.OpError:
+		r.detail = fmt.Sprintf("server error: %s", r.x.reply.Payload)
+	case err != nil:
+		r.detail = err.Error()
+	case r.x == nil:
+		r.detail = "client did not send the command"
+	case r.x.reply == nil:
+		r.detail = "no reply"
+	case len(tc.want) > 0:
+		got := *r.x.reply
+		for _, w := range tc.want {
+			if w.opcode == got.Opcode && bytes.Equal(w.payload, got.Payload) {
+				r.status = pass
+				return r
+			}
+		}
+		// Diff against the alternative of the same shape, if there is one
+		w := tc.want[0]
+		for _, alt := range tc.want {
+			if alt.opcode == got.Opcode {
+				w = alt
+				break
+			}
+		}
+		r.want = &wire.Frame{Version: got.Version, Opcode: w.opcode, Flags: got.Flags, RequestID: got.RequestID, StreamID: got.StreamID, Payload: w.payload}
+		r.detail = fmt.Sprintf("reply differs from the expected %s", replyName(w.opcode))
+	default:
+		r.status = pass
+	}
+	return r
+}
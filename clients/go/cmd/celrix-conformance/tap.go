@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// tap records the bytes exchanged on every connection it dials. It sits
+// below the client, so the frames of dedicated stream connections are seen
+// too, exactly as they crossed the network.
+type tap struct {
+	mu    sync.Mutex
+	conns []*tapConn
+}
+
+func (t *tap) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	tc := &tapConn{Conn: conn}
+	t.mu.Lock()
+	t.conns = append(t.conns, tc)
+	t.mu.Unlock()
+	return tc, nil
+}
+
+type tapConn struct {
+	net.Conn
+	mu         sync.Mutex
+	sent, recv []byte
+}
+
+func (c *tapConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.mu.Lock()
+	c.recv = append(c.recv, p[:n]...)
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *tapConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.mu.Lock()
+	c.sent = append(c.sent, p[:n]...)
+	c.mu.Unlock()
+	return n, err
+}
+
+// frames decodes the complete frames captured so far in each direction.
+// All connections speak layout version 1: the suite never offers another.
+func (c *tapConn) frames() (sent, recv []wire.Frame) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return decodeAll(c.sent), decodeAll(c.recv)
+}
+
+func decodeAll(b []byte) []wire.Frame {
+	var out []wire.Frame
+	for len(b) > 0 {
+		f, n, err := wire.Decode(b)
+		if err != nil {
+			break
+		}
+		f.Payload = append([]byte(nil), f.Payload...)
+		out = append(out, f)
+		b = b[n:]
+	}
+	return out
+}
+
+// mark is a point in the capture: the number of frames each connection had
+// exchanged
+type mark struct {
+	sent, recv map[*tapConn]int
+}
+
+func (t *tap) mark() mark {
+	t.mu.Lock()
+	conns := append([]*tapConn(nil), t.conns...)
+	t.mu.Unlock()
+	m := mark{sent: make(map[*tapConn]int), recv: make(map[*tapConn]int)}
+	for _, c := range conns {
+		sent, recv := c.frames()
+		m.sent[c], m.recv[c] = len(sent), len(recv)
+	}
+	return m
+}
+
+// exchange is a request and the first reply frame carrying its request ID
+type exchange struct {
+	req wire.Frame
+	// reply is nil if the server never answered
+	reply *wire.Frame
+}
+
+// since returns the exchanges started after m on every connection, grouped
+// by connection in the order they were opened
+func (t *tap) since(m mark) []exchange {
+	t.mu.Lock()
+	conns := append([]*tapConn(nil), t.conns...)
+	t.mu.Unlock()
+	var out []exchange
+	for _, c := range conns {
+		sent, recv := c.frames()
+		sent, recv = sent[m.sent[c]:], recv[m.recv[c]:]
+		for _, req := range sent {
+			x := exchange{req: req}
+			for i := range recv {
+				// Attributes frames precede the reply they describe
+				if recv[i].RequestID == req.RequestID && recv[i].Opcode != celrix.OpAttributes {
+					x.reply = &recv[i]
+					break
+				}
+			}
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// observed returns the distinct opcodes the server sent over the whole run
+func (t *tap) observed() []uint8 {
+	t.mu.Lock()
+	conns := append([]*tapConn(nil), t.conns...)
+	t.mu.Unlock()
+	seen := make(map[uint8]bool)
+	for _, c := range conns {
+		_, recv := c.frames()
+		for _, f := range recv {
+			seen[f.Opcode] = true
+		}
+	}
+	out := make([]uint8, 0, len(seen))
+	for op := range seen {
+		out = append(out, op)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
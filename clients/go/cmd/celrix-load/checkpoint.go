@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// checkpoint records how many leading records of Input have been loaded
+type checkpoint struct {
+	Input    string `json:"input"`
+	Records  int64  `json:"records"`
+	Complete bool   `json:"complete"`
+}
+
+func loadCheckpoint(path, input string) (checkpoint, error) {
+	abs, _ := filepath.Abs(input)
+	ckpt := checkpoint{Input: abs}
+	if path == "" {
+		return ckpt, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ckpt, nil
+	}
+	if err != nil {
+		return ckpt, err
+	}
+	var saved checkpoint
+	if err := json.Unmarshal(b, &saved); err != nil {
+		return ckpt, fmt.Errorf("read checkpoint: %w", err)
+	}
+	if saved.Input != abs {
+		return ckpt, fmt.Errorf("checkpoint %s belongs to %s, not %s", path, saved.Input, abs)
+	}
+	if saved.Complete {
+		return ckpt, fmt.Errorf("checkpoint %s records a completed load; remove it to load again", path)
+	}
+	return saved, nil
+}
+
+func saveCheckpoint(path string, ckpt checkpoint) error {
+	b, err := json.Marshal(ckpt)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// tracker advances the checkpoint past batches in order. Batches finish out
+// of order across workers, so the checkpoint only moves over the longest
+// run of completed batches from the start.
+type tracker struct {
+	mu       sync.Mutex
+	path     string
+	ckpt     checkpoint
+	sizes    map[int]int
+	offsets  map[int]int64
+	finished map[int]bool
+	next     int
+	pending  int64
+}
+
+func newTracker(ckpt checkpoint, path string) *tracker {
+	return &tracker{
+		path:     path,
+		ckpt:     ckpt,
+		sizes:    make(map[int]int),
+		offsets:  make(map[int]int64),
+		finished: make(map[int]bool),
+		pending:  ckpt.Records,
+	}
+}
+
+func (t *tracker) started(seq, n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sizes[seq] = n
+	t.offsets[seq] = t.pending
+	t.pending += int64(n)
+}
+
+// offset returns the absolute record index at which a batch starts
+func (t *tracker) offset(seq int) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.offsets[seq]
+}
+
+func (t *tracker) done(seq int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished[seq] = true
+	advanced := false
+	for t.finished[t.next] {
+		t.ckpt.Records += int64(t.sizes[t.next])
+		delete(t.finished, t.next)
+		delete(t.sizes, t.next)
+		delete(t.offsets, t.next)
+		t.next++
+		advanced = true
+	}
+	if advanced && t.path != "" {
+		return saveCheckpoint(t.path, t.ckpt)
+	}
+	return nil
+}
+
+func (t *tracker) complete() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.path == "" {
+		return nil
+	}
+	t.ckpt.Complete = true
+	return saveCheckpoint(t.path, t.ckpt)
+}
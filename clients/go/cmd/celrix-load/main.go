@@ -0,0 +1,194 @@
+// Command celrix-load bulk loads embeddings and metadata into CELRIX.
+//
+// Input is JSONL (one {"key":..,"vector":[..],"metadata":{..}} object per
+// line) or CSV with a header row containing "key" and "vector" columns; the
+// vector cell is a JSON array or space-separated floats and every other column
+// becomes a metadata field with its type inferred from the text.
+//
+//	celrix-load -addr 127.0.0.1:6380 -workers 8 -batch 256 -checkpoint load.ckpt docs.jsonl
+//
+// Records are sent in batches with VAddBatch from several connections in
+// parallel. With -collection, each batch is validated against the
+// collection's schema and its adds are pipelined in one round trip. With
+// -checkpoint, the number of records durably loaded is saved as batches
+// complete, and a rerun with the same checkpoint skips them.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+	"github.com/YASSERRMD/celrix/clients/go/internal/dataset"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6380", "CELRIX server address")
+	format := flag.String("format", "", "input format: jsonl, csv or parquet (default: from file extension)")
+	collection := flag.String("collection", "", "load into this collection, validating against its schema")
+	workers := flag.Int("workers", 4, "parallel connections")
+	batchSize := flag.Int("batch", 256, "records per batch")
+	checkpoint := flag.String("checkpoint", "", "checkpoint file for resumable loads")
+	quiet := flag.Bool("quiet", false, "disable the progress bar")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: celrix-load [flags] FILE\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *workers < 1 || *batchSize < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, config{
+		addr:       *addr,
+		path:       path,
+		format:     dataset.DetectFormat(*format, path),
+		collection: *collection,
+		workers:    *workers,
+		batchSize:  *batchSize,
+		checkpoint: *checkpoint,
+		progress:   !*quiet,
+	}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type config struct {
+	addr       string
+	path       string
+	format     string
+	collection string
+	workers    int
+	batchSize  int
+	checkpoint string
+	progress   bool
+}
+
+// batch is a run of consecutive records; seq orders batches for
+// checkpointing
+type batch struct {
+	seq    int
+	offset int64 // input bytes consumed once the batch was read
+	items  []celrix.VectorItem
+}
+
+func run(ctx context.Context, cfg config) error {
+	ckpt, err := loadCheckpoint(cfg.checkpoint, cfg.path)
+	if err != nil {
+		return err
+	}
+
+	src, err := dataset.Open(cfg.path, cfg.format)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if ckpt.Records > 0 {
+		log.Printf("resuming after %d records", ckpt.Records)
+		if err := src.Skip(ckpt.Records); err != nil {
+			return fmt.Errorf("skip checkpointed records: %w", err)
+		}
+	}
+
+	clients := make([]*celrix.Client, cfg.workers)
+	for i := range clients {
+		c, err := celrix.Connect(cfg.addr)
+		if err != nil {
+			return fmt.Errorf("connect: %w", err)
+		}
+		defer c.Close()
+		clients[i] = c
+	}
+
+	prog := newProgress(cfg.progress, src.Size(), ckpt.Records)
+	defer prog.finish()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan batch, cfg.workers*2)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	tracker := newTracker(ckpt, cfg.checkpoint)
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *celrix.Client) {
+			defer wg.Done()
+			for b := range batches {
+				if err := send(c, cfg.collection, b.items); err != nil {
+					fail(fmt.Errorf("batch starting at record %d: %w", tracker.offset(b.seq), err))
+					return
+				}
+				if err := tracker.done(b.seq); err != nil {
+					fail(err)
+					return
+				}
+				prog.add(len(b.items), b.offset)
+			}
+		}(c)
+	}
+
+	// Read and dispatch batches
+	readErr := func() error {
+		defer close(batches)
+		for seq := 0; ; seq++ {
+			items, err := src.Next(cfg.batchSize)
+			if len(items) > 0 {
+				tracker.started(seq, len(items))
+				select {
+				case batches <- batch{seq: seq, offset: src.Offset(), items: items}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		}
+	}()
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if readErr != nil {
+		return readErr
+	}
+	prog.finish()
+	log.Printf("loaded %d records in %s", prog.total(), prog.elapsed().Round(time.Millisecond))
+	return tracker.complete()
+}
+
+func send(c *celrix.Client, collection string, items []celrix.VectorItem) error {
+	if collection == "" {
+		return c.VAddBatch(items)
+	}
+	return c.Collection(collection).VAddBatch(items)
+}
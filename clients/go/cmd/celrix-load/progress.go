@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progress renders a single-line progress bar on stderr, based on input
+// bytes consumed
+type progress struct {
+	mu      sync.Mutex
+	enabled bool
+	size    int64
+	start   time.Time
+	last    time.Time
+	records int64
+	skipped int64
+	done    bool
+}
+
+func newProgress(enabled bool, size, skipped int64) *progress {
+	now := time.Now()
+	return &progress{enabled: enabled, size: size, start: now, skipped: skipped}
+}
+
+func (p *progress) add(n int, offset int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records += int64(n)
+	if !p.enabled || p.done {
+		return
+	}
+	now := time.Now()
+	if now.Sub(p.last) < 200*time.Millisecond {
+		return
+	}
+	p.last = now
+	p.render(offset)
+}
+
+func (p *progress) render(offset int64) {
+	const width = 30
+	frac := 1.0
+	if p.size > 0 {
+		frac = float64(offset) / float64(p.size)
+		if frac > 1 {
+			frac = 1
+		}
+	}
+	filled := int(frac * width)
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		bar += ">" + strings.Repeat(" ", width-filled-1)
+	}
+	rate := float64(p.records) / time.Since(p.start).Seconds()
+	fmt.Fprintf(os.Stderr, "\r[%s] %5.1f%% %d records %.0f rec/s", bar, frac*100, p.records+p.skipped, rate)
+}
+
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return
+	}
+	p.done = true
+	if p.enabled {
+		p.render(p.size)
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func (p *progress) total() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.records
+}
+
+func (p *progress) elapsed() time.Duration { return time.Since(p.start) }
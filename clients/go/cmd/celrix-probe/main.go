@@ -0,0 +1,107 @@
+// Command celrix-probe is a Kubernetes liveness/readiness probe for CELRIX.
+//
+// It connects, runs one check and exits 0 on success or 1 on failure, all
+// within -timeout:
+//
+//	livenessProbe:
+//	  exec:
+//	    command: ["celrix-probe", "-mode", "live"]
+//	readinessProbe:
+//	  exec:
+//	    command: ["celrix-probe", "-mode", "ready", "-timeout", "2s"]
+//
+// The live mode sends PING. The ready mode requests the server health report
+// and fails unless it is healthy (or degraded, with -allow-degraded).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6380", "CELRIX server address")
+	mode := flag.String("mode", "ready", "probe mode: live or ready")
+	timeout := flag.Duration("timeout", time.Second, "deadline for the whole probe")
+	allowDegraded := flag.Bool("allow-degraded", false, "treat a degraded server as ready")
+	verbose := flag.Bool("v", false, "print the result on success")
+	flag.Parse()
+
+	msg, err := probe(*addr, *mode, *timeout, *allowDegraded)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "celrix-probe: %v\n", err)
+		os.Exit(1)
+	}
+	if *verbose {
+		fmt.Println(msg)
+	}
+}
+
+func probe(addr, mode string, timeout time.Duration, allowDegraded bool) (string, error) {
+	if mode != "live" && mode != "ready" {
+		return "", fmt.Errorf("unknown mode %q", mode)
+	}
+	deadline := time.Now().Add(timeout)
+
+	// Every read and write on the connection shares the probe deadline, so
+	// a hung server fails the probe instead of stalling it
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		ctx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+
+	c, err := celrix.Connect(addr, celrix.WithDialer(dial))
+	if err != nil {
+		return "", timeoutError(err, timeout)
+	}
+	defer c.Close()
+
+	if mode == "live" {
+		if err := c.Ping(); err != nil {
+			return "", timeoutError(err, timeout)
+		}
+		return "live", nil
+	}
+
+	h, err := c.Health()
+	if err != nil {
+		return "", timeoutError(err, timeout)
+	}
+	switch {
+	case h.Ready():
+		return fmt.Sprintf("ready (version %s, up %s)", h.Version, h.Uptime), nil
+	case h.Status == celrix.HealthDegraded && allowDegraded:
+		return "ready (degraded)", nil
+	}
+	for _, ch := range h.Checks {
+		if ch.Status != celrix.HealthHealthy {
+			fmt.Fprintf(os.Stderr, "check %s: %s %s\n", ch.Name, ch.Status, ch.Message)
+		}
+	}
+	return "", fmt.Errorf("server is %s", h.Status)
+}
+
+func timeoutError(err error, timeout time.Duration) error {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return fmt.Errorf("no response within %s", timeout)
+	}
+	return err
+}
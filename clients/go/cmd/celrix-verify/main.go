@@ -0,0 +1,256 @@
+// Command celrix-verify checks a loaded dataset against its source file.
+//
+// Every record in the source (JSONL or CSV, as accepted by celrix-load) is
+// read back with VGet and compared: the key must exist, the dimension must
+// match, and the CRC-32 of the stored vector must equal that of the source
+// vector. Metadata is compared field by field.
+//
+// A sample of source vectors is then used as queries. The exact top-k for
+// each query is computed by brute force over the source, and the mean recall
+// of the server's VSearch results against it is reported.
+//
+//	celrix-verify -addr 127.0.0.1:6380 -queries 200 -k 10 -min-recall 0.95 docs.jsonl
+//
+// The exit status is 1 if any record mismatches or recall falls below
+// -min-recall.
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+	"github.com/YASSERRMD/celrix/clients/go/internal/dataset"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6380", "CELRIX server address")
+	format := flag.String("format", "", "input format: jsonl or csv (default: from file extension)")
+	queries := flag.Int("queries", 100, "number of sampled recall queries (0 disables)")
+	k := flag.Int("k", 10, "neighbours per recall query")
+	metric := flag.String("metric", "cosine", "similarity metric of the index: cosine, l2 or dot")
+	minRecall := flag.Float64("min-recall", 0, "fail if mean recall is below this value")
+	maxReport := flag.Int("max-report", 20, "mismatches to print before summarising")
+	seed := flag.Int64("seed", 1, "seed for query sampling")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: celrix-verify [flags] FILE\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *k < 1 || *queries < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	m, ok := metrics[*metric]
+	if !ok {
+		log.Fatalf("unknown metric %q", *metric)
+	}
+	score, err := dataset.ScorerFor(m)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	path := flag.Arg(0)
+	v := &verifier{
+		maxReport: *maxReport,
+		queries:   *queries,
+		rng:       rand.New(rand.NewSource(*seed)),
+	}
+	if err := v.run(ctx, *addr, path, dataset.DetectFormat(*format, path), *k, score); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("records:    %d\n", v.records)
+	fmt.Printf("missing:    %d\n", v.missing)
+	fmt.Printf("mismatched: %d\n", v.mismatched)
+	if v.recallQueries > 0 {
+		fmt.Printf("recall@%d:  %.4f mean, %.4f min over %d queries\n", *k, v.recallSum/float64(v.recallQueries), v.recallMin, v.recallQueries)
+	}
+
+	failed := v.missing > 0 || v.mismatched > 0
+	if v.recallQueries > 0 && v.recallSum/float64(v.recallQueries) < *minRecall {
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+type verifier struct {
+	maxReport int
+	queries   int
+	rng       *rand.Rand
+
+	records    int64
+	missing    int64
+	mismatched int64
+	reported   int
+
+	// all holds every source vector when recall is measured; sample is a
+	// reservoir of indexes into it
+	all    []celrix.VectorItem
+	sample []int
+
+	recallQueries int
+	recallSum     float64
+	recallMin     float64
+}
+
+func (v *verifier) run(ctx context.Context, addr, path, format string, k int, score dataset.Scorer) error {
+	src, err := dataset.Open(path, format)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	c, err := celrix.Connect(addr)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer c.Close()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		items, err := src.Next(256)
+		for _, it := range items {
+			if err := v.check(c, it); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, i := range v.sample {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := v.measure(c, v.all[i], k, score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *verifier) check(c *celrix.Client, want celrix.VectorItem) error {
+	v.records++
+	if v.queries > 0 {
+		v.all = append(v.all, want)
+		if len(v.sample) < v.queries {
+			v.sample = append(v.sample, len(v.all)-1)
+		} else if j := v.rng.Int63n(v.records); j < int64(v.queries) {
+			v.sample[j] = len(v.all) - 1
+		}
+	}
+
+	got, ok, err := c.VGet(want.Key)
+	if err != nil {
+		return fmt.Errorf("VGET %q: %w", want.Key, err)
+	}
+	if !ok {
+		v.missing++
+		v.report("%s: missing", want.Key)
+		return nil
+	}
+	if len(got.Vector) != len(want.Vector) {
+		v.mismatched++
+		v.report("%s: dims %d, source has %d", want.Key, len(got.Vector), len(want.Vector))
+		return nil
+	}
+	if gs, ws := checksum(got.Vector), checksum(want.Vector); gs != ws {
+		v.mismatched++
+		v.report("%s: vector checksum %08x, source has %08x", want.Key, gs, ws)
+		return nil
+	}
+	if field, ok := metadataDiff(got.Metadata, want.Metadata); !ok {
+		v.mismatched++
+		v.report("%s: metadata field %q differs", want.Key, field)
+	}
+	return nil
+}
+
+func (v *verifier) report(format string, args ...interface{}) {
+	v.reported++
+	if v.reported <= v.maxReport {
+		fmt.Printf(format+"\n", args...)
+	} else if v.reported == v.maxReport+1 {
+		fmt.Println("further mismatches not shown")
+	}
+}
+
+// measure runs one recall query and folds it into the running totals
+func (v *verifier) measure(c *celrix.Client, query celrix.VectorItem, k int, score dataset.Scorer) error {
+	if k > len(v.all) {
+		k = len(v.all)
+	}
+	exact := dataset.TopK(v.all, query.Vector, k, score)
+	got, err := c.VSearch(query.Vector, k, nil)
+	if err != nil {
+		return fmt.Errorf("VSEARCH for %q: %w", query.Key, err)
+	}
+
+	hits := 0
+	for _, m := range got {
+		if exact[m.Key] {
+			hits++
+		}
+	}
+	recall := float64(hits) / float64(k)
+	if v.recallQueries == 0 || recall < v.recallMin {
+		v.recallMin = recall
+	}
+	v.recallQueries++
+	v.recallSum += recall
+	return nil
+}
+
+// checksum is the CRC-32 of the vector in wire encoding
+func checksum(vec []float32) uint32 {
+	b := make([]byte, 0, len(vec)*4)
+	for _, f := range vec {
+		b = binary.BigEndian.AppendUint32(b, math.Float32bits(f))
+	}
+	return crc32.ChecksumIEEE(b)
+}
+
+// metadataDiff returns the first field that differs between got and want
+func metadataDiff(got, want celrix.Metadata) (string, bool) {
+	for name, w := range want {
+		g, ok := got[name]
+		if !ok || g.Type() != w.Type() || g.String() != w.String() {
+			return name, false
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			return name, false
+		}
+	}
+	return "", true
+}
+
+// metrics maps -metric names to the index metric
+var metrics = map[string]celrix.Metric{
+	"cosine": celrix.MetricCosine,
+	"dot":    celrix.MetricDot,
+	"l2":     celrix.MetricL2,
+}
@@ -0,0 +1,21 @@
+package celrix
+
+// Cmdable is the core command set shared by Client and the wrappers built
+// on it, so decorators (mirroring, shadowing, fault injection) can be
+// stacked transparently
+type Cmdable interface {
+	Ping() error
+	Get(key string) (string, bool, error)
+	Set(key, value string) error
+	Del(key string) (bool, error)
+	VAdd(key string, vector []float32) error
+	VSearch(vector []float32, k int, opts *VSearchOptions) ([]VectorMatch, error)
+}
+
+var (
+	_ Cmdable = (*Client)(nil)
+	_ Cmdable = (*MirrorClient)(nil)
+	_ Cmdable = (*ShadowClient)(nil)
+	_ Cmdable = (*Session)(nil)
+	_ Cmdable = (*HedgedClient)(nil)
+)
@@ -0,0 +1,420 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Metric is the distance metric used by a collection's vector index. The
+// zero value, MetricDefault, leaves the choice to the server: a collection
+// created with it uses cosine, and a search uses its index's metric.
+type Metric uint8
+
+// Distance metrics
+const (
+	MetricDefault Metric = iota
+	MetricCosine
+	MetricL2
+	MetricDot
+)
+
+// wire returns the metric's protocol value, on which cosine is 0, so that
+// MetricDefault and MetricCosine encode alike
+func (m Metric) wire() uint8 {
+	if m == MetricDefault {
+		return 0
+	}
+	return uint8(m) - 1
+}
+
+// String returns the metric name
+func (m Metric) String() string {
+	switch m {
+	case MetricDefault:
+		return "default"
+	case MetricCosine:
+		return "cosine"
+	case MetricL2:
+		return "l2"
+	case MetricDot:
+		return "dot"
+	default:
+		return fmt.Sprintf("Metric(%d)", uint8(m))
+	}
+}
+
+// Schema describes the vectors and metadata stored in a collection
+type Schema struct {
+	Dims   int
+	Metric Metric
+	Fields map[string]MetaType
+	// DefaultTTL expires vectors added without an explicit TTL. Zero keeps
+	// them until deleted. The server tracks expiry with second granularity.
+	DefaultTTL time.Duration
+}
+
+// ValidationError reports a payload rejected locally against a cached schema
+type ValidationError struct {
+	Collection string
+	Key        string
+	Field      string
+	Reason     string
+}
+
+func (e *ValidationError) Error() string {
+	s := fmt.Sprintf("celrix: collection %q", e.Collection)
+	if e.Key != "" {
+		s += fmt.Sprintf(" key %q", e.Key)
+	}
+	if e.Field != "" {
+		s += fmt.Sprintf(" field %q", e.Field)
+	}
+	return s + ": " + e.Reason
+}
+
+// Validate checks a vector and its metadata against the schema
+func (s Schema) Validate(vector []float32, meta Metadata) error {
+	if s.Dims > 0 && len(vector) != s.Dims {
+		return &ValidationError{Reason: fmt.Sprintf("vector has %d dims, schema expects %d", len(vector), s.Dims)}
+	}
+	for name, v := range meta {
+		typ, ok := s.Fields[name]
+		if !ok {
+			return &ValidationError{Field: name, Reason: "field not defined in schema"}
+		}
+		if typ != v.Type() {
+			return &ValidationError{Field: name, Reason: fmt.Sprintf("value is %s, schema expects %s", v.Type(), typ)}
+		}
+	}
+	return nil
+}
+
+func (s Schema) appendTo(buf []byte) []byte {
+	// [dims][metric][field_count]([name_len][name][type])...[default_ttl]
+	buf = binary.BigEndian.AppendUint32(buf, uint32(s.Dims))
+	buf = append(buf, s.Metric.wire())
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s.Fields)))
+	for name, typ := range s.Fields {
+		buf = appendString(buf, name)
+		buf = append(buf, byte(typ))
+	}
+	return binary.BigEndian.AppendUint64(buf, ttlSeconds(s.DefaultTTL))
+}
+
+func decodeSchema(b []byte) (Schema, error) {
+	if len(b) < 9 {
+		return Schema{}, errors.New("incomplete schema")
+	}
+	s := Schema{
+		Dims:   int(binary.BigEndian.Uint32(b)),
+		Metric: Metric(b[4] + 1),
+	}
+	count := int(binary.BigEndian.Uint32(b[5:]))
+	offset := 9
+	s.Fields = make(map[string]MetaType, count)
+	for i := 0; i < count; i++ {
+		name, n, err := readString(b[offset:])
+		if err != nil {
+			return Schema{}, fmt.Errorf("schema field: %w", err)
+		}
+		offset += n
+		if offset >= len(b) {
+			return Schema{}, errors.New("incomplete schema field type")
+		}
+		s.Fields[name] = MetaType(b[offset])
+		offset++
+	}
+	if offset+8 <= len(b) {
+		s.DefaultTTL = time.Duration(binary.BigEndian.Uint64(b[offset:])) * time.Second
+	}
+	return s, nil
+}
+
+// schemaCache holds schemas of collections known to the client
+type schemaCache struct {
+	mu      sync.RWMutex
+	schemas map[string]Schema
+}
+
+func (sc *schemaCache) get(name string) (Schema, bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	s, ok := sc.schemas[name]
+	return s, ok
+}
+
+func (sc *schemaCache) put(name string, s Schema) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.schemas == nil {
+		sc.schemas = make(map[string]Schema)
+	}
+	sc.schemas[name] = s
+}
+
+func (sc *schemaCache) remove(name string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.schemas, name)
+}
+
+// CreateCollection creates a named collection with the given schema and
+// caches the schema for local validation
+func (c *Client) CreateCollection(name string, schema Schema) (*Collection, error) {
+	if name == "" {
+		return nil, errors.New("collection name is empty")
+	}
+	if schema.Dims <= 0 {
+		return nil, &ValidationError{Collection: name, Reason: "schema dims must be positive"}
+	}
+	if schema.DefaultTTL < 0 {
+		return nil, &ValidationError{Collection: name, Reason: "schema default TTL must not be negative"}
+	}
+
+	payload := appendString(nil, name)
+	payload = schema.appendTo(payload)
+
+	c.nextCollection = name
+	if err := c.sendFrame(OpCreateCollection, payload); err != nil {
+		return nil, err
+	}
+	if err := c.expectOK(); err != nil {
+		return nil, err
+	}
+	c.schemas.put(name, schema)
+	return c.Collection(name), nil
+}
+
+// DropCollection deletes a collection and all of its vectors
+func (c *Client) DropCollection(name string) error {
+	c.nextCollection = name
+	if err := c.sendFrame(OpDropCollection, appendString(nil, name)); err != nil {
+		return err
+	}
+	if err := c.expectOK(); err != nil {
+		return err
+	}
+	c.schemas.remove(name)
+	return nil
+}
+
+// DescribeCollection fetches a collection's schema from the server and
+// refreshes the local cache
+func (c *Client) DescribeCollection(name string) (Schema, error) {
+	c.nextCollection = name
+	if err := c.sendFrame(OpDescribeCollection, appendString(nil, name)); err != nil {
+		return Schema{}, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return Schema{}, err
+	}
+	if resp == nil {
+		return Schema{}, fmt.Errorf("collection %q not found", name)
+	}
+	raw, ok := resp.(string)
+	if !ok {
+		return Schema{}, c.unexpectedReply()
+	}
+	schema, err := decodeSchema([]byte(raw))
+	if err != nil {
+		return Schema{}, err
+	}
+	c.schemas.put(name, schema)
+	return schema, nil
+}
+
+// ListCollections returns the names of all collections on the server
+func (c *Client) ListCollections() ([]string, error) {
+	if err := c.sendFrame(OpListCollections, nil); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	return toKeys(resp)
+}
+
+// Collection returns a handle to a named collection. No request is made
+// until an operation is invoked.
+func (c *Client) Collection(name string) *Collection {
+	return &Collection{client: c, name: name}
+}
+
+// Collection is a handle to a named vector collection
+type Collection struct {
+	client *Client
+	name   string
+}
+
+// Name returns the collection name
+func (col *Collection) Name() string { return col.name }
+
+// Schema returns the cached schema, fetching it from the server on first use
+func (col *Collection) Schema() (Schema, error) {
+	if s, ok := col.client.schemas.get(col.name); ok {
+		return s, nil
+	}
+	return col.client.DescribeCollection(col.name)
+}
+
+// VAdd validates the vector and metadata against the collection schema and
+// adds it to the collection. The vector expires after the collection's
+// DefaultTTL, if any.
+func (col *Collection) VAdd(key string, vector []float32, meta Metadata) error {
+	return col.VAddWithTTL(key, vector, meta, 0)
+}
+
+// VAddWithTTL is like VAdd but expires the vector after ttl, overriding the
+// collection default. A zero ttl falls back to the default.
+func (col *Collection) VAddWithTTL(key string, vector []float32, meta Metadata, ttl time.Duration) error {
+	payload, err := col.vaddPayload(key, vector, meta, ttl)
+	if err != nil {
+		return err
+	}
+	col.client.nextCollection = col.name
+	return col.serverChecked(col.client.write(OpCVAdd, key, payload))
+}
+
+// VAddBatch adds items to the collection, validating each against its
+// schema first. The adds are pipelined, so a batch costs one round trip
+// once the schema is cached. Nothing is sent if any item fails validation.
+// Unlike Client.VAddBatch the batch is not atomic: an item the server
+// rejects does not stop the others, and the first such error is returned.
+func (col *Collection) VAddBatch(items []VectorItem) error {
+	p := col.client.Pipeline()
+	for _, it := range items {
+		payload, err := col.vaddPayload(it.Key, it.Vector, it.Metadata, 0)
+		if err != nil {
+			return err
+		}
+		p.queue(OpCVAdd, it.Key, payload)
+	}
+	_, err := p.Exec()
+	return col.serverChecked(err)
+}
+
+// vaddPayload validates an add against the collection schema and encodes
+// its CVADD payload
+func (col *Collection) vaddPayload(key string, vector []float32, meta Metadata, ttl time.Duration) ([]byte, error) {
+	if ttl < 0 {
+		return nil, &ValidationError{Collection: col.name, Key: key, Reason: "TTL must not be negative"}
+	}
+	if err := col.client.checkVector(OpCVAdd, key, vector); err != nil {
+		return nil, err
+	}
+	err := col.validated(func(schema Schema) error {
+		return schema.Validate(vector, meta)
+	})
+	if err != nil {
+		var ve *ValidationError
+		if errors.As(err, &ve) {
+			ve.Collection, ve.Key = col.name, key
+		}
+		return nil, err
+	}
+
+	// Payload: [coll_len][coll][key_len][key][count][f32...][metadata][ttl]
+	payload := make([]byte, 0, 8+len(col.name)+len(key)+4+len(vector)*4+meta.encodedLen()+8)
+	payload = appendString(payload, col.name)
+	payload = appendString(payload, key)
+	payload = appendVector(payload, vector)
+	if payload, err = meta.appendTo(payload); err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.AppendUint64(payload, ttlSeconds(ttl)), nil
+}
+
+// VSearch searches the collection. filter may be nil; when set it is
+// validated against the collection schema before sending.
+func (col *Collection) VSearch(vector []float32, k int, filter *Filter) ([]string, error) {
+	payload, err := col.searchPayload(nil, vector, k, filter)
+	if err != nil {
+		return nil, err
+	}
+	col.client.nextCollection = col.name
+	if err := col.client.sendFrame(OpCVSearch, payload); err != nil {
+		return nil, err
+	}
+	resp, err := col.client.readResponse()
+	if err != nil {
+		return nil, col.serverChecked(err)
+	}
+	return toKeys(resp)
+}
+
+// validated runs check against the collection's schema. A cached schema
+// that fails it is fetched again and rechecked before the failure stands:
+// the collection may have changed, or an alias been swapped, since it was
+// cached.
+func (col *Collection) validated(check func(Schema) error) error {
+	schema, cached := col.client.schemas.get(col.name)
+	if !cached {
+		var err error
+		if schema, err = col.client.DescribeCollection(col.name); err != nil {
+			return err
+		}
+	}
+	err := check(schema)
+	if err != nil && cached {
+		var ferr error
+		if schema, ferr = col.client.DescribeCollection(col.name); ferr != nil {
+			return ferr
+		}
+		err = check(schema)
+	}
+	return err
+}
+
+// serverChecked drops the cached schema when the server rejects a command
+// that passed local validation, so a stale schema is fetched afresh next
+// time, and returns err
+func (col *Collection) serverChecked(err error) error {
+	if isServerError(err) {
+		col.client.schemas.remove(col.name)
+	}
+	return err
+}
+
+// searchPayload validates a search against the collection schema and
+// appends its CVSEARCH payload to buf
+func (col *Collection) searchPayload(buf []byte, vector []float32, k int, filter *Filter) ([]byte, error) {
+	if err := col.client.checkVector(OpCVSearch, "", vector); err != nil {
+		return nil, err
+	}
+	err := col.validated(func(schema Schema) error {
+		if schema.Dims > 0 && len(vector) != schema.Dims {
+			return &ValidationError{Collection: col.name, Reason: fmt.Sprintf("query has %d dims, schema expects %d", len(vector), schema.Dims)}
+		}
+		if filter != nil {
+			if err := filter.Validate(schema.Fields); err != nil {
+				return &ValidationError{Collection: col.name, Reason: err.Error()}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Payload: [coll_len][coll][count][f32...][k][has_filter][filter]
+	if buf == nil {
+		buf = make([]byte, 0, 4+len(col.name)+4+len(vector)*4+5+64)
+	}
+	payload := appendString(buf, col.name)
+	payload = appendVector(payload, vector)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(k))
+	if filter == nil {
+		return append(payload, 0), nil
+	}
+	return filter.appendTo(append(payload, 1))
+}
+
+// Drop deletes the collection
+func (col *Collection) Drop() error {
+	return col.client.DropCollection(col.name)
+}
@@ -0,0 +1,94 @@
+package celrix
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// collectionServer answers DESCRIBE with schema and holds CVADD replies
+// until hold of them have arrived
+func collectionServer(schema Schema, hold int) (*testServer, *[]string) {
+	var mu sync.Mutex
+	var added []string
+	var held []wire.Frame
+	s := &testServer{handle: func(_ int, f wire.Frame) []wire.Frame {
+		mu.Lock()
+		defer mu.Unlock()
+		switch f.Opcode {
+		case OpPing:
+			return reply(f, OpPong, nil)
+		case OpDescribeCollection:
+			return reply(f, OpValue, schema.appendTo(nil))
+		case OpCVAdd:
+			_, n, _ := readString(f.Payload)
+			key, _, _ := readString(f.Payload[n:])
+			added = append(added, key)
+			if key == "rejected" {
+				held = append(held, reply(f, OpError, []byte("rejected"))...)
+			} else {
+				held = append(held, reply(f, OpOk, nil)...)
+			}
+			if len(held) < hold {
+				return nil
+			}
+			out := held
+			held = nil
+			return out
+		}
+		return reply(f, OpError, []byte("unknown command"))
+	}}
+	return s, &added
+}
+
+func TestCollectionVAddBatchPipelines(t *testing.T) {
+	s, added := collectionServer(Schema{Dims: 2}, 3)
+	c := s.connect(t)
+
+	// Replies are held until every add has arrived, so a batch sent one
+	// add per round trip never completes
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Collection("docs").VAddBatch([]VectorItem{
+			{Key: "a", Vector: []float32{1, 0}},
+			{Key: "rejected", Vector: []float32{0, 1}},
+			{Key: "c", Vector: []float32{1, 1}},
+		})
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("VAddBatch did not pipeline its adds")
+	}
+	var se *ServerError
+	if !errors.As(err, &se) {
+		t.Fatalf("got %v, want the rejected add's server error", err)
+	}
+	if got := len(*added); got != 3 {
+		t.Errorf("server saw %d adds, want 3", got)
+	}
+}
+
+func TestCollectionVAddBatchValidatesBeforeSending(t *testing.T) {
+	s, added := collectionServer(Schema{Dims: 2}, 1)
+	c := s.connect(t)
+
+	err := c.Collection("docs").VAddBatch([]VectorItem{
+		{Key: "a", Vector: []float32{1, 0}},
+		{Key: "b", Vector: []float32{1, 0, 0}},
+	})
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Key != "b" {
+		t.Fatalf("got %v, want a validation error for b", err)
+	}
+	if len(*added) != 0 {
+		t.Errorf("server saw adds %v, want none", *added)
+	}
+	if err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,216 @@
+package celrix
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Codec compresses and decompresses values with a preset dictionary. The
+// built-in FlateDictionary uses DEFLATE; zstd dictionaries can be plugged in
+// by wrapping a zstd library in a Codec, keeping the client free of
+// third-party dependencies.
+type Codec interface {
+	// Compress appends the compressed form of src to dst
+	Compress(dst, src []byte) ([]byte, error)
+	// Decompress appends the decompressed form of src to dst
+	Decompress(dst, src []byte) ([]byte, error)
+}
+
+// Dictionary is a preset compression dictionary, registered with the
+// server under ID. Every client reading values compressed with a
+// dictionary must be configured with the same ID and Codec.
+type Dictionary struct {
+	ID    uint32
+	Codec Codec
+}
+
+// maxDecompressedSize bounds a decompressed value, so a corrupt or hostile
+// reply cannot exhaust memory
+const maxDecompressedSize = 512 << 20
+
+// ErrDictionaryUnknown is wrapped by errors for compressed replies whose
+// dictionary the client is not configured with
+var ErrDictionaryUnknown = errors.New("celrix: unknown compression dictionary")
+
+// WithValueCompression compresses string values of at least minSize bytes
+// with a preset dictionary, which suits many small, similar values such as
+// JSON documents far better than compressing each value alone.
+//
+// At connect the client offers the dictionary IDs to the server, which
+// accepts those it has registered. Writes use the first accepted dictionary
+// in the order given; values that do not shrink are sent uncompressed.
+// Compressed replies are decoded with any configured dictionary. A server
+// that does not support dictionaries leaves compression off.
+func WithValueCompression(minSize int, dicts ...Dictionary) Option {
+	return func(o *options) {
+		o.compression = &compression{minSize: minSize, dicts: dicts}
+	}
+}
+
+type compression struct {
+	minSize int
+	dicts   []Dictionary
+}
+
+func (cp *compression) lookup(id uint32) (Codec, bool) {
+	for _, d := range cp.dicts {
+		if d.ID == id {
+			return d.Codec, true
+		}
+	}
+	return nil, false
+}
+
+// negotiateDictionaries offers the configured dictionary IDs and selects
+// the first the server accepts for writes.
+//
+// Request:  [count: u8][id: u32]...
+// Response: OpValue [count: u8][id: u32]... (the accepted IDs)
+func (c *Client) negotiateDictionaries() error {
+	c.writeDict = nil
+	cp := c.opts.compression
+	if cp == nil || len(cp.dicts) == 0 {
+		return nil
+	}
+	if len(cp.dicts) > 255 {
+		return errors.New("celrix: at most 255 compression dictionaries may be offered")
+	}
+	payload := []byte{byte(len(cp.dicts))}
+	for _, d := range cp.dicts {
+		payload = binary.BigEndian.AppendUint32(payload, d.ID)
+	}
+	if err := c.sendFrame(OpDictionaries, payload); err != nil {
+		return err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		if isServerError(err) {
+			c.logReconnect("dictionaries refused, compression disabled", "error", err)
+			return nil
+		}
+		return err
+	}
+	raw, ok := resp.(string)
+	if !ok || len(raw) < 1 || len(raw) != 1+4*int(raw[0]) {
+		return fmt.Errorf("unexpected DICTIONARIES response: %v", resp)
+	}
+	accepted := make(map[uint32]bool, raw[0])
+	for i := 0; i < int(raw[0]); i++ {
+		accepted[binary.BigEndian.Uint32([]byte(raw[1+4*i:]))] = true
+	}
+	for i := range cp.dicts {
+		if accepted[cp.dicts[i].ID] {
+			c.writeDict = &cp.dicts[i]
+			return nil
+		}
+	}
+	return nil
+}
+
+// setPayload builds a SET request, compressing the value when a dictionary
+// is in use and it pays off. Compressed values are sent as SETZ:
+// [key_len][key][dict_id: u32][val_len][val][ttl]
+func (c *Client) setPayload(key string, value []byte, ttl time.Duration) (uint8, []byte, error) {
+	if err := c.checkValue(OpSet, key, len(value)); err != nil {
+		return 0, nil, err
+	}
+	d := c.writeDict
+	if d == nil || len(value) < c.opts.compression.minSize {
+		return OpSet, encodeSet(key, value, ttl), nil
+	}
+	z, err := d.Codec.Compress(nil, value)
+	if err != nil {
+		return 0, nil, fmt.Errorf("compress value: %w", err)
+	}
+	if len(z)+4 >= len(value) {
+		return OpSet, encodeSet(key, value, ttl), nil
+	}
+	payload := make([]byte, 0, 4+len(key)+4+4+len(z)+8)
+	payload = appendString(payload, key)
+	payload = binary.BigEndian.AppendUint32(payload, d.ID)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(z)))
+	payload = append(payload, z...)
+	return OpSetCompressed, binary.BigEndian.AppendUint64(payload, ttlSeconds(ttl)), nil
+}
+
+// inflate turns an OpCompressed reply, [dict_id: u32][compressed], into the
+// OpValue it stands for
+func (c *Client) inflate(f *frame) error {
+	if len(f.payload) < 4 {
+		return errors.New("incomplete compressed value")
+	}
+	id := binary.BigEndian.Uint32(f.payload)
+	var codec Codec
+	ok := false
+	if c.opts.compression != nil {
+		codec, ok = c.opts.compression.lookup(id)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrDictionaryUnknown, id)
+	}
+	value, err := codec.Decompress(nil, f.payload[4:])
+	if err != nil {
+		return fmt.Errorf("decompress value with dictionary %d: %w", id, err)
+	}
+	f.opcode, f.payload = OpValue, value
+	return nil
+}
+
+// FlateDictionary returns a DEFLATE codec primed with dict, registered on
+// the server under id. Train dict on a sample of real values: the most
+// common substrings belong at its end.
+func FlateDictionary(id uint32, dict []byte) Dictionary {
+	return Dictionary{ID: id, Codec: &flateCodec{dict: append([]byte(nil), dict...)}}
+}
+
+type flateCodec struct {
+	dict    []byte
+	writers sync.Pool
+	readers sync.Pool
+}
+
+func (fc *flateCodec) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	w, _ := fc.writers.Get().(*flate.Writer)
+	if w == nil {
+		var err error
+		if w, err = flate.NewWriterDict(buf, flate.BestCompression, fc.dict); err != nil {
+			return dst, err
+		}
+	} else {
+		w.Reset(buf)
+	}
+	defer fc.writers.Put(w)
+	if _, err := w.Write(src); err != nil {
+		return dst, err
+	}
+	if err := w.Close(); err != nil {
+		return dst, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (fc *flateCodec) Decompress(dst, src []byte) ([]byte, error) {
+	r, _ := fc.readers.Get().(io.ReadCloser)
+	if r == nil {
+		r = flate.NewReaderDict(bytes.NewReader(src), fc.dict)
+	} else if err := r.(flate.Resetter).Reset(bytes.NewReader(src), fc.dict); err != nil {
+		return dst, err
+	}
+	defer fc.readers.Put(r)
+	buf := bytes.NewBuffer(dst)
+	n, err := buf.ReadFrom(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return dst, err
+	}
+	if n > maxDecompressedSize {
+		return dst, fmt.Errorf("decompressed value exceeds %d bytes", maxDecompressedSize)
+	}
+	return buf.Bytes(), nil
+}
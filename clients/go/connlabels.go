@@ -0,0 +1,121 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Well-known connection label names, used by ConnLabels
+const (
+	LabelService     = "service"
+	LabelTeam        = "team"
+	LabelEnvironment = "environment"
+)
+
+// ConnLabels identifies the application behind a connection, for
+// attributing load on a shared server. The server shows them against the
+// connection in Admin.Clients.
+type ConnLabels struct {
+	Service     string
+	Team        string
+	Environment string
+	// Extra holds any further labels; it may not override the fields
+	// above
+	Extra map[string]string
+}
+
+// WithConnLabels registers labels for every connection the client opens,
+// including dedicated stream connections. They are sent in the HELLO
+// handshake, which the option enables; servers that refuse HELLO accept the
+// connection unlabelled.
+func WithConnLabels(labels ConnLabels) Option {
+	return func(o *options) {
+		m := make(map[string]string, len(labels.Extra)+3)
+		for name, v := range labels.Extra {
+			m[name] = v
+		}
+		for name, v := range map[string]string{
+			LabelService:     labels.Service,
+			LabelTeam:        labels.Team,
+			LabelEnvironment: labels.Environment,
+		} {
+			if v != "" {
+				m[name] = v
+			} else {
+				delete(m, name)
+			}
+		}
+		o.connLabels = m
+	}
+}
+
+// appendLabels encodes labels as [count: u32]([name][value])..., sorted
+// by name so the handshake is deterministic
+func appendLabels(buf []byte, labels map[string]string) []byte {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(names)))
+	for _, name := range names {
+		buf = appendString(buf, name)
+		buf = appendString(buf, labels[name])
+	}
+	return buf
+}
+
+// ClientInfo describes one connection to the server, as listed by
+// Admin.Clients
+type ClientInfo struct {
+	ID   int64
+	Addr string
+	// Labels are those the connection registered with WithConnLabels
+	Labels map[string]string
+	// Age is how long the connection has been open, and Idle how long
+	// since its last command
+	Age  time.Duration
+	Idle time.Duration
+}
+
+// Clients lists the connections open on the server, this one included
+//
+// Response: array of maps with id, addr, age_ms, idle_ms and labels
+func (a *Admin) Clients() ([]ClientInfo, error) {
+	if err := a.c.sendFrame(OpClientList, nil); err != nil {
+		return nil, err
+	}
+	resp, err := a.c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := resp.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected array response, got %T", resp)
+	}
+	clients := make([]ClientInfo, len(arr))
+	for i, el := range arr {
+		m, ok := el.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("client record %d: expected map, got %T", i, el)
+		}
+		info := &clients[i]
+		info.ID, _ = m["id"].(int64)
+		info.Addr, _ = m["addr"].(string)
+		if ms, ok := m["age_ms"].(int64); ok {
+			info.Age = time.Duration(ms) * time.Millisecond
+		}
+		if ms, ok := m["idle_ms"].(int64); ok {
+			info.Idle = time.Duration(ms) * time.Millisecond
+		}
+		if labels, ok := m["labels"].(map[string]interface{}); ok {
+			info.Labels = make(map[string]string, len(labels))
+			for name, v := range labels {
+				info.Labels[name] = fmt.Sprint(v)
+			}
+		}
+	}
+	return clients, nil
+}
@@ -0,0 +1,158 @@
+package celrix
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ContentType is the server's hint about how a value is encoded, carried by
+// typed string replies
+type ContentType uint8
+
+// Content types
+const (
+	// ContentUnknown marks plain value replies, which carry no hint
+	ContentUnknown ContentType = iota
+	ContentBinary
+	ContentText
+	ContentJSON
+)
+
+// String returns the content type name
+func (t ContentType) String() string {
+	switch t {
+	case ContentUnknown:
+		return "unknown"
+	case ContentBinary:
+		return "binary"
+	case ContentText:
+		return "text"
+	case ContentJSON:
+		return "json"
+	default:
+		return fmt.Sprintf("ContentType(%d)", uint8(t))
+	}
+}
+
+// ContentType returns the content type hint of a typed string reply, or
+// ContentUnknown for other replies. Typed strings are value replies, so
+// Bytes and Str work on them unchanged.
+func (r Reply) ContentType() ContentType { return r.content }
+
+// decodeTypedString decodes [content_type: u8][bytes]
+func decodeTypedString(payload []byte) (Reply, error) {
+	if len(payload) < 1 {
+		return Reply{}, errors.New("invalid typed string payload")
+	}
+	return Reply{typ: ReplyValue, bytes: payload[1:], content: ContentType(payload[0])}, nil
+}
+
+// Pretty formats the reply for people: JSON values are indented, text is
+// shown as is, and binary values or values that are not valid UTF-8 are
+// quoted with escapes. Other replies format as String.
+func (r Reply) Pretty() string {
+	switch r.typ {
+	case ReplyValue:
+		switch r.content {
+		case ContentJSON:
+			var buf bytes.Buffer
+			if json.Indent(&buf, r.bytes, "", "  ") == nil {
+				return buf.String()
+			}
+		case ContentText, ContentUnknown:
+			if utf8.Valid(r.bytes) {
+				return string(r.bytes)
+			}
+		}
+		return strconv.Quote(string(r.bytes))
+	default:
+		return r.String()
+	}
+}
+
+// GetAs gets the value of key and decodes it into a T, choosing the codec
+// from the reply's content type:
+//
+//   - string and []byte receive the raw value
+//   - ContentJSON values are decoded with encoding/json
+//   - ContentText values use T's encoding.TextUnmarshaler
+//   - ContentBinary values use T's encoding.BinaryUnmarshaler
+//   - values without a hint use whichever of those T supports, trying
+//     binary, then text, then JSON
+func GetAs[T any](c *Client, key string) (T, bool, error) {
+	var zero T
+	if err := c.sendKeyed(OpGet, key, appendString(nil, key)); err != nil {
+		return zero, false, err
+	}
+	f, err := c.recvFrame()
+	if err != nil {
+		return zero, false, err
+	}
+	r, err := decodeReply(f.opcode, f.payload)
+	if err != nil {
+		return zero, false, c.cmdErr(err)
+	}
+	switch r.typ {
+	case ReplyNil:
+		return zero, false, nil
+	case ReplyError:
+		return zero, false, c.cmdErr(r.err)
+	case ReplyValue:
+	default:
+		return zero, false, c.cmdErr(fmt.Errorf("expected a value, got %s", r.typ))
+	}
+	var v T
+	if err := decodeValueInto(&v, r.bytes, r.content); err != nil {
+		return zero, true, fmt.Errorf("celrix: decode %s value of %q: %w", r.content, key, err)
+	}
+	return v, true, nil
+}
+
+func decodeValueInto(dest interface{}, b []byte, ct ContentType) error {
+	switch d := dest.(type) {
+	case *string:
+		*d = string(b)
+		return nil
+	case *[]byte:
+		*d = append([]byte(nil), b...)
+		return nil
+	}
+	bu, isBinary := dest.(encoding.BinaryUnmarshaler)
+	tu, isText := dest.(encoding.TextUnmarshaler)
+	switch ct {
+	case ContentJSON:
+		return json.Unmarshal(b, dest)
+	case ContentText:
+		if !isText {
+			return fmt.Errorf("%T does not implement encoding.TextUnmarshaler", dest)
+		}
+		return tu.UnmarshalText(b)
+	case ContentBinary:
+		if !isBinary {
+			return fmt.Errorf("%T does not implement encoding.BinaryUnmarshaler", dest)
+		}
+		return bu.UnmarshalBinary(b)
+	}
+	switch {
+	case isBinary:
+		return bu.UnmarshalBinary(b)
+	case isText:
+		return tu.UnmarshalText(b)
+	default:
+		return json.Unmarshal(b, dest)
+	}
+}
+
+// Reply decodes a recorded response frame, so tools can Pretty-print
+// recordings. It fails for frames sent by the client.
+func (f RecordedFrame) Reply() (Reply, error) {
+	if f.Outgoing {
+		return Reply{}, errors.New("celrix: recorded frame is a request")
+	}
+	return decodeReply(f.Opcode, f.Payload)
+}
@@ -0,0 +1,114 @@
+package celrix
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// RunContext runs fn, which issues commands on c, bounded by ctx: the
+// context's deadline is applied to the connection, and cancelling the
+// context interrupts any read or write fn is blocked in. It gives every
+// command a context-aware form; the ...Context methods are shorthands for
+// the core command set.
+//
+// A command interrupted mid-flight leaves its reply unread, so the
+// connection is closed and the error wraps ctx.Err(); as after a
+// TimeoutError, the next command reconnects first. A context that is
+// already done fails before anything is sent and leaves the client usable.
+func (c *Client) RunContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if !deadline.IsZero() {
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	c.ctxDeadline, c.ctxDone = deadline, ctx.Done()
+
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		// A deadline in the past unblocks pending I/O at once
+		c.connMu.Lock()
+		c.conn.SetDeadline(time.Unix(1, 0))
+		c.connMu.Unlock()
+		close(interrupted)
+	})
+	err := fn()
+	c.ctxDeadline, c.ctxDone = time.Time{}, nil
+	if stop() {
+		if deadline.IsZero() {
+			return err
+		}
+		if derr := c.conn.SetDeadline(time.Time{}); err == nil {
+			err = derr
+		}
+		if err == nil || !errors.Is(err, os.ErrDeadlineExceeded) {
+			return err
+		}
+		// The connection's deadline fired before the context's timer
+	} else {
+		<-interrupted
+		if err == nil {
+			// fn finished before the interruption reached it
+			return c.conn.SetDeadline(time.Time{})
+		}
+	}
+
+	cause := ctx.Err()
+	if cause == nil {
+		cause = context.DeadlineExceeded
+	}
+	c.conn.Close()
+	c.releaseSlot()
+	if !c.closed {
+		c.broken = cause
+	}
+	c.emit(EventDisconnected, 0, cause)
+	return c.cmdErr(cause)
+}
+
+// PingContext is Ping bounded by ctx
+func (c *Client) PingContext(ctx context.Context) error {
+	return c.RunContext(ctx, c.Ping)
+}
+
+// GetContext is Get bounded by ctx
+func (c *Client) GetContext(ctx context.Context, key string) (val string, ok bool, err error) {
+	err = c.RunContext(ctx, func() error {
+		val, ok, err = c.Get(key)
+		return err
+	})
+	return val, ok, err
+}
+
+// SetContext is Set bounded by ctx
+func (c *Client) SetContext(ctx context.Context, key, value string) error {
+	return c.RunContext(ctx, func() error { return c.Set(key, value) })
+}
+
+// DelContext is Del bounded by ctx
+func (c *Client) DelContext(ctx context.Context, key string) (existed bool, err error) {
+	err = c.RunContext(ctx, func() error {
+		existed, err = c.Del(key)
+		return err
+	})
+	return existed, err
+}
+
+// VAddContext is VAdd bounded by ctx
+func (c *Client) VAddContext(ctx context.Context, key string, vector []float32) error {
+	return c.RunContext(ctx, func() error { return c.VAdd(key, vector) })
+}
+
+// VSearchContext is VSearch bounded by ctx
+func (c *Client) VSearchContext(ctx context.Context, vector []float32, k int, opts *VSearchOptions) (matches []VectorMatch, err error) {
+	err = c.RunContext(ctx, func() error {
+		matches, err = c.VSearch(vector, k, opts)
+		return err
+	})
+	return matches, err
+}
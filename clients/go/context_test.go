@@ -0,0 +1,47 @@
+package celrix
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+func TestRunContextReplacesInterruptedConnection(t *testing.T) {
+	var store kv
+	s := &testServer{handle: func(conn int, f wire.Frame) []wire.Frame {
+		if key, _, _ := readString(f.Payload); f.Opcode == OpGet && key == "slow" {
+			return nil
+		}
+		return store.handle(f)
+	}}
+	c := s.connect(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.GetContext(ctx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetContext on an unanswered GET: got %v, want DeadlineExceeded", err)
+	}
+
+	// Without WithRetry the next command still gets a new connection
+	if err := c.Set("k", "v"); err != nil {
+		t.Fatalf("Set after a timed-out context: %v", err)
+	}
+	if n := s.dialCount(); n != 2 {
+		t.Errorf("%d dials, want 2", n)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, _, err := c.GetContext(ctx, "slow"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetContext cancelled: got %v, want Canceled", err)
+	}
+	if v, ok, err := c.Get("k"); err != nil || !ok || v != "v" {
+		t.Errorf("Get after a cancelled context: %q %v %v", v, ok, err)
+	}
+}
@@ -0,0 +1,209 @@
+package celrix
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LogLevel is the severity of a log entry
+type LogLevel int
+
+// Log levels
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the level name
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("LogLevel(%d)", int(l))
+	}
+}
+
+// Logger receives the client's diagnostic output. keyvals alternate keys
+// (strings) and values.
+type Logger interface {
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+// WithLogger sends the client's diagnostics to l. Without it, diagnostics
+// enabled by debug flags go to standard error.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// stderrLogger writes key=value lines through package log
+type stderrLogger struct {
+	l *log.Logger
+}
+
+var defaultLogger Logger = &stderrLogger{l: log.New(os.Stderr, "celrix: ", log.LstdFlags|log.Lmicroseconds)}
+
+func (s *stderrLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", keyvals[i], keyvals[i+1])
+	}
+	s.l.Print(b.String())
+}
+
+func (c *Client) logger() Logger {
+	if c.opts.logger != nil {
+		return c.opts.logger
+	}
+	return defaultLogger
+}
+
+// DebugFlags selects diagnostic subsystems
+type DebugFlags uint32
+
+// Debug subsystems
+const (
+	// DebugFrames logs every frame sent and received
+	DebugFrames DebugFlags = 1 << iota
+	// DebugSlowlog logs commands slower than the slowlog threshold
+	DebugSlowlog
+	// DebugReconnect logs connection setup, fallbacks and reconnects
+	DebugReconnect
+)
+
+var debugNames = []struct {
+	name string
+	flag DebugFlags
+}{
+	{"frames", DebugFrames},
+	{"slowlog", DebugSlowlog},
+	{"reconnect", DebugReconnect},
+}
+
+// String lists the enabled subsystems, comma separated
+func (f DebugFlags) String() string {
+	var names []string
+	for _, d := range debugNames {
+		if f&d.flag != 0 {
+			names = append(names, d.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// DefaultSlowlogThreshold is the slowlog threshold unless set with
+// "slowlog=<duration>"
+const DefaultSlowlogThreshold = 100 * time.Millisecond
+
+var (
+	debugFlags atomic.Uint32
+	slowlogNs  atomic.Int64
+)
+
+func init() {
+	slowlogNs.Store(int64(DefaultSlowlogThreshold))
+	if env := os.Getenv("CELRIX_CLIENT_DEBUG"); env != "" {
+		flags, slow, err := ParseDebugFlags(env)
+		if err != nil {
+			defaultLogger.Log(LevelWarn, "ignoring CELRIX_CLIENT_DEBUG entry", "error", err)
+		}
+		SetDebug(flags)
+		if slow > 0 {
+			SetSlowlogThreshold(slow)
+		}
+	}
+}
+
+// ParseDebugFlags parses a CELRIX_CLIENT_DEBUG value: a comma-separated
+// list of subsystem names, where slowlog may carry a threshold, e.g.
+// "frames,slowlog=250ms,reconnect". Unknown entries are reported in err but
+// do not prevent the others from being returned.
+func ParseDebugFlags(s string) (flags DebugFlags, slowlog time.Duration, err error) {
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(entry, "=")
+		found := false
+		for _, d := range debugNames {
+			if d.name == name {
+				flags |= d.flag
+				found = true
+			}
+		}
+		switch {
+		case !found:
+			err = fmt.Errorf("unknown debug subsystem %q", name)
+		case hasValue && name == "slowlog":
+			d, perr := time.ParseDuration(value)
+			if perr != nil {
+				err = fmt.Errorf("slowlog threshold: %w", perr)
+				continue
+			}
+			slowlog = d
+		case hasValue:
+			err = fmt.Errorf("debug subsystem %q takes no value", name)
+		}
+	}
+	return flags, slowlog, err
+}
+
+// SetDebug replaces the enabled debug subsystems for all clients in the
+// process. It takes effect immediately, including on open connections.
+func SetDebug(flags DebugFlags) { debugFlags.Store(uint32(flags)) }
+
+// Debug returns the enabled debug subsystems
+func Debug() DebugFlags { return DebugFlags(debugFlags.Load()) }
+
+// SetSlowlogThreshold sets the latency above which DebugSlowlog logs a
+// command
+func SetSlowlogThreshold(d time.Duration) { slowlogNs.Store(int64(d)) }
+
+func debugOn(f DebugFlags) bool { return DebugFlags(debugFlags.Load())&f != 0 }
+
+// logFrame logs a frame when DebugFrames is on
+func (c *Client) logFrame(outgoing bool, opcode uint8, reqID uint64, n int) {
+	if !debugOn(DebugFrames) {
+		return
+	}
+	dir := "recv"
+	if outgoing {
+		dir = "send"
+	}
+	c.logger().Log(LevelDebug, "frame", "dir", dir, "op", Op(opcode), "req", reqID, "bytes", n, "addr", c.addr)
+}
+
+// logSlow logs a command whose reply took longer than the slowlog
+// threshold
+func (c *Client) logSlow(opcode uint8, reqID uint64, d time.Duration) {
+	if !debugOn(DebugSlowlog) || d < time.Duration(slowlogNs.Load()) {
+		return
+	}
+	c.logger().Log(LevelWarn, "slow command", "op", Op(opcode), "req", reqID, "duration", d, "addr", c.addr)
+}
+
+// logReconnect logs a connection lifecycle event when DebugReconnect is on
+func (c *Client) logReconnect(msg string, keyvals ...interface{}) {
+	if !debugOn(DebugReconnect) {
+		return
+	}
+	c.logger().Log(LevelInfo, msg, append([]interface{}{"addr", c.addr}, keyvals...)...)
+}
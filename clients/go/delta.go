@@ -0,0 +1,156 @@
+package celrix
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ExportSince writes every change after seq (exclusive) up to the server's
+// current head to w as a delta. Chained from a full Export's Seq, deltas
+// form a cheap incremental backup. Deltas are resumed by exporting since
+// the last sequence restored rather than with ResumeExport, which is
+// ignored here.
+func (c *Client) ExportSince(ctx context.Context, w io.Writer, seq uint64, opts ...ExportOption) (ExportInfo, error) {
+	eo := buildExportOptions(opts)
+	eo.resume = nil
+	release, err := c.streamSlot(ctx, OpExportSince)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	defer release()
+	sub, err := c.dialDedicated(ctx)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	defer sub.closeDedicated()
+	defer closeOnDone(ctx, sub.conn)()
+
+	if err := sub.sendFrame(OpExportSince, binary.BigEndian.AppendUint64(nil, seq)); err != nil {
+		return ExportInfo{}, ctxErr(ctx, err)
+	}
+
+	ew, err := newExportWriter(w, deltaMagic, seq+1, eo, false)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	last := seq
+	for {
+		f, err := sub.recvFrame()
+		if err != nil {
+			return ew.info, ctxErr(ctx, err)
+		}
+		switch f.opcode {
+		case OpChangeEvent:
+			if len(f.payload) < 8 {
+				return ew.info, errors.New("incomplete change event")
+			}
+			if s := binary.BigEndian.Uint64(f.payload); s > last {
+				last = s
+			}
+			if err := ew.writeChunk(f.payload); err != nil {
+				return ew.info, err
+			}
+			eo.report(ew.info.Bytes, 0)
+		case OpInteger:
+			// Terminator carrying the head sequence at the time of export
+			if len(f.payload) >= 8 {
+				if head := binary.BigEndian.Uint64(f.payload); head > last {
+					last = head
+				}
+			}
+			return ew.info, ew.close(last)
+		default:
+			if _, err := decodeResponse(f.opcode, f.payload); err != nil {
+				return ew.info, err
+			}
+			return ew.info, fmt.Errorf("unexpected opcode in delta stream: %d", f.opcode)
+		}
+	}
+}
+
+// ApplyDelta replays a delta produced by ExportSince against the server
+// using ordinary write commands, in sequence order. Of the options, only
+// Progress applies.
+func (c *Client) ApplyDelta(ctx context.Context, r io.Reader, opts ...ExportOption) (ExportInfo, error) {
+	eo := buildExportOptions(opts)
+	return c.applyDelta(ctx, r, func(ChangeEvent) deltaAction { return deltaApply }, eo.report)
+}
+
+type deltaAction int
+
+const (
+	deltaApply deltaAction = iota
+	deltaSkip
+	deltaStop
+)
+
+// applyDelta replays changes as directed by decide, stopping at the first
+// change it marks deltaStop, and reports each applied change to progress
+// if set
+func (c *Client) applyDelta(ctx context.Context, r io.Reader, decide func(ChangeEvent) deltaAction, progress func(done, total int64)) (ExportInfo, error) {
+	er, err := newExportReader(r, deltaMagic)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	var applied ExportInfo
+	applied.BaseSeq = er.info.BaseSeq
+	for {
+		if err := ctx.Err(); err != nil {
+			return applied, err
+		}
+		chunk, err := er.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return applied, err
+		}
+		ev, err := decodeChangeEvent(chunk)
+		if err != nil {
+			return applied, fmt.Errorf("delta change %d: %w", er.info.Chunks, err)
+		}
+		switch decide(ev) {
+		case deltaSkip:
+			continue
+		case deltaStop:
+			return applied, nil
+		}
+		if err := c.applyChange(ev); err != nil {
+			return applied, fmt.Errorf("apply change %d: %w", ev.Seq, err)
+		}
+		applied.Chunks++
+		applied.Bytes += int64(len(chunk))
+		applied.Seq = ev.Seq
+		if progress != nil {
+			progress(applied.Bytes, 0)
+		}
+	}
+	if er.info.Seq > applied.Seq {
+		applied.Seq = er.info.Seq
+	}
+	return applied, nil
+}
+
+func (c *Client) applyChange(ev ChangeEvent) error {
+	switch ev.Kind {
+	case ChangeSet:
+		opcode, payload, err := c.setPayload(ev.Key, ev.Value, ev.TTL)
+		if err != nil {
+			return err
+		}
+		return c.write(opcode, ev.Key, payload)
+	case ChangeDel:
+		_, err := c.Del(ev.Key)
+		return err
+	case ChangeVAdd:
+		if len(ev.Metadata) > 0 {
+			return c.VAddWithMetadata(ev.Key, ev.Vector, ev.Metadata)
+		}
+		return c.VAdd(ev.Key, ev.Vector)
+	default:
+		return fmt.Errorf("unknown change kind: %d", ev.Kind)
+	}
+}
@@ -0,0 +1,91 @@
+package celrix
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// desyncPayloadBytes is how much of an offending frame's payload a
+// DesyncError keeps
+const desyncPayloadBytes = 64
+
+// DesyncError reports a reply the client could not match to the command in
+// flight: a malformed frame, a reply to another request, or an opcode the
+// command does not answer with. The connection can no longer be trusted to
+// be in step, so it is quarantined: it is closed, the error is logged at
+// LevelError, and the next command dials a replacement before it is sent.
+// The log carries the offending payload's length only; its bytes are logged
+// at LevelDebug under DebugFrames, unless WithKeyRedaction is set.
+// Later replies are never read from the quarantined connection.
+type DesyncError struct {
+	// Op and RequestID identify the command whose reply was being read
+	Op        Op
+	RequestID uint64
+	// Reply, ReplyID and Payload describe the offending frame, with
+	// Payload cut to its first 64 bytes; they are zero if the frame could
+	// not be decoded
+	Reply   Op
+	ReplyID uint64
+	Payload []byte
+	// Reason says what was wrong with the frame
+	Reason string
+	// Err is the decoding error behind a malformed frame
+	Err error
+}
+
+func (e *DesyncError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("celrix: protocol desync on %s request %d: %s: %v", e.Op, e.RequestID, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("celrix: protocol desync on %s request %d: %s (reply %s, request %d)", e.Op, e.RequestID, e.Reason, e.Reply, e.ReplyID)
+}
+
+func (e *DesyncError) Unwrap() error { return e.Err }
+
+// malformedFrame reports whether err, from reading a frame, means the bytes
+// on the connection were not a valid frame
+func malformedFrame(err error) bool {
+	return errors.Is(err, wire.ErrInvalidMagic) || errors.Is(err, wire.ErrChecksum) || errors.Is(err, wire.ErrVersion)
+}
+
+// desync quarantines the connection after f, or a frame that failed to
+// decode with err, could not be matched to the command in flight, and
+// returns the error for the command
+func (c *Client) desync(f *frame, reason string, err error) error {
+	derr := &DesyncError{Op: Op(c.pendingOp), RequestID: c.pendingReqID, Reason: reason, Err: err}
+	kv := []interface{}{"op", derr.Op, "req", derr.RequestID, "reason", reason}
+	if f != nil {
+		derr.Reply, derr.ReplyID = Op(f.opcode), f.reqID
+		derr.Payload = append([]byte(nil), f.payload[:min(len(f.payload), desyncPayloadBytes)]...)
+		kv = append(kv, "reply", derr.Reply, "reply_req", derr.ReplyID, "payload_bytes", len(f.payload))
+	}
+	if err != nil {
+		kv = append(kv, "error", err)
+	}
+	c.desyncs.Add(1)
+	if c.otlp != nil {
+		c.otlp.add(&c.otlp.desyncs, 1)
+	}
+	c.logger().Log(LevelError, "protocol desync, connection quarantined", append(kv, "addr", c.addr)...)
+	// The payload holds keys and values, so it is only logged when frames
+	// are being debugged and keys are not redacted
+	if f != nil && debugOn(DebugFrames) && c.opts.redactKey == nil {
+		c.logger().Log(LevelDebug, "protocol desync payload", "req", derr.RequestID, "payload", fmt.Sprintf("%x", derr.Payload), "addr", c.addr)
+	}
+	c.conn.Close()
+	c.releaseSlot()
+	if !c.closed {
+		c.broken = derr
+	}
+	c.emit(EventDesync, 0, derr)
+	return c.cmdErr(derr)
+}
+
+// unexpectedReply quarantines the connection after readResponse decoded a
+// reply the command does not answer with
+func (c *Client) unexpectedReply() error {
+	f := c.lastReply
+	return c.desync(&f, "unexpected reply opcode", nil)
+}
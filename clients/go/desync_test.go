@@ -0,0 +1,75 @@
+package celrix
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// desyncServer misanswers every request for key "bad" on the first
+// connection with misreply, and serves everything else from a kv
+func desyncServer(misreply func(f wire.Frame) []wire.Frame) *testServer {
+	var store kv
+	return &testServer{handle: func(conn int, f wire.Frame) []wire.Frame {
+		if key, _, _ := readString(f.Payload); conn == 1 && key == "bad" {
+			return misreply(f)
+		}
+		return store.handle(f)
+	}}
+}
+
+func TestDesyncQuarantinesConnection(t *testing.T) {
+	cases := []struct {
+		name     string
+		misreply func(f wire.Frame) []wire.Frame
+		run      func(c *Client) error
+	}{
+		{"reply to another request", func(f wire.Frame) []wire.Frame {
+			return reply(wire.Frame{RequestID: f.RequestID + 7}, OpValue, []byte("v"))
+		}, func(c *Client) error {
+			_, _, err := c.Get("bad")
+			return err
+		}},
+		{"unexpected opcode", func(f wire.Frame) []wire.Frame {
+			return reply(f, OpValue, []byte("v"))
+		}, func(c *Client) error {
+			_, err := c.Exists("bad")
+			return err
+		}},
+		{"foreach reply to another request", func(f wire.Frame) []wire.Frame {
+			return reply(wire.Frame{RequestID: f.RequestID + 7}, OpNil, nil)
+		}, func(c *Client) error {
+			return c.ForEach([]string{"ok", "bad", "ok"}, func(string, Reply) error { return nil })
+		}},
+		{"del pipeline reply to another request", func(f wire.Frame) []wire.Frame {
+			return reply(wire.Frame{RequestID: f.RequestID + 7}, OpInteger, make([]byte, 8))
+		}, func(c *Client) error {
+			_, err := c.delPipelined([]string{"ok", "bad"})
+			return err
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := desyncServer(tc.misreply)
+			c := s.connect(t)
+			var derr *DesyncError
+			if err := tc.run(c); !errors.As(err, &derr) {
+				t.Fatalf("got %v, want a DesyncError", err)
+			}
+			if n := c.Stats().Desyncs; n != 1 {
+				t.Errorf("Stats().Desyncs = %d, want 1", n)
+			}
+			// The next command runs on a replacement connection
+			if err := c.Set("k", "v"); err != nil {
+				t.Fatalf("Set after desync: %v", err)
+			}
+			if v, ok, err := c.Get("k"); err != nil || !ok || v != "v" {
+				t.Errorf("Get after desync: %q %v %v", v, ok, err)
+			}
+			if n := s.dialCount(); n != 2 {
+				t.Errorf("%d dials, want 2", n)
+			}
+		})
+	}
+}
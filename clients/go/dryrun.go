@@ -0,0 +1,89 @@
+package celrix
+
+import (
+	"fmt"
+	"sync"
+)
+
+// AdminOption configures an Admin
+type AdminOption func(*Admin)
+
+// WithDryRun makes state-changing Admin operations report what they would
+// do instead of doing it. Each command is wrapped in a DRYRUN envelope, and
+// the server replies with a description of its effect, collected by
+// Effects. Read-only operations run normally.
+//
+// An envelope is used rather than a header flag so that a server without
+// dry-run support rejects the command outright instead of ignoring an
+// unknown flag and executing it.
+func WithDryRun() AdminOption {
+	return func(a *Admin) {
+		a.dryRun = &dryRunLog{}
+	}
+}
+
+// DryRunEffect is the reported effect of one dry-run operation
+type DryRunEffect struct {
+	Op     string
+	Target string
+	// Effect is the server's description, e.g. "would delete 1204 keys"
+	Effect string
+}
+
+// String formats the effect for review
+func (e DryRunEffect) String() string {
+	if e.Target == "" {
+		return fmt.Sprintf("%s: %s", e.Op, e.Effect)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Op, e.Target, e.Effect)
+}
+
+type dryRunLog struct {
+	mu      sync.Mutex
+	effects []DryRunEffect
+}
+
+// DryRun reports whether the Admin was created with WithDryRun
+func (a *Admin) DryRun() bool { return a.dryRun != nil }
+
+// Effects returns the effects reported so far by dry-run operations, in
+// order. It returns nil for an Admin not in dry-run mode.
+func (a *Admin) Effects() []DryRunEffect {
+	if a.dryRun == nil {
+		return nil
+	}
+	a.dryRun.mu.Lock()
+	defer a.dryRun.mu.Unlock()
+	return append([]DryRunEffect(nil), a.dryRun.effects...)
+}
+
+// simulate sends op wrapped in a DRYRUN envelope and records the effect
+func (a *Admin) simulate(op uint8, target string, payload []byte) error {
+	// The policy governs the wrapped command too, so a denied command
+	// cannot be probed through the envelope
+	if err := a.c.opts.policy.check(op); err != nil {
+		a.c.pendingOp = op
+		return a.c.cmdErr(err)
+	}
+
+	// Payload: [opcode u8][inner payload]
+	env := make([]byte, 0, 1+len(payload))
+	env = append(env, op)
+	env = append(env, payload...)
+	if err := a.c.sendFrame(OpDryRun, env); err != nil {
+		return err
+	}
+	resp, err := a.c.readResponse()
+	if err != nil {
+		return err
+	}
+	effect, ok := resp.(string)
+	if !ok {
+		return a.c.unexpectedReply()
+	}
+
+	a.dryRun.mu.Lock()
+	a.dryRun.effects = append(a.dryRun.effects, DryRunEffect{Op: Op(op).String(), Target: target, Effect: effect})
+	a.dryRun.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,101 @@
+package celrix
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrJournaled is wrapped by errors returned from writes that failed to reach
+// the server but were saved to the write journal for later replay
+var ErrJournaled = errors.New("celrix: write journaled for replay")
+
+// ServerError is an error reply sent by the server
+type ServerError struct {
+	Message string
+}
+
+func (e *ServerError) Error() string { return e.Message }
+
+// isServerError reports whether err came from an error reply, as opposed to
+// a transport failure
+func isServerError(err error) bool {
+	var se *ServerError
+	return errors.As(err, &se)
+}
+
+// TimeoutError is returned when a command misses the deadline set by
+// WithAdaptiveTimeout. The connection is closed when it is returned.
+type TimeoutError struct {
+	Op Op
+	// Limit is the deadline budget the command was given
+	Limit time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s deadline of %s exceeded", e.Op, e.Limit)
+}
+
+// Timeout reports true, matching net.Error
+func (e *TimeoutError) Timeout() bool { return true }
+
+func (e *TimeoutError) Unwrap() error { return os.ErrDeadlineExceeded }
+
+// CommandError wraps every error returned by a command with what was being
+// attempted, so a logged error is diagnosable on its own:
+//
+//	celrix: GET req=42 key="user:1" attempt=1: connection reset by peer
+//
+// It unwraps to the underlying error, so errors.Is and errors.As see through
+// it to *ServerError, *TimeoutError and the rest.
+type CommandError struct {
+	Op    Op
+	ReqID uint64
+	// Key is the key the command operated on, empty for keyless commands.
+	// With WithKeyRedaction it holds the redacted form.
+	Key string
+	// Attempt is the 1-based try number
+	Attempt int
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "celrix: %s req=%d", e.Op, e.ReqID)
+	if e.Key != "" {
+		fmt.Fprintf(&b, " key=%q", e.Key)
+	}
+	fmt.Fprintf(&b, " attempt=%d: %v", e.Attempt, e.Err)
+	return b.String()
+}
+
+func (e *CommandError) Unwrap() error { return e.Err }
+
+// cmdErr wraps err in a CommandError for the command in flight. Errors that
+// are already wrapped are returned unchanged.
+func (c *Client) cmdErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	var ce *CommandError
+	if errors.As(err, &ce) {
+		return err
+	}
+	expvarError(Op(c.pendingOp))
+	if c.otlp != nil {
+		c.otlp.addOp(c.otlp.errors, c.pendingOp)
+	}
+	return &CommandError{Op: Op(c.pendingOp), ReqID: c.pendingReqID, Key: c.redacted(c.pendingKey), Attempt: max(1, c.pendingAttempt), Err: err}
+}
+
+// RedactKey replaces a key with a short digest, for use with
+// WithKeyRedaction. Equal keys redact equally, so errors about the same key
+// can still be correlated.
+func RedactKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,119 @@
+package celrix
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClientEventKind classifies a ClientEvent
+type ClientEventKind int
+
+// Client event kinds
+const (
+	// EventConnected: a connection was established and negotiated
+	EventConnected ClientEventKind = iota
+	// EventDisconnected: the connection was closed, by Close (Err nil) or
+	// because of a failure (Err set)
+	EventDisconnected
+	// EventRetry: a connection or command attempt failed and is being
+	// retried
+	EventRetry
+	// EventDesync: a reply could not be matched to its command and the
+	// connection was quarantined; Err is the *DesyncError
+	EventDesync
+)
+
+// String returns the event kind name
+func (k ClientEventKind) String() string {
+	switch k {
+	case EventConnected:
+		return "connected"
+	case EventDisconnected:
+		return "disconnected"
+	case EventRetry:
+		return "retry"
+	case EventDesync:
+		return "desync"
+	default:
+		return fmt.Sprintf("ClientEventKind(%d)", int(k))
+	}
+}
+
+// ClientEvent reports a change in a client's connection state
+type ClientEvent struct {
+	Kind ClientEventKind
+	Addr string
+	Time time.Time
+	// Attempt numbers retries, starting at 1
+	Attempt int
+	// Err is the failure behind the event, if any
+	Err error
+}
+
+// eventBuffer is the capacity of each client's event channel
+const eventBuffer = 64
+
+// eventStream delivers events without ever blocking the client: when the
+// consumer falls behind, events are dropped and counted
+type eventStream struct {
+	mu      sync.Mutex
+	ch      chan ClientEvent
+	closed  bool
+	dropped atomic.Uint64
+}
+
+func newEventStream() *eventStream {
+	return &eventStream{ch: make(chan ClientEvent, eventBuffer)}
+}
+
+// Events returns the channel of this client's connection events. It is
+// buffered; events that arrive while it is full are dropped and counted by
+// EventsDropped. The channel is closed by Close.
+func (c *Client) Events() <-chan ClientEvent {
+	if c.events == nil {
+		return nil
+	}
+	return c.events.ch
+}
+
+// EventsDropped returns the number of events dropped because the Events
+// channel was full
+func (c *Client) EventsDropped() uint64 {
+	if c.events == nil {
+		return 0
+	}
+	return c.events.dropped.Load()
+}
+
+func (c *Client) emit(kind ClientEventKind, attempt int, err error) {
+	es := c.events
+	if es == nil {
+		return
+	}
+	ev := ClientEvent{Kind: kind, Addr: c.addr, Time: c.opts.now(), Attempt: attempt, Err: err}
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.closed {
+		return
+	}
+	select {
+	case es.ch <- ev:
+	default:
+		es.dropped.Add(1)
+	}
+}
+
+func (c *Client) closeEvents() {
+	es := c.events
+	if es == nil {
+		return
+	}
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if !es.closed {
+		es.closed = true
+		close(es.ch)
+	}
+}
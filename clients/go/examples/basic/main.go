@@ -9,18 +9,13 @@ import (
 )
 
 func main() {
-	var client *celrix.Client
-	var err error
-
-	// Retry connection
-	for i := 0; i < 5; i++ {
-		client, err = celrix.Connect("127.0.0.1:6380")
-		if err == nil {
-			break
-		}
-		fmt.Printf("Connection attempt %d failed: %v\n", i, err)
-		time.Sleep(1 * time.Second)
-	}
+	// Retry the connection, and reconnect if it drops later
+	client, err := celrix.ConnectWithOptions("127.0.0.1:6380", celrix.ConnectOptions{
+		Retry: celrix.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, Jitter: 0.2},
+		OnReconnect: func(attempt int, cause error) {
+			fmt.Printf("Reconnected on attempt %d after: %v\n", attempt, cause)
+		},
+	})
 	if err != nil {
 		log.Fatal("Failed to connect:", err)
 	}
@@ -58,7 +53,7 @@ func main() {
 	}
 	fmt.Println("VAdd success")
 
-	results, err := client.VSearch(vector, 5)
+	results, err := client.VSearch(vector, 5, &celrix.VSearchOptions{Metric: celrix.MetricCosine})
 	if err != nil {
 		log.Fatal("VSearch failed:", err)
 	}
@@ -66,7 +61,7 @@ func main() {
 
 	foundKey := false
 	for _, res := range results {
-		if res == "v_go" {
+		if res.Key == "v_go" {
 			foundKey = true
 			break
 		}
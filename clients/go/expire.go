@@ -0,0 +1,64 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// NoExpiry is the TTL reported for a key that does not expire
+const NoExpiry time.Duration = -1
+
+// SetWithTTL sets a key-value pair that the server expires after ttl. The
+// server tracks expiry with second granularity, so ttl is rounded up to a
+// whole second.
+func (c *Client) SetWithTTL(key, value string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.New("TTL must be positive")
+	}
+	opcode, payload, err := c.setPayload(key, []byte(value), ttl)
+	if err != nil {
+		return err
+	}
+	return c.write(opcode, key, payload)
+}
+
+// Expire sets key to expire after ttl, replacing any TTL it had, and
+// reports whether the key exists
+func (c *Client) Expire(key string, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return false, errors.New("TTL must be positive")
+	}
+	// Payload: [key][ttl u64 seconds]
+	payload := binary.BigEndian.AppendUint64(appendString(nil, key), ttlSeconds(ttl))
+	return c.writeBool(OpExpire, key, payload)
+}
+
+// TTL returns how long key has left before it expires, or NoExpiry if it
+// does not expire. The boolean is false if the key does not exist.
+func (c *Client) TTL(key string) (time.Duration, bool, error) {
+	if err := c.sendKeyed(OpTTL, key, appendString(nil, key)); err != nil {
+		return 0, false, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return 0, false, err
+	}
+	// Reply: the remaining seconds, -1 for no expiry, or nil for no key
+	switch v := resp.(type) {
+	case nil:
+		return 0, false, nil
+	case int64:
+		if v < 0 {
+			return NoExpiry, true, nil
+		}
+		return time.Duration(v) * time.Second, true, nil
+	}
+	return 0, false, c.unexpectedReply()
+}
+
+// Persist removes the TTL of key, so it no longer expires, and reports
+// whether there was one to remove
+func (c *Client) Persist(key string) (bool, error) {
+	return c.writeBool(OpPersist, key, appendString(nil, key))
+}
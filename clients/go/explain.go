@@ -0,0 +1,112 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// SearchPlan describes how the server answered a search, as reported by
+// VSearchExplain
+type SearchPlan struct {
+	// Index is the access path taken, e.g. "hnsw", or "flat" when the
+	// filter was selective enough that a brute-force scan was cheaper
+	Index string
+	// Visited is the number of candidate vectors scored
+	Visited int64
+	// Selectivity is the fraction of vectors passing the filter, or 1
+	// for unfiltered searches
+	Selectivity float64
+	// FilterTime, SearchTime and Total are the server-side time spent
+	// evaluating the filter, traversing the index, and on the whole request
+	FilterTime time.Duration
+	SearchTime time.Duration
+	Total      time.Duration
+	// Details holds any further plan fields the server reports, such as
+	// index-specific tuning parameters
+	Details map[string]interface{}
+}
+
+// String summarises the plan on one line
+func (p SearchPlan) String() string {
+	return fmt.Sprintf("index=%s visited=%d selectivity=%.3g filter=%v search=%v total=%v",
+		p.Index, p.Visited, p.Selectivity, p.FilterTime, p.SearchTime, p.Total)
+}
+
+// VSearchExplain runs a search like VSearchFilter with scores and metadata,
+// and also returns the server's plan for it, for diagnosing recall or
+// latency anomalies without access to server logs. filter may be nil.
+// Explaining adds bookkeeping to the search, so timings run slightly high.
+func (c *Client) VSearchExplain(vector []float32, k int, filter *Filter) ([]SearchHit, SearchPlan, error) {
+	if err := c.checkVector(OpVSearchExplain, "", vector); err != nil {
+		return nil, SearchPlan{}, err
+	}
+	// Payload: [count][f32...][k][filter?], as for VSEARCHMETA
+	payload := appendVector(make([]byte, 0, 4+len(vector)*4+4), vector)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(k))
+	if filter != nil {
+		if err := filter.validate(); err != nil {
+			return nil, SearchPlan{}, err
+		}
+		var err error
+		if payload, err = filter.appendTo(payload); err != nil {
+			return nil, SearchPlan{}, err
+		}
+	}
+
+	if err := c.sendFrame(OpVSearchExplain, payload); err != nil {
+		return nil, SearchPlan{}, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, SearchPlan{}, err
+	}
+
+	// Response: a map of the plan fields plus "hits", an array of
+	// VSEARCHMETA records
+	m, ok := resp.(map[string]interface{})
+	if !ok {
+		return nil, SearchPlan{}, c.unexpectedReply()
+	}
+	records, err := toKeys(m["hits"])
+	if err != nil {
+		return nil, SearchPlan{}, fmt.Errorf("explain hits: %w", err)
+	}
+	hits, err := decodeSearchHits(records)
+	if err != nil {
+		return nil, SearchPlan{}, err
+	}
+	return hits, decodePlan(m), nil
+}
+
+// decodePlan reads the known plan fields, leaving the rest in Details.
+// Durations are reported in microseconds.
+func decodePlan(m map[string]interface{}) SearchPlan {
+	p := SearchPlan{Selectivity: 1, Details: make(map[string]interface{})}
+	micros := func(v interface{}) time.Duration {
+		n, _ := v.(int64)
+		return time.Duration(n) * time.Microsecond
+	}
+	for name, v := range m {
+		switch name {
+		case "hits":
+		case "index":
+			p.Index, _ = v.(string)
+		case "visited":
+			p.Visited, _ = v.(int64)
+		case "selectivity":
+			if f, ok := v.(float64); ok {
+				p.Selectivity = f
+			}
+		case "filter_us":
+			p.FilterTime = micros(v)
+		case "search_us":
+			p.SearchTime = micros(v)
+		case "total_us":
+			p.Total = micros(v)
+		default:
+			p.Details[name] = v
+		}
+	}
+	return p
+}
@@ -0,0 +1,419 @@
+package celrix
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Export file layout: [magic: 7 bytes][version: u8][base_seq: u64] followed
+// by chunk records [len: u32][crc32: u32][bytes], a zero-length end record and
+// [seq: u64], the last change sequence covered. The end record lets readers
+// tell a complete export from a truncated one.
+//
+// Compressed exports wrap the same layout in gzip. Full snapshots start a
+// new gzip member for the header, each chunk record and the end record, so
+// an interrupted export can be cut back to its last complete chunk and
+// resumed; deltas use a single member. Readers detect compression by the
+// gzip magic.
+//
+// Full snapshots use the "CELXEXP" magic with opaque server chunks; deltas use
+// "CELXDLT" with one change event per chunk.
+const (
+	exportMagic   = "CELXEXP"
+	deltaMagic    = "CELXDLT"
+	exportVersion = 1
+)
+
+// ErrExportTruncated is returned when an export stream ends without its end
+// record
+var ErrExportTruncated = errors.New("celrix: export stream truncated")
+
+// ExportInfo summarises a completed export or restore
+type ExportInfo struct {
+	Chunks int
+	Bytes  int64
+	// BaseSeq is the first change sequence included in a delta; zero for
+	// full snapshots
+	BaseSeq uint64
+	// Seq is the last change sequence reflected in the export
+	Seq uint64
+	// Snapshot identifies the server snapshot a full export reads, for
+	// resuming it; zero if the server does not support resumption
+	Snapshot uint64
+	// Offset is the length of the output written through the last
+	// complete chunk record. After a failed Export, truncate the output to
+	// Offset and pass the info to ResumeExport to carry on from there.
+	Offset int64
+}
+
+// Export attributes the server sends ahead of the first chunk
+const (
+	attrExportSnapshot = "snapshot"
+	attrExportTotal    = "total"
+)
+
+// ExportOption configures Export, ExportSince, Restore and ApplyDelta
+type ExportOption func(*exportOptions)
+
+type exportOptions struct {
+	gzip     bool
+	progress func(done, total int64)
+	resume   *ExportInfo
+}
+
+func (o *exportOptions) report(done, total int64) {
+	if o.progress != nil {
+		o.progress(done, total)
+	}
+}
+
+func buildExportOptions(opts []ExportOption) *exportOptions {
+	o := &exportOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ExportGzip compresses the export with gzip. Restore and ApplyDelta read
+// compressed streams without it.
+func ExportGzip() ExportOption {
+	return func(o *exportOptions) {
+		o.gzip = true
+	}
+}
+
+// Progress calls fn after every chunk with the uncompressed bytes done so
+// far and the total the server expects to send, or zero when it is not
+// known, as for deltas and restores
+func Progress(fn func(done, total int64)) ExportOption {
+	return func(o *exportOptions) {
+		o.progress = fn
+	}
+}
+
+// ResumeExport continues an interrupted Export from the state it returned.
+// The output must be the earlier one truncated to from.Offset and
+// positioned at its end; the export's header is not written again. The
+// server must still hold from.Snapshot, and the compression setting must
+// match the original export.
+func ResumeExport(from ExportInfo) ExportOption {
+	return func(o *exportOptions) {
+		o.resume = &from
+	}
+}
+
+// Export streams a full snapshot of the server's dataset to w. The snapshot
+// is read on a dedicated connection so the client stays usable meanwhile.
+// Whether it succeeds or not, the returned info records how far the export
+// got, for ResumeExport.
+func (c *Client) Export(ctx context.Context, w io.Writer, opts ...ExportOption) (ExportInfo, error) {
+	eo := buildExportOptions(opts)
+	release, err := c.streamSlot(ctx, OpExport)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	defer release()
+	sub, err := c.dialDedicated(ctx)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	defer sub.closeDedicated()
+	defer closeOnDone(ctx, sub.conn)()
+
+	// Resuming payload: [snapshot: u64][chunks to skip: u64]
+	var payload []byte
+	if r := eo.resume; r != nil {
+		payload = binary.BigEndian.AppendUint64(payload, r.Snapshot)
+		payload = binary.BigEndian.AppendUint64(payload, uint64(r.Chunks))
+	}
+	if err := sub.sendFrame(OpExport, payload); err != nil {
+		return ExportInfo{}, ctxErr(ctx, err)
+	}
+
+	ew, err := newExportWriter(w, exportMagic, 0, eo, true)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	var total int64
+	for {
+		f, err := sub.recvFrame()
+		if err != nil {
+			return ew.info, ctxErr(ctx, err)
+		}
+		if attrs := sub.lastAttrs; attrs != nil {
+			if id, ok := attrs[attrExportSnapshot].Int(); ok {
+				ew.info.Snapshot = uint64(id)
+			}
+			if n, ok := attrs[attrExportTotal].Int(); ok {
+				total = n
+			}
+		}
+		switch f.opcode {
+		case OpExportChunk:
+			if err := ew.writeChunk(f.payload); err != nil {
+				return ew.info, err
+			}
+			eo.report(ew.info.Bytes, total)
+		case OpInteger:
+			// Terminator carrying the change sequence the snapshot reflects
+			if len(f.payload) < 8 {
+				return ew.info, errors.New("invalid integer payload")
+			}
+			return ew.info, ew.close(binary.BigEndian.Uint64(f.payload))
+		default:
+			if _, err := decodeResponse(f.opcode, f.payload); err != nil {
+				return ew.info, err
+			}
+			return ew.info, fmt.Errorf("unexpected opcode in export stream: %d", f.opcode)
+		}
+	}
+}
+
+// Restore loads an export produced by Export into the server, replacing its
+// dataset once the final chunk has been acknowledged. Of the options, only
+// Progress applies.
+func (c *Client) Restore(ctx context.Context, r io.Reader, opts ...ExportOption) (ExportInfo, error) {
+	eo := buildExportOptions(opts)
+	er, err := newExportReader(r, exportMagic)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+
+	release, err := c.streamSlot(ctx, OpRestore)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	defer release()
+	sub, err := c.dialDedicated(ctx)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	defer sub.closeDedicated()
+	defer closeOnDone(ctx, sub.conn)()
+
+	if err := sub.sendFrame(OpRestore, nil); err != nil {
+		return ExportInfo{}, ctxErr(ctx, err)
+	}
+	if err := sub.expectOK(); err != nil {
+		return ExportInfo{}, ctxErr(ctx, err)
+	}
+
+	for {
+		chunk, err := er.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return er.info, err
+		}
+		if err := sub.sendFrame(OpRestoreChunk, chunk); err != nil {
+			return er.info, ctxErr(ctx, err)
+		}
+		if err := sub.expectOK(); err != nil {
+			return er.info, ctxErr(ctx, err)
+		}
+		eo.report(er.info.Bytes, 0)
+	}
+
+	// The end frame carries the snapshot's sequence so the server resumes
+	// its change log from there
+	end := binary.BigEndian.AppendUint64(nil, er.info.Seq)
+	if err := sub.sendFrame(OpRestoreEnd, end); err != nil {
+		return er.info, ctxErr(ctx, err)
+	}
+	return er.info, ctxErr(ctx, sub.expectOK())
+}
+
+// ctxErr prefers the context's error when a stream failed because the
+// context closed the connection
+func ctxErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+type exportWriter struct {
+	w  *bufio.Writer
+	cw *countingWriter
+	// gz compresses records when set. With members each record is its
+	// own gzip member, flushed through to the output as it completes.
+	gz      *gzip.Writer
+	members bool
+	info    ExportInfo
+}
+
+// newExportWriter writes the stream header, or, when resuming, picks up
+// the state of the interrupted export instead. Resumable exports write
+// each record through to w so that info.Offset only ever covers complete
+// records.
+func newExportWriter(w io.Writer, magic string, baseSeq uint64, eo *exportOptions, resumable bool) (*exportWriter, error) {
+	cw := &countingWriter{w: w}
+	ew := &exportWriter{w: bufio.NewWriter(cw), cw: cw, members: resumable, info: ExportInfo{BaseSeq: baseSeq}}
+	if eo.gzip {
+		ew.gz = gzip.NewWriter(ew.w)
+	}
+	if r := eo.resume; r != nil {
+		ew.info = *r
+		cw.n = r.Offset
+		return ew, nil
+	}
+	hdr := append([]byte(magic), exportVersion)
+	hdr = binary.BigEndian.AppendUint64(hdr, baseSeq)
+	if err := ew.writeRecord(hdr); err != nil {
+		return nil, err
+	}
+	return ew, nil
+}
+
+// writeRecord writes the pieces of one record
+func (ew *exportWriter) writeRecord(parts ...[]byte) error {
+	var out io.Writer = ew.w
+	if ew.gz != nil {
+		out = ew.gz
+	}
+	for _, p := range parts {
+		if _, err := out.Write(p); err != nil {
+			return err
+		}
+	}
+	if !ew.members {
+		return nil
+	}
+	if ew.gz != nil {
+		if err := ew.gz.Close(); err != nil {
+			return err
+		}
+		ew.gz.Reset(ew.w)
+	}
+	if err := ew.w.Flush(); err != nil {
+		return err
+	}
+	ew.info.Offset = ew.cw.n
+	return nil
+}
+
+func (ew *exportWriter) writeChunk(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	var hdr [8]byte
+	binary.BigEndian.PutUint32(hdr[0:], uint32(len(b)))
+	binary.BigEndian.PutUint32(hdr[4:], crc32.ChecksumIEEE(b))
+	if err := ew.writeRecord(hdr[:], b); err != nil {
+		return err
+	}
+	ew.info.Chunks++
+	ew.info.Bytes += int64(len(b))
+	return nil
+}
+
+func (ew *exportWriter) close(seq uint64) error {
+	var end [16]byte
+	binary.BigEndian.PutUint64(end[8:], seq)
+	if err := ew.writeRecord(end[:]); err != nil {
+		return err
+	}
+	if ew.gz != nil && !ew.members {
+		if err := ew.gz.Close(); err != nil {
+			return err
+		}
+	}
+	if err := ew.w.Flush(); err != nil {
+		return err
+	}
+	ew.info.Seq = seq
+	ew.info.Offset = ew.cw.n
+	return nil
+}
+
+// countingWriter counts the bytes w accepted
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+type exportReader struct {
+	r    *bufio.Reader
+	info ExportInfo
+	done bool
+}
+
+func newExportReader(r io.Reader, magic string) (*exportReader, error) {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(2); err == nil && b[0] == 0x1f && b[1] == 0x8b {
+		// Multistream reading, the default, joins the members of a
+		// resumed export
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("read export header: %w", err)
+		}
+		br = bufio.NewReader(gz)
+	}
+	hdr := make([]byte, len(magic)+1+8)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, fmt.Errorf("read export header: %w", err)
+	}
+	switch string(hdr[:len(magic)]) {
+	case magic:
+	case exportMagic, deltaMagic:
+		return nil, fmt.Errorf("celrix: expected %s stream, got %s", magic, hdr[:len(magic)])
+	default:
+		return nil, errors.New("celrix: not an export stream")
+	}
+	if v := hdr[len(magic)]; v != exportVersion {
+		return nil, fmt.Errorf("celrix: unsupported export version %d", v)
+	}
+	base := binary.BigEndian.Uint64(hdr[len(magic)+1:])
+	return &exportReader{r: br, info: ExportInfo{BaseSeq: base}}, nil
+}
+
+// next returns the next verified chunk, or io.EOF after the end record
+func (er *exportReader) next() ([]byte, error) {
+	if er.done {
+		return nil, io.EOF
+	}
+	var hdr [8]byte
+	if _, err := io.ReadFull(er.r, hdr[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrExportTruncated
+		}
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[0:])
+	if n == 0 {
+		var seq [8]byte
+		if _, err := io.ReadFull(er.r, seq[:]); err != nil {
+			return nil, ErrExportTruncated
+		}
+		er.info.Seq = binary.BigEndian.Uint64(seq[:])
+		er.done = true
+		return nil, io.EOF
+	}
+	chunk := make([]byte, n)
+	if _, err := io.ReadFull(er.r, chunk); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrExportTruncated
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(chunk) != binary.BigEndian.Uint32(hdr[4:]) {
+		return nil, fmt.Errorf("celrix: export chunk %d checksum mismatch", er.info.Chunks)
+	}
+	er.info.Chunks++
+	er.info.Bytes += int64(n)
+	return chunk, nil
+}
@@ -0,0 +1,77 @@
+package celrix
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+)
+
+// Expvar names published by PublishExpvar
+const (
+	// ExpvarCommands maps opcode names to the number of requests sent
+	ExpvarCommands = "celrix.client.commands"
+	// ExpvarErrors maps opcode names to the number of failed commands
+	ExpvarErrors = "celrix.client.errors"
+	// ExpvarConns reports connection usage: "open" clients, "dials" made
+	// and "inflight" commands awaiting a reply
+	ExpvarConns = "celrix.client.conns"
+)
+
+var (
+	expvarOn   atomic.Bool
+	expvarOnce sync.Once
+
+	varCommands expvar.Map
+	varErrors   expvar.Map
+	varOpen     expvar.Int
+	varDials    expvar.Int
+	varInflight expvar.Int
+)
+
+// PublishExpvar publishes the stats of every client in the process under
+// the celrix.client.* expvar names, so they appear on /debug/vars. Counting
+// starts when it is first called; calling it again has no effect.
+func PublishExpvar() {
+	expvarOnce.Do(func() {
+		expvar.Publish(ExpvarCommands, &varCommands)
+		expvar.Publish(ExpvarErrors, &varErrors)
+		conns := new(expvar.Map)
+		conns.Set("open", &varOpen)
+		conns.Set("dials", &varDials)
+		conns.Set("inflight", &varInflight)
+		expvar.Publish(ExpvarConns, conns)
+		expvarOn.Store(true)
+	})
+}
+
+func expvarCommand(opcode uint8) {
+	if expvarOn.Load() {
+		varCommands.Add(Op(opcode).String(), 1)
+	}
+}
+
+func expvarError(op Op) {
+	if expvarOn.Load() {
+		varErrors.Add(op.String(), 1)
+	}
+}
+
+func expvarAdd(v *expvar.Int, delta int64) {
+	if expvarOn.Load() {
+		v.Add(delta)
+	}
+}
+
+// countOpen adds a newly connected client to the open gauge. Clients
+// connected before PublishExpvar are left out of the gauges entirely, so
+// they never drive them negative.
+func (c *Client) countOpen() {
+	c.counted = expvarOn.Load()
+	c.gauge(&varOpen, 1)
+}
+
+func (c *Client) gauge(v *expvar.Int, delta int64) {
+	if c.counted {
+		v.Add(delta)
+	}
+}
@@ -0,0 +1,103 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// maxRetainedBuffer caps the buffers a client keeps for reuse between
+// commands, so one large value does not pin its memory for the life of the
+// connection
+const maxRetainedBuffer = 64 << 10
+
+func retain(buf []byte) []byte {
+	if cap(buf) > maxRetainedBuffer {
+		return nil
+	}
+	return buf
+}
+
+// GetInto reads the value of key into buf and returns its length. It is
+// the allocation-free form of Get: the request and reply reuse buffers
+// owned by the client, and the value is copied straight into buf. If buf
+// is too small, GetInto returns the value's length with io.ErrShortBuffer
+// and leaves buf unchanged.
+func (c *Client) GetInto(key string, buf []byte) (n int, ok bool, err error) {
+	c.pbuf = appendString(c.pbuf[:0], key)
+	if err := c.sendKeyed(OpGet, key, c.pbuf); err != nil {
+		return 0, false, err
+	}
+	f, err := c.recvFrameInto(&c.rbuf)
+	c.rbuf = retain(c.rbuf)
+	if err != nil {
+		return 0, false, err
+	}
+	value, ok, err := c.fastValue(f)
+	if !ok || err != nil {
+		return 0, false, err
+	}
+	if len(value) > len(buf) {
+		return len(value), true, c.cmdErr(io.ErrShortBuffer)
+	}
+	return copy(buf, value), true, nil
+}
+
+// SetString is the allocation-free form of Set. Values large enough for
+// WithValueCompression to compress take the regular path.
+func (c *Client) SetString(key, value string) error {
+	if err := c.checkValue(OpSet, key, len(value)); err != nil {
+		return err
+	}
+	if c.writeDict != nil && len(value) >= c.opts.compression.minSize {
+		return c.Set(key, value)
+	}
+	// Payload: [key_len][key][val_len][val][ttl], as built by encodeSet
+	p := appendString(c.pbuf[:0], key)
+	p = appendString(p, value)
+	p = binary.BigEndian.AppendUint64(p, 0)
+	c.pbuf = retain(p)
+
+	if err := c.sendKeyed(OpSet, key, p); err != nil {
+		return c.journalFailure(OpSet, p, err)
+	}
+	f, err := c.recvFrameInto(&c.rbuf)
+	c.rbuf = retain(c.rbuf)
+	if err != nil {
+		return c.journalFailure(OpSet, p, err)
+	}
+	if f.opcode == OpOk {
+		return nil
+	}
+	return c.fastReplyErr(f)
+}
+
+// fastValue returns the value carried by a GET reply read by a fast path.
+// A typed string is a value like any other; its content-type hint is only
+// exposed through Reply.
+func (c *Client) fastValue(f frame) ([]byte, bool, error) {
+	switch f.opcode {
+	case OpNil:
+		return nil, false, nil
+	case OpValue:
+		return f.payload, true, nil
+	case OpTypedString:
+		// [content_type: u8][bytes]
+		if len(f.payload) < 1 {
+			return nil, false, c.desync(&f, "malformed typed string", nil)
+		}
+		return f.payload[1:], true, nil
+	default:
+		return nil, false, c.fastReplyErr(f)
+	}
+}
+
+// fastReplyErr is the error for a reply the fast paths do not expect. The
+// frame answered the request in flight, so the connection stays in step:
+// only a reply to another request or an undecodable frame is a desync.
+func (c *Client) fastReplyErr(f frame) error {
+	if f.opcode == OpError {
+		return c.cmdErr(&ServerError{Message: string(f.payload)})
+	}
+	return c.cmdErr(fmt.Errorf("unexpected reply %s", Op(f.opcode)))
+}
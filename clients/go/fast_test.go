@@ -0,0 +1,108 @@
+package celrix
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// fastServer answers GET with value and every other command with OK,
+// reusing its buffers so that it does not allocate per request itself
+func fastServer(t testing.TB, value []byte) *Client {
+	t.Helper()
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+		var in, out []byte
+		for {
+			var f wire.Frame
+			var err error
+			if f, in, err = wire.ReadFrameInto(r, in); err != nil {
+				return
+			}
+			reply := wire.Frame{Opcode: OpOk, RequestID: f.RequestID}
+			if f.Opcode == OpGet {
+				reply.Opcode, reply.Payload = OpValue, value
+			}
+			out = wire.AppendFrame(out[:0], reply)
+			if _, err := server.Write(out); err != nil {
+				return
+			}
+		}
+	}()
+	c, err := Connect("fast", WithDialer(func(context.Context, string, string) (net.Conn, error) {
+		return client, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestFastPathAllocs(t *testing.T) {
+	c := fastServer(t, []byte("value"))
+	buf := make([]byte, 64)
+
+	getAllocs := testing.AllocsPerRun(100, func() {
+		if _, _, err := c.GetInto("key", buf); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if getAllocs != 0 {
+		t.Errorf("GetInto: %v allocs per call, want 0", getAllocs)
+	}
+	setAllocs := testing.AllocsPerRun(100, func() {
+		if err := c.SetString("key", "value"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if setAllocs != 0 {
+		t.Errorf("SetString: %v allocs per call, want 0", setAllocs)
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	c := fastServer(b, []byte("value"))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, _, err := c.Get("key"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetInto(b *testing.B) {
+	c := fastServer(b, []byte("value"))
+	buf := make([]byte, 64)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, _, err := c.GetInto("key", buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	c := fastServer(b, nil)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := c.Set("key", "value"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSetString(b *testing.B) {
+	c := fastServer(b, nil)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := c.SetString("key", "value"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
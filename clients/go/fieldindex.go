@@ -0,0 +1,69 @@
+package celrix
+
+import "fmt"
+
+// CreateFieldIndex builds a secondary index on a metadata field of a
+// collection, so SearchByField can look up exact values without scanning.
+// The field must be defined in the collection schema. Vectors added later
+// are indexed as they are written.
+func (c *Client) CreateFieldIndex(collection, field string) error {
+	schema, err := c.Collection(collection).Schema()
+	if err != nil {
+		return err
+	}
+	if _, ok := schema.Fields[field]; !ok {
+		return &ValidationError{Collection: collection, Field: field, Reason: "field not defined in schema"}
+	}
+	c.nextCollection = collection
+	if err := c.sendFrame(OpCreateFieldIndex, appendString(appendString(nil, collection), field)); err != nil {
+		return err
+	}
+	return c.expectOK()
+}
+
+// DropFieldIndex removes a secondary index created by CreateFieldIndex
+func (c *Client) DropFieldIndex(collection, field string) error {
+	c.nextCollection = collection
+	if err := c.sendFrame(OpDropFieldIndex, appendString(appendString(nil, collection), field)); err != nil {
+		return err
+	}
+	return c.expectOK()
+}
+
+// CreateFieldIndex indexes a metadata field of the collection; see
+// Client.CreateFieldIndex
+func (col *Collection) CreateFieldIndex(field string) error {
+	return col.client.CreateFieldIndex(col.name, field)
+}
+
+// SearchByField returns the keys of vectors whose metadata field equals
+// value, using the field's secondary index. The server rejects fields that
+// are not indexed rather than falling back to a scan.
+func (col *Collection) SearchByField(field string, value MetaValue) ([]string, error) {
+	schema, err := col.Schema()
+	if err != nil {
+		return nil, err
+	}
+	typ, ok := schema.Fields[field]
+	if !ok {
+		return nil, &ValidationError{Collection: col.name, Field: field, Reason: "field not defined in schema"}
+	}
+	if typ != value.Type() {
+		return nil, &ValidationError{Collection: col.name, Field: field, Reason: fmt.Sprintf("value is %s, schema expects %s", value.Type(), typ)}
+	}
+
+	// Payload: [coll_len][coll][field_len][field][value]
+	payload := appendString(appendString(nil, col.name), field)
+	if payload, err = value.appendTo(payload); err != nil {
+		return nil, err
+	}
+	col.client.nextCollection = col.name
+	if err := col.client.sendFrame(OpSearchByField, payload); err != nil {
+		return nil, err
+	}
+	resp, err := col.client.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	return toKeys(resp)
+}
@@ -0,0 +1,170 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// FilterOp is a filter expression operator
+type FilterOp uint8
+
+// Filter operators
+const (
+	FilterEq  FilterOp = 0x01
+	FilterNe  FilterOp = 0x02
+	FilterLt  FilterOp = 0x03
+	FilterLte FilterOp = 0x04
+	FilterGt  FilterOp = 0x05
+	FilterGte FilterOp = 0x06
+	FilterAnd FilterOp = 0x10
+	FilterOr  FilterOp = 0x11
+	FilterNot FilterOp = 0x12
+)
+
+// String returns the operator symbol
+func (op FilterOp) String() string {
+	switch op {
+	case FilterEq:
+		return "=="
+	case FilterNe:
+		return "!="
+	case FilterLt:
+		return "<"
+	case FilterLte:
+		return "<="
+	case FilterGt:
+		return ">"
+	case FilterGte:
+		return ">="
+	case FilterAnd:
+		return "AND"
+	case FilterOr:
+		return "OR"
+	case FilterNot:
+		return "NOT"
+	default:
+		return fmt.Sprintf("FilterOp(%d)", uint8(op))
+	}
+}
+
+// Filter is a metadata filter expression evaluated server-side during search
+type Filter struct {
+	op       FilterOp
+	field    string
+	value    MetaValue
+	children []Filter
+}
+
+// Eq matches vectors whose field equals value
+func Eq(field string, value MetaValue) Filter { return cmp(FilterEq, field, value) }
+
+// Ne matches vectors whose field differs from value
+func Ne(field string, value MetaValue) Filter { return cmp(FilterNe, field, value) }
+
+// Lt matches vectors whose field is less than value
+func Lt(field string, value MetaValue) Filter { return cmp(FilterLt, field, value) }
+
+// Lte matches vectors whose field is less than or equal to value
+func Lte(field string, value MetaValue) Filter { return cmp(FilterLte, field, value) }
+
+// Gt matches vectors whose field is greater than value
+func Gt(field string, value MetaValue) Filter { return cmp(FilterGt, field, value) }
+
+// Gte matches vectors whose field is greater than or equal to value
+func Gte(field string, value MetaValue) Filter { return cmp(FilterGte, field, value) }
+
+// And matches vectors satisfying every filter
+func And(filters ...Filter) Filter { return Filter{op: FilterAnd, children: filters} }
+
+// Or matches vectors satisfying at least one filter
+func Or(filters ...Filter) Filter { return Filter{op: FilterOr, children: filters} }
+
+func cmp(op FilterOp, field string, value MetaValue) Filter {
+	return Filter{op: op, field: field, value: value}
+}
+
+// String formats the expression for display
+func (f Filter) String() string {
+	switch f.op {
+	case FilterAnd, FilterOr:
+		s := "("
+		for i, c := range f.children {
+			if i > 0 {
+				s += " " + f.op.String() + " "
+			}
+			s += c.String()
+		}
+		return s + ")"
+	case FilterNot:
+		if len(f.children) == 1 {
+			return "NOT " + f.children[0].String()
+		}
+		return "NOT <invalid>"
+	default:
+		if f.value.typ == MetaString {
+			return fmt.Sprintf("%s %s %q", f.field, f.op, f.value.s)
+		}
+		return fmt.Sprintf("%s %s %s", f.field, f.op, f.value)
+	}
+}
+
+// validate checks the expression is well formed
+func (f Filter) validate() error {
+	switch f.op {
+	case FilterAnd, FilterOr:
+		if len(f.children) == 0 {
+			return fmt.Errorf("%s filter requires at least one operand", f.op)
+		}
+		for _, c := range f.children {
+			if err := c.validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FilterNot:
+		if len(f.children) != 1 {
+			return errors.New("NOT filter requires exactly one operand")
+		}
+		return f.children[0].validate()
+	case FilterEq, FilterNe:
+	case FilterLt, FilterLte, FilterGt, FilterGte:
+		if f.value.typ == MetaBool {
+			return fmt.Errorf("range operator %s not supported for bool field %q", f.op, f.field)
+		}
+	default:
+		return fmt.Errorf("invalid filter operator: %d", f.op)
+	}
+	if f.field == "" {
+		return errors.New("filter field name is empty")
+	}
+	if f.value.typ == 0 {
+		return fmt.Errorf("filter on %q has no value", f.field)
+	}
+	return nil
+}
+
+// appendTo appends the expression in prefix form:
+// comparison: [op: u8][field_len: u32][field][value]
+// logical:    [op: u8][count: u32][child]...
+// negation:   [op: u8][child]
+func (f Filter) appendTo(buf []byte) ([]byte, error) {
+	buf = append(buf, byte(f.op))
+	switch f.op {
+	case FilterNot:
+		return f.children[0].appendTo(buf)
+	case FilterAnd, FilterOr:
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(f.children)))
+		var err error
+		for _, c := range f.children {
+			if buf, err = c.appendTo(buf); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(f.field)))
+		buf = append(buf, f.field...)
+		return f.value.appendTo(buf)
+	}
+}
@@ -0,0 +1,161 @@
+package celrix
+
+import (
+	"fmt"
+	"time"
+)
+
+// Scalar is the set of Go types that map onto metadata value types
+type Scalar interface {
+	string | int | int64 | float64 | bool | time.Time
+}
+
+// FieldRef is a typed reference to a metadata field. The type parameter pins
+// the comparison operand type so mismatches are caught by the compiler:
+//
+//	F[string]("lang").Eq("en").And(F[int]("year").Gte(2020))
+type FieldRef[T Scalar] struct {
+	name string
+}
+
+// F returns a typed reference to the named metadata field
+func F[T Scalar](name string) FieldRef[T] {
+	return FieldRef[T]{name: name}
+}
+
+// Name returns the field name
+func (f FieldRef[T]) Name() string { return f.name }
+
+// Eq matches vectors whose field equals v
+func (f FieldRef[T]) Eq(v T) Filter { return cmp(FilterEq, f.name, metaValueOf(v)) }
+
+// Ne matches vectors whose field differs from v
+func (f FieldRef[T]) Ne(v T) Filter { return cmp(FilterNe, f.name, metaValueOf(v)) }
+
+// Lt matches vectors whose field is less than v
+func (f FieldRef[T]) Lt(v T) Filter { return cmp(FilterLt, f.name, metaValueOf(v)) }
+
+// Lte matches vectors whose field is less than or equal to v
+func (f FieldRef[T]) Lte(v T) Filter { return cmp(FilterLte, f.name, metaValueOf(v)) }
+
+// Gt matches vectors whose field is greater than v
+func (f FieldRef[T]) Gt(v T) Filter { return cmp(FilterGt, f.name, metaValueOf(v)) }
+
+// Gte matches vectors whose field is greater than or equal to v
+func (f FieldRef[T]) Gte(v T) Filter { return cmp(FilterGte, f.name, metaValueOf(v)) }
+
+// Between matches vectors whose field lies in the closed range [lo, hi]
+func (f FieldRef[T]) Between(lo, hi T) Filter {
+	return And(f.Gte(lo), f.Lte(hi))
+}
+
+// In matches vectors whose field equals any of vs
+func (f FieldRef[T]) In(vs ...T) Filter {
+	children := make([]Filter, len(vs))
+	for i, v := range vs {
+		children[i] = f.Eq(v)
+	}
+	return Or(children...)
+}
+
+// And combines f with others so that all must match
+func (f Filter) And(others ...Filter) Filter {
+	return combine(FilterAnd, f, others)
+}
+
+// Or combines f with others so that at least one must match
+func (f Filter) Or(others ...Filter) Filter {
+	return combine(FilterOr, f, others)
+}
+
+// Not negates a filter
+func Not(f Filter) Filter { return Filter{op: FilterNot, children: []Filter{f}} }
+
+// combine flattens nested expressions of the same operator so long chains
+// stay shallow on the wire
+func combine(op FilterOp, f Filter, others []Filter) Filter {
+	var children []Filter
+	if f.op == op {
+		children = append(children, f.children...)
+	} else {
+		children = append(children, f)
+	}
+	for _, o := range others {
+		if o.op == op {
+			children = append(children, o.children...)
+		} else {
+			children = append(children, o)
+		}
+	}
+	return Filter{op: op, children: children}
+}
+
+// Fields returns the distinct field names referenced by the expression
+func (f Filter) Fields() []string {
+	seen := make(map[string]bool)
+	var names []string
+	f.walk(func(c Filter) {
+		if c.field != "" && !seen[c.field] {
+			seen[c.field] = true
+			names = append(names, c.field)
+		}
+	})
+	return names
+}
+
+// Validate checks the expression against a set of known fields and types,
+// typically taken from a collection schema
+func (f Filter) Validate(fields map[string]MetaType) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	var err error
+	f.walk(func(c Filter) {
+		if err != nil || c.field == "" {
+			return
+		}
+		typ, ok := fields[c.field]
+		if !ok {
+			err = fmt.Errorf("filter references unknown field %q", c.field)
+			return
+		}
+		if typ != c.value.typ {
+			err = fmt.Errorf("filter field %q is %s, compared with %s", c.field, typ, c.value.typ)
+		}
+	})
+	return err
+}
+
+// MarshalBinary encodes the expression in the server's filter format
+func (f Filter) MarshalBinary() ([]byte, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return f.appendTo(nil)
+}
+
+func (f Filter) walk(fn func(Filter)) {
+	fn(f)
+	for _, c := range f.children {
+		c.walk(fn)
+	}
+}
+
+// metaValueOf converts a Go scalar into its metadata representation
+func metaValueOf[T Scalar](v T) MetaValue {
+	switch x := any(v).(type) {
+	case string:
+		return StringValue(x)
+	case int:
+		return IntValue(int64(x))
+	case int64:
+		return IntValue(x)
+	case float64:
+		return FloatValue(x)
+	case bool:
+		return BoolValue(x)
+	case time.Time:
+		return TimeValue(x)
+	}
+	panic("unreachable")
+}
@@ -0,0 +1,140 @@
+package celrix
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// flushScanCount is the SCAN batch size used by the FlushPrefix fallback;
+// each batch is deleted in one pipeline
+const flushScanCount = 500
+
+// FlushPrefix deletes every key starting with prefix in the connection's
+// current database and returns how many were deleted, so tests and tenant
+// offboarding can clear their own keys without FLUSHDB.
+//
+// Servers without FLUSHPREFIX are handled by scanning for the prefix and
+// deleting each batch of matches in a pipeline. The fallback is not atomic:
+// keys written under the prefix while it runs may survive, and on error the
+// count covers the keys deleted so far.
+func (a *Admin) FlushPrefix(prefix string) (int64, error) {
+	if prefix == "" {
+		return 0, errors.New("flush prefix is empty; use FlushDB to flush everything")
+	}
+	payload := appendString(nil, prefix)
+	if a.dryRun != nil {
+		return 0, a.simulate(OpFlushPrefix, prefix, payload)
+	}
+
+	n, err := a.flushPrefix(prefix, payload)
+	return n, a.record(OpFlushPrefix, prefix, fmt.Sprintf("deleted=%d", n), err)
+}
+
+func (a *Admin) flushPrefix(prefix string, payload []byte) (int64, error) {
+	err := a.c.sendFrame(OpFlushPrefix, payload)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := a.c.readResponse()
+	if err == nil {
+		n, ok := resp.(int64)
+		if !ok {
+			return 0, a.c.unexpectedReply()
+		}
+		a.c.vectors.forgetPrefix(prefix)
+		return n, nil
+	}
+	if !isServerError(err) {
+		return 0, err
+	}
+
+	// Older server: the glob is only a hint, since prefixes containing
+	// pattern characters cannot be matched exactly, so every key is checked
+	match := prefix + "*"
+	if strings.ContainsAny(prefix, `*?[]\`) {
+		match = ""
+	}
+	var (
+		cursor  uint64
+		deleted int64
+	)
+	for {
+		next, keys, err := a.c.Scan(cursor, match, flushScanCount)
+		if err != nil {
+			return deleted, err
+		}
+		matched := keys[:0]
+		for _, k := range keys {
+			if strings.HasPrefix(k, prefix) {
+				matched = append(matched, k)
+			}
+		}
+		n, err := a.c.delPipelined(matched)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		if next == 0 {
+			return deleted, nil
+		}
+		cursor = next
+	}
+}
+
+// delPipelined deletes keys with one pipelined round trip and returns how
+// many existed
+func (c *Client) delPipelined(keys []string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	c.pendingOp, c.pendingKey = OpDel, ""
+	if err := c.ready([]uint8{OpDel}, nil); err != nil {
+		return 0, err
+	}
+
+	ids := make([]uint64, len(keys))
+	for i, key := range keys {
+		c.spend(4 + len(key))
+		c.vectors.forget(key)
+		id, err := c.queueFrame(OpDel, appendString(nil, key))
+		if err != nil {
+			return 0, c.cmdErr(err)
+		}
+		ids[i] = id
+	}
+	if err := c.rw.Flush(); err != nil {
+		return 0, c.cmdErr(err)
+	}
+
+	// Every reply is read, even past a server error, so the connection
+	// stays in step
+	var (
+		deleted  int64
+		firstErr error
+	)
+	for i, key := range keys {
+		c.pendingOp, c.pendingReqID, c.pendingKey = OpDel, ids[i], key
+		f, err := c.recvFrame()
+		if err != nil {
+			return deleted, err
+		}
+		if f.reqID != ids[i] {
+			return deleted, c.desync(&f, "reply to another request", nil)
+		}
+		reply, err := decodeReply(f.opcode, f.payload)
+		if err != nil {
+			return deleted, c.cmdErr(err)
+		}
+		if rerr := reply.Err(); rerr != nil {
+			if firstErr == nil {
+				firstErr = c.cmdErr(rerr)
+			}
+			continue
+		}
+		if ok, _ := reply.Bool(); ok {
+			deleted++
+		}
+	}
+	return deleted, firstErr
+}
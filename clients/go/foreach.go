@@ -0,0 +1,73 @@
+package celrix
+
+// forEachWindow is the number of GETs ForEach keeps in flight
+const forEachWindow = 256
+
+// ForEach fetches every key and calls fn with each reply as it arrives, in
+// key order. Requests are pipelined with a bounded window, so memory stays
+// flat however many keys are passed. Missing keys arrive as nil replies and
+// per-key server errors as error replies; neither stops the iteration.
+//
+// If fn returns an error, no further requests are sent, replies already in
+// flight are read and discarded, and the error is returned. Adaptive
+// timeouts do not apply to pipelined requests. With WithZeroCopyReads each
+// reply is only valid until fn returns.
+func (c *Client) ForEach(keys []string, fn func(key string, val Reply) error) error {
+	c.pendingOp, c.pendingKey = OpGet, ""
+	if err := c.ready([]uint8{OpGet}, nil); err != nil {
+		return err
+	}
+
+	var (
+		sent, recv int
+		ids        = make([]uint64, len(keys))
+		stop       error
+	)
+	// refill queues requests up to the window and flushes them
+	refill := func() error {
+		if stop != nil || sent == len(keys) || sent-recv > forEachWindow/2 {
+			return nil
+		}
+		for sent < len(keys) && sent-recv < forEachWindow {
+			if c.opts.hotKeys != nil {
+				c.opts.hotKeys.hit(keys[sent])
+			}
+			c.spend(4 + len(keys[sent]))
+			id, err := c.queueFrame(OpGet, appendString(nil, keys[sent]))
+			if err != nil {
+				return err
+			}
+			ids[sent] = id
+			sent++
+		}
+		return c.rw.Flush()
+	}
+
+	for recv < len(keys) {
+		if err := refill(); err != nil {
+			c.pendingOp, c.pendingKey = OpGet, ""
+			return c.cmdErr(err)
+		}
+		if recv == sent {
+			break
+		}
+		c.pendingOp, c.pendingReqID, c.pendingKey = OpGet, ids[recv], keys[recv]
+		f, err := c.recvReplyFrame()
+		if err != nil {
+			return err
+		}
+		if f.reqID != ids[recv] {
+			return c.desync(&f, "reply to another request", nil)
+		}
+		reply, err := decodeReply(f.opcode, f.payload)
+		if err != nil {
+			return c.cmdErr(err)
+		}
+		key := keys[recv]
+		recv++
+		if stop == nil {
+			stop = fn(key, c.withAttributes(reply))
+		}
+	}
+	return stop
+}
@@ -0,0 +1,122 @@
+package celrix
+
+import "fmt"
+
+// GetOrNilFast fetches several keys in one round trip and returns their
+// values in key order, with nil for keys that do not exist. It uses the
+// server's MGET when available and otherwise pipelines one GET per key,
+// which still costs a single round trip.
+//
+// A server error for one key fails the whole call.
+func (c *Client) GetOrNilFast(keys ...string) ([]*string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if !c.noMGet {
+		vals, err := c.mget(keys)
+		if !isServerError(err) {
+			return vals, err
+		}
+		// Servers without MGET refuse it; per-key errors arrive inside
+		// the array instead
+		c.noMGet = true
+	}
+
+	var arena *batchArena
+	var vals []*string
+	if c.opts.batchArena {
+		arena = newBatchArena(len(keys))
+	} else {
+		vals = make([]*string, 0, len(keys))
+	}
+	err := c.ForEach(keys, func(key string, r Reply) error {
+		if err := r.Err(); err != nil {
+			return c.cmdErr(err)
+		}
+		b, ok := r.Bytes()
+		switch {
+		case arena != nil && ok:
+			arena.add(b)
+		case arena != nil:
+			arena.addNil()
+		case ok:
+			s := string(b)
+			vals = append(vals, &s)
+		default:
+			vals = append(vals, nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if arena != nil {
+		return arena.values(), nil
+	}
+	return vals, nil
+}
+
+// mget sends MGET, [count u32][key...], answered by a typed array of
+// value and nil elements in key order
+func (c *Client) mget(keys []string) ([]*string, error) {
+	if c.opts.hotKeys != nil {
+		for _, key := range keys {
+			c.opts.hotKeys.hit(key)
+		}
+	}
+	if err := c.sendFrame(OpMGet, appendStrings(nil, keys)); err != nil {
+		return nil, err
+	}
+	if c.opts.batchArena {
+		return c.mgetArena(keys)
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := resp.([]interface{})
+	if !ok || len(arr) != len(keys) {
+		return nil, c.cmdErr(fmt.Errorf("expected %d values, got %v", len(keys), resp))
+	}
+	vals := make([]*string, len(arr))
+	for i, v := range arr {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			vals[i] = &v
+		case error:
+			c.pendingKey = keys[i]
+			return nil, c.cmdErr(v)
+		default:
+			return nil, c.cmdErr(fmt.Errorf("value %d: unexpected type %T", i, v))
+		}
+	}
+	return vals, nil
+}
+
+// mgetArena reads the MGET reply for WithBatchArena, slicing every value
+// out of one copy of the frame
+func (c *Client) mgetArena(keys []string) ([]*string, error) {
+	f, err := c.recvFrame()
+	if err != nil {
+		return nil, err
+	}
+	if f.opcode != OpTypedArray {
+		_, err := decodeResponse(f.opcode, f.payload)
+		if err == nil {
+			err = fmt.Errorf("unexpected response opcode %s", Op(f.opcode))
+		}
+		return nil, c.cmdErr(err)
+	}
+	vals, i, err := decodeValuesArena(f.payload)
+	if err != nil {
+		if i >= 0 {
+			c.pendingKey = keys[i]
+		}
+		return nil, c.cmdErr(err)
+	}
+	if len(vals) != len(keys) {
+		return nil, c.cmdErr(fmt.Errorf("expected %d values, got %d", len(keys), len(vals)))
+	}
+	return vals, nil
+}
@@ -0,0 +1,83 @@
+package celrix
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// handshake offers the configured protocol versions and switches the
+// connection to the layout the server selects. HELLO is always sent with
+// the version 1 layout, which every server understands.
+//
+// Request:  [count: u8][version: u8]...[nonce: 32]?[labels]?
+// Response: OpValue [version: u8][identity]?
+//
+// The nonce and identity are present only when server pins are configured;
+// see WithServerPin. Connection labels, [count: u32]([name][value])...,
+// follow an all-zero nonce when no pins are configured; see
+// WithConnLabels.
+func (c *Client) handshake() error {
+	versions := c.opts.versions
+	if len(versions) == 0 {
+		versions = []uint8{wire.Version}
+	}
+	payload := []byte{byte(len(versions))}
+	for _, v := range versions {
+		if _, err := wire.LayoutFor(v); err != nil {
+			return err
+		}
+		payload = append(payload, v)
+	}
+	var nonce []byte
+	if len(c.opts.pins) > 0 {
+		var err error
+		if nonce, err = newHelloNonce(); err != nil {
+			return err
+		}
+		payload = append(payload, nonce...)
+	}
+	if len(c.opts.connLabels) > 0 {
+		if nonce == nil {
+			payload = append(payload, make([]byte, helloNonceSize)...)
+		}
+		payload = appendLabels(payload, c.opts.connLabels)
+	}
+	if err := c.sendFrame(OpHello, payload); err != nil {
+		return err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return err
+	}
+	raw, ok := resp.(string)
+	if !ok || len(raw) < 1 {
+		return fmt.Errorf("unexpected HELLO response: %v", resp)
+	}
+
+	chosen := raw[0]
+	offered := false
+	for _, v := range versions {
+		offered = offered || v == chosen
+	}
+	if !offered {
+		return errors.New("server selected a protocol version that was not offered")
+	}
+	if nonce != nil {
+		if err := c.verifyIdentity([]byte(raw[1:]), nonce, chosen); err != nil {
+			return err
+		}
+	}
+	layout, err := wire.LayoutFor(chosen)
+	if err != nil {
+		return err
+	}
+	c.layout = layout
+	return nil
+}
+
+// ProtocolVersion returns the frame layout version in use on the connection
+func (c *Client) ProtocolVersion() uint8 {
+	return c.layout.Version()
+}
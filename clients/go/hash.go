@@ -0,0 +1,144 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// ErrNotFound is returned by Hash for a key that does not exist
+var ErrNotFound = errors.New("celrix: key not found")
+
+// Hash returns the server-computed hash of the value stored under key, so
+// two copies of a value can be compared without transferring either. The
+// hash is 64-bit XXH64 with seed zero over the stored bytes, which for
+// string values matches HashValue.
+func (c *Client) Hash(key string) (uint64, error) {
+	if err := c.sendKeyed(OpHash, key, appendString(nil, key)); err != nil {
+		return 0, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return 0, err
+	}
+	switch v := resp.(type) {
+	case nil:
+		return 0, c.cmdErr(ErrNotFound)
+	case int64:
+		return uint64(v), nil
+	default:
+		return 0, c.unexpectedReply()
+	}
+}
+
+// Hashes is Hash for several keys in one round trip, returning the hashes
+// in key order with nil for keys that do not exist
+func (c *Client) Hashes(keys ...string) ([]*uint64, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	// Payload: [count u32][key...], answered by a typed array of integer
+	// and nil elements in key order
+	if err := c.sendFrame(OpMHash, appendStrings(nil, keys)); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := resp.([]interface{})
+	if !ok || len(arr) != len(keys) {
+		return nil, c.cmdErr(fmt.Errorf("expected %d hashes, got %v", len(keys), resp))
+	}
+	hashes := make([]uint64, len(arr))
+	out := make([]*uint64, len(arr))
+	for i, v := range arr {
+		switch v := v.(type) {
+		case nil:
+		case int64:
+			hashes[i] = uint64(v)
+			out[i] = &hashes[i]
+		case error:
+			c.pendingKey = keys[i]
+			return nil, c.cmdErr(v)
+		default:
+			return nil, c.cmdErr(fmt.Errorf("hash %d: unexpected type %T", i, v))
+		}
+	}
+	return out, nil
+}
+
+// HashValue computes locally the hash Hash reports for a string value, to
+// check a value held by the application against the server's copy
+func HashValue(value []byte) uint64 {
+	return xxh64(value)
+}
+
+// XXH64 primes, variables so that the seed setup may wrap around
+var (
+	prime64x1 uint64 = 11400714785074694791
+	prime64x2 uint64 = 14029467366897019727
+	prime64x3 uint64 = 1609587929392839161
+	prime64x4 uint64 = 9650029242287828579
+	prime64x5 uint64 = 2870177450012600261
+)
+
+// xxh64 is XXH64 with seed zero
+func xxh64(b []byte) uint64 {
+	n := len(b)
+	var h uint64
+	if n >= 32 {
+		v1 := prime64x1 + prime64x2
+		v2 := prime64x2
+		v3 := uint64(0)
+		v4 := -prime64x1
+		for len(b) >= 32 {
+			v1 = xxhRound(v1, binary.LittleEndian.Uint64(b[0:]))
+			v2 = xxhRound(v2, binary.LittleEndian.Uint64(b[8:]))
+			v3 = xxhRound(v3, binary.LittleEndian.Uint64(b[16:]))
+			v4 = xxhRound(v4, binary.LittleEndian.Uint64(b[24:]))
+			b = b[32:]
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxhMerge(h, v1)
+		h = xxhMerge(h, v2)
+		h = xxhMerge(h, v3)
+		h = xxhMerge(h, v4)
+	} else {
+		h = prime64x5
+	}
+	h += uint64(n)
+
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxhRound(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*prime64x1 + prime64x4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * prime64x1
+		h = bits.RotateLeft64(h, 23)*prime64x2 + prime64x3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * prime64x5
+		h = bits.RotateLeft64(h, 11) * prime64x1
+	}
+
+	h ^= h >> 33
+	h *= prime64x2
+	h ^= h >> 29
+	h *= prime64x3
+	h ^= h >> 32
+	return h
+}
+
+func xxhRound(acc, input uint64) uint64 {
+	acc += input * prime64x2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * prime64x1
+}
+
+func xxhMerge(acc, v uint64) uint64 {
+	acc ^= xxhRound(0, v)
+	return acc*prime64x1 + prime64x4
+}
@@ -0,0 +1,86 @@
+package celrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HealthStatus is the server's assessment of itself or one of its checks
+type HealthStatus string
+
+// Health statuses reported by the server
+const (
+	HealthHealthy   HealthStatus = "healthy"
+	HealthDegraded  HealthStatus = "degraded"
+	HealthUnhealthy HealthStatus = "unhealthy"
+)
+
+// Health is the server's health report
+type Health struct {
+	Status  HealthStatus
+	Uptime  time.Duration
+	Version string
+	Checks  []HealthCheck
+}
+
+// HealthCheck is the result of one server-side check
+type HealthCheck struct {
+	Name     string
+	Status   HealthStatus
+	Duration time.Duration
+	Message  string
+}
+
+// Ready reports whether every server check is healthy
+func (h Health) Ready() bool { return h.Status == HealthHealthy }
+
+// healthReport is the JSON body of a HEALTH reply
+type healthReport struct {
+	Status     HealthStatus `json:"status"`
+	UptimeSecs int64        `json:"uptime_secs"`
+	Version    string       `json:"version"`
+	Checks     []struct {
+		Name       string       `json:"name"`
+		Status     HealthStatus `json:"status"`
+		DurationMs int64        `json:"duration_ms"`
+		Message    string       `json:"message"`
+	} `json:"checks"`
+}
+
+// Health runs the server's health checks and returns the report. A server
+// that answers at all is live; Ready tells whether it should receive
+// traffic.
+func (c *Client) Health() (Health, error) {
+	if err := c.sendFrame(OpHealth, nil); err != nil {
+		return Health{}, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return Health{}, err
+	}
+	raw, ok := resp.(string)
+	if !ok {
+		return Health{}, c.unexpectedReply()
+	}
+
+	var r healthReport
+	if err := json.Unmarshal([]byte(raw), &r); err != nil {
+		return Health{}, fmt.Errorf("decode health report: %w", err)
+	}
+	h := Health{
+		Status:  r.Status,
+		Uptime:  time.Duration(r.UptimeSecs) * time.Second,
+		Version: r.Version,
+		Checks:  make([]HealthCheck, len(r.Checks)),
+	}
+	for i, ch := range r.Checks {
+		h.Checks[i] = HealthCheck{
+			Name:     ch.Name,
+			Status:   ch.Status,
+			Duration: time.Duration(ch.DurationMs) * time.Millisecond,
+			Message:  ch.Message,
+		}
+	}
+	return h, nil
+}
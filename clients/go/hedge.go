@@ -0,0 +1,257 @@
+package celrix
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HedgeOptions configures a HedgedClient
+type HedgeOptions struct {
+	// Percentile of the connection's own latency for the command after
+	// which a duplicate is sent. Defaults to 95.
+	Percentile float64
+	// MinDelay is the shortest wait before hedging, so that very fast
+	// commands are not duplicated on noise. Defaults to 1ms.
+	MinDelay time.Duration
+}
+
+// HedgeStats counts hedged reads
+type HedgeStats struct {
+	Reads  uint64
+	Hedges uint64
+	// Wins counts hedges whose duplicate answered first
+	Wins uint64
+}
+
+// HedgedClient cuts read tail latency by hedging: if a read has not
+// returned after the connection's pX latency for that command, the same
+// read is sent on another idle connection, which may point at a replica,
+// and the first reply wins.
+//
+// The losing request cannot be withdrawn from the wire. Its reply is read
+// and discarded in the background, and its connection is busy until then.
+// Writes are never hedged; they always go to the first connection.
+//
+// Hedging starts once a connection has enough samples for the command (see
+// WithAdaptiveTimeout for the warmup threshold); before that, reads are sent
+// once.
+type HedgedClient struct {
+	conns []*hedgeConn
+	opts  HedgeOptions
+	next  atomic.Uint32
+
+	reads  atomic.Uint64
+	hedges atomic.Uint64
+	wins   atomic.Uint64
+}
+
+type hedgeConn struct {
+	mu sync.Mutex
+	c  *Client
+}
+
+// NewHedgedClient hedges reads across conns. conns[0] is the primary and
+// receives all writes; the rest may be extra connections to the same server
+// or to read replicas.
+func NewHedgedClient(conns []*Client, opts HedgeOptions) (*HedgedClient, error) {
+	if len(conns) == 0 {
+		return nil, errors.New("celrix: hedged client needs at least one connection")
+	}
+	if opts.Percentile <= 0 || opts.Percentile > 100 {
+		opts.Percentile = 95
+	}
+	if opts.MinDelay <= 0 {
+		opts.MinDelay = time.Millisecond
+	}
+	h := &HedgedClient{opts: opts}
+	for _, c := range conns {
+		h.conns = append(h.conns, &hedgeConn{c: c})
+	}
+	return h, nil
+}
+
+// Stats returns a snapshot of the hedging counters
+func (h *HedgedClient) Stats() HedgeStats {
+	return HedgeStats{Reads: h.reads.Load(), Hedges: h.hedges.Load(), Wins: h.wins.Load()}
+}
+
+// Close waits for outstanding requests and closes every connection
+func (h *HedgedClient) Close() error {
+	var first error
+	for _, hc := range h.conns {
+		hc.mu.Lock()
+		if err := hc.c.Close(); err != nil && first == nil {
+			first = err
+		}
+		hc.mu.Unlock()
+	}
+	return first
+}
+
+// Ping checks the primary
+func (h *HedgedClient) Ping() error {
+	return h.primary(func(c *Client) error { return c.Ping() })
+}
+
+// Set writes to the primary
+func (h *HedgedClient) Set(key, value string) error {
+	return h.primary(func(c *Client) error { return c.Set(key, value) })
+}
+
+// Del deletes on the primary
+func (h *HedgedClient) Del(key string) (deleted bool, err error) {
+	err = h.primary(func(c *Client) error {
+		deleted, err = c.Del(key)
+		return err
+	})
+	return deleted, err
+}
+
+// VAdd writes to the primary
+func (h *HedgedClient) VAdd(key string, vector []float32) error {
+	return h.primary(func(c *Client) error { return c.VAdd(key, vector) })
+}
+
+// Get is a hedged read
+func (h *HedgedClient) Get(key string) (string, bool, error) {
+	type result struct {
+		val string
+		ok  bool
+	}
+	v, err := h.hedge(OpGet, func(c *Client) (interface{}, error) {
+		val, ok, err := c.Get(key)
+		return result{val, ok}, err
+	})
+	r, _ := v.(result)
+	return r.val, r.ok, err
+}
+
+// VGet is a hedged read
+func (h *HedgedClient) VGet(key string) (VectorItem, bool, error) {
+	type result struct {
+		item VectorItem
+		ok   bool
+	}
+	v, err := h.hedge(OpVGet, func(c *Client) (interface{}, error) {
+		item, ok, err := c.VGet(key)
+		return result{item, ok}, err
+	})
+	r, _ := v.(result)
+	return r.item, r.ok, err
+}
+
+// VSearch is a hedged read
+func (h *HedgedClient) VSearch(vector []float32, k int, opts *VSearchOptions) ([]VectorMatch, error) {
+	v, err := h.hedge(OpVSearch, func(c *Client) (interface{}, error) {
+		return c.VSearch(vector, k, opts)
+	})
+	matches, _ := v.([]VectorMatch)
+	return matches, err
+}
+
+// VSearchFilter is a hedged read
+func (h *HedgedClient) VSearchFilter(vector []float32, k int, filter Filter) ([]string, error) {
+	v, err := h.hedge(OpVSearchFilter, func(c *Client) (interface{}, error) {
+		return c.VSearchFilter(vector, k, filter)
+	})
+	keys, _ := v.([]string)
+	return keys, err
+}
+
+func (h *HedgedClient) primary(fn func(c *Client) error) error {
+	hc := h.conns[0]
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	return fn(hc.c)
+}
+
+type hedgeResult struct {
+	conn *hedgeConn
+	val  interface{}
+	err  error
+}
+
+// hedge runs fn on one connection and, if it is slow, again on another
+func (h *HedgedClient) hedge(opcode uint8, fn func(c *Client) (interface{}, error)) (interface{}, error) {
+	h.reads.Add(1)
+	first := h.acquire()
+	delay := h.delay(first.c, opcode)
+
+	// Buffered so the loser can deliver and exit after the caller returns
+	results := make(chan hedgeResult, 2)
+	run := func(hc *hedgeConn) {
+		defer hc.mu.Unlock()
+		var v interface{}
+		err := hc.c.supervised("hedged read", func() (err error) {
+			v, err = fn(hc.c)
+			return err
+		})
+		results <- hedgeResult{conn: hc, val: v, err: err}
+	}
+	go run(first)
+
+	if delay > 0 {
+		timer := first.c.opts.timer(delay)
+		defer timer.Stop()
+		select {
+		case r := <-results:
+			return r.val, r.err
+		case <-timer.C():
+		}
+		if second := h.tryAcquire(first); second != nil {
+			h.hedges.Add(1)
+			go run(second)
+			r := <-results
+			if r.conn == second {
+				h.wins.Add(1)
+			}
+			return r.val, r.err
+		}
+	}
+	r := <-results
+	return r.val, r.err
+}
+
+// delay is how long to wait for c before hedging, or 0 to never hedge
+func (h *HedgedClient) delay(c *Client, opcode uint8) time.Duration {
+	if len(h.conns) < 2 {
+		return 0
+	}
+	p, n := c.latency.percentile(opcode, h.opts.Percentile)
+	if n < adaptiveWarmup {
+		return 0
+	}
+	if p < h.opts.MinDelay {
+		return h.opts.MinDelay
+	}
+	return p
+}
+
+// acquire locks an idle connection, starting from a rotating position, or
+// waits for one if all are busy
+func (h *HedgedClient) acquire() *hedgeConn {
+	start := int(h.next.Add(1))
+	for i := range h.conns {
+		hc := h.conns[(start+i)%len(h.conns)]
+		if hc.mu.TryLock() {
+			return hc
+		}
+	}
+	hc := h.conns[start%len(h.conns)]
+	hc.mu.Lock()
+	return hc
+}
+
+// tryAcquire locks an idle connection other than skip, or returns nil
+func (h *HedgedClient) tryAcquire(skip *hedgeConn) *hedgeConn {
+	start := int(h.next.Add(1))
+	for i := range h.conns {
+		hc := h.conns[(start+i)%len(h.conns)]
+		if hc != skip && hc.mu.TryLock() {
+			return hc
+		}
+	}
+	return nil
+}
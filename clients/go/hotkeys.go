@@ -0,0 +1,158 @@
+package celrix
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// HotKey is a key with its access count
+type HotKey struct {
+	Key  string
+	Hits uint64
+}
+
+// HotKeys returns the server's topN most frequently accessed keys, hottest
+// first. Counts come from the server's access-frequency tracking and are
+// approximate.
+func (a *Admin) HotKeys(topN int) ([]HotKey, error) {
+	if topN <= 0 {
+		return nil, fmt.Errorf("topN must be positive, got %d", topN)
+	}
+	if err := a.c.sendFrame(OpHotKeys, binary.BigEndian.AppendUint32(nil, uint32(topN))); err != nil {
+		return nil, err
+	}
+	resp, err := a.c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	records, err := toKeys(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each element: [key_len][key][hits u64]
+	keys := make([]HotKey, len(records))
+	for i, rec := range records {
+		b := []byte(rec)
+		key, n, err := readString(b)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) < n+8 {
+			return nil, errors.New("incomplete hot key record")
+		}
+		keys[i] = HotKey{Key: key, Hits: binary.BigEndian.Uint64(b[n:])}
+	}
+	return keys, nil
+}
+
+// WithLocalHotKeys counts this client's own per-key calls, reported by
+// HotKeysLocal. At most capacity keys are tracked; when full, the least
+// counted key is replaced (the Space-Saving algorithm), so counts are upper
+// bounds and truly hot keys are never lost.
+func WithLocalHotKeys(capacity int) Option {
+	return func(o *options) {
+		if capacity > 0 {
+			o.hotKeys = newHotKeyTracker(capacity)
+		}
+	}
+}
+
+// HotKeysLocal returns the keys this client has called most often, hottest
+// first. It returns nil unless the client was created with
+// WithLocalHotKeys.
+func (c *Client) HotKeysLocal() []HotKey {
+	if c.opts.hotKeys == nil {
+		return nil
+	}
+	return c.opts.hotKeys.top()
+}
+
+// hotKeyTracker keeps its counters in a min-heap as well as a map, so the
+// least counted key, which a miss replaces, is found in O(log capacity)
+type hotKeyTracker struct {
+	mu       sync.Mutex
+	capacity int
+	counts   map[string]*hotKeyCount
+	heap     hotKeyHeap
+}
+
+type hotKeyCount struct {
+	key   string
+	hits  uint64
+	index int
+}
+
+// hotKeyHeap orders counters by hits, least first
+type hotKeyHeap []*hotKeyCount
+
+func (h hotKeyHeap) Len() int           { return len(h) }
+func (h hotKeyHeap) Less(i, j int) bool { return h[i].hits < h[j].hits }
+func (h hotKeyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *hotKeyHeap) Push(x interface{}) {
+	e := x.(*hotKeyCount)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *hotKeyHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+func newHotKeyTracker(capacity int) *hotKeyTracker {
+	return &hotKeyTracker{
+		capacity: capacity,
+		counts:   make(map[string]*hotKeyCount, capacity),
+		heap:     make(hotKeyHeap, 0, capacity),
+	}
+}
+
+func (t *hotKeyTracker) hit(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.counts[key]; ok {
+		e.hits++
+		heap.Fix(&t.heap, e.index)
+		return
+	}
+	if len(t.heap) < t.capacity {
+		e := &hotKeyCount{key: key, hits: 1}
+		t.counts[key] = e
+		heap.Push(&t.heap, e)
+		return
+	}
+	// Replace the least counted key, inheriting its count
+	e := t.heap[0]
+	delete(t.counts, e.key)
+	e.key = key
+	e.hits++
+	t.counts[key] = e
+	heap.Fix(&t.heap, 0)
+}
+
+func (t *hotKeyTracker) top() []HotKey {
+	t.mu.Lock()
+	out := make([]HotKey, 0, len(t.heap))
+	for _, e := range t.heap {
+		out = append(out, HotKey{Key: e.key, Hits: e.hits})
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Hits != out[j].Hits {
+			return out[i].Hits > out[j].Hits
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
@@ -0,0 +1,290 @@
+// Package dataset reads embedding datasets in the JSONL and CSV layouts
+// shared by the celrix-load and celrix-verify commands, and finds exact
+// nearest neighbours in them for recall checks.
+package dataset
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// Source yields records from an input file
+type Source interface {
+	// Next returns up to n records; err is io.EOF after the last record
+	Next(n int) ([]celrix.VectorItem, error)
+	// Skip discards n records
+	Skip(n int64) error
+	// Size is the input size in bytes, for progress reporting
+	Size() int64
+	// Offset is the number of input bytes consumed so far
+	Offset() int64
+	Close() error
+}
+
+// DetectFormat returns format if set, otherwise a format inferred from the
+// file extension
+func DetectFormat(format, path string) string {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".parquet":
+		return "parquet"
+	default:
+		return "jsonl"
+	}
+}
+
+// Open opens path as a jsonl or csv dataset
+func Open(path, format string) (Source, error) {
+	if format == "parquet" {
+		return nil, errors.New("parquet input is not supported by this build; convert to JSONL (e.g. with duckdb or pyarrow) and load that")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	cr := &countingReader{r: f}
+	br := bufio.NewReaderSize(cr, 1<<20)
+
+	switch format {
+	case "jsonl":
+		return &jsonlSource{f: f, cr: cr, br: br, size: st.Size()}, nil
+	case "csv":
+		s := &csvSource{f: f, cr: cr, r: csv.NewReader(br), size: st.Size()}
+		s.r.ReuseRecord = true
+		if err := s.readHeader(); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return s, nil
+	default:
+		f.Close()
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type jsonlRecord struct {
+	Key      string                 `json:"key"`
+	Vector   []float32              `json:"vector"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+type jsonlSource struct {
+	f    *os.File
+	cr   *countingReader
+	br   *bufio.Reader
+	size int64
+	line int64
+}
+
+func (s *jsonlSource) readLine() ([]byte, error) {
+	for {
+		line, err := s.br.ReadBytes('\n')
+		if len(line) > 0 {
+			s.line++
+			if trimmed := strings.TrimSpace(string(line)); trimmed != "" {
+				return []byte(trimmed), nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *jsonlSource) Next(n int) ([]celrix.VectorItem, error) {
+	items := make([]celrix.VectorItem, 0, n)
+	for len(items) < n {
+		line, err := s.readLine()
+		if err != nil {
+			return items, err
+		}
+		var rec jsonlRecord
+		dec := json.NewDecoder(strings.NewReader(string(line)))
+		dec.UseNumber()
+		if err := dec.Decode(&rec); err != nil {
+			return items, fmt.Errorf("line %d: %w", s.line, err)
+		}
+		item, err := toItem(rec.Key, rec.Vector, rec.Metadata)
+		if err != nil {
+			return items, fmt.Errorf("line %d: %w", s.line, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *jsonlSource) Skip(n int64) error {
+	for i := int64(0); i < n; i++ {
+		if _, err := s.readLine(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *jsonlSource) Size() int64   { return s.size }
+func (s *jsonlSource) Offset() int64 { return s.cr.n - int64(s.br.Buffered()) }
+func (s *jsonlSource) Close() error  { return s.f.Close() }
+
+func toItem(key string, vector []float32, raw map[string]interface{}) (celrix.VectorItem, error) {
+	if key == "" {
+		return celrix.VectorItem{}, errors.New("record has no key")
+	}
+	if len(vector) == 0 {
+		return celrix.VectorItem{}, fmt.Errorf("record %q has no vector", key)
+	}
+	var meta celrix.Metadata
+	if len(raw) > 0 {
+		meta = make(celrix.Metadata, len(raw))
+		for k, v := range raw {
+			// JSON already carries number and bool types; only
+			// timestamps need recovering from strings
+			if s, ok := v.(string); ok {
+				if mv := celrix.ParseMetaValue(s); mv.Type() == celrix.MetaTime {
+					meta[k] = mv
+				} else {
+					meta[k] = celrix.StringValue(s)
+				}
+				continue
+			}
+			mv, err := celrix.MetaValueOf(v)
+			if err != nil {
+				return celrix.VectorItem{}, fmt.Errorf("record %q field %q: %w", key, k, err)
+			}
+			meta[k] = mv
+		}
+	}
+	return celrix.VectorItem{Key: key, Vector: vector, Metadata: meta}, nil
+}
+
+type csvSource struct {
+	f      *os.File
+	cr     *countingReader
+	r      *csv.Reader
+	size   int64
+	keyCol int
+	vecCol int
+	fields []string
+}
+
+func (s *csvSource) readHeader() error {
+	header, err := s.r.Read()
+	if err != nil {
+		return fmt.Errorf("read CSV header: %w", err)
+	}
+	s.keyCol, s.vecCol = -1, -1
+	s.fields = make([]string, len(header))
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		switch strings.ToLower(h) {
+		case "key":
+			s.keyCol = i
+		case "vector":
+			s.vecCol = i
+		default:
+			s.fields[i] = h
+		}
+	}
+	if s.keyCol < 0 || s.vecCol < 0 {
+		return errors.New(`CSV header must contain "key" and "vector" columns`)
+	}
+	return nil
+}
+
+func (s *csvSource) Next(n int) ([]celrix.VectorItem, error) {
+	items := make([]celrix.VectorItem, 0, n)
+	for len(items) < n {
+		row, err := s.r.Read()
+		if err != nil {
+			return items, err
+		}
+		line, _ := s.r.FieldPos(0)
+		vec, err := parseVector(row[s.vecCol])
+		if err != nil {
+			return items, fmt.Errorf("line %d: %w", line, err)
+		}
+		meta := make(celrix.Metadata)
+		for i, name := range s.fields {
+			if name == "" || i >= len(row) || row[i] == "" {
+				continue
+			}
+			meta[name] = celrix.ParseMetaValue(row[i])
+		}
+		key := row[s.keyCol]
+		if key == "" {
+			return items, fmt.Errorf("line %d: record has no key", line)
+		}
+		items = append(items, celrix.VectorItem{Key: key, Vector: vec, Metadata: meta})
+	}
+	return items, nil
+}
+
+func (s *csvSource) Skip(n int64) error {
+	for i := int64(0); i < n; i++ {
+		if _, err := s.r.Read(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *csvSource) Size() int64 { return s.size }
+
+func (s *csvSource) Offset() int64 { return s.r.InputOffset() }
+
+func (s *csvSource) Close() error { return s.f.Close() }
+
+// parseVector accepts a JSON array or whitespace-separated floats
+func parseVector(cell string) ([]float32, error) {
+	cell = strings.TrimSpace(cell)
+	if strings.HasPrefix(cell, "[") {
+		var v []float32
+		if err := json.Unmarshal([]byte(cell), &v); err != nil {
+			return nil, fmt.Errorf("vector: %w", err)
+		}
+		return v, nil
+	}
+	parts := strings.Fields(cell)
+	if len(parts) == 0 {
+		return nil, errors.New("empty vector")
+	}
+	v := make([]float32, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(p, 32)
+		if err != nil {
+			return nil, fmt.Errorf("vector element %d: %w", i, err)
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
+}
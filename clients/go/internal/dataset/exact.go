@@ -0,0 +1,82 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+// Scorer orders vectors so that higher is nearer
+type Scorer func(a, b []float32) float64
+
+// ScorerFor returns the scorer for a metric. MetricDefault is taken as
+// cosine, the server's default.
+func ScorerFor(m celrix.Metric) (Scorer, error) {
+	switch m {
+	case celrix.MetricCosine, celrix.MetricDefault:
+		return cosine, nil
+	case celrix.MetricDot:
+		return dot, nil
+	case celrix.MetricL2:
+		return negL2, nil
+	default:
+		return nil, fmt.Errorf("unknown metric %v", m)
+	}
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func dot(a, b []float32) float64 {
+	var d float64
+	for i := range a {
+		d += float64(a[i]) * float64(b[i])
+	}
+	return d
+}
+
+// negL2 is the negated squared distance, so that nearer scores higher
+func negL2(a, b []float32) float64 {
+	var d float64
+	for i := range a {
+		x := float64(a[i]) - float64(b[i])
+		d += x * x
+	}
+	return -d
+}
+
+// TopK returns the keys of the k items nearest to query by brute force.
+// Items of another dimension are skipped.
+func TopK(items []celrix.VectorItem, query []float32, k int, score Scorer) map[string]bool {
+	type scored struct {
+		key   string
+		score float64
+	}
+	all := make([]scored, 0, len(items))
+	for _, it := range items {
+		if len(it.Vector) == len(query) {
+			all = append(all, scored{it.Key, score(query, it.Vector)})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].score > all[j].score })
+	if len(all) > k {
+		all = all[:k]
+	}
+	keys := make(map[string]bool, len(all))
+	for _, s := range all {
+		keys[s.key] = true
+	}
+	return keys
+}
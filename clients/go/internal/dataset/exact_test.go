@@ -0,0 +1,49 @@
+package dataset
+
+import (
+	"testing"
+
+	celrix "github.com/YASSERRMD/celrix/clients/go"
+)
+
+func TestTopK(t *testing.T) {
+	items := []celrix.VectorItem{
+		{Key: "east", Vector: []float32{1, 0}},
+		{Key: "far-east", Vector: []float32{10, 0}},
+		{Key: "north", Vector: []float32{0, 1}},
+		{Key: "west", Vector: []float32{-1, 0}},
+		{Key: "3d", Vector: []float32{1, 0, 0}},
+	}
+	query := []float32{1, 0.1}
+	tests := []struct {
+		metric celrix.Metric
+		want   []string
+	}{
+		// Cosine ignores magnitude, so east and far-east tie ahead of north
+		{celrix.MetricDefault, []string{"east", "far-east"}},
+		{celrix.MetricDot, []string{"far-east", "east"}},
+		{celrix.MetricL2, []string{"east", "north"}},
+	}
+	for _, tt := range tests {
+		score, err := ScorerFor(tt.metric)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := TopK(items, query, 2, score)
+		if len(got) != len(tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.metric, got, tt.want)
+			continue
+		}
+		for _, k := range tt.want {
+			if !got[k] {
+				t.Errorf("%v: got %v, want %v", tt.metric, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestScorerForUnknownMetric(t *testing.T) {
+	if _, err := ScorerFor(celrix.Metric(99)); err == nil {
+		t.Fatal("want an error for an unknown metric")
+	}
+}
@@ -0,0 +1,274 @@
+package celrix
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const journalFile = "celrix.journal"
+
+// ErrJournalInUse is returned when a client is given a write journal that
+// another client in the process already has open
+var ErrJournalInUse = errors.New("celrix: write journal in use by another client")
+
+// ErrJournalCorrupt is wrapped by the error ReplayJournal returns when the
+// journal holds an entry that fails its checksum
+var ErrJournalCorrupt = errors.New("celrix: write journal corrupt")
+
+// openJournals holds the paths of the journals open in the process. Each
+// journal belongs to one client, since replay rewrites the file under any
+// other writer.
+var openJournals = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: make(map[string]bool)}
+
+type journalEntry struct {
+	opcode  uint8
+	payload []byte
+}
+
+// writeJournal is an append-only log of writes awaiting delivery.
+// Entry layout: [len: u32][crc32: u32][opcode: u8][payload], where len
+// covers opcode and payload. A torn tail from a crash is ignored on read.
+type writeJournal struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+func openJournal(dir string) (*writeJournal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path, err := filepath.Abs(filepath.Join(dir, journalFile))
+	if err != nil {
+		return nil, err
+	}
+	openJournals.Lock()
+	defer openJournals.Unlock()
+	if openJournals.paths[path] {
+		return nil, fmt.Errorf("%w: %s", ErrJournalInUse, path)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	openJournals.paths[path] = true
+	return &writeJournal{path: path, f: f}, nil
+}
+
+func (j *writeJournal) append(opcode uint8, payload []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	buf := make([]byte, 9, 9+len(payload))
+	binary.BigEndian.PutUint32(buf[0:], uint32(1+len(payload)))
+	buf[8] = opcode
+	buf = append(buf, payload...)
+	binary.BigEndian.PutUint32(buf[4:], crc32.ChecksumIEEE(buf[8:]))
+
+	if _, err := j.f.Write(buf); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+func (j *writeJournal) entries() ([]journalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return readJournal(j.path)
+}
+
+// rewrite atomically replaces the journal contents with the given entries
+func (j *writeJournal) rewrite(entries []journalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tmp := j.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, e := range entries {
+		var hdr [8]byte
+		binary.BigEndian.PutUint32(hdr[0:], uint32(1+len(e.payload)))
+		crc := crc32.NewIEEE()
+		crc.Write([]byte{e.opcode})
+		crc.Write(e.payload)
+		binary.BigEndian.PutUint32(hdr[4:], crc.Sum32())
+		w.Write(hdr[:])
+		w.WriteByte(e.opcode)
+		w.Write(e.payload)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	j.f.Close()
+	if err := os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+	j.f, err = os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	return err
+}
+
+// setAside moves a corrupt journal out of the way for inspection and starts
+// an empty one, returning where the old contents went
+func (j *writeJournal) setAside() (string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	aside := fmt.Sprintf("%s.corrupt-%d", j.path, time.Now().UnixNano())
+	j.f.Close()
+	if err := os.Rename(j.path, aside); err != nil {
+		return "", err
+	}
+	var err error
+	j.f, err = os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	return aside, err
+}
+
+func (j *writeJournal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	openJournals.Lock()
+	delete(openJournals.paths, j.path)
+	openJournals.Unlock()
+	return j.f.Close()
+}
+
+func readJournal(path string) ([]journalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var entries []journalEntry
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return entries, nil
+			}
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(hdr[0:])
+		if n == 0 {
+			return entries, nil
+		}
+		body := make([]byte, n)
+		if _, err := io.ReadFull(r, body); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return entries, nil
+			}
+			return nil, err
+		}
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(hdr[4:]) {
+			return entries, fmt.Errorf("%w: entry %d: checksum mismatch", ErrJournalCorrupt, len(entries))
+		}
+		entries = append(entries, journalEntry{opcode: body[0], payload: body[1:]})
+	}
+}
+
+// journalFailure saves a write that failed in transit. Error replies from
+// the server are returned unchanged since retrying them cannot succeed.
+func (c *Client) journalFailure(opcode uint8, payload []byte, err error) error {
+	if err == nil || c.journal == nil || isServerError(err) {
+		return err
+	}
+	if jerr := c.journal.append(opcode, payload); jerr != nil {
+		return fmt.Errorf("%w (journal append failed: %v)", err, jerr)
+	}
+	return fmt.Errorf("%w: %v", ErrJournaled, err)
+}
+
+// ReplayJournal resends journaled writes in order and returns how many were
+// delivered. Entries rejected by the server are dropped; replay stops at the
+// first transport failure and the remaining entries stay journaled. It runs
+// on Connect and after every reconnection made under WithRetry.
+//
+// A journal with a corrupt entry is moved aside, keeping its undecodable
+// tail, and the entries before it are replayed; the error then wraps
+// ErrJournalCorrupt and names the file.
+func (c *Client) ReplayJournal() (int, error) {
+	if c.journal == nil {
+		return 0, nil
+	}
+	entries, corrupt := c.journal.entries()
+	if corrupt != nil {
+		if !errors.Is(corrupt, ErrJournalCorrupt) {
+			return 0, corrupt
+		}
+		aside, err := c.journal.setAside()
+		if err != nil {
+			return 0, fmt.Errorf("%w (moving journal aside failed: %v)", corrupt, err)
+		}
+		corrupt = fmt.Errorf("%w; journal moved to %s", corrupt, aside)
+	}
+
+	delivered := 0
+	for i, e := range entries {
+		err := c.sendFrame(e.opcode, e.payload)
+		if err == nil {
+			if _, err = c.readResponse(); isServerError(err) {
+				err = nil
+			}
+		}
+		if err != nil {
+			if rerr := c.journal.rewrite(entries[i:]); rerr != nil {
+				return delivered, rerr
+			}
+			return delivered, err
+		}
+		delivered++
+	}
+	if len(entries) > 0 && corrupt == nil {
+		if err := c.journal.rewrite(nil); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, corrupt
+}
+
+// replayPending replays the journal on a connection just dialed. Retries
+// are suspended so a failure surfaces to the dial instead of reconnecting
+// recursively; a corrupt journal is logged, not fatal.
+func (c *Client) replayPending() error {
+	if c.journal == nil {
+		return nil
+	}
+	c.replaying = true
+	n, err := c.ReplayJournal()
+	c.replaying = false
+	if errors.Is(err, ErrJournalCorrupt) {
+		c.logger().Log(LevelError, "write journal corrupt", "addr", c.addr, "error", err)
+		err = nil
+	}
+	if n > 0 {
+		c.logReconnect("replayed write journal", "writes", n)
+	}
+	return err
+}
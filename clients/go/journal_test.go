@@ -0,0 +1,184 @@
+package celrix
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// journalServer serves a kv store that rejects SETs of key "rejected" and
+// drops the connection on requests for which drop reports true
+func journalServer(store *kv, drop func(conn int, f wire.Frame) bool) *testServer {
+	s := &testServer{}
+	s.handle = func(conn int, f wire.Frame) []wire.Frame {
+		if drop != nil && drop(conn, f) {
+			s.drop(conn)
+			return nil
+		}
+		if key, _, _ := readString(f.Payload); f.Opcode == OpSet && key == "rejected" {
+			return reply(f, OpError, []byte("rejected"))
+		}
+		return store.handle(f)
+	}
+	return s
+}
+
+// journalSets writes SETs of keys into the journal in dir, as a client that
+// lost its connection would
+func journalSets(t *testing.T, dir string, keys ...string) {
+	t.Helper()
+	j, err := openJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.close()
+	for _, k := range keys {
+		if err := j.append(OpSet, appendString(appendString(nil, k), "v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func journalKeys(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := readJournal(filepath.Join(dir, journalFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, e := range entries {
+		k, _, _ := readString(e.payload)
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestJournalSavesWriteLostInTransit(t *testing.T) {
+	dir := t.TempDir()
+	var store kv
+	s := journalServer(&store, func(conn int, f wire.Frame) bool { return conn == 1 && f.Opcode == OpSet })
+	c := s.connect(t, WithWriteJournal(dir))
+
+	if err := c.Set("a", "1"); !errors.Is(err, ErrJournaled) {
+		t.Fatalf("got %v, want ErrJournaled", err)
+	}
+	c.Close()
+	if got := journalKeys(t, dir); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("journal holds %v, want [a]", got)
+	}
+
+	// A new client replays the journal as it connects
+	s.connect(t, WithWriteJournal(dir))
+	if store.data["a"] != "1" {
+		t.Errorf("a = %q after replay, want %q", store.data["a"], "1")
+	}
+	if got := journalKeys(t, dir); len(got) != 0 {
+		t.Errorf("journal holds %v after replay, want nothing", got)
+	}
+}
+
+func TestJournalReplayDropsRejectedWrites(t *testing.T) {
+	dir := t.TempDir()
+	journalSets(t, dir, "a", "rejected", "b")
+	var store kv
+	c := journalServer(&store, nil).connect(t, WithWriteJournal(dir))
+
+	if n, err := c.ReplayJournal(); n != 0 || err != nil {
+		t.Errorf("second replay delivered %d, %v; want nothing", n, err)
+	}
+	if store.data["a"] != "v" || store.data["b"] != "v" {
+		t.Errorf("store holds %v, want a and b", store.data)
+	}
+	if got := journalKeys(t, dir); len(got) != 0 {
+		t.Errorf("journal holds %v, want nothing", got)
+	}
+}
+
+func TestJournalKeepsUndeliveredWrites(t *testing.T) {
+	dir := t.TempDir()
+	journalSets(t, dir, "a", "b", "c")
+	var store kv
+	s := journalServer(&store, func(conn int, f wire.Frame) bool {
+		key, _, _ := readString(f.Payload)
+		return conn == 1 && key == "b"
+	})
+
+	if _, err := Connect("test", WithDialer(s.dial), WithWriteJournal(dir)); err == nil {
+		t.Fatal("Connect succeeded with the connection dropped during replay")
+	}
+	if got := journalKeys(t, dir); strings.Join(got, ",") != "b,c" {
+		t.Errorf("journal holds %v, want [b c]", got)
+	}
+
+	s.connect(t, WithWriteJournal(dir))
+	if len(store.data) != 3 {
+		t.Errorf("store holds %v, want a, b and c", store.data)
+	}
+}
+
+func TestJournalIgnoresTornTail(t *testing.T) {
+	dir := t.TempDir()
+	journalSets(t, dir, "a", "b")
+	path := filepath.Join(dir, journalFile)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A crash mid-append leaves part of a body or part of a header
+	for _, tt := range []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"torn body", b[:len(b)-3], 1},
+		{"torn header", append(b, 0, 0, 0), 2},
+	} {
+		if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := readJournal(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(entries) != tt.want {
+			t.Errorf("%s: read %d entries, want %d", tt.name, len(entries), tt.want)
+		}
+	}
+}
+
+func TestJournalCorruptEntryMovedAside(t *testing.T) {
+	dir := t.TempDir()
+	journalSets(t, dir, "a", "b", "c")
+	path := filepath.Join(dir, journalFile)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip the last payload byte of the second entry
+	entry := len(b) / 3
+	b[2*entry-1] ^= 0xff
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var store kv
+	s := journalServer(&store, nil)
+	s.connect(t, WithWriteJournal(dir))
+	if store.data["a"] != "v" || len(store.data) != 1 {
+		t.Errorf("store holds %v, want only a", store.data)
+	}
+	if got := journalKeys(t, dir); len(got) != 0 {
+		t.Errorf("journal holds %v, want a fresh one", got)
+	}
+	aside, _ := filepath.Glob(path + ".corrupt-*")
+	if len(aside) != 1 {
+		t.Fatalf("found %v, want one journal moved aside", aside)
+	}
+	if moved, err := os.ReadFile(aside[0]); err != nil || len(moved) != len(b) {
+		t.Errorf("moved journal is %d bytes (%v), want the original %d", len(moved), err, len(b))
+	}
+}
@@ -0,0 +1,143 @@
+package celrix
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// KeyEventKind identifies why a key was dropped by the server
+type KeyEventKind uint8
+
+// Key event kinds
+const (
+	KeyExpired KeyEventKind = 0x01
+	KeyEvicted KeyEventKind = 0x02
+)
+
+// String returns the key event kind name
+func (k KeyEventKind) String() string {
+	switch k {
+	case KeyExpired:
+		return "expired"
+	case KeyEvicted:
+		return "evicted"
+	default:
+		return fmt.Sprintf("KeyEventKind(%d)", uint8(k))
+	}
+}
+
+// KeyEvent is a keyspace notification for a key the server dropped on its
+// own, as opposed to one deleted by a client
+type KeyEvent struct {
+	Kind KeyEventKind
+	Time time.Time
+	Key  string
+	// Value is the value the key held when it was dropped
+	Value []byte
+
+	// Err is set on the final event delivered before the channel closes
+	// when the stream ended abnormally
+	Err error
+}
+
+// KeyEvents subscribes to notifications for keys starting with prefix
+// (empty for all keys) that expire or are evicted; with no kinds given both
+// are delivered. Like CDC, events are read on a dedicated connection and the
+// channel is closed when ctx is cancelled or the stream fails, in which case
+// the last event delivered carries Err.
+func (c *Client) KeyEvents(ctx context.Context, prefix string, kinds ...KeyEventKind) (<-chan KeyEvent, error) {
+	mask := uint8(KeyExpired | KeyEvicted)
+	if len(kinds) > 0 {
+		mask = 0
+		for _, k := range kinds {
+			mask |= uint8(k)
+		}
+	}
+
+	sub, err := c.dialDedicated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn := sub.conn
+
+	// Payload: [kinds u8][prefix]
+	payload := appendString([]byte{mask}, prefix)
+	if err := sub.sendFrame(OpKeyEventSubscribe, payload); err != nil {
+		sub.closeDedicated()
+		return nil, err
+	}
+	if err := sub.expectOK(); err != nil {
+		sub.closeDedicated()
+		return nil, err
+	}
+
+	events := make(chan KeyEvent, cdcBufferSize)
+	stop := closeOnDone(ctx, conn)
+	go func() {
+		defer close(events)
+		defer stop()
+		defer sub.closeDedicated()
+		err := sub.supervised("key event reader", func() error {
+			for {
+				ev, err := sub.readKeyEvent()
+				if err != nil {
+					return err
+				}
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			select {
+			case events <- KeyEvent{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (c *Client) readKeyEvent() (KeyEvent, error) {
+	f, err := c.recvFrame()
+	if err != nil {
+		return KeyEvent{}, err
+	}
+	if f.opcode != OpKeyEvent {
+		if _, err := decodeResponse(f.opcode, f.payload); err != nil {
+			return KeyEvent{}, err
+		}
+		return KeyEvent{}, fmt.Errorf("unexpected opcode in key event stream: %d", f.opcode)
+	}
+	return decodeKeyEvent(f.payload)
+}
+
+// decodeKeyEvent decodes [kind: u8][ts: i64][key_len][key][val_len][val]
+func decodeKeyEvent(b []byte) (KeyEvent, error) {
+	if len(b) < 9 {
+		return KeyEvent{}, errors.New("incomplete key event")
+	}
+	ev := KeyEvent{
+		Kind: KeyEventKind(b[0]),
+		Time: time.Unix(0, int64(binary.BigEndian.Uint64(b[1:]))).UTC(),
+	}
+	if ev.Kind != KeyExpired && ev.Kind != KeyEvicted {
+		return KeyEvent{}, fmt.Errorf("unknown key event kind: %d", ev.Kind)
+	}
+	key, n, err := readString(b[9:])
+	if err != nil {
+		return KeyEvent{}, fmt.Errorf("key event key: %w", err)
+	}
+	ev.Key = key
+	val, _, err := readString(b[9+n:])
+	if err != nil {
+		return KeyEvent{}, fmt.Errorf("key event value: %w", err)
+	}
+	ev.Value = []byte(val)
+	return ev, nil
+}
@@ -0,0 +1,84 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// KeyType is the kind of value stored under a key
+type KeyType uint8
+
+// Key types
+const (
+	KeyString KeyType = 0x01
+	KeyVector KeyType = 0x02
+)
+
+// String returns the key type name
+func (t KeyType) String() string {
+	switch t {
+	case KeyString:
+		return "string"
+	case KeyVector:
+		return "vector"
+	default:
+		return fmt.Sprintf("KeyType(%d)", uint8(t))
+	}
+}
+
+// KeySample describes one sampled key
+type KeySample struct {
+	Key  string
+	Type KeyType
+	// Size is the server's estimate of the memory held by the key, in bytes
+	Size int64
+	// TTL is the remaining time to live, or zero for keys without expiry
+	TTL time.Duration
+}
+
+// KeyspaceSample returns up to n keys picked at random by the server, with
+// their type, size and TTL. Sampling is cheap regardless of keyspace size,
+// so it suits capacity estimates that would be too costly as full scans.
+func (a *Admin) KeyspaceSample(n int) ([]KeySample, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("sample size must be positive, got %d", n)
+	}
+	if err := a.c.sendFrame(OpKeyspaceSample, binary.BigEndian.AppendUint32(nil, uint32(n))); err != nil {
+		return nil, err
+	}
+	resp, err := a.c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	records, err := toKeys(resp)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]KeySample, len(records))
+	for i, rec := range records {
+		if samples[i], err = decodeKeySample([]byte(rec)); err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i, err)
+		}
+	}
+	return samples, nil
+}
+
+// decodeKeySample decodes [key_len][key][type u8][size u64][ttl_secs u64]
+func decodeKeySample(b []byte) (KeySample, error) {
+	key, n, err := readString(b)
+	if err != nil {
+		return KeySample{}, err
+	}
+	b = b[n:]
+	if len(b) < 17 {
+		return KeySample{}, errors.New("incomplete key sample")
+	}
+	return KeySample{
+		Key:  key,
+		Type: KeyType(b[0]),
+		Size: int64(binary.BigEndian.Uint64(b[1:])),
+		TTL:  time.Duration(binary.BigEndian.Uint64(b[9:])) * time.Second,
+	}, nil
+}
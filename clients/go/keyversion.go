@@ -0,0 +1,41 @@
+package celrix
+
+import "fmt"
+
+// KeyVersions returns the modification version of each key in key order,
+// or zero for keys that do not exist. The server bumps a key's version on
+// every write and carries it through replication and restores, so of two
+// instances holding the same key, the one with the lower version is
+// behind.
+func (c *Client) KeyVersions(keys ...string) ([]uint64, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	// Payload: [count u32][key...], answered by a typed array of integer
+	// and nil elements in key order
+	if err := c.sendFrame(OpMVersion, appendStrings(nil, keys)); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := resp.([]interface{})
+	if !ok || len(arr) != len(keys) {
+		return nil, c.cmdErr(fmt.Errorf("expected %d versions, got %v", len(keys), resp))
+	}
+	versions := make([]uint64, len(arr))
+	for i, v := range arr {
+		switch v := v.(type) {
+		case nil:
+		case int64:
+			versions[i] = uint64(v)
+		case error:
+			c.pendingKey = keys[i]
+			return nil, c.cmdErr(v)
+		default:
+			return nil, c.cmdErr(fmt.Errorf("version %d: unexpected type %T", i, v))
+		}
+	}
+	return versions, nil
+}
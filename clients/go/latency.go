@@ -0,0 +1,175 @@
+package celrix
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Latency histogram buckets grow geometrically from histMin, covering
+// roughly 10µs to 7 minutes with about 12% resolution
+const (
+	histMin     = 10 * time.Microsecond
+	histGrowth  = 1.25
+	histBuckets = 80
+)
+
+// histogram is a fixed-bucket latency histogram
+type histogram struct {
+	counts [histBuckets]uint64
+	total  uint64
+	sum    time.Duration
+}
+
+func bucketFor(d time.Duration) int {
+	if d <= histMin {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(histMin)) / math.Log(histGrowth)))
+	if i >= histBuckets {
+		return histBuckets - 1
+	}
+	return i
+}
+
+// bucketUpper is the upper bound of bucket i
+func bucketUpper(i int) time.Duration {
+	return time.Duration(float64(histMin) * math.Pow(histGrowth, float64(i)))
+}
+
+func (h *histogram) observe(d time.Duration) {
+	h.counts[bucketFor(d)]++
+	h.total++
+	h.sum += d
+}
+
+// percentile returns the upper bound of the bucket holding the p-th
+// percentile, 0 < p <= 100
+func (h *histogram) percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p / 100 * float64(h.total)))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range h.counts {
+		seen += n
+		if seen >= rank {
+			return bucketUpper(i)
+		}
+	}
+	return bucketUpper(histBuckets - 1)
+}
+
+// latencyTracker keeps a histogram per opcode of the time from sending a
+// request to receiving the first frame of its reply
+type latencyTracker struct {
+	mu   sync.Mutex
+	byOp map[uint8]*histogram
+}
+
+func (t *latencyTracker) observe(opcode uint8, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.byOp == nil {
+		t.byOp = make(map[uint8]*histogram)
+	}
+	h := t.byOp[opcode]
+	if h == nil {
+		h = &histogram{}
+		t.byOp[opcode] = h
+	}
+	h.observe(d)
+}
+
+// snapshot returns a copy of every opcode's histogram
+func (t *latencyTracker) snapshot() map[uint8]histogram {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[uint8]histogram, len(t.byOp))
+	for op, h := range t.byOp {
+		out[op] = *h
+	}
+	return out
+}
+
+// percentile returns the p-th percentile latency for opcode and the number
+// of samples it is based on
+func (t *latencyTracker) percentile(opcode uint8, p float64) (time.Duration, uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.byOp[opcode]
+	if h == nil {
+		return 0, 0
+	}
+	return h.percentile(p), h.total
+}
+
+// Latency returns the p-th percentile (0 < p <= 100) of observed latency
+// for op on this client, and the number of samples behind it. Latency is
+// measured from sending a request to receiving the first reply frame.
+func (c *Client) Latency(op Op, p float64) (time.Duration, uint64) {
+	return c.latency.percentile(uint8(op), p)
+}
+
+// Bounds for adaptive timeouts. Until an opcode has adaptiveWarmup samples
+// its commands use adaptiveInitial.
+const (
+	adaptiveFloor   = 5 * time.Millisecond
+	adaptiveCeiling = 30 * time.Second
+	adaptiveInitial = 10 * time.Second
+	adaptiveWarmup  = 20
+)
+
+type adaptiveTimeout struct {
+	percentile float64
+	multiplier float64
+}
+
+// timeout returns the deadline budget for the next command with opcode
+func (a *adaptiveTimeout) timeout(t *latencyTracker, opcode uint8) time.Duration {
+	p, n := t.percentile(opcode, a.percentile)
+	if n < adaptiveWarmup {
+		return adaptiveInitial
+	}
+	d := time.Duration(float64(p) * a.multiplier)
+	if d < adaptiveFloor {
+		return adaptiveFloor
+	}
+	if d > adaptiveCeiling {
+		return adaptiveCeiling
+	}
+	return d
+}
+
+// WithAdaptiveTimeout bounds each command by a deadline derived from the
+// client's own latency history for that opcode: the given percentile
+// (e.g. 99) of observed latency times multiplier (e.g. 3). A cheap PING and
+// a large VADDBATCH therefore get very different budgets. Deadlines are
+// clamped to between 5ms and 30s, and an opcode is allowed 10s until 20
+// samples have been seen.
+//
+// A command that misses its deadline returns a *TimeoutError and closes the
+// connection, since the late reply would otherwise be read as the answer to
+// the next command. The next command dials a replacement before it is sent,
+// with or without WithRetry; only WithRetry resends the command that timed
+// out.
+//
+// Connect fails if percentile is not in (0, 100] or multiplier is not
+// positive.
+func WithAdaptiveTimeout(percentile, multiplier float64) Option {
+	return func(o *options) {
+		if percentile <= 0 || percentile > 100 || math.IsNaN(percentile) {
+			o.invalid(fmt.Errorf("adaptive timeout percentile %v is not in (0, 100]", percentile))
+			return
+		}
+		if !(multiplier > 0) || math.IsInf(multiplier, 1) {
+			o.invalid(fmt.Errorf("adaptive timeout multiplier %v is not positive", multiplier))
+			return
+		}
+		o.adaptive = &adaptiveTimeout{percentile: percentile, multiplier: multiplier}
+	}
+}
@@ -0,0 +1,80 @@
+package celrix
+
+import "fmt"
+
+// WithMaxValueSize rejects values longer than n bytes before anything is
+// sent, with a *ValueTooLargeError. Writing a value blocks the connection
+// for as long as it takes to transfer, so a guard against an accidental
+// multi-hundred-megabyte Set keeps one caller from stalling every other
+// user of a shared client. The limit applies to the value before
+// compression. Zero, the default, leaves values unbounded.
+func WithMaxValueSize(n int) Option {
+	return func(o *options) {
+		o.maxValueSize = n
+	}
+}
+
+// WithMaxVectorDims rejects vectors, stored or queried, with more than n
+// dimensions before anything is sent, with a *VectorTooLargeError. Zero, the
+// default, leaves vectors unbounded.
+func WithMaxVectorDims(n int) Option {
+	return func(o *options) {
+		o.maxVectorDims = n
+	}
+}
+
+// ValueTooLargeError is returned for a value over the WithMaxValueSize limit.
+// Nothing is sent, and the client remains usable.
+type ValueTooLargeError struct {
+	Op Op
+	// Key is the key written, redacted as with WithKeyRedaction
+	Key   string
+	Size  int
+	Limit int
+}
+
+func (e *ValueTooLargeError) Error() string {
+	return fmt.Sprintf("celrix: %s key=%q: value of %d bytes exceeds the %d-byte limit", e.Op, e.Key, e.Size, e.Limit)
+}
+
+// VectorTooLargeError is returned for a vector over the WithMaxVectorDims
+// limit. Nothing is sent, and the client remains usable.
+type VectorTooLargeError struct {
+	Op Op
+	// Key is the key written, redacted as with WithKeyRedaction, and empty
+	// for a search query
+	Key   string
+	Dims  int
+	Limit int
+}
+
+func (e *VectorTooLargeError) Error() string {
+	s := fmt.Sprintf("celrix: %s", e.Op)
+	if e.Key != "" {
+		s += fmt.Sprintf(" key=%q", e.Key)
+	}
+	return s + fmt.Sprintf(": vector of %d dims exceeds the %d-dim limit", e.Dims, e.Limit)
+}
+
+// checkValue enforces WithMaxValueSize on a value of size bytes
+func (c *Client) checkValue(opcode uint8, key string, size int) error {
+	if limit := c.opts.maxValueSize; limit > 0 && size > limit {
+		return &ValueTooLargeError{Op: Op(opcode), Key: c.redacted(key), Size: size, Limit: limit}
+	}
+	return nil
+}
+
+// checkVector enforces WithMaxVectorDims on a vector, keyless for a query
+func (c *Client) checkVector(opcode uint8, key string, vector []float32) error {
+	if limit := c.opts.maxVectorDims; limit > 0 && len(vector) > limit {
+		return &VectorTooLargeError{Op: Op(opcode), Key: c.redacted(key), Dims: len(vector), Limit: limit}
+	}
+	return nil
+}
+
+func (c *Client) redacted(key string) string {
+	if key != "" && c.opts.redactKey != nil {
+		return c.opts.redactKey(key)
+	}
+	return key
+}
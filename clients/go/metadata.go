@@ -0,0 +1,293 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// MetaType identifies the wire type of a metadata value
+type MetaType uint8
+
+// Metadata value types
+const (
+	MetaString MetaType = 0x01
+	MetaInt    MetaType = 0x02
+	MetaFloat  MetaType = 0x03
+	MetaBool   MetaType = 0x04
+	MetaTime   MetaType = 0x05
+)
+
+// String returns the type name
+func (t MetaType) String() string {
+	switch t {
+	case MetaString:
+		return "string"
+	case MetaInt:
+		return "int"
+	case MetaFloat:
+		return "float"
+	case MetaBool:
+		return "bool"
+	case MetaTime:
+		return "time"
+	default:
+		return fmt.Sprintf("MetaType(%d)", uint8(t))
+	}
+}
+
+// MetaValue is a typed metadata value attached to a vector
+type MetaValue struct {
+	typ MetaType
+	s   string
+	i   int64
+	f   float64
+	b   bool
+}
+
+// StringValue creates a string metadata value
+func StringValue(s string) MetaValue { return MetaValue{typ: MetaString, s: s} }
+
+// IntValue creates an integer metadata value
+func IntValue(i int64) MetaValue { return MetaValue{typ: MetaInt, i: i} }
+
+// FloatValue creates a floating point metadata value
+func FloatValue(f float64) MetaValue { return MetaValue{typ: MetaFloat, f: f} }
+
+// BoolValue creates a boolean metadata value
+func BoolValue(b bool) MetaValue { return MetaValue{typ: MetaBool, b: b} }
+
+// TimeValue creates a timestamp metadata value (nanosecond precision, UTC)
+func TimeValue(t time.Time) MetaValue { return MetaValue{typ: MetaTime, i: t.UnixNano()} }
+
+// Type returns the value type
+func (v MetaValue) Type() MetaType { return v.typ }
+
+// Str returns the string value
+func (v MetaValue) Str() (string, bool) { return v.s, v.typ == MetaString }
+
+// Int returns the integer value
+func (v MetaValue) Int() (int64, bool) { return v.i, v.typ == MetaInt }
+
+// Float returns the float value
+func (v MetaValue) Float() (float64, bool) { return v.f, v.typ == MetaFloat }
+
+// Bool returns the boolean value
+func (v MetaValue) Bool() (bool, bool) { return v.b, v.typ == MetaBool }
+
+// Time returns the timestamp value
+func (v MetaValue) Time() (time.Time, bool) {
+	if v.typ != MetaTime {
+		return time.Time{}, false
+	}
+	return time.Unix(0, v.i).UTC(), true
+}
+
+// String formats the value for display
+func (v MetaValue) String() string {
+	switch v.typ {
+	case MetaString:
+		return v.s
+	case MetaInt:
+		return fmt.Sprintf("%d", v.i)
+	case MetaFloat:
+		return fmt.Sprintf("%g", v.f)
+	case MetaBool:
+		return fmt.Sprintf("%t", v.b)
+	case MetaTime:
+		t, _ := v.Time()
+		return t.Format(time.RFC3339Nano)
+	default:
+		return "<invalid>"
+	}
+}
+
+// Metadata holds typed fields attached to a vector
+type Metadata map[string]MetaValue
+
+// MetaValueOf converts a Go value into a metadata value. Accepted types are
+// string, bool, signed integers, float32/float64, time.Time, json.Number
+// (integral numbers become ints) and MetaValue itself.
+func MetaValueOf(v interface{}) (MetaValue, error) {
+	switch x := v.(type) {
+	case MetaValue:
+		return x, nil
+	case string:
+		return StringValue(x), nil
+	case bool:
+		return BoolValue(x), nil
+	case int:
+		return IntValue(int64(x)), nil
+	case int8:
+		return IntValue(int64(x)), nil
+	case int16:
+		return IntValue(int64(x)), nil
+	case int32:
+		return IntValue(int64(x)), nil
+	case int64:
+		return IntValue(x), nil
+	case float32:
+		return FloatValue(float64(x)), nil
+	case float64:
+		return FloatValue(x), nil
+	case time.Time:
+		return TimeValue(x), nil
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return IntValue(i), nil
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return MetaValue{}, err
+		}
+		return FloatValue(f), nil
+	default:
+		return MetaValue{}, fmt.Errorf("unsupported metadata value type %T", v)
+	}
+}
+
+// ParseMetaValue infers a typed value from text, as found in CSV cells:
+// integers, floats, true/false and RFC 3339 timestamps are recognised,
+// anything else is a string
+func ParseMetaValue(s string) MetaValue {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return IntValue(i)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return FloatValue(f)
+	}
+	if b, err := strconv.ParseBool(s); err == nil && (s == "true" || s == "false") {
+		return BoolValue(b)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return TimeValue(t)
+	}
+	return StringValue(s)
+}
+
+// encodedLen returns the wire size of a single value
+func (v MetaValue) encodedLen() int {
+	switch v.typ {
+	case MetaString:
+		return 1 + 4 + len(v.s)
+	case MetaBool:
+		return 1 + 1
+	default:
+		return 1 + 8
+	}
+}
+
+// appendTo appends the value as [type: u8][value]
+func (v MetaValue) appendTo(buf []byte) ([]byte, error) {
+	buf = append(buf, byte(v.typ))
+	switch v.typ {
+	case MetaString:
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(v.s)))
+		buf = append(buf, v.s...)
+	case MetaInt, MetaTime:
+		buf = binary.BigEndian.AppendUint64(buf, uint64(v.i))
+	case MetaFloat:
+		buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(v.f))
+	case MetaBool:
+		if v.b {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+	default:
+		return nil, fmt.Errorf("invalid metadata type: %d", v.typ)
+	}
+	return buf, nil
+}
+
+// decodeMetaValue decodes a single value and returns the bytes consumed
+func decodeMetaValue(b []byte) (MetaValue, int, error) {
+	if len(b) < 1 {
+		return MetaValue{}, 0, errors.New("incomplete metadata value")
+	}
+	typ := MetaType(b[0])
+	switch typ {
+	case MetaString:
+		if len(b) < 5 {
+			return MetaValue{}, 0, errors.New("incomplete metadata string")
+		}
+		n := int(binary.BigEndian.Uint32(b[1:]))
+		if len(b) < 5+n {
+			return MetaValue{}, 0, errors.New("incomplete metadata string")
+		}
+		return StringValue(string(b[5 : 5+n])), 5 + n, nil
+	case MetaInt, MetaTime, MetaFloat:
+		if len(b) < 9 {
+			return MetaValue{}, 0, errors.New("incomplete metadata number")
+		}
+		bits := binary.BigEndian.Uint64(b[1:])
+		if typ == MetaFloat {
+			return FloatValue(math.Float64frombits(bits)), 9, nil
+		}
+		return MetaValue{typ: typ, i: int64(bits)}, 9, nil
+	case MetaBool:
+		if len(b) < 2 {
+			return MetaValue{}, 0, errors.New("incomplete metadata bool")
+		}
+		return BoolValue(b[1] != 0), 2, nil
+	default:
+		return MetaValue{}, 0, fmt.Errorf("invalid metadata type: %d", typ)
+	}
+}
+
+// appendTo appends the metadata as [count: u32]([key_len: u32][key][value])...
+func (m Metadata) appendTo(buf []byte) ([]byte, error) {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(m)))
+	var err error
+	for k, v := range m {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(k)))
+		buf = append(buf, k...)
+		if buf, err = v.appendTo(buf); err != nil {
+			return nil, fmt.Errorf("metadata field %q: %w", k, err)
+		}
+	}
+	return buf, nil
+}
+
+// encodedLen returns the wire size of the metadata block
+func (m Metadata) encodedLen() int {
+	n := 4
+	for k, v := range m {
+		n += 4 + len(k) + v.encodedLen()
+	}
+	return n
+}
+
+// decodeMetadata decodes a metadata block and returns the bytes consumed
+func decodeMetadata(b []byte) (Metadata, int, error) {
+	if len(b) < 4 {
+		return nil, 0, errors.New("incomplete metadata")
+	}
+	count := int(binary.BigEndian.Uint32(b))
+	offset := 4
+	m := make(Metadata, count)
+	for i := 0; i < count; i++ {
+		if offset+4 > len(b) {
+			return nil, 0, errors.New("incomplete metadata key")
+		}
+		keyLen := int(binary.BigEndian.Uint32(b[offset:]))
+		offset += 4
+		if offset+keyLen > len(b) {
+			return nil, 0, errors.New("incomplete metadata key")
+		}
+		key := string(b[offset : offset+keyLen])
+		offset += keyLen
+
+		v, n, err := decodeMetaValue(b[offset:])
+		if err != nil {
+			return nil, 0, fmt.Errorf("metadata field %q: %w", key, err)
+		}
+		m[key] = v
+		offset += n
+	}
+	return m, offset, nil
+}
@@ -0,0 +1,130 @@
+package celrix
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// MirrorClient duplicates writes to a primary and a secondary instance while
+// serving reads from the primary, for zero-downtime migrations. The primary
+// is authoritative: its result is returned, and a write that fails on the
+// primary is not sent to the secondary. Like Client, a MirrorClient is not
+// safe for concurrent use.
+type MirrorClient struct {
+	primary   *Client
+	secondary *Client
+	stats     mirrorCounters
+
+	// OnDivergence, if set, is called whenever the secondary fails a write
+	// or disagrees with the primary about its outcome
+	OnDivergence func(op, key string, err error)
+}
+
+// MirrorStats counts mirrored traffic and divergences
+type MirrorStats struct {
+	Writes           uint64
+	SecondaryErrors  uint64
+	ResultMismatches uint64
+}
+
+type mirrorCounters struct {
+	writes           atomic.Uint64
+	secondaryErrors  atomic.Uint64
+	resultMismatches atomic.Uint64
+}
+
+// NewMirrorClient wraps primary and secondary connections
+func NewMirrorClient(primary, secondary *Client) *MirrorClient {
+	return &MirrorClient{primary: primary, secondary: secondary}
+}
+
+// Primary returns the authoritative client
+func (m *MirrorClient) Primary() *Client { return m.primary }
+
+// Secondary returns the mirrored client
+func (m *MirrorClient) Secondary() *Client { return m.secondary }
+
+// Stats returns a snapshot of the divergence counters
+func (m *MirrorClient) Stats() MirrorStats {
+	return MirrorStats{
+		Writes:           m.stats.writes.Load(),
+		SecondaryErrors:  m.stats.secondaryErrors.Load(),
+		ResultMismatches: m.stats.resultMismatches.Load(),
+	}
+}
+
+// Close closes both clients
+func (m *MirrorClient) Close() error {
+	err := m.primary.Close()
+	if serr := m.secondary.Close(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Ping checks the primary
+func (m *MirrorClient) Ping() error { return m.primary.Ping() }
+
+// Get reads from the primary
+func (m *MirrorClient) Get(key string) (string, bool, error) { return m.primary.Get(key) }
+
+// VSearch searches the primary
+func (m *MirrorClient) VSearch(vector []float32, k int, opts *VSearchOptions) ([]VectorMatch, error) {
+	return m.primary.VSearch(vector, k, opts)
+}
+
+// Set writes to both instances
+func (m *MirrorClient) Set(key, value string) error {
+	return m.mirror("SET", key, func(c *Client) error { return c.Set(key, value) })
+}
+
+// Del deletes from both instances. A secondary that reports a different
+// existence result counts as a mismatch.
+func (m *MirrorClient) Del(key string) (bool, error) {
+	deleted, err := m.primary.Del(key)
+	if err != nil {
+		return false, err
+	}
+	m.stats.writes.Add(1)
+	sdeleted, serr := m.secondary.Del(key)
+	switch {
+	case serr != nil:
+		m.diverged(&m.stats.secondaryErrors, "DEL", key, serr)
+	case sdeleted != deleted:
+		m.diverged(&m.stats.resultMismatches, "DEL", key, nil)
+	}
+	return deleted, nil
+}
+
+// VAdd adds the vector to both instances
+func (m *MirrorClient) VAdd(key string, vector []float32) error {
+	return m.mirror("VADD", key, func(c *Client) error { return c.VAdd(key, vector) })
+}
+
+// VAddWithMetadata adds the vector and metadata to both instances
+func (m *MirrorClient) VAddWithMetadata(key string, vector []float32, meta Metadata) error {
+	return m.mirror("VADD", key, func(c *Client) error { return c.VAddWithMetadata(key, vector, meta) })
+}
+
+// VAddWithTTL adds the expiring vector to both instances
+func (m *MirrorClient) VAddWithTTL(key string, vector []float32, ttl time.Duration) error {
+	return m.mirror("VADD", key, func(c *Client) error { return c.VAddWithTTL(key, vector, ttl) })
+}
+
+func (m *MirrorClient) mirror(op, key string, fn func(*Client) error) error {
+	if err := fn(m.primary); err != nil {
+		return err
+	}
+	m.stats.writes.Add(1)
+	if err := fn(m.secondary); err != nil {
+		m.diverged(&m.stats.secondaryErrors, op, key, err)
+	}
+	return nil
+}
+
+func (m *MirrorClient) diverged(counter *atomic.Uint64, op, key string, err error) {
+	counter.Add(1)
+	if m.OnDivergence != nil {
+		m.OnDivergence(op, key, err)
+	}
+}
@@ -0,0 +1,165 @@
+package celrix
+
+import (
+	"strings"
+	"sync"
+)
+
+// Mux shares a single server connection among many logical sessions, for
+// environments such as serverless functions where the server's connection
+// limit is tighter than the number of callers.
+//
+// Each Session carries its own database number and key prefix. The Mux
+// tracks which database the connection is on and sends SELECT only when the
+// next command comes from a session on a different one. Commands from all
+// sessions are serialised on the connection.
+//
+// The connection is dialed on first use and dropped after a transport
+// error, so a Mux survives a frozen and thawed execution environment whose
+// idle socket was closed underneath it. The command that hit the error is
+// not retried, since it may have reached the server.
+type Mux struct {
+	addr string
+	opts []Option
+
+	mu sync.Mutex
+	c  *Client
+	db int
+}
+
+// NewMux returns a multiplexer for addr. No connection is made until a
+// session issues its first command.
+func NewMux(addr string, opts ...Option) *Mux {
+	return &Mux{addr: addr, opts: opts}
+}
+
+// Session returns a logical session on database db whose keys are
+// transparently prefixed with prefix
+func (m *Mux) Session(db int, prefix string) *Session {
+	return &Session{m: m, db: db, prefix: prefix}
+}
+
+// Close closes the shared connection. Sessions may still be used
+// afterwards; the next command dials again.
+func (m *Mux) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.c == nil {
+		return nil
+	}
+	err := m.c.Close()
+	m.c = nil
+	return err
+}
+
+// do runs fn on the shared connection after switching it to db
+func (m *Mux) do(db int, fn func(c *Client) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.c == nil {
+		c, err := Connect(m.addr, m.opts...)
+		if err != nil {
+			return err
+		}
+		m.c, m.db = c, 0
+	}
+	if m.db != db {
+		if err := m.c.Select(db); err != nil {
+			return m.fail(err)
+		}
+		m.db = db
+	}
+	return m.fail(fn(m.c))
+}
+
+// fail drops the connection if err is a transport failure and returns err
+func (m *Mux) fail(err error) error {
+	if err != nil && !isServerError(err) {
+		m.c.Close()
+		m.c = nil
+	}
+	return err
+}
+
+// Session is a logical client multiplexed onto a Mux connection. Sessions
+// are cheap and safe for concurrent use.
+type Session struct {
+	m      *Mux
+	db     int
+	prefix string
+}
+
+// DB returns the session's database number
+func (s *Session) DB() int { return s.db }
+
+// Prefix returns the prefix added to the session's keys
+func (s *Session) Prefix() string { return s.prefix }
+
+// Select returns a session on database db sharing this session's prefix.
+// The receiver is unchanged.
+func (s *Session) Select(db int) *Session {
+	return &Session{m: s.m, db: db, prefix: s.prefix}
+}
+
+// WithPrefix returns a session whose keys are additionally prefixed with
+// prefix. The receiver is unchanged.
+func (s *Session) WithPrefix(prefix string) *Session {
+	return &Session{m: s.m, db: s.db, prefix: s.prefix + prefix}
+}
+
+// Ping checks the shared connection
+func (s *Session) Ping() error {
+	return s.m.do(s.db, func(c *Client) error { return c.Ping() })
+}
+
+// Get returns the value of the session key
+func (s *Session) Get(key string) (val string, ok bool, err error) {
+	err = s.m.do(s.db, func(c *Client) error {
+		val, ok, err = c.Get(s.prefix + key)
+		return err
+	})
+	return val, ok, err
+}
+
+// Set stores the session key
+func (s *Session) Set(key, value string) error {
+	return s.m.do(s.db, func(c *Client) error { return c.Set(s.prefix+key, value) })
+}
+
+// Del deletes the session key
+func (s *Session) Del(key string) (deleted bool, err error) {
+	err = s.m.do(s.db, func(c *Client) error {
+		deleted, err = c.Del(s.prefix + key)
+		return err
+	})
+	return deleted, err
+}
+
+// VAdd adds a vector under the session key
+func (s *Session) VAdd(key string, vector []float32) error {
+	return s.m.do(s.db, func(c *Client) error { return c.VAdd(s.prefix+key, vector) })
+}
+
+// VSearch searches the session's database. The vector index is shared by
+// every prefix on a database, so matches outside the session prefix are
+// dropped and fewer than k may be returned. Returned keys have the prefix
+// removed.
+func (s *Session) VSearch(vector []float32, k int, opts *VSearchOptions) ([]VectorMatch, error) {
+	var matches []VectorMatch
+	err := s.m.do(s.db, func(c *Client) (err error) {
+		matches, err = c.VSearch(vector, k, opts)
+		return err
+	})
+	if err != nil || s.prefix == "" {
+		return matches, err
+	}
+	out := matches[:0]
+	for _, m := range matches {
+		if rest, ok := strings.CutPrefix(m.Key, s.prefix); ok {
+			m.Key = rest
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
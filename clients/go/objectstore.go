@@ -0,0 +1,285 @@
+package celrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ObjectStore is the minimal blob storage needed to hold exports. Adapters
+// for S3, GCS or other providers register themselves with
+// RegisterObjectStore.
+type ObjectStore interface {
+	// Put stores an object of the given size under key
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get opens an object for reading
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// ObjectStoreOpener opens a store for a bucket URL such as s3://bucket/path
+type ObjectStoreOpener func(u *url.URL) (ObjectStore, error)
+
+var (
+	objectStoresMu sync.RWMutex
+	objectStores   = map[string]ObjectStoreOpener{
+		"file": func(u *url.URL) (ObjectStore, error) { return FileStore{}, nil },
+	}
+)
+
+// RegisterObjectStore makes an object store available to bucket URLs with
+// the given scheme
+func RegisterObjectStore(scheme string, open ObjectStoreOpener) {
+	objectStoresMu.Lock()
+	defer objectStoresMu.Unlock()
+	objectStores[scheme] = open
+}
+
+// OpenObjectStore resolves a bucket URL into a store and the key prefix
+// under which objects are placed
+func OpenObjectStore(bucketURL string) (ObjectStore, string, error) {
+	u, err := url.Parse(bucketURL)
+	if err != nil {
+		return nil, "", err
+	}
+	objectStoresMu.RLock()
+	open, ok := objectStores[u.Scheme]
+	objectStoresMu.RUnlock()
+	if !ok {
+		return nil, "", fmt.Errorf("celrix: no object store registered for scheme %q", u.Scheme)
+	}
+	store, err := open(u)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Scheme == "file" {
+		return store, filepath.FromSlash(u.Host + u.Path), nil
+	}
+	return store, u.Host + u.Path, nil
+}
+
+// FileStore stores objects as files; keys are file paths
+type FileStore struct{}
+
+// Put writes the object to a temporary file and renames it into place
+func (FileStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(key), 0o755); err != nil {
+		return err
+	}
+	tmp := key + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, key)
+}
+
+// Get opens the object file
+func (FileStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(key)
+}
+
+// ObjectPartSize is the size of each part object written by
+// ExportToObjectStore
+const ObjectPartSize = 8 << 20
+
+const manifestName = "manifest.json"
+
+// ObjectManifest describes an export stored as part objects
+type ObjectManifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Parts     []string  `json:"parts"`
+	Bytes     int64     `json:"bytes"`
+}
+
+// ExportToObjectStore streams a snapshot into the bucket URL as a series of
+// part objects followed by a manifest. Only one part is buffered in memory.
+// The options are those of Export, except ResumeExport, since parts already
+// uploaded cannot be truncated.
+func (c *Client) ExportToObjectStore(ctx context.Context, bucketURL string, opts ...ExportOption) (*ObjectManifest, error) {
+	store, prefix, err := OpenObjectStore(bucketURL)
+	if err != nil {
+		return nil, err
+	}
+	return c.ExportToStore(ctx, store, prefix, opts...)
+}
+
+// ExportToStore is ExportToObjectStore for an already opened store
+func (c *Client) ExportToStore(ctx context.Context, store ObjectStore, prefix string, opts ...ExportOption) (*ObjectManifest, error) {
+	if buildExportOptions(opts).resume != nil {
+		return nil, errors.New("ResumeExport does not apply to object store exports")
+	}
+	pw := &partWriter{ctx: ctx, store: store, prefix: prefix}
+	if _, err := c.Export(ctx, pw, opts...); err != nil {
+		return nil, err
+	}
+	if err := pw.flush(); err != nil {
+		return nil, err
+	}
+
+	m := &ObjectManifest{
+		Version:   exportVersion,
+		CreatedAt: c.opts.now().UTC(),
+		Parts:     pw.parts,
+		Bytes:     pw.total,
+	}
+	body, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Put(ctx, joinKey(prefix, manifestName), bytes.NewReader(body), int64(len(body))); err != nil {
+		return nil, fmt.Errorf("upload manifest: %w", err)
+	}
+	return m, nil
+}
+
+// RestoreFromObjectStore restores an export written by ExportToObjectStore,
+// compressed or not. Of the options, only Progress applies.
+func (c *Client) RestoreFromObjectStore(ctx context.Context, bucketURL string, opts ...ExportOption) (ExportInfo, error) {
+	store, prefix, err := OpenObjectStore(bucketURL)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	return c.RestoreFromStore(ctx, store, prefix, opts...)
+}
+
+// RestoreFromStore is RestoreFromObjectStore for an already opened store
+func (c *Client) RestoreFromStore(ctx context.Context, store ObjectStore, prefix string, opts ...ExportOption) (ExportInfo, error) {
+	m, err := ReadObjectManifest(ctx, store, prefix)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	pr := &partReader{ctx: ctx, store: store, prefix: prefix, parts: m.Parts}
+	defer pr.Close()
+	return c.Restore(ctx, pr, opts...)
+}
+
+// ReadObjectManifest loads the manifest of an export stored under prefix
+func ReadObjectManifest(ctx context.Context, store ObjectStore, prefix string) (*ObjectManifest, error) {
+	rc, err := store.Get(ctx, joinKey(prefix, manifestName))
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	defer rc.Close()
+	var m ObjectManifest
+	if err := json.NewDecoder(rc).Decode(&m); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	if m.Version != exportVersion {
+		return nil, fmt.Errorf("celrix: unsupported manifest version %d", m.Version)
+	}
+	return &m, nil
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	if filepath.IsAbs(prefix) || filepath.VolumeName(prefix) != "" {
+		return filepath.Join(prefix, name)
+	}
+	return path.Join(prefix, name)
+}
+
+// partWriter uploads everything written to it as fixed-size part objects
+type partWriter struct {
+	ctx    context.Context
+	store  ObjectStore
+	prefix string
+	buf    bytes.Buffer
+	parts  []string
+	total  int64
+}
+
+func (pw *partWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		room := ObjectPartSize - pw.buf.Len()
+		if room > len(p) {
+			room = len(p)
+		}
+		pw.buf.Write(p[:room])
+		p = p[room:]
+		if pw.buf.Len() == ObjectPartSize {
+			if err := pw.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (pw *partWriter) flush() error {
+	if pw.buf.Len() == 0 {
+		return nil
+	}
+	name := fmt.Sprintf("part-%06d", len(pw.parts)+1)
+	size := int64(pw.buf.Len())
+	if err := pw.store.Put(pw.ctx, joinKey(pw.prefix, name), bytes.NewReader(pw.buf.Bytes()), size); err != nil {
+		return fmt.Errorf("upload %s: %w", name, err)
+	}
+	pw.parts = append(pw.parts, name)
+	pw.total += size
+	pw.buf.Reset()
+	return nil
+}
+
+// partReader concatenates part objects, opening each one lazily
+type partReader struct {
+	ctx    context.Context
+	store  ObjectStore
+	prefix string
+	parts  []string
+	cur    io.ReadCloser
+}
+
+func (pr *partReader) Read(p []byte) (int, error) {
+	for {
+		if pr.cur == nil {
+			if len(pr.parts) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := pr.store.Get(pr.ctx, joinKey(pr.prefix, pr.parts[0]))
+			if err != nil {
+				return 0, fmt.Errorf("open %s: %w", pr.parts[0], err)
+			}
+			pr.cur = rc
+			pr.parts = pr.parts[1:]
+		}
+		n, err := pr.cur.Read(p)
+		if errors.Is(err, io.EOF) {
+			pr.cur.Close()
+			pr.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (pr *partReader) Close() error {
+	if pr.cur != nil {
+		return pr.cur.Close()
+	}
+	return nil
+}
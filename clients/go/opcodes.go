@@ -0,0 +1,228 @@
+package celrix
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Op is a frame opcode
+type Op uint8
+
+// Extension range reserved for private server extensions. Opcodes in this
+// range are never assigned by CELRIX itself.
+const (
+	OpExtensionFirst Op = 0xE0
+	OpExtensionLast  Op = 0xFF
+)
+
+// builtinOpNames maps built-in opcodes to their command names
+var builtinOpNames = map[Op]string{
+	OpPing:               "PING",
+	OpPong:               "PONG",
+	OpGet:                "GET",
+	OpSet:                "SET",
+	OpDel:                "DEL",
+	OpExists:             "EXISTS",
+	OpSetCompressed:      "SETZ",
+	OpRenameBatch:        "RENAMEBATCH",
+	OpSetNX:              "SETNX",
+	OpCompareAndSwap:     "CAS",
+	OpCompareAndDelete:   "CAD",
+	OpIncrBy:             "INCRBY",
+	OpSoftDel:            "SOFTDEL",
+	OpUndelete:           "UNDELETE",
+	OpScan:               "SCAN",
+	OpOk:                 "OK",
+	OpError:              "ERROR",
+	OpValue:              "VALUE",
+	OpNil:                "NIL",
+	OpInteger:            "INTEGER",
+	OpArray:              "ARRAY",
+	OpCompressed:         "COMPRESSED",
+	OpTypedArray:         "TYPEDARRAY",
+	OpDouble:             "DOUBLE",
+	OpMap:                "MAP",
+	OpBool:               "BOOL",
+	OpBigInt:             "BIGINT",
+	OpTypedString:        "TYPEDSTRING",
+	OpAttributes:         "ATTRIBUTES",
+	OpValueChunk:         "VALUECHUNK",
+	OpVAdd:               "VADD",
+	OpVSearch:            "VSEARCH",
+	OpVAddMeta:           "VADDMETA",
+	OpVSearchFilter:      "VSEARCHFILTER",
+	OpVAddTTL:            "VADDTTL",
+	OpVAddBatch:          "VADDBATCH",
+	OpVGet:               "VGET",
+	OpVSearchMeta:        "VSEARCHMETA",
+	OpVDelta:             "VDELTA",
+	OpVSearchExplain:     "VSEARCHEXPLAIN",
+	OpVSearchBatch:       "VSEARCHBATCH",
+	OpCreateCollection:   "CREATECOLLECTION",
+	OpDropCollection:     "DROPCOLLECTION",
+	OpDescribeCollection: "DESCRIBECOLLECTION",
+	OpCVAdd:              "CVADD",
+	OpCVSearch:           "CVSEARCH",
+	OpCreateAlias:        "CREATEALIAS",
+	OpSwapAlias:          "SWAPALIAS",
+	OpDropAlias:          "DROPALIAS",
+	OpListCollections:    "LISTCOLLECTIONS",
+	OpCreateFieldIndex:   "CREATEFIELDINDEX",
+	OpDropFieldIndex:     "DROPFIELDINDEX",
+	OpSearchByField:      "SEARCHBYFIELD",
+	OpAggregate:          "AGGREGATE",
+	OpSnapshotSearch:     "SNAPSHOTSEARCH",
+	OpSnapshotVSearch:    "SNAPSHOTVSEARCH",
+	OpSnapshotRelease:    "SNAPSHOTRELEASE",
+	OpCDCSubscribe:       "CDC",
+	OpChangeEvent:        "CHANGEEVENT",
+	OpKeyEventSubscribe:  "KEYEVENTS",
+	OpKeyEvent:           "KEYEVENT",
+	OpExport:             "EXPORT",
+	OpExportChunk:        "EXPORTCHUNK",
+	OpRestore:            "RESTORE",
+	OpRestoreChunk:       "RESTORECHUNK",
+	OpRestoreEnd:         "RESTOREEND",
+	OpExportSince:        "EXPORTSINCE",
+	OpHello:              "HELLO",
+	OpHealth:             "HEALTH",
+	OpSelect:             "SELECT",
+	OpDictionaries:       "DICTIONARIES",
+	OpCapabilities:       "CAPABILITIES",
+	OpACLList:            "ACLLIST",
+	OpACLSetUser:         "ACLSETUSER",
+	OpACLDelUser:         "ACLDELUSER",
+	OpConfigGet:          "CONFIGGET",
+	OpConfigSet:          "CONFIGSET",
+	OpKeyspaceSample:     "KEYSPACESAMPLE",
+	OpHotKeys:            "HOTKEYS",
+	OpMemoryUsage:        "MEMORYUSAGE",
+	OpFlushDB:            "FLUSHDB",
+	OpAuditLog:           "AUDITLOG",
+	OpDryRun:             "DRYRUN",
+	OpFlushPrefix:        "FLUSHPREFIX",
+	OpPreloadIndex:       "PRELOADINDEX",
+	OpPreloadProgress:    "PRELOADPROGRESS",
+	OpClientList:         "CLIENTLIST",
+	OpZAdd:               "ZADD",
+	OpZRem:               "ZREM",
+	OpZScore:             "ZSCORE",
+	OpZCard:              "ZCARD",
+	OpZRangeByScore:      "ZRANGEBYSCORE",
+	OpBFReserve:          "BFRESERVE",
+	OpBFAdd:              "BFADD",
+	OpBFExists:           "BFEXISTS",
+	OpBFMAdd:             "BFMADD",
+	OpBFMExists:          "BFMEXISTS",
+	OpCMSInit:            "CMSINIT",
+	OpCMSIncr:            "CMSINCR",
+	OpCMSQuery:           "CMSQUERY",
+	OpTopKReserve:        "TOPKRESERVE",
+	OpTopKAdd:            "TOPKADD",
+	OpTopKList:           "TOPKLIST",
+	OpTSCreate:           "TSCREATE",
+	OpTSAdd:              "TSADD",
+	OpTSRange:            "TSRANGE",
+	OpTSCreateRule:       "TSCREATERULE",
+	OpTSDeleteRule:       "TSDELETERULE",
+	OpMGet:               "MGET",
+	OpMVersion:           "MVERSION",
+	OpHash:               "HASH",
+	OpMHash:              "MHASH",
+	OpExpire:             "EXPIRE",
+	OpTTL:                "TTL",
+	OpPersist:            "PERSIST",
+	OpSetStream:          "SETSTREAM",
+	OpSetChunk:           "SETCHUNK",
+	OpSetStreamEnd:       "SETSTREAMEND",
+	OpGetStream:          "GETSTREAM",
+}
+
+// String returns the command name, or a hex form for unknown opcodes
+func (op Op) String() string {
+	if name, ok := builtinOpNames[op]; ok {
+		return name
+	}
+	if spec, ok := LookupOpcode(op); ok {
+		return spec.Name
+	}
+	return fmt.Sprintf("OP(0x%02X)", uint8(op))
+}
+
+// OpcodeSpec describes a custom opcode handled by a private server
+// extension
+type OpcodeSpec struct {
+	// Name is used in errors and diagnostics
+	Name string
+	// Marshal encodes the argument passed to Client.Do into a request
+	// payload. Required for request opcodes.
+	Marshal func(arg interface{}) ([]byte, error)
+	// Unmarshal decodes the payload of a response frame carrying this
+	// opcode. Required for response opcodes.
+	Unmarshal func(payload []byte) (interface{}, error)
+}
+
+var (
+	opcodeMu  sync.RWMutex
+	extension = map[Op]OpcodeSpec{}
+)
+
+// RegisterOpcode registers a custom opcode in the extension range. It
+// fails if op is outside 0xE0–0xFF or already registered.
+func RegisterOpcode(op Op, spec OpcodeSpec) error {
+	if op < OpExtensionFirst {
+		return fmt.Errorf("celrix: opcode 0x%02X is outside the extension range 0x%02X-0x%02X", uint8(op), uint8(OpExtensionFirst), uint8(OpExtensionLast))
+	}
+	if spec.Name == "" {
+		return fmt.Errorf("celrix: opcode 0x%02X registered without a name", uint8(op))
+	}
+	if spec.Marshal == nil && spec.Unmarshal == nil {
+		return fmt.Errorf("celrix: opcode %s needs a Marshal or Unmarshal function", spec.Name)
+	}
+	opcodeMu.Lock()
+	defer opcodeMu.Unlock()
+	if prev, ok := extension[op]; ok {
+		return fmt.Errorf("celrix: opcode 0x%02X already registered as %s", uint8(op), prev.Name)
+	}
+	extension[op] = spec
+	return nil
+}
+
+// MustRegisterOpcode is like RegisterOpcode but panics on error, for use
+// in package init
+func MustRegisterOpcode(op Op, spec OpcodeSpec) {
+	if err := RegisterOpcode(op, spec); err != nil {
+		panic(err)
+	}
+}
+
+// LookupOpcode returns the spec of a registered custom opcode
+func LookupOpcode(op Op) (OpcodeSpec, bool) {
+	opcodeMu.RLock()
+	defer opcodeMu.RUnlock()
+	spec, ok := extension[op]
+	return spec, ok
+}
+
+// Do sends a custom opcode with arg encoded by its registered Marshal and
+// returns the decoded reply. Replies with built-in response opcodes are
+// decoded as usual, into string, int64, *big.Int, float64, bool, nil,
+// []interface{} or map[string]interface{}; replies with registered custom
+// opcodes go through their Unmarshal.
+func (c *Client) Do(op Op, arg interface{}) (interface{}, error) {
+	spec, ok := LookupOpcode(op)
+	if !ok {
+		return nil, fmt.Errorf("celrix: opcode 0x%02X is not registered", uint8(op))
+	}
+	if spec.Marshal == nil {
+		return nil, fmt.Errorf("celrix: opcode %s is response-only", spec.Name)
+	}
+	payload, err := spec.Marshal(arg)
+	if err != nil {
+		return nil, fmt.Errorf("celrix: marshal %s: %w", spec.Name, err)
+	}
+	if err := c.sendFrame(uint8(op), payload); err != nil {
+		return nil, err
+	}
+	return c.readResponse()
+}
@@ -0,0 +1,91 @@
+package celrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// WithOpConcurrencyLimit bounds how many commands of each listed opcode may
+// be in flight at once, so heavy operations such as VSEARCHBATCH or EXPORT
+// cannot occupy every connection of a shared pool and hold up cheap GETs
+// queued behind them. A command over its limit waits for a slot before it
+// is sent; under RunContext the wait ends with the context. Opcodes not in
+// limits, and limits of zero or less, are unbounded.
+//
+// The limits are shared by every client configured with the returned
+// Option, so passing it in PoolOptions.ClientOptions bounds the pool as a
+// whole. A command holds its slot until its reply starts to arrive; EXPORT,
+// EXPORTSINCE, RESTORE and PRELOADINDEX hold theirs for the whole stream.
+// Pipelined commands and CDC and key-event subscriptions are not limited.
+func WithOpConcurrencyLimit(limits map[Op]int) Option {
+	l := make(opLimits, len(limits))
+	for op, n := range limits {
+		if n > 0 {
+			l[uint8(op)] = make(chan struct{}, n)
+		}
+	}
+	return func(o *options) {
+		o.opLimits = l
+	}
+}
+
+// errSlotWait is returned when the context ends while a command waits for
+// a concurrency slot
+var errSlotWait = errors.New("context done waiting for a concurrency slot")
+
+// opLimits holds a semaphore per limited opcode
+type opLimits map[uint8]chan struct{}
+
+// acquire takes a slot for opcode, giving up when done is closed. The
+// returned semaphore is released with releaseSlot, and is nil when opcode
+// is unbounded.
+func (l opLimits) acquire(done <-chan struct{}, opcode uint8) (chan struct{}, error) {
+	// Limited as the command it stands for, as by WithCommandPolicy
+	opcode = canonicalOp(opcode)
+	sem := l[opcode]
+	if sem == nil {
+		return nil, nil
+	}
+	// A free slot is taken even if done is already closed
+	select {
+	case sem <- struct{}{}:
+		return sem, nil
+	default:
+	}
+	select {
+	case sem <- struct{}{}:
+		return sem, nil
+	case <-done:
+		return nil, fmt.Errorf("%s: %w", Op(opcode), errSlotWait)
+	}
+}
+
+// acquireSlot takes the slot of the command in flight
+func (c *Client) acquireSlot(opcode uint8) error {
+	sem, err := c.opts.opLimits.acquire(c.ctxDone, opcode)
+	c.slot = sem
+	return err
+}
+
+// releaseSlot returns the slot of the command in flight, if it holds one
+func (c *Client) releaseSlot() {
+	if c.slot != nil {
+		<-c.slot
+		c.slot = nil
+	}
+}
+
+// streamSlot takes a slot for a stream on a dedicated connection, held until
+// the returned function is called
+func (c *Client) streamSlot(ctx context.Context, opcode uint8) (release func(), err error) {
+	sem, err := c.opts.opLimits.acquire(ctx.Done(), opcode)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if sem != nil {
+			<-sem
+		}
+	}, nil
+}
@@ -0,0 +1,119 @@
+package celrix
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Option configures a Client
+type Option func(*options)
+
+// DialFunc opens the transport connection to the server
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+type options struct {
+	// err is the first invalid option, reported by Connect
+	err error
+
+	journalDir string
+	dial       DialFunc
+	recorder   *Recorder
+	versions   []uint8
+	adaptive   *adaptiveTimeout
+	redactKey  func(string) string
+
+	onAsyncError   func(error)
+	clock          Clock
+	hotKeys        *hotKeyTracker
+	audit          *auditor
+	policy         *commandPolicy
+	pins           []ServerPin
+	logger         Logger
+	labelBase      context.Context
+	compression    *compression
+	vectorDeltas   int
+	serverTiming   bool
+	otlp           *otlpExporter
+	budget         *budget
+	trashRetention time.Duration
+	zeroCopy       bool
+	batchArena     bool
+	connLabels     map[string]string
+	capture        *sampledCapture
+	retry          *RetryPolicy
+	onReconnect    func(attempt int, cause error)
+	maxValueSize   int
+	maxVectorDims  int
+	tls            *tls.Config
+	opLimits       opLimits
+}
+
+func (o *options) dialer() DialFunc {
+	dial := o.dial
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	if o.tls != nil {
+		return tlsDialer(dial, o.tls)
+	}
+	return dial
+}
+
+// invalid records an option given bad arguments, for Connect to report
+func (o *options) invalid(err error) {
+	if o.err == nil {
+		o.err = fmt.Errorf("celrix: invalid option: %w", err)
+	}
+}
+
+// WithDialer replaces the function used to open connections, including the
+// dedicated connections used for streams. Useful for proxies, in-memory
+// transports and fault injection.
+func WithDialer(dial DialFunc) Option {
+	return func(o *options) {
+		o.dial = dial
+	}
+}
+
+// WithWriteJournal persists writes that fail because the connection is down
+// into an append-only journal under dir. Journaled writes are replayed, in
+// order, when the client reconnects under WithRetry or the next time a
+// client with the same journal connects. A journal belongs to one client at
+// a time: a second client opening it fails with ErrJournalInUse, and a Pool
+// refuses the option.
+func WithWriteJournal(dir string) Option {
+	return func(o *options) {
+		o.journalDir = dir
+	}
+}
+
+// WithRecorder captures every frame sent and received on the client's
+// connection into rec
+func WithRecorder(rec *Recorder) Option {
+	return func(o *options) {
+		o.recorder = rec
+	}
+}
+
+// WithProtocolVersions enables the HELLO handshake, offering the given frame
+// layout versions in order of preference. The server picks one; servers that
+// predate the handshake are spoken to with version 1. Without this option no
+// handshake is sent and version 1 is used.
+func WithProtocolVersions(versions ...uint8) Option {
+	return func(o *options) {
+		o.versions = versions
+	}
+}
+
+// WithKeyRedaction passes keys through redact before they are placed in a
+// CommandError, for deployments where keys carry personal data. RedactKey
+// is a ready-made digest.
+func WithKeyRedaction(redact func(key string) string) Option {
+	return func(o *options) {
+		o.redactKey = redact
+	}
+}
@@ -0,0 +1,432 @@
+package celrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OTLPOptions configures push export of client metrics over OTLP/HTTP
+type OTLPOptions struct {
+	// Endpoint is the collector's metrics URL, such as
+	// "http://localhost:4318/v1/metrics"
+	Endpoint string
+	// Interval between pushes. Defaults to one minute.
+	Interval time.Duration
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+	// HTTPClient sends the requests. Defaults to a client with a timeout
+	// of Interval.
+	HTTPClient *http.Client
+}
+
+// WithOTLPExporter pushes client metrics to an OpenTelemetry collector
+// every Interval, for environments without Prometheus scraping. Metrics are
+// encoded as OTLP JSON with cumulative temporality, per server address:
+//
+//   - celrix.client.command.duration: time from request to first reply
+//     frame, per opcode
+//   - celrix.client.server.duration: server execution time, per opcode,
+//     when WithServerTiming is in use
+//   - celrix.client.commands and celrix.client.errors: requests sent and
+//     commands failed, per opcode
+//   - celrix.client.sent: request payload bytes
+//   - celrix.client.dials and celrix.client.desyncs: connections opened and
+//     quarantined after a DesyncError
+//   - celrix.client.connections: clients currently open, a gauge
+//
+// The exporter is shared by every client configured with the returned
+// Option, so passing it in PoolOptions.ClientOptions makes one push per
+// Interval for the whole pool, and its series outlive the connections that
+// fed them. It runs while any such client is open; closing the last one
+// pushes a final sample. Failed pushes are reported through
+// WithOnAsyncError and do not affect commands.
+func WithOTLPExporter(opts OTLPOptions) Option {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: opts.Interval}
+	}
+	e := &otlpExporter{opts: opts, series: make(map[string]*otlpSeries)}
+	return func(o *options) {
+		o.otlp = e
+	}
+}
+
+// otlpExporter runs the periodic pushes of the clients sharing it
+type otlpExporter struct {
+	opts OTLPOptions
+
+	mu     sync.Mutex
+	series map[string]*otlpSeries
+	// clients counts the open clients; the first starts the pushes and
+	// the last stops them. o holds the clock and error callback of the
+	// first client registered.
+	clients int
+	o       *options
+	start   time.Time
+	// done stops the running pushes, which close finished on exit
+	done, finished chan struct{}
+}
+
+// otlpSeries accumulates the metrics of the clients of one server address
+type otlpSeries struct {
+	latency       latencyTracker
+	serverLatency latencyTracker
+
+	mu                   sync.Mutex
+	commands, errors     map[uint8]uint64
+	sent, dials, desyncs uint64
+	open                 int64
+}
+
+func (s *otlpSeries) add(counter *uint64, n uint64) {
+	s.mu.Lock()
+	*counter += n
+	s.mu.Unlock()
+}
+
+func (s *otlpSeries) addOp(m map[uint8]uint64, opcode uint8) {
+	s.mu.Lock()
+	m[opcode]++
+	s.mu.Unlock()
+}
+
+// startOTLP registers a newly connected client with its exporter, starting
+// the pushes if it is the first
+func (c *Client) startOTLP() {
+	e := c.opts.otlp
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := e.series[c.addr]
+	if s == nil {
+		s = &otlpSeries{commands: make(map[uint8]uint64), errors: make(map[uint8]uint64)}
+		e.series[c.addr] = s
+	}
+	s.mu.Lock()
+	// The connection just made is counted here, as the client was not yet
+	// registered when it dialed
+	s.open++
+	s.dials++
+	s.mu.Unlock()
+	c.otlp = s
+
+	if e.clients++; e.clients > 1 {
+		return
+	}
+	if e.o == nil {
+		e.o, e.start = &c.opts, c.opts.now()
+	}
+	done, finished := make(chan struct{}), make(chan struct{})
+	e.done, e.finished = done, finished
+	go func() {
+		defer close(finished)
+		e.run(done)
+	}()
+}
+
+// stopOTLP unregisters a closing client, stopping the pushes with a final
+// one if it is the last
+func (c *Client) stopOTLP() {
+	e := c.opts.otlp
+	if e == nil || c.otlp == nil {
+		return
+	}
+	c.otlp.mu.Lock()
+	c.otlp.open--
+	c.otlp.mu.Unlock()
+	c.otlp = nil
+
+	e.mu.Lock()
+	e.clients--
+	if e.clients > 0 {
+		e.mu.Unlock()
+		return
+	}
+	close(e.done)
+	finished := e.finished
+	e.mu.Unlock()
+	<-finished
+	ctx, cancel := context.WithTimeout(context.Background(), e.opts.Interval)
+	defer cancel()
+	e.report(e.push(ctx))
+}
+
+func (e *otlpExporter) run(done <-chan struct{}) {
+	defer func() {
+		if v := recover(); v != nil {
+			e.report(&PanicError{Goroutine: "otlp exporter", Value: v, Stack: debug.Stack()})
+		}
+	}()
+	for {
+		t := e.o.timer(e.opts.Interval)
+		select {
+		case <-t.C():
+			e.report(e.push(context.Background()))
+		case <-done:
+			t.Stop()
+			return
+		}
+	}
+}
+
+func (e *otlpExporter) report(err error) {
+	if err != nil && e.o.onAsyncError != nil {
+		e.o.onAsyncError(fmt.Errorf("celrix: otlp export: %w", err))
+	}
+}
+
+func (e *otlpExporter) push(ctx context.Context) error {
+	body, err := json.Marshal(e.payload(e.o.now()))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP JSON encoding of the metrics data model. 64-bit integers are strings,
+// as the protobuf JSON mapping requires.
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpMetric struct {
+		Name      string         `json:"name"`
+		Unit      string         `json:"unit"`
+		Histogram *otlpHistogram `json:"histogram,omitempty"`
+		Sum       *otlpSum       `json:"sum,omitempty"`
+		Gauge     *otlpGauge     `json:"gauge,omitempty"`
+	}
+	otlpHistogram struct {
+		AggregationTemporality int                      `json:"aggregationTemporality"`
+		DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+	}
+	otlpHistogramDataPoint struct {
+		Attributes        []otlpKeyValue `json:"attributes"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		TimeUnixNano      string         `json:"timeUnixNano"`
+		Count             string         `json:"count"`
+		Sum               float64        `json:"sum"`
+		BucketCounts      []string       `json:"bucketCounts"`
+		ExplicitBounds    []float64      `json:"explicitBounds"`
+	}
+	otlpSum struct {
+		AggregationTemporality int                   `json:"aggregationTemporality"`
+		IsMonotonic            bool                  `json:"isMonotonic"`
+		DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+	}
+	otlpNumberDataPoint struct {
+		Attributes        []otlpKeyValue `json:"attributes"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano,omitempty"`
+		TimeUnixNano      string         `json:"timeUnixNano"`
+		AsInt             string         `json:"asInt"`
+	}
+	otlpKeyValue struct {
+		Key   string        `json:"key"`
+		Value otlpAnyString `json:"value"`
+	}
+	otlpAnyString struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// otlpCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE
+const otlpCumulative = 2
+
+// otlpBounds are the histogram bucket bounds in seconds. The last bucket is
+// open-ended, so it has no bound.
+var otlpBounds = func() []float64 {
+	b := make([]float64, histBuckets-1)
+	for i := range b {
+		b[i] = bucketUpper(i).Seconds()
+	}
+	return b
+}()
+
+func (e *otlpExporter) payload(now time.Time) otlpRequest {
+	e.mu.Lock()
+	addrs := make([]string, 0, len(e.series))
+	for addr := range e.series {
+		addrs = append(addrs, addr)
+	}
+	series := make([]*otlpSeries, len(addrs))
+	sort.Strings(addrs)
+	for i, addr := range addrs {
+		series[i] = e.series[addr]
+	}
+	e.mu.Unlock()
+
+	metrics := map[string]*otlpMetric{}
+	var order []string
+	add := func(m otlpMetric) {
+		if prev := metrics[m.Name]; prev != nil {
+			switch {
+			case m.Histogram != nil:
+				prev.Histogram.DataPoints = append(prev.Histogram.DataPoints, m.Histogram.DataPoints...)
+			case m.Sum != nil:
+				prev.Sum.DataPoints = append(prev.Sum.DataPoints, m.Sum.DataPoints...)
+			case m.Gauge != nil:
+				prev.Gauge.DataPoints = append(prev.Gauge.DataPoints, m.Gauge.DataPoints...)
+			}
+			return
+		}
+		metrics[m.Name] = &m
+		order = append(order, m.Name)
+	}
+	for i, s := range series {
+		for _, m := range e.metrics(addrs[i], s, now) {
+			add(m)
+		}
+	}
+	out := make([]otlpMetric, len(order))
+	for i, name := range order {
+		out[i] = *metrics[name]
+	}
+
+	resource := []otlpKeyValue{{Key: "service.name", Value: otlpAnyString{e.opts.ServiceName}}}
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: resource},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: "github.com/YASSERRMD/celrix/clients/go"},
+			Metrics: out,
+		}},
+	}}}
+}
+
+// metrics returns the metrics of the clients of addr
+func (e *otlpExporter) metrics(addr string, s *otlpSeries, now time.Time) []otlpMetric {
+	s.mu.Lock()
+	commands, errs := maps.Clone(s.commands), maps.Clone(s.errors)
+	sent, dials, desyncs, open := s.sent, s.dials, s.desyncs, s.open
+	s.mu.Unlock()
+
+	return []otlpMetric{
+		e.histogram("celrix.client.command.duration", addr, &s.latency, now),
+		e.histogram("celrix.client.server.duration", addr, &s.serverLatency, now),
+		e.opSum("celrix.client.commands", "{command}", addr, commands, now),
+		e.opSum("celrix.client.errors", "{command}", addr, errs, now),
+		e.sum("celrix.client.sent", "By", e.point(addr, "", int64(sent), now)),
+		e.sum("celrix.client.dials", "{connection}", e.point(addr, "", int64(dials), now)),
+		e.sum("celrix.client.desyncs", "{connection}", e.point(addr, "", int64(desyncs), now)),
+		{Name: "celrix.client.connections", Unit: "{connection}", Gauge: &otlpGauge{
+			DataPoints: []otlpNumberDataPoint{{
+				Attributes:   e.attributes(addr, ""),
+				TimeUnixNano: strconv.FormatInt(now.UnixNano(), 10),
+				AsInt:        strconv.FormatInt(open, 10),
+			}},
+		}},
+	}
+}
+
+// attributes are the stable attributes of a series: the server address and,
+// for per-opcode metrics, the opcode
+func (e *otlpExporter) attributes(addr, op string) []otlpKeyValue {
+	attrs := make([]otlpKeyValue, 0, 2)
+	if op != "" {
+		attrs = append(attrs, otlpKeyValue{Key: "celrix.op", Value: otlpAnyString{op}})
+	}
+	return append(attrs, otlpKeyValue{Key: "server.address", Value: otlpAnyString{addr}})
+}
+
+func (e *otlpExporter) point(addr, op string, n int64, now time.Time) otlpNumberDataPoint {
+	return otlpNumberDataPoint{
+		Attributes:        e.attributes(addr, op),
+		StartTimeUnixNano: strconv.FormatInt(e.start.UnixNano(), 10),
+		TimeUnixNano:      strconv.FormatInt(now.UnixNano(), 10),
+		AsInt:             strconv.FormatInt(n, 10),
+	}
+}
+
+func (e *otlpExporter) sum(name, unit string, points ...otlpNumberDataPoint) otlpMetric {
+	return otlpMetric{Name: name, Unit: unit, Sum: &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true, DataPoints: points}}
+}
+
+func (e *otlpExporter) opSum(name, unit, addr string, counts map[uint8]uint64, now time.Time) otlpMetric {
+	ops := make([]uint8, 0, len(counts))
+	for op := range counts {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+	points := make([]otlpNumberDataPoint, len(ops))
+	for i, op := range ops {
+		points[i] = e.point(addr, Op(op).String(), int64(counts[op]), now)
+	}
+	return e.sum(name, unit, points...)
+}
+
+func (e *otlpExporter) histogram(name, addr string, t *latencyTracker, now time.Time) otlpMetric {
+	snap := t.snapshot()
+	ops := make([]uint8, 0, len(snap))
+	for op := range snap {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+
+	points := make([]otlpHistogramDataPoint, 0, len(ops))
+	for _, op := range ops {
+		h := snap[op]
+		counts := make([]string, histBuckets)
+		for i, n := range h.counts {
+			counts[i] = strconv.FormatUint(n, 10)
+		}
+		points = append(points, otlpHistogramDataPoint{
+			Attributes:        e.attributes(addr, Op(op).String()),
+			StartTimeUnixNano: strconv.FormatInt(e.start.UnixNano(), 10),
+			TimeUnixNano:      strconv.FormatInt(now.UnixNano(), 10),
+			Count:             strconv.FormatUint(h.total, 10),
+			Sum:               h.sum.Seconds(),
+			BucketCounts:      counts,
+			ExplicitBounds:    otlpBounds,
+		})
+	}
+	return otlpMetric{Name: name, Unit: "s", Histogram: &otlpHistogram{AggregationTemporality: otlpCumulative, DataPoints: points}}
+}
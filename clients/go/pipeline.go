@@ -0,0 +1,183 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Pipeline queues commands for Exec to send in one round trip: the frames
+// are written back to back and flushed once, then every reply is read, so a
+// batch costs one network latency rather than one per command.
+//
+// A Pipeline is not safe for concurrent use, and its client must not be
+// used by anyone else while Exec runs. Pipelined writes are not journaled,
+// and adaptive timeouts and sampled capture do not apply to them.
+type Pipeline struct {
+	c    *Client
+	cmds []pipelined
+	// err is the first command that could not be encoded, reported by Exec
+	err error
+}
+
+type pipelined struct {
+	op      uint8
+	flags   uint16
+	key     string
+	payload []byte
+}
+
+// Pipeline returns an empty pipeline on c
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{c: c}
+}
+
+// Len returns the number of commands queued
+func (p *Pipeline) Len() int { return len(p.cmds) }
+
+func (p *Pipeline) queue(op uint8, key string, payload []byte) {
+	p.cmds = append(p.cmds, pipelined{op: op, key: key, payload: payload})
+}
+
+// fail records a command that could not be queued, for Exec to report
+func (p *Pipeline) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// Ping queues a PING, answered by an OK-type reply
+func (p *Pipeline) Ping() {
+	p.queue(OpPing, "", nil)
+}
+
+// Get queues a GET, answered by the value or a nil reply
+func (p *Pipeline) Get(key string) {
+	p.queue(OpGet, key, appendString(nil, key))
+}
+
+// Set queues a SET, answered by OK
+func (p *Pipeline) Set(key, value string) {
+	op, payload, err := p.c.setPayload(key, []byte(value), 0)
+	if err != nil {
+		p.fail(fmt.Errorf("celrix: pipeline SET %q: %w", key, err))
+		return
+	}
+	p.queue(op, key, payload)
+}
+
+// Del queues a DEL, answered by whether the key existed, read with
+// Reply.Bool
+func (p *Pipeline) Del(key string) {
+	p.queue(OpDel, key, appendString(nil, key))
+}
+
+// Exists queues an EXISTS, answered as Del is
+func (p *Pipeline) Exists(key string) {
+	p.queue(OpExists, key, appendString(nil, key))
+}
+
+// IncrBy queues an INCRBY, answered by the new value
+func (p *Pipeline) IncrBy(key string, delta int64) {
+	p.queue(OpIncrBy, key, binary.BigEndian.AppendUint64(appendString(nil, key), uint64(delta)))
+}
+
+// VAdd queues a VADD, answered by OK. The vector is always sent in full,
+// even with WithVectorDeltas.
+func (p *Pipeline) VAdd(key string, vector []float32) {
+	if err := p.c.checkVector(OpVAdd, key, vector); err != nil {
+		p.fail(err)
+		return
+	}
+	p.queue(OpVAdd, key, appendVector(appendString(nil, key), vector))
+}
+
+// VSearch queues a VSEARCH, answered as Client.VSearch is by the scored
+// matches, read with Reply.Matches. opts may be nil, to use the index's
+// metric.
+func (p *Pipeline) VSearch(vector []float32, k int, opts *VSearchOptions) {
+	if err := p.c.checkVector(OpVSearch, "", vector); err != nil {
+		p.fail(err)
+		return
+	}
+	flags, err := vsearchFlags(opts)
+	if err != nil {
+		p.fail(fmt.Errorf("celrix: pipeline VSEARCH: %w", err))
+		return
+	}
+	p.cmds = append(p.cmds, pipelined{op: OpVSearch, flags: flags, payload: vsearchPayload(vector, k)})
+}
+
+// Exec sends the queued commands and returns their replies in the order
+// they were queued, emptying the pipeline. Replies are matched to commands
+// by request ID, so a server may answer them in any order.
+//
+// A command the server rejects gets an error reply and does not stop the
+// others; the first such error is also returned, with the replies. A
+// transport failure returns no replies, and the commands may or may not
+// have been applied.
+func (p *Pipeline) Exec() ([]Reply, error) {
+	cmds, qerr := p.cmds, p.err
+	p.cmds, p.err = nil, nil
+	if qerr != nil {
+		return nil, qerr
+	}
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	c := p.c
+	c.pendingOp, c.pendingKey = cmds[0].op, ""
+	ops, keys := make([]uint8, len(cmds)), make([]string, len(cmds))
+	for i, cmd := range cmds {
+		ops[i], keys[i] = cmd.op, cmd.key
+	}
+	if err := c.ready(ops, keys); err != nil {
+		return nil, err
+	}
+
+	index := make(map[uint64]int, len(cmds))
+	for i, cmd := range cmds {
+		if c.opts.hotKeys != nil && cmd.key != "" {
+			c.opts.hotKeys.hit(cmd.key)
+		}
+		if cmd.op == OpDel || cmd.op == OpVAdd {
+			c.vectors.forget(cmd.key)
+		}
+		c.spend(len(cmd.payload))
+		id, err := c.queueFlagged(cmd.op, cmd.flags, cmd.payload)
+		if err != nil {
+			c.pendingOp, c.pendingKey = cmd.op, cmd.key
+			return nil, c.transportFailed(c.cmdErr(err))
+		}
+		index[id] = i
+	}
+	if err := c.rw.Flush(); err != nil {
+		c.pendingOp, c.pendingKey = cmds[0].op, ""
+		return nil, c.transportFailed(c.cmdErr(err))
+	}
+
+	// Every reply is read, even past a server error, so the connection
+	// stays in step
+	results := make([]Reply, len(cmds))
+	var firstErr error
+	for range cmds {
+		f, err := c.recvFrame()
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[f.reqID]
+		if !ok {
+			return nil, c.desync(&f, "reply to a request the pipeline did not send or already answered", nil)
+		}
+		delete(index, f.reqID)
+		c.pendingOp, c.pendingReqID, c.pendingKey = cmds[i].op, f.reqID, cmds[i].key
+		reply, err := decodeReply(f.opcode, f.payload)
+		if err != nil {
+			return nil, c.cmdErr(err)
+		}
+		if rerr := reply.Err(); rerr != nil && firstErr == nil {
+			firstErr = c.cmdErr(rerr)
+		}
+		results[i] = c.withAttributes(reply)
+	}
+	return results, firstErr
+}
@@ -0,0 +1,79 @@
+package celrix
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+func TestPipelineMatchesRepliesByRequestID(t *testing.T) {
+	store := kv{data: map[string]string{"a": "1", "b": "2"}}
+	// Hold every reply until the third request, then answer in reverse
+	var mu sync.Mutex
+	var held [][]wire.Frame
+	s := &testServer{handle: func(_ int, f wire.Frame) []wire.Frame {
+		mu.Lock()
+		defer mu.Unlock()
+		held = append(held, store.handle(f))
+		if len(held) < 3 {
+			return nil
+		}
+		var out []wire.Frame
+		for i := len(held) - 1; i >= 0; i-- {
+			out = append(out, held[i]...)
+		}
+		held = nil
+		return out
+	}}
+	c := s.connect(t)
+
+	p := c.Pipeline()
+	p.Get("a")
+	p.Get("missing")
+	p.Get("b")
+	replies, err := p.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1", "", "2"}
+	for i, r := range replies {
+		b, ok := r.Bytes()
+		if string(b) != want[i] || ok != (want[i] != "") {
+			t.Errorf("reply %d: got %q %v, want %q", i, b, ok, want[i])
+		}
+	}
+}
+
+func TestPipelineReconnectsAfterQuarantine(t *testing.T) {
+	var store kv
+	s := &testServer{handle: func(conn int, f wire.Frame) []wire.Frame {
+		if key, _, _ := readString(f.Payload); conn == 1 && key == "bad" {
+			return reply(wire.Frame{RequestID: f.RequestID + 7}, OpNil, nil)
+		}
+		return store.handle(f)
+	}}
+	c := s.connect(t)
+
+	p := c.Pipeline()
+	p.Get("bad")
+	var derr *DesyncError
+	if _, err := p.Exec(); !errors.As(err, &derr) {
+		t.Fatalf("Exec with a stray reply: got %v, want a DesyncError", err)
+	}
+
+	// The next Exec replaces the quarantined connection before writing
+	p.Set("k", "v")
+	p.Get("k")
+	replies, err := p.Exec()
+	if err != nil {
+		t.Fatalf("Exec after quarantine: %v", err)
+	}
+	if b, ok := replies[1].Bytes(); !ok || string(b) != "v" {
+		t.Errorf("GET after quarantine: got %q %v", b, ok)
+	}
+	if n := s.dialCount(); n != 2 {
+		t.Errorf("%d dials, want 2", n)
+	}
+}
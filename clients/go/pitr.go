@@ -0,0 +1,208 @@
+package celrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// BackupSource opens a backup stream. Sources are opened once for
+// validation and again for the restore itself.
+type BackupSource func() (io.ReadCloser, error)
+
+// FileSource returns a BackupSource reading the file at path
+func FileSource(path string) BackupSource {
+	return func() (io.ReadCloser, error) { return os.Open(path) }
+}
+
+// RestorePlan describes a point-in-time restore: a full snapshot plus the
+// deltas taken after it, in order
+type RestorePlan struct {
+	Snapshot BackupSource
+	Deltas   []BackupSource
+
+	// TargetSeq stops the replay after this change sequence. Zero means
+	// no sequence bound.
+	TargetSeq uint64
+	// TargetTime stops the replay before the first change recorded after
+	// this instant. The zero time means no time bound.
+	TargetTime time.Time
+}
+
+// RestoreReport describes what a restore did, or would do
+type RestoreReport struct {
+	SnapshotSeq    uint64
+	SnapshotChunks int
+	// DeltaChanges is the number of delta changes within the target that
+	// are applied on top of the snapshot
+	DeltaChanges int
+	// FinalSeq is the sequence the dataset reflects after the restore
+	FinalSeq uint64
+}
+
+// Validate reads the whole plan without contacting a server: every chunk's
+// checksum must match, streams must be complete, deltas must chain without
+// gaps and the target must be reachable. It returns what a restore would do.
+func (p RestorePlan) Validate(ctx context.Context) (*RestoreReport, error) {
+	if p.Snapshot == nil {
+		return nil, errors.New("celrix: restore plan has no snapshot")
+	}
+	rep := &RestoreReport{}
+
+	info, err := scanSource(ctx, p.Snapshot, exportMagic, nil)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	rep.SnapshotSeq, rep.SnapshotChunks, rep.FinalSeq = info.Seq, info.Chunks, info.Seq
+	if p.TargetSeq != 0 && p.TargetSeq < info.Seq {
+		return nil, fmt.Errorf("celrix: target sequence %d precedes snapshot sequence %d", p.TargetSeq, info.Seq)
+	}
+
+	// covered is the highest sequence reflected by the snapshot and the
+	// deltas read so far
+	covered := info.Seq
+	stopped := false
+	for i, src := range p.Deltas {
+		stoppedBefore := stopped
+		info, err := scanSource(ctx, src, deltaMagic, func(ev ChangeEvent) error {
+			if stopped {
+				return nil
+			}
+			switch p.decide(ev, rep.SnapshotSeq) {
+			case deltaApply:
+				rep.DeltaChanges++
+				rep.FinalSeq = ev.Seq
+			case deltaStop:
+				stopped = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("delta %d: %w", i, err)
+		}
+		if !stoppedBefore && info.BaseSeq > covered+1 {
+			return nil, fmt.Errorf("celrix: delta %d starts at sequence %d, leaving a gap after %d", i, info.BaseSeq, covered)
+		}
+		if info.Seq > covered {
+			covered = info.Seq
+		}
+	}
+
+	switch {
+	case p.TargetSeq != 0:
+		if covered < p.TargetSeq {
+			return nil, fmt.Errorf("celrix: target sequence %d is beyond the last backed up change %d", p.TargetSeq, covered)
+		}
+		rep.FinalSeq = p.TargetSeq
+	case !stopped:
+		rep.FinalSeq = covered
+	}
+	return rep, nil
+}
+
+// RestoreToPoint validates the plan, then restores the snapshot and replays
+// deltas up to the target. Nothing is sent to the server if validation
+// fails.
+func (c *Client) RestoreToPoint(ctx context.Context, p RestorePlan) (*RestoreReport, error) {
+	want, err := p.Validate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := p.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	info, err := c.Restore(ctx, rc)
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("restore snapshot: %w", err)
+	}
+
+	rep := &RestoreReport{SnapshotSeq: info.Seq, SnapshotChunks: info.Chunks, FinalSeq: info.Seq}
+	stopped := false
+	for i, src := range p.Deltas {
+		if stopped {
+			break
+		}
+		rc, err := src()
+		if err != nil {
+			return rep, fmt.Errorf("delta %d: %w", i, err)
+		}
+		applied, err := c.applyDelta(ctx, rc, func(ev ChangeEvent) deltaAction {
+			a := p.decide(ev, rep.SnapshotSeq)
+			if a == deltaStop {
+				stopped = true
+			}
+			return a
+		}, nil)
+		rc.Close()
+		rep.DeltaChanges += applied.Chunks
+		if applied.Chunks > 0 {
+			rep.FinalSeq = applied.Seq
+		}
+		if err != nil {
+			return rep, fmt.Errorf("delta %d: %w", i, err)
+		}
+	}
+	if rep.DeltaChanges != want.DeltaChanges {
+		return rep, fmt.Errorf("celrix: applied %d delta changes, validation expected %d", rep.DeltaChanges, want.DeltaChanges)
+	}
+	rep.FinalSeq = want.FinalSeq
+	return rep, nil
+}
+
+// decide reports what to do with a delta change given the snapshot's
+// sequence and the plan's target
+func (p RestorePlan) decide(ev ChangeEvent, snapshotSeq uint64) deltaAction {
+	if ev.Seq <= snapshotSeq {
+		return deltaSkip
+	}
+	if p.TargetSeq != 0 && ev.Seq > p.TargetSeq {
+		return deltaStop
+	}
+	if !p.TargetTime.IsZero() && ev.Timestamp.After(p.TargetTime) {
+		return deltaStop
+	}
+	return deltaApply
+}
+
+// scanSource reads and verifies a backup stream end to end. For deltas each
+// change is decoded and passed to fn.
+func scanSource(ctx context.Context, src BackupSource, magic string, fn func(ChangeEvent) error) (ExportInfo, error) {
+	rc, err := src()
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	defer rc.Close()
+
+	er, err := newExportReader(rc, magic)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return er.info, err
+		}
+		chunk, err := er.next()
+		if err == io.EOF {
+			return er.info, nil
+		}
+		if err != nil {
+			return er.info, err
+		}
+		if fn == nil {
+			continue
+		}
+		ev, err := decodeChangeEvent(chunk)
+		if err != nil {
+			return er.info, fmt.Errorf("change %d: %w", er.info.Chunks, err)
+		}
+		if err := fn(ev); err != nil {
+			return er.info, err
+		}
+	}
+}
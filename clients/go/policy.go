@@ -0,0 +1,66 @@
+package celrix
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCommandDenied is wrapped by errors for commands rejected by the
+// client's command policy. Denied commands are never sent.
+var ErrCommandDenied = errors.New("celrix: command denied by policy")
+
+// WithCommandPolicy restricts which commands the client may send, for
+// embedding it in code that should not be able to, say, FLUSHDB or change
+// config. If allow is non-empty only the listed opcodes are permitted; any
+// opcode in deny is rejected regardless. The connection handshake,
+// dictionary and capability negotiation, and PING are exempt, so that the
+// health checks of a Pool keep working under any policy. SELECT is only
+// sent on reconnection to restore a database Select chose, which the policy
+// allowed.
+func WithCommandPolicy(allow, deny []Op) Option {
+	return func(o *options) {
+		p := &commandPolicy{deny: make(map[Op]bool, len(deny))}
+		if len(allow) > 0 {
+			p.allow = make(map[Op]bool, len(allow))
+			for _, op := range allow {
+				p.allow[op] = true
+			}
+		}
+		for _, op := range deny {
+			p.deny[op] = true
+		}
+		o.policy = p
+	}
+}
+
+type commandPolicy struct {
+	allow map[Op]bool // nil allows everything not denied
+	deny  map[Op]bool
+}
+
+func (p *commandPolicy) check(opcode uint8) error {
+	if p == nil || opcode == OpHello || opcode == OpDictionaries || opcode == OpCapabilities || opcode == OpPing {
+		return nil
+	}
+	op := Op(canonicalOp(opcode))
+	if p.deny[op] || (p.allow != nil && !p.allow[op]) {
+		return fmt.Errorf("%w: %s", ErrCommandDenied, op)
+	}
+	return nil
+}
+
+// canonicalOp returns the command opcode stands for, as seen by
+// WithCommandPolicy and WithOpConcurrencyLimit: a compressed SET and the
+// frames of a streamed upload are still a SET, a streamed read a GET, and a
+// vector delta a VADD
+func canonicalOp(opcode uint8) uint8 {
+	switch opcode {
+	case OpSetCompressed, OpSetStream, OpSetChunk, OpSetStreamEnd:
+		return OpSet
+	case OpGetStream:
+		return OpGet
+	case OpVDelta:
+		return OpVAdd
+	}
+	return opcode
+}
@@ -0,0 +1,56 @@
+package celrix
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicyCoversStreamOpcodes(t *testing.T) {
+	var o options
+	WithCommandPolicy(nil, []Op{OpSet, OpGet})(&o)
+	for _, opcode := range []uint8{OpSetStream, OpSetChunk, OpSetStreamEnd, OpGetStream, OpSetCompressed} {
+		if err := o.policy.check(opcode); !errors.Is(err, ErrCommandDenied) {
+			t.Errorf("%s with SET and GET denied: got %v, want ErrCommandDenied", Op(opcode), err)
+		}
+	}
+
+	WithCommandPolicy([]Op{OpPing}, nil)(&o)
+	for _, opcode := range []uint8{OpSetStream, OpGetStream} {
+		if err := o.policy.check(opcode); !errors.Is(err, ErrCommandDenied) {
+			t.Errorf("%s outside the allowlist: got %v, want ErrCommandDenied", Op(opcode), err)
+		}
+	}
+
+	WithCommandPolicy([]Op{OpSet, OpGet}, nil)(&o)
+	for _, opcode := range []uint8{OpSetStream, OpSetChunk, OpSetStreamEnd, OpGetStream} {
+		if err := o.policy.check(opcode); err != nil {
+			t.Errorf("%s with SET and GET allowed: %v", Op(opcode), err)
+		}
+	}
+}
+
+func TestOpLimitCoversStreamOpcodes(t *testing.T) {
+	var o options
+	WithOpConcurrencyLimit(map[Op]int{OpSet: 1, OpGet: 1})(&o)
+	done := make(chan struct{})
+	close(done)
+
+	for _, pair := range [][2]uint8{{OpSet, OpSetStream}, {OpSet, OpSetChunk}, {OpGet, OpGetStream}} {
+		sem, err := o.opLimits.acquire(done, pair[0])
+		if err != nil || sem == nil {
+			t.Fatalf("acquire %s: sem %v, err %v", Op(pair[0]), sem, err)
+		}
+		if _, err := o.opLimits.acquire(done, pair[1]); !errors.Is(err, errSlotWait) {
+			t.Errorf("%s while %s holds the only slot: got %v, want errSlotWait", Op(pair[1]), Op(pair[0]), err)
+		}
+		<-sem
+	}
+}
+
+func TestPolicyExemptsPing(t *testing.T) {
+	var o options
+	WithCommandPolicy([]Op{OpGet}, []Op{OpPing})(&o)
+	if err := o.policy.check(OpPing); err != nil {
+		t.Errorf("PING under a policy without it: %v", err)
+	}
+}
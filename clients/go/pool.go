@@ -0,0 +1,444 @@
+package celrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPoolClosed is returned by Get on a closed pool
+var ErrPoolClosed = errors.New("celrix: pool closed")
+
+// PoolOptions configures a Pool
+type PoolOptions struct {
+	// MaxConns caps the number of open connections. Defaults to 10.
+	MaxConns int
+	// MinIdle keeps at least this many connections open and idle, capped
+	// at MaxConns, so bursts do not pay for dialling. The pool tops itself
+	// up in the background when it opens and whenever a connection is
+	// closed, including those recycled for age or idleness.
+	MinIdle int
+	// PingOnCheckout makes Get ping an idle connection before handing it
+	// out, closing it and trying another if the ping fails. It costs a
+	// round trip per Get but means callers never receive a connection
+	// that broke while idle.
+	PingOnCheckout bool
+	// SweepInterval runs HealthSweep in the background at this interval.
+	// Zero disables scheduled sweeps.
+	SweepInterval time.Duration
+	// SweepWorkers bounds how many connections a sweep pings at once.
+	// Defaults to 4.
+	SweepWorkers int
+	// MaxConnLifetime recycles connections once they have been open this
+	// long, give or take a tenth, so that load rebalances when servers
+	// behind a load balancer are rolled. The connection is closed when it
+	// is next returned or found idle. Zero keeps connections indefinitely.
+	MaxConnLifetime time.Duration
+	// MaxConnIdleTime closes connections left idle for longer than this.
+	// Zero keeps idle connections indefinitely.
+	MaxConnIdleTime time.Duration
+	// LeakThreshold reports, through the logger given with WithLogger in
+	// ClientOptions, connections checked out for longer than this. Zero
+	// disables leak detection.
+	LeakThreshold time.Duration
+	// StackSampleRate is the fraction of checkouts, between 0 and 1, whose
+	// stack is captured so a leak report shows where the connection was
+	// taken. Capturing stacks is costly; keep it low in production.
+	StackSampleRate float64
+	// ClientOptions are applied to every connection the pool opens. A
+	// clock given with WithClock also drives scheduled sweeps.
+	// WithWriteJournal is refused, as a journal belongs to one client.
+	ClientOptions []Option
+}
+
+// PoolStats is a snapshot of a pool's connections
+type PoolStats struct {
+	Open  int
+	Idle  int
+	InUse int
+	// Evicted counts connections closed by health sweeps
+	Evicted uint64
+	// Leaked counts checkouts held beyond LeakThreshold
+	Leaked uint64
+	// Recycled counts connections closed for exceeding MaxConnLifetime or
+	// MaxConnIdleTime
+	Recycled uint64
+}
+
+// SweepResult reports the outcome of a HealthSweep
+type SweepResult struct {
+	Checked int
+	Evicted int
+}
+
+// Pool shares a bounded set of connections to one server among
+// goroutines. Each connection is used by one goroutine at a time: Get
+// checks one out and Put returns it.
+type Pool struct {
+	addr string
+	opts PoolOptions
+	o    options // resolved ClientOptions, for the clock
+
+	slots chan struct{} // one token per open connection
+	idle  chan *Client
+
+	mu      sync.Mutex
+	closed  bool
+	evicted uint64
+	leaked  uint64
+
+	recycled uint64
+	lives    map[*Client]*connLife
+
+	checkouts map[*Client]*checkout
+	held      histogram
+
+	filling atomic.Bool
+
+	// fair schedules checkouts made through tenants
+	fair *fairQueue
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPool returns a pool of connections to addr. Connections are opened on
+// demand.
+func NewPool(addr string, opts PoolOptions) *Pool {
+	if opts.MaxConns <= 0 {
+		opts.MaxConns = 10
+	}
+	if opts.SweepWorkers <= 0 {
+		opts.SweepWorkers = 4
+	}
+	if opts.MinIdle > opts.MaxConns {
+		opts.MinIdle = opts.MaxConns
+	}
+	p := &Pool{
+		addr:  addr,
+		opts:  opts,
+		slots: make(chan struct{}, opts.MaxConns),
+		idle:  make(chan *Client, opts.MaxConns),
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts.ClientOptions {
+		opt(&p.o)
+	}
+	if opts.SweepInterval > 0 {
+		p.wg.Add(1)
+		go p.sweepLoop()
+	}
+	if opts.LeakThreshold > 0 {
+		p.wg.Add(1)
+		go p.leakLoop()
+	}
+	p.fill()
+	return p
+}
+
+// Get checks out a connection, reusing an idle one or opening a new one if
+// the pool is below MaxConns, and otherwise waiting until one is returned
+// or ctx is done. With PingOnCheckout, idle connections that fail a ping
+// are replaced.
+func (p *Pool) Get(ctx context.Context) (*Client, error) {
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+	c, err := p.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.checkout(c)
+	return c, nil
+}
+
+func (p *Pool) get(ctx context.Context) (*Client, error) {
+	for {
+		select {
+		case c := <-p.idle:
+			if !p.usable(ctx, c) {
+				continue
+			}
+			return c, nil
+		default:
+		}
+		select {
+		case c := <-p.idle:
+			if !p.usable(ctx, c) {
+				continue
+			}
+			return c, nil
+		case p.slots <- struct{}{}:
+			c, err := p.open(ctx)
+			if err != nil {
+				<-p.slots
+				return nil, err
+			}
+			p.track(c)
+			return c, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// usable vets an idle connection for checkout, closing it if it is stale
+// or, with PingOnCheckout, fails a ping
+func (p *Pool) usable(ctx context.Context, c *Client) bool {
+	if p.stale(c, true) {
+		p.retire(c)
+		return false
+	}
+	if !p.opts.PingOnCheckout {
+		return true
+	}
+	stop := closeOnDone(ctx, c.conn)
+	err := c.Ping()
+	stop()
+	if err != nil {
+		p.evict(c)
+		return false
+	}
+	return true
+}
+
+func (p *Pool) open(ctx context.Context) (*Client, error) {
+	type result struct {
+		c   *Client
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		c, err := p.connect()
+		ch <- result{c, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.c, r.err
+	case <-ctx.Done():
+		// Let the dial finish in the background and discard it
+		go func() {
+			if r := <-ch; r.c != nil {
+				r.c.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// connect opens a connection with the pool's client options. A write
+// journal cannot be shared by the pool's connections, so WithWriteJournal
+// is refused.
+func (p *Pool) connect() (*Client, error) {
+	if p.o.journalDir != "" {
+		return nil, fmt.Errorf("%w: WithWriteJournal cannot be used with a Pool", ErrJournalInUse)
+	}
+	return Connect(p.addr, p.opts.ClientOptions...)
+}
+
+// Put returns a connection obtained from Get. Connections that failed with
+// a transport error should be passed to Discard instead.
+func (p *Pool) Put(c *Client) {
+	p.checkin(c)
+	if p.isClosed() || c.Err() != nil {
+		p.Discard(c)
+		return
+	}
+	if p.stale(c, false) {
+		p.retire(c)
+		return
+	}
+	p.markIdle(c)
+	p.idle <- c
+}
+
+// Discard closes a connection obtained from Get and frees its slot
+func (p *Pool) Discard(c *Client) {
+	p.checkin(c)
+	p.untrack(c)
+	c.Close()
+	<-p.slots
+	p.fill()
+}
+
+// fill opens connections in the background until MinIdle are idle or the
+// pool is full. Only one fill runs at a time; a dial failure ends it until
+// the next trigger, so an unreachable server is not dialled in a loop.
+func (p *Pool) fill() {
+	if p.opts.MinIdle <= 0 || len(p.idle) >= p.opts.MinIdle || !p.filling.CompareAndSwap(false, true) {
+		return
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.filling.Store(false)
+		return
+	}
+	p.wg.Add(1)
+	p.mu.Unlock()
+
+	go func() {
+		defer p.wg.Done()
+		defer p.filling.Store(false)
+		for len(p.idle) < p.opts.MinIdle && !p.isClosed() {
+			select {
+			case p.slots <- struct{}{}:
+			default:
+				return
+			}
+			c, err := p.connect()
+			if err != nil {
+				<-p.slots
+				p.logger().Log(LevelWarn, "pool could not open idle connection", "addr", p.addr, "error", err)
+				return
+			}
+			p.track(c)
+			p.markIdle(c)
+			p.requeue(c)
+		}
+	}()
+}
+
+// Stats returns a snapshot of the pool's connections
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	evicted, leaked, recycled := p.evicted, p.leaked, p.recycled
+	p.mu.Unlock()
+	open, idle := len(p.slots), len(p.idle)
+	return PoolStats{Open: open, Idle: idle, InUse: open - idle, Evicted: evicted, Leaked: leaked, Recycled: recycled}
+}
+
+// HealthSweep pings every idle connection, at most SweepWorkers at a time,
+// and closes those that fail, so that broken connections are found in the
+// background rather than by the next caller to check them out. Connections
+// past MaxConnLifetime or MaxConnIdleTime are closed without a ping, and
+// connections checked out during the sweep are not pinged. If ctx ends
+// mid-sweep, pings in flight are abandoned, their connections evicted, and
+// the rest returned unchecked.
+func (p *Pool) HealthSweep(ctx context.Context) (SweepResult, error) {
+	var batch []*Client
+drain:
+	for {
+		select {
+		case c := <-p.idle:
+			batch = append(batch, c)
+		default:
+			break drain
+		}
+	}
+
+	var (
+		mu  sync.Mutex
+		res SweepResult
+		wg  sync.WaitGroup
+	)
+	work := make(chan *Client)
+	for i := 0; i < p.opts.SweepWorkers && i < len(batch); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range work {
+				if p.stale(c, true) {
+					p.retire(c)
+					continue
+				}
+				stop := closeOnDone(ctx, c.conn)
+				err := c.Ping()
+				stop()
+				mu.Lock()
+				res.Checked++
+				if err != nil {
+					res.Evicted++
+				}
+				mu.Unlock()
+				if err != nil {
+					p.evict(c)
+				} else {
+					p.requeue(c)
+				}
+			}
+		}()
+	}
+	for i, c := range batch {
+		select {
+		case work <- c:
+		case <-ctx.Done():
+			for _, rest := range batch[i:] {
+				p.requeue(rest)
+			}
+			close(work)
+			wg.Wait()
+			return res, ctx.Err()
+		}
+	}
+	close(work)
+	wg.Wait()
+	return res, nil
+}
+
+// requeue returns a swept connection to the idle set without resetting its
+// idle time
+func (p *Pool) requeue(c *Client) {
+	if p.isClosed() {
+		p.Discard(c)
+		return
+	}
+	p.idle <- c
+}
+
+func (p *Pool) evict(c *Client) {
+	p.mu.Lock()
+	p.evicted++
+	p.mu.Unlock()
+	p.Discard(c)
+}
+
+func (p *Pool) sweepLoop() {
+	defer p.wg.Done()
+	for {
+		t := p.o.timer(p.opts.SweepInterval)
+		select {
+		case <-t.C():
+			// Bound each sweep by the interval so a stalled server cannot
+			// hold connections out of the pool indefinitely
+			ctx, cancel := context.WithTimeout(context.Background(), p.opts.SweepInterval)
+			p.HealthSweep(ctx)
+			cancel()
+			p.fill()
+		case <-p.done:
+			t.Stop()
+			return
+		}
+	}
+}
+
+func (p *Pool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// Close stops scheduled sweeps and closes idle connections. Connections
+// checked out are closed when they are returned.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	p.mu.Unlock()
+	p.wg.Wait()
+	for {
+		select {
+		case c := <-p.idle:
+			p.Discard(c)
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package celrix
+
+import (
+	"math/rand/v2"
+	"runtime/debug"
+	"time"
+)
+
+// checkout records a connection handed out by Get
+type checkout struct {
+	start    time.Time
+	stack    []byte // set for sampled checkouts
+	reported bool
+}
+
+func (p *Pool) checkout(c *Client) {
+	co := &checkout{start: p.o.now()}
+	if r := p.opts.StackSampleRate; r > 0 && (r >= 1 || rand.Float64() < r) {
+		co.stack = debug.Stack()
+	}
+	p.mu.Lock()
+	if p.checkouts == nil {
+		p.checkouts = make(map[*Client]*checkout)
+	}
+	p.checkouts[c] = co
+	p.mu.Unlock()
+}
+
+// checkin ends c's checkout, if it has one, and records how long it was
+// held
+func (p *Pool) checkin(c *Client) {
+	p.mu.Lock()
+	co := p.checkouts[c]
+	if co == nil {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.checkouts, c)
+	held := p.o.now().Sub(co.start)
+	p.held.observe(held)
+	p.mu.Unlock()
+	if co.reported {
+		p.logger().Log(LevelInfo, "leaked pool connection returned", "addr", p.addr, "held", held)
+	}
+}
+
+// CheckoutDuration returns the p-th percentile (0 < p <= 100) of how long
+// connections were held between Get and Put or Discard, and the number of
+// checkouts behind it
+func (p *Pool) CheckoutDuration(pct float64) (time.Duration, uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.held.percentile(pct), p.held.total
+}
+
+func (p *Pool) logger() Logger {
+	if p.o.logger != nil {
+		return p.o.logger
+	}
+	return defaultLogger
+}
+
+// detectLeaks reports, once each, connections checked out for longer than
+// LeakThreshold
+func (p *Pool) detectLeaks() {
+	now := p.o.now()
+	type leak struct {
+		held  time.Duration
+		stack []byte
+	}
+	var leaks []leak
+	p.mu.Lock()
+	for _, co := range p.checkouts {
+		if held := now.Sub(co.start); !co.reported && held > p.opts.LeakThreshold {
+			co.reported = true
+			p.leaked++
+			leaks = append(leaks, leak{held, co.stack})
+		}
+	}
+	p.mu.Unlock()
+	for _, l := range leaks {
+		kv := []interface{}{"addr", p.addr, "held", l.held, "threshold", p.opts.LeakThreshold}
+		if l.stack != nil {
+			kv = append(kv, "stack", string(l.stack))
+		}
+		p.logger().Log(LevelWarn, "pooled connection held beyond leak threshold", kv...)
+	}
+}
+
+func (p *Pool) leakLoop() {
+	defer p.wg.Done()
+	interval := p.opts.LeakThreshold / 2
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	for {
+		t := p.o.timer(interval)
+		select {
+		case <-t.C():
+			p.detectLeaks()
+		case <-p.done:
+			t.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package celrix
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// connLife tracks when a pooled connection must be recycled
+type connLife struct {
+	expires   time.Time // zero without MaxConnLifetime
+	idleSince time.Time
+}
+
+// lifetimeJitter spreads connection expiry over the last tenth of
+// MaxConnLifetime, so connections opened together are not all recycled at
+// once
+const lifetimeJitter = 0.1
+
+func (p *Pool) track(c *Client) {
+	var life connLife
+	if d := p.opts.MaxConnLifetime; d > 0 {
+		d -= time.Duration(rand.Float64() * lifetimeJitter * float64(d))
+		life.expires = p.o.now().Add(d)
+	}
+	p.mu.Lock()
+	if p.lives == nil {
+		p.lives = make(map[*Client]*connLife)
+	}
+	p.lives[c] = &life
+	p.mu.Unlock()
+}
+
+func (p *Pool) untrack(c *Client) {
+	p.mu.Lock()
+	delete(p.lives, c)
+	p.mu.Unlock()
+}
+
+// markIdle records that c was returned to the idle set
+func (p *Pool) markIdle(c *Client) {
+	p.mu.Lock()
+	if life := p.lives[c]; life != nil {
+		life.idleSince = p.o.now()
+	}
+	p.mu.Unlock()
+}
+
+// stale reports whether c has outlived MaxConnLifetime or, when idle, sat
+// unused for longer than MaxConnIdleTime
+func (p *Pool) stale(c *Client, idle bool) bool {
+	now := p.o.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	life := p.lives[c]
+	if life == nil {
+		return false
+	}
+	if !life.expires.IsZero() && !now.Before(life.expires) {
+		return true
+	}
+	return idle && p.opts.MaxConnIdleTime > 0 && now.Sub(life.idleSince) > p.opts.MaxConnIdleTime
+}
+
+// retire closes a connection recycled for age or idleness
+func (p *Pool) retire(c *Client) {
+	p.mu.Lock()
+	p.recycled++
+	p.mu.Unlock()
+	p.Discard(c)
+}
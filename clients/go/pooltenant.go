@@ -0,0 +1,207 @@
+package celrix
+
+import (
+	"context"
+	"sync"
+)
+
+// Tenant is a share of a pool's connections for one class of traffic.
+// While the pool has connections to spare, tenants check them out freely;
+// once every connection is in use, waiting tenants are served by weighted
+// fair queuing, so a tenant running a bulk load cannot starve another's
+// interactive traffic. Over a busy period each waiting tenant is handed
+// connections in proportion to its weight, whatever the length of its
+// queue, and within a tenant waiters are served in order.
+//
+// Only checkouts made through tenants are scheduled; Pool.Get bypasses
+// the queue. A Tenant is safe for concurrent use.
+type Tenant struct {
+	fq     *fairQueue
+	p      *Pool
+	name   string
+	weight float64
+
+	// finish is the virtual time at which the tenant's last grant ends
+	finish  float64
+	waiters []*tenantWaiter
+	inUse   int
+	granted uint64
+}
+
+// TenantStats is a snapshot of a tenant's checkouts
+type TenantStats struct {
+	Name string
+	// InUse counts connections checked out through the tenant, and
+	// Waiting the callers queued for one
+	InUse   int
+	Waiting int
+	// Checkouts counts connections handed out through the tenant
+	Checkouts uint64
+}
+
+// Tenant returns the tenant called name, creating it with weight on first
+// use; later calls return the same tenant and update its weight. A weight
+// below 1 is treated as 1.
+func (p *Pool) Tenant(name string, weight int) *Tenant {
+	p.mu.Lock()
+	if p.fair == nil {
+		p.fair = &fairQueue{capacity: p.opts.MaxConns, tenants: make(map[string]*Tenant)}
+	}
+	fq := p.fair
+	p.mu.Unlock()
+
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+	t, ok := fq.tenants[name]
+	if !ok {
+		t = &Tenant{fq: fq, p: p, name: name}
+		fq.tenants[name] = t
+	}
+	t.weight = float64(max(weight, 1))
+	return t
+}
+
+// Name returns the tenant's name
+func (t *Tenant) Name() string { return t.name }
+
+// Get checks out a connection for the tenant, waiting for its turn if the
+// pool is fully in use, or until ctx is done. The connection must be given
+// back with the tenant's Put or Discard.
+func (t *Tenant) Get(ctx context.Context) (*Client, error) {
+	if t.p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+	if err := t.fq.acquire(ctx, t, t.p.done); err != nil {
+		return nil, err
+	}
+	c, err := t.p.Get(ctx)
+	if err != nil {
+		t.fq.release(t)
+		return nil, err
+	}
+	return c, nil
+}
+
+// Put returns a connection obtained from the tenant's Get, as Pool.Put does
+func (t *Tenant) Put(c *Client) {
+	t.p.Put(c)
+	t.fq.release(t)
+}
+
+// Discard closes a connection obtained from the tenant's Get, as
+// Pool.Discard does
+func (t *Tenant) Discard(c *Client) {
+	t.p.Discard(c)
+	t.fq.release(t)
+}
+
+// Stats returns a snapshot of the tenant's checkouts
+func (t *Tenant) Stats() TenantStats {
+	t.fq.mu.Lock()
+	defer t.fq.mu.Unlock()
+	return TenantStats{Name: t.name, InUse: t.inUse, Waiting: len(t.waiters), Checkouts: t.granted}
+}
+
+type tenantWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// fairQueue schedules tenant checkouts by start-time fair queuing: each
+// grant advances its tenant's virtual finish time by 1/weight, and the next
+// connection goes to the waiting tenant whose grant would start earliest.
+// A tenant returning from idle starts at the current virtual time, so
+// that idleness does not bank credit.
+type fairQueue struct {
+	mu       sync.Mutex
+	capacity int
+	inUse    int
+	vtime    float64
+	tenants  map[string]*Tenant
+	waiting  int
+}
+
+func (fq *fairQueue) acquire(ctx context.Context, t *Tenant, closed <-chan struct{}) error {
+	fq.mu.Lock()
+	if fq.inUse < fq.capacity && fq.waiting == 0 {
+		fq.grant(t)
+		fq.mu.Unlock()
+		return nil
+	}
+	w := &tenantWaiter{ready: make(chan struct{})}
+	t.waiters = append(t.waiters, w)
+	fq.waiting++
+	fq.mu.Unlock()
+
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-closed:
+		err = ErrPoolClosed
+	}
+
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+	if w.granted {
+		// Granted while giving up: pass the connection on
+		fq.inUse--
+		t.inUse--
+		fq.dispatch()
+		return err
+	}
+	for i, x := range t.waiters {
+		if x == w {
+			t.waiters = append(t.waiters[:i], t.waiters[i+1:]...)
+			break
+		}
+	}
+	fq.waiting--
+	return err
+}
+
+func (fq *fairQueue) release(t *Tenant) {
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+	fq.inUse--
+	t.inUse--
+	fq.dispatch()
+}
+
+// grant hands t a connection, advancing the virtual clock. Called with mu
+// held.
+func (fq *fairQueue) grant(t *Tenant) {
+	start := max(fq.vtime, t.finish)
+	fq.vtime = start
+	t.finish = start + 1/t.weight
+	fq.inUse++
+	t.inUse++
+	t.granted++
+}
+
+// dispatch serves waiters while connections are free. Called with mu held.
+func (fq *fairQueue) dispatch() {
+	for fq.inUse < fq.capacity && fq.waiting > 0 {
+		var next *Tenant
+		for _, t := range fq.tenants {
+			if len(t.waiters) == 0 {
+				continue
+			}
+			start, best := max(fq.vtime, t.finish), 0.0
+			if next != nil {
+				best = max(fq.vtime, next.finish)
+			}
+			if next == nil || start < best || (start == best && t.name < next.name) {
+				next = t
+			}
+		}
+		w := next.waiters[0]
+		next.waiters = next.waiters[1:]
+		fq.waiting--
+		fq.grant(next)
+		w.granted = true
+		close(w.ready)
+	}
+}
@@ -0,0 +1,51 @@
+package celrix
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// Profiler label keys set by WithProfilerLabels
+const (
+	LabelOp         = "celrix.op"
+	LabelCollection = "celrix.collection"
+)
+
+// WithProfilerLabels tags the calling goroutine with pprof labels while a
+// command is outstanding: LabelOp names the opcode and, for collection
+// commands, LabelCollection names the collection. CPU profiles then
+// attribute encoding, decoding and I/O to the command that caused it.
+//
+// pprof offers no way to read a goroutine's current labels, so when the
+// reply arrives the goroutine's labels are reset to those carried by base.
+// Pass the context holding the service's own labels, or nil for none.
+func WithProfilerLabels(base context.Context) Option {
+	if base == nil {
+		base = context.Background()
+	}
+	return func(o *options) {
+		o.labelBase = base
+	}
+}
+
+// labelCommand applies the labels for the command being sent
+func (c *Client) labelCommand(opcode uint8) {
+	if c.opts.labelBase == nil {
+		return
+	}
+	labels := []string{LabelOp, Op(opcode).String()}
+	if c.pendingCollection != "" {
+		labels = append(labels, LabelCollection, c.pendingCollection)
+	}
+	pprof.SetGoroutineLabels(pprof.WithLabels(c.opts.labelBase, pprof.Labels(labels...)))
+	c.labeled = true
+}
+
+// unlabel restores the base labels once the command is done
+func (c *Client) unlabel() {
+	if !c.labeled {
+		return
+	}
+	c.labeled = false
+	pprof.SetGoroutineLabels(c.opts.labelBase)
+}
@@ -0,0 +1,78 @@
+package celrix
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PreloadProgress reports how much of a collection's index is resident
+type PreloadProgress struct {
+	Collection string
+	// Loaded and Total count index bytes paged in so far and overall
+	Loaded, Total uint64
+	Elapsed       time.Duration
+}
+
+// Fraction returns the share of the index loaded, between 0 and 1
+func (p PreloadProgress) Fraction() float64 {
+	if p.Total == 0 {
+		return 1
+	}
+	return float64(p.Loaded) / float64(p.Total)
+}
+
+// PreloadIndex pages a collection's vector index into server memory, as
+// after a restart, and returns once it is fully resident. progress, if not
+// nil, is called as the server reports headway, so callers can gate traffic
+// until the index is warm. Preloading runs on a dedicated connection;
+// cancelling ctx stops waiting but lets the server finish loading.
+func (a *Admin) PreloadIndex(ctx context.Context, collection string, progress func(PreloadProgress)) (PreloadProgress, error) {
+	p := PreloadProgress{Collection: collection}
+	release, err := a.c.streamSlot(ctx, OpPreloadIndex)
+	if err != nil {
+		return p, err
+	}
+	defer release()
+	sub, err := a.c.dialDedicated(ctx)
+	if err != nil {
+		return p, err
+	}
+	defer sub.closeDedicated()
+	defer closeOnDone(ctx, sub.conn)()
+
+	start := a.c.opts.now()
+	sub.nextCollection = collection
+	if err := sub.sendFrame(OpPreloadIndex, appendString(nil, collection)); err != nil {
+		return p, ctxErr(ctx, err)
+	}
+	for {
+		f, err := sub.recvFrame()
+		if err != nil {
+			return p, ctxErr(ctx, err)
+		}
+		p.Elapsed = a.c.opts.now().Sub(start)
+		switch f.opcode {
+		case OpPreloadProgress:
+			// Payload: [loaded u64][total u64]
+			if len(f.payload) < 16 {
+				return p, errors.New("invalid preload progress payload")
+			}
+			p.Loaded = binary.BigEndian.Uint64(f.payload)
+			p.Total = binary.BigEndian.Uint64(f.payload[8:])
+			if progress != nil {
+				progress(p)
+			}
+		case OpOk:
+			p.Loaded = p.Total
+			return p, nil
+		default:
+			if _, err := decodeResponse(f.opcode, f.payload); err != nil {
+				return p, err
+			}
+			return p, fmt.Errorf("unexpected opcode in preload stream: %d", f.opcode)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package celrix
+
+import (
+	"errors"
+	"fmt"
+)
+
+// defaultQueryLimit is the page size of a Query without Limit
+const defaultQueryLimit = 10
+
+// Query builds a vector search. It is the single entry point for searches
+// that combine a filter, paging and scores:
+//
+//	hits, err := client.Query().
+//		Vector(v).
+//		Filter(celrix.F[string]("lang").Eq("en")).
+//		Limit(20).Offset(40).
+//		WithScores().
+//		Run()
+//
+// A query compiles to the narrowest wire command that can answer it:
+// VSEARCH for plain searches, VSEARCHFILTER when filtered, VSEARCHMETA when
+// scores or metadata are wanted, and CVSEARCH for collection queries.
+// Servers return the top k only, so an offset is applied by fetching
+// offset+limit results and skipping the first offset; deep pages cost
+// accordingly.
+//
+// Builder methods modify and return the receiver. Queries are not safe for
+// concurrent use.
+type Query struct {
+	c          *Client
+	vector     []float32
+	filter     *Filter
+	limit      int
+	offset     int
+	scores     bool
+	collection string
+}
+
+// Query starts a new search
+func (c *Client) Query() *Query {
+	return &Query{c: c, limit: defaultQueryLimit}
+}
+
+// Vector sets the query vector
+func (q *Query) Vector(v []float32) *Query {
+	q.vector = v
+	return q
+}
+
+// Filter restricts results to vectors whose metadata matches f
+func (q *Query) Filter(f Filter) *Query {
+	q.filter = &f
+	return q
+}
+
+// Limit sets the page size
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Offset skips the first n results
+func (q *Query) Offset(n int) *Query {
+	q.offset = n
+	return q
+}
+
+// WithScores includes similarity scores and metadata in Run's hits
+func (q *Query) WithScores() *Query {
+	q.scores = true
+	return q
+}
+
+// Collection searches the named collection instead of the default keyspace.
+// Collection searches return keys only and cannot be combined with
+// WithScores or Into.
+func (q *Query) Collection(name string) *Query {
+	q.collection = name
+	return q
+}
+
+// NextPage returns a copy of the query advanced by one page
+func (q *Query) NextPage() *Query {
+	next := *q
+	next.offset += next.limit
+	return &next
+}
+
+func (q *Query) validate() error {
+	switch {
+	case len(q.vector) == 0:
+		return errors.New("celrix: query has no vector")
+	case q.limit <= 0:
+		return fmt.Errorf("celrix: query limit must be positive, got %d", q.limit)
+	case q.offset < 0:
+		return fmt.Errorf("celrix: query offset must not be negative, got %d", q.offset)
+	}
+	return nil
+}
+
+// Run executes the query. Hits carry scores and metadata only if WithScores
+// was set.
+func (q *Query) Run() ([]SearchHit, error) {
+	if q.scores {
+		return q.hits()
+	}
+	keys, err := q.Keys()
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]SearchHit, len(keys))
+	for i, key := range keys {
+		hits[i].Key = key
+	}
+	return hits, nil
+}
+
+// Keys executes the query and returns the matching keys
+func (q *Query) Keys() ([]string, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	k := q.offset + q.limit
+
+	var keys []string
+	var err error
+	switch {
+	case q.collection != "":
+		keys, err = q.c.Collection(q.collection).VSearch(q.vector, k, q.filter)
+	case q.filter != nil:
+		keys, err = q.c.VSearchFilter(q.vector, k, *q.filter)
+	default:
+		var matches []VectorMatch
+		matches, err = q.c.VSearch(q.vector, k, nil)
+		keys = MatchKeys(matches)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return page(keys, q.offset), nil
+}
+
+// Into executes the query and decodes the hits into dest as VSearchInto
+// does
+func (q *Query) Into(dest interface{}) error {
+	if err := checkSliceDest(dest); err != nil {
+		return err
+	}
+	hits, err := q.hits()
+	if err != nil {
+		return err
+	}
+	return decodeHits(hits, dest)
+}
+
+func (q *Query) hits() ([]SearchHit, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	if q.collection != "" {
+		return nil, errors.New("celrix: collection queries do not return scores or metadata")
+	}
+	hits, err := q.c.vsearchHits(q.vector, q.offset+q.limit, q.filter)
+	if err != nil {
+		return nil, err
+	}
+	return page(hits, q.offset), nil
+}
+
+// page drops the first offset results
+func page[T any](results []T, offset int) []T {
+	if offset >= len(results) {
+		return nil
+	}
+	return results[offset:]
+}
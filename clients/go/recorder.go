@@ -0,0 +1,139 @@
+package celrix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// RecordedFrame is a frame captured by a Recorder
+type RecordedFrame struct {
+	// Outgoing is true for frames sent by the client
+	Outgoing bool
+	// Version is the protocol version of the layout the frame was sent
+	// in; zero is taken as version 1
+	Version uint8
+	Opcode  uint8
+	Flags   uint16
+	ReqID   uint64
+	Payload []byte
+}
+
+// Recorder captures the frames exchanged on a connection, for debugging and
+// for building replay mocks. It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	frames []RecordedFrame
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) record(outgoing bool, version, opcode uint8, flags uint16, reqID uint64, payload []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, RecordedFrame{
+		Outgoing: outgoing,
+		Version:  version,
+		Opcode:   opcode,
+		Flags:    flags,
+		ReqID:    reqID,
+		Payload:  append([]byte(nil), payload...),
+	})
+}
+
+// Frames returns a copy of the captured frames in order
+func (r *Recorder) Frames() []RecordedFrame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedFrame(nil), r.frames...)
+}
+
+// Reset discards the captured frames
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = nil
+}
+
+// Recording file layout: each frame is [direction: u8] followed by the
+// frame exactly as on the wire, in the layout of its protocol version, with
+// '>' for outgoing and '<' for incoming.
+const (
+	dirOutgoing = '>'
+	dirIncoming = '<'
+)
+
+// WriteTo saves the captured frames to w
+func (r *Recorder) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	for _, f := range r.Frames() {
+		dir := byte(dirIncoming)
+		if f.Outgoing {
+			dir = dirOutgoing
+		}
+		l, err := f.layout()
+		if err != nil {
+			return n, err
+		}
+		buf := append([]byte{dir}, encodeFrame(l, f.Opcode, f.Flags, f.ReqID, f.Payload)...)
+		m, err := bw.Write(buf)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
+
+// ReadRecording loads frames saved with Recorder.WriteTo
+func ReadRecording(r io.Reader) ([]RecordedFrame, error) {
+	br := bufio.NewReader(r)
+	var frames []RecordedFrame
+	for {
+		dir, err := br.ReadByte()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if dir != dirOutgoing && dir != dirIncoming {
+			return nil, fmt.Errorf("celrix: recording frame %d: invalid direction %q", len(frames), dir)
+		}
+		f, err := wire.ReadAnyFrame(br)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("celrix: recording frame %d: %w", len(frames), err)
+		}
+		frames = append(frames, RecordedFrame{
+			Outgoing: dir == dirOutgoing,
+			Version:  f.Version,
+			Opcode:   f.Opcode,
+			Flags:    f.Flags,
+			ReqID:    f.RequestID,
+			Payload:  f.Payload,
+		})
+	}
+}
+
+// layout returns the layout the frame was recorded in
+func (f RecordedFrame) layout() (wire.Layout, error) {
+	if f.Version == 0 {
+		return wire.V1, nil
+	}
+	return wire.LayoutFor(f.Version)
+}
+
+// encodeFrame serialises a frame header and payload in layout l
+func encodeFrame(l wire.Layout, opcode uint8, flags uint16, reqID uint64, payload []byte) []byte {
+	return l.AppendFrame(nil, wire.Frame{Opcode: opcode, Flags: flags, RequestID: reqID, Payload: payload})
+}
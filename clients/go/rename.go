@@ -0,0 +1,48 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// RenameBatch renames every source key in pairs to its destination in one
+// atomic server operation, so readers see either all of the old names or
+// all of the new ones. Existing destinations are overwritten. Swapping a
+// versioned key set into place looks like:
+//
+//	c.RenameBatch(map[string]string{
+//		"config:v2:db":    "config:current:db",
+//		"config:v2:cache": "config:current:cache",
+//	})
+//
+// If any source key is missing the server renames nothing and returns an
+// error.
+func (c *Client) RenameBatch(pairs map[string]string) error {
+	if len(pairs) == 0 {
+		return nil
+	}
+	srcs := make([]string, 0, len(pairs))
+	dsts := make(map[string]string, len(pairs))
+	for src, dst := range pairs {
+		if prev, dup := dsts[dst]; dup {
+			return fmt.Errorf("rename batch: %q and %q both rename to %q", prev, src, dst)
+		}
+		dsts[dst] = src
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+
+	// Payload: [count u32]([src][dst])...
+	payload := binary.BigEndian.AppendUint32(nil, uint32(len(srcs)))
+	for _, src := range srcs {
+		payload = appendString(payload, src)
+		payload = appendString(payload, pairs[src])
+		c.vectors.forget(src)
+		c.vectors.forget(pairs[src])
+	}
+	if err := c.sendFrame(OpRenameBatch, payload); err != nil {
+		return c.journalFailure(OpRenameBatch, payload, err)
+	}
+	return c.journalFailure(OpRenameBatch, payload, c.expectOK())
+}
@@ -0,0 +1,211 @@
+package celrix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// ErrUnexpectedCommand is returned when a replayed client sends a frame
+// that does not match the recording
+var ErrUnexpectedCommand = errors.New("celrix: unexpected command in replay")
+
+var errReplayClosed = errors.New("celrix: replay connection closed")
+
+// exchange is one recorded request and the frames that answered it
+type exchange struct {
+	request   RecordedFrame
+	responses []RecordedFrame
+}
+
+// Replay serves recorded responses to a client, failing on any command that
+// deviates from the recorded sequence
+type Replay struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	exchanges []exchange
+	next      int
+	pending   bytes.Buffer // bytes written by the client, not yet a full frame
+	out       bytes.Buffer // response bytes waiting to be read
+	err       error
+	closed    bool
+}
+
+// NewReplayClient returns a client whose connection is backed by a
+// recording instead of a server. Each frame the client sends must match the
+// next recorded request (opcode, flags and payload) and be in the layout it
+// was recorded in; the recorded responses are then returned in their
+// recorded layouts with their request IDs rewritten to match. A session
+// recorded after a version 2 handshake replays only on a client offering
+// version 2.
+func NewReplayClient(frames []RecordedFrame, opts ...Option) (*Client, *Replay, error) {
+	rp := &Replay{}
+	rp.cond = sync.NewCond(&rp.mu)
+	for _, f := range frames {
+		if f.Outgoing {
+			rp.exchanges = append(rp.exchanges, exchange{request: f})
+			continue
+		}
+		if len(rp.exchanges) == 0 {
+			return nil, nil, errors.New("celrix: recording starts with an incoming frame")
+		}
+		last := &rp.exchanges[len(rp.exchanges)-1]
+		last.responses = append(last.responses, f)
+	}
+
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return &replayConn{rp: rp}, nil
+	}
+	c, err := Connect("replay", append(opts, WithDialer(dial))...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, rp, nil
+}
+
+// Remaining returns the number of recorded requests not yet replayed
+func (rp *Replay) Remaining() int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return len(rp.exchanges) - rp.next
+}
+
+// Verify returns the first mismatch seen, or an error if recorded requests
+// were never sent
+func (rp *Replay) Verify() error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.err != nil {
+		return rp.err
+	}
+	if n := len(rp.exchanges) - rp.next; n > 0 {
+		return fmt.Errorf("celrix: replay finished with %d recorded requests unsent (next opcode 0x%02x)", n, rp.exchanges[rp.next].request.Opcode)
+	}
+	return nil
+}
+
+// write consumes client bytes and answers every complete frame
+func (rp *Replay) write(p []byte) error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.err != nil {
+		return rp.err
+	}
+	if rp.closed {
+		return errReplayClosed
+	}
+	rp.pending.Write(p)
+
+	for {
+		l, err := rp.layout()
+		if err != nil {
+			rp.err = fmt.Errorf("%w: %v", ErrUnexpectedCommand, err)
+			return rp.err
+		}
+		if rp.pending.Len() < l.HeaderSize() {
+			return nil
+		}
+		f, n, err := l.Decode(rp.pending.Bytes())
+		if err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			rp.err = fmt.Errorf("%w: %v", ErrUnexpectedCommand, err)
+			return rp.err
+		}
+		rp.pending.Next(n)
+
+		if rp.next >= len(rp.exchanges) {
+			rp.err = fmt.Errorf("%w: opcode 0x%02x sent after the recording ended", ErrUnexpectedCommand, f.Opcode)
+			return rp.err
+		}
+		ex := rp.exchanges[rp.next]
+		if ex.request.Opcode != f.Opcode || ex.request.Flags != f.Flags || !bytes.Equal(ex.request.Payload, f.Payload) {
+			rp.err = fmt.Errorf("%w: request %d: got opcode 0x%02x (%d byte payload), recorded opcode 0x%02x (%d byte payload)",
+				ErrUnexpectedCommand, rp.next, f.Opcode, len(f.Payload), ex.request.Opcode, len(ex.request.Payload))
+			return rp.err
+		}
+		rp.next++
+		for _, r := range ex.responses {
+			rl, err := r.layout()
+			if err != nil {
+				rp.err = err
+				return rp.err
+			}
+			rp.out.Write(encodeFrame(rl, r.Opcode, r.Flags, f.RequestID, r.Payload))
+		}
+		rp.cond.Broadcast()
+	}
+}
+
+// layout returns the layout to read the next request in: the one it was
+// recorded in, or after the recording has ended the one named by the
+// pending bytes, so that the extra request can be reported
+func (rp *Replay) layout() (wire.Layout, error) {
+	if rp.next < len(rp.exchanges) {
+		return rp.exchanges[rp.next].request.layout()
+	}
+	if b := rp.pending.Bytes(); len(b) > len(wire.Magic) {
+		return wire.LayoutFor(b[len(wire.Magic)])
+	}
+	return wire.V1, nil
+}
+
+func (rp *Replay) read(p []byte) (int, error) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	for rp.out.Len() == 0 {
+		if rp.err != nil {
+			return 0, rp.err
+		}
+		if rp.closed {
+			return 0, io.EOF
+		}
+		rp.cond.Wait()
+	}
+	return rp.out.Read(p)
+}
+
+func (rp *Replay) close() {
+	rp.mu.Lock()
+	rp.closed = true
+	rp.mu.Unlock()
+	rp.cond.Broadcast()
+}
+
+// replayConn adapts a Replay to net.Conn
+type replayConn struct {
+	rp *Replay
+}
+
+func (c *replayConn) Read(p []byte) (int, error) { return c.rp.read(p) }
+
+func (c *replayConn) Write(p []byte) (int, error) {
+	if err := c.rp.write(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *replayConn) Close() error {
+	c.rp.close()
+	return nil
+}
+
+func (c *replayConn) LocalAddr() net.Addr                { return replayAddr{} }
+func (c *replayConn) RemoteAddr() net.Addr               { return replayAddr{} }
+func (c *replayConn) SetDeadline(t time.Time) error      { return nil }
+func (c *replayConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *replayConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type replayAddr struct{}
+
+func (replayAddr) Network() string { return "replay" }
+func (replayAddr) String() string  { return "replay" }
@@ -0,0 +1,90 @@
+package celrix
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// recordSession runs a short session against a kv server that accepts
+// HELLO with the last version offered, and returns its recording
+func recordSession(t *testing.T, opts ...Option) *Recorder {
+	t.Helper()
+	var store kv
+	s := &testServer{handle: func(_ int, f wire.Frame) []wire.Frame {
+		if f.Opcode == OpHello {
+			return reply(f, OpValue, []byte{f.Payload[f.Payload[0]]})
+		}
+		return store.handle(f)
+	}}
+	rec := NewRecorder()
+	c := s.connect(t, append(opts, WithRecorder(rec))...)
+	if err := c.Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func replaySession(t *testing.T, frames []RecordedFrame, opts ...Option) {
+	t.Helper()
+	c, rp, err := NewReplayClient(frames, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	v, ok, err := c.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || v != "1" {
+		t.Errorf("GET replayed %q %v, want %q", v, ok, "1")
+	}
+	if err := rp.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplayV2Session(t *testing.T) {
+	frames := recordSession(t, WithProtocolVersions(1, 2)).Frames()
+	if last := frames[len(frames)-1]; last.Version != 2 {
+		t.Fatalf("last frame recorded with version %d, want 2", last.Version)
+	}
+	replaySession(t, frames, WithProtocolVersions(1, 2))
+}
+
+func TestReplayV2SessionFromFile(t *testing.T) {
+	rec := recordSession(t, WithProtocolVersions(1, 2))
+	var buf bytes.Buffer
+	if _, err := rec.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	frames, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replaySession(t, frames, WithProtocolVersions(1, 2))
+}
+
+func TestReplayRejectsAnotherLayout(t *testing.T) {
+	frames := recordSession(t, WithProtocolVersions(1, 2)).Frames()
+	// Have the HELLO reply choose version 1: the client then sends version
+	// 1 frames where version 2 ones were recorded
+	frames[1].Payload = []byte{1}
+	c, rp, err := NewReplayClient(frames, WithProtocolVersions(1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Set("a", "1")
+	if err := rp.Verify(); !errors.Is(err, ErrUnexpectedCommand) {
+		t.Fatalf("got %v, want ErrUnexpectedCommand", err)
+	}
+}
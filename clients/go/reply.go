@@ -0,0 +1,364 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReplyType is the kind of value carried by a Reply
+type ReplyType uint8
+
+// Reply types
+const (
+	ReplyNil ReplyType = iota
+	ReplyOK
+	ReplyValue
+	ReplyInteger
+	ReplyArray
+	ReplyError
+	ReplyFloat
+	ReplyMap
+	ReplyBool
+	ReplyBigInt
+)
+
+// String returns the reply type name
+func (t ReplyType) String() string {
+	switch t {
+	case ReplyNil:
+		return "nil"
+	case ReplyOK:
+		return "ok"
+	case ReplyValue:
+		return "value"
+	case ReplyInteger:
+		return "integer"
+	case ReplyArray:
+		return "array"
+	case ReplyError:
+		return "error"
+	case ReplyFloat:
+		return "float"
+	case ReplyMap:
+		return "map"
+	case ReplyBool:
+		return "bool"
+	case ReplyBigInt:
+		return "bigint"
+	default:
+		return fmt.Sprintf("ReplyType(%d)", uint8(t))
+	}
+}
+
+// Reply is a single server reply, for APIs that hand back raw results
+// instead of a command-specific Go type
+type Reply struct {
+	typ   ReplyType
+	bytes []byte
+	i     int64
+	f     float64
+	b     bool
+	big   *big.Int
+	// content is the hint carried by typed string replies
+	content ContentType
+	attrs   map[string]Reply
+	array   []Reply
+	m       map[string]Reply
+	err     error
+}
+
+// Type returns the reply type
+func (r Reply) Type() ReplyType { return r.typ }
+
+// IsNil reports whether the reply is nil, e.g. a missing key
+func (r Reply) IsNil() bool { return r.typ == ReplyNil }
+
+// Err returns the server error carried by an error reply, or nil
+func (r Reply) Err() error { return r.err }
+
+// Bytes returns the payload of a value reply. The slice must not be
+// modified, and under WithZeroCopyReads is only valid until the next reply
+// is read.
+func (r Reply) Bytes() ([]byte, bool) { return r.bytes, r.typ == ReplyValue }
+
+// Str returns a value reply as a string
+func (r Reply) Str() (string, bool) { return string(r.bytes), r.typ == ReplyValue }
+
+// Int returns the integer of an integer reply
+func (r Reply) Int() (int64, bool) { return r.i, r.typ == ReplyInteger }
+
+// Float returns the number of a float reply
+func (r Reply) Float() (float64, bool) { return r.f, r.typ == ReplyFloat }
+
+// Bool returns the truth value of a bool reply. Integer replies, which
+// servers predating OpBool send for yes/no answers, are true when non-zero.
+func (r Reply) Bool() (bool, bool) {
+	switch r.typ {
+	case ReplyBool:
+		return r.b, true
+	case ReplyInteger:
+		return r.i != 0, true
+	default:
+		return false, false
+	}
+}
+
+// BigInt returns the number of a big integer reply, for counters that can
+// exceed int64. Integer replies are converted, so callers need not care
+// which of the two the server chose. The result must not be modified.
+func (r Reply) BigInt() (*big.Int, bool) {
+	switch r.typ {
+	case ReplyBigInt:
+		return r.big, true
+	case ReplyInteger:
+		return big.NewInt(r.i), true
+	default:
+		return nil, false
+	}
+}
+
+// Array returns the elements of an array reply
+func (r Reply) Array() ([]Reply, bool) { return r.array, r.typ == ReplyArray }
+
+// Map returns the entries of a map reply
+func (r Reply) Map() (map[string]Reply, bool) { return r.m, r.typ == ReplyMap }
+
+// String formats the reply for display
+func (r Reply) String() string {
+	switch r.typ {
+	case ReplyNil:
+		return "(nil)"
+	case ReplyOK:
+		return "OK"
+	case ReplyValue:
+		return string(r.bytes)
+	case ReplyInteger:
+		return fmt.Sprintf("%d", r.i)
+	case ReplyFloat:
+		return strconv.FormatFloat(r.f, 'g', -1, 64)
+	case ReplyBool:
+		return strconv.FormatBool(r.b)
+	case ReplyBigInt:
+		return r.big.String()
+	case ReplyArray:
+		parts := make([]string, len(r.array))
+		for i, el := range r.array {
+			parts[i] = el.String()
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	case ReplyMap:
+		keys := make([]string, 0, len(r.m))
+		for k := range r.m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k + ":" + r.m[k].String()
+		}
+		return "{" + strings.Join(parts, " ") + "}"
+	case ReplyError:
+		return "(error) " + r.err.Error()
+	default:
+		return "<invalid>"
+	}
+}
+
+// decodeReply decodes a reply frame. Error replies become a Reply of type
+// ReplyError; only malformed frames return an error.
+func decodeReply(opcode uint8, payload []byte) (Reply, error) {
+	switch opcode {
+	case OpOk, OpPong:
+		return Reply{typ: ReplyOK}, nil
+	case OpNil:
+		return Reply{typ: ReplyNil}, nil
+	case OpError:
+		return Reply{typ: ReplyError, err: &ServerError{Message: string(payload)}}, nil
+	case OpValue:
+		return Reply{typ: ReplyValue, bytes: payload}, nil
+	case OpInteger:
+		if len(payload) < 8 {
+			return Reply{}, errors.New("invalid integer payload")
+		}
+		return Reply{typ: ReplyInteger, i: int64(binary.BigEndian.Uint64(payload))}, nil
+	case OpArray:
+		// [count: u32][len: u32][bytes]...
+		if len(payload) < 4 {
+			return Reply{typ: ReplyArray}, nil
+		}
+		count := int(binary.BigEndian.Uint32(payload))
+		b := payload[4:]
+		arr := make([]Reply, 0, count)
+		for i := 0; i < count; i++ {
+			if len(b) < 4 {
+				return Reply{}, errors.New("incomplete array")
+			}
+			n := int(binary.BigEndian.Uint32(b))
+			if len(b) < 4+n {
+				return Reply{}, errors.New("incomplete array item")
+			}
+			arr = append(arr, Reply{typ: ReplyValue, bytes: b[4 : 4+n]})
+			b = b[4+n:]
+		}
+		return Reply{typ: ReplyArray, array: arr}, nil
+	case OpTypedArray:
+		return decodeTypedArray(payload)
+	case OpMap, OpAttributes:
+		return decodeMap(payload)
+	case OpBool:
+		if len(payload) < 1 {
+			return Reply{}, errors.New("invalid bool payload")
+		}
+		return Reply{typ: ReplyBool, b: payload[0] != 0}, nil
+	case OpTypedString:
+		return decodeTypedString(payload)
+	case OpBigInt:
+		n, err := decodeBigInt(payload)
+		if err != nil {
+			return Reply{}, err
+		}
+		return Reply{typ: ReplyBigInt, big: n}, nil
+	case OpDouble:
+		if len(payload) < 8 {
+			return Reply{}, errors.New("invalid double payload")
+		}
+		return Reply{typ: ReplyFloat, f: math.Float64frombits(binary.BigEndian.Uint64(payload))}, nil
+	default:
+		return Reply{}, fmt.Errorf("unknown opcode: %d", opcode)
+	}
+}
+
+// decodeTypedArray decodes an array whose elements carry their own reply
+// opcode, so integers, floats, nils and nested arrays can be mixed:
+//
+//	[count: u32]([opcode: u8][len: u32][payload])...
+func decodeTypedArray(payload []byte) (Reply, error) {
+	if len(payload) < 4 {
+		return Reply{}, errors.New("incomplete typed array")
+	}
+	count := int(binary.BigEndian.Uint32(payload))
+	b := payload[4:]
+	// Each element takes at least 5 bytes, which bounds a hostile count
+	if count > len(b)/5 {
+		return Reply{}, errors.New("typed array count exceeds payload")
+	}
+	arr := make([]Reply, 0, count)
+	for i := 0; i < count; i++ {
+		if len(b) < 5 {
+			return Reply{}, errors.New("incomplete typed array element")
+		}
+		op, n := b[0], int(binary.BigEndian.Uint32(b[1:]))
+		if len(b) < 5+n {
+			return Reply{}, errors.New("incomplete typed array element")
+		}
+		el, err := decodeReply(op, b[5:5+n])
+		if err != nil {
+			return Reply{}, fmt.Errorf("element %d: %w", i, err)
+		}
+		arr = append(arr, el)
+		b = b[5+n:]
+	}
+	return Reply{typ: ReplyArray, array: arr}, nil
+}
+
+// maxBigIntBytes bounds the magnitude of a big integer reply
+const maxBigIntBytes = 1 << 16
+
+// decodeBigInt decodes [sign: u8][magnitude: big-endian bytes], where sign
+// is 0 for non-negative and 1 for negative numbers
+func decodeBigInt(payload []byte) (*big.Int, error) {
+	if len(payload) < 1 || payload[0] > 1 {
+		return nil, errors.New("invalid big integer payload")
+	}
+	if len(payload)-1 > maxBigIntBytes {
+		return nil, fmt.Errorf("big integer exceeds %d bytes", maxBigIntBytes)
+	}
+	n := new(big.Int).SetBytes(payload[1:])
+	if payload[0] == 1 {
+		n.Neg(n)
+	}
+	return n, nil
+}
+
+// decodeMap decodes a map reply, whose values are encoded like typed array
+// elements:
+//
+//	[count: u32]([key_len: u32][key][opcode: u8][len: u32][payload])...
+func decodeMap(payload []byte) (Reply, error) {
+	if len(payload) < 4 {
+		return Reply{}, errors.New("incomplete map")
+	}
+	count := int(binary.BigEndian.Uint32(payload))
+	b := payload[4:]
+	// Each entry takes at least 9 bytes, which bounds a hostile count
+	if count > len(b)/9 {
+		return Reply{}, errors.New("map count exceeds payload")
+	}
+	m := make(map[string]Reply, count)
+	for i := 0; i < count; i++ {
+		key, n, err := readString(b)
+		if err != nil {
+			return Reply{}, fmt.Errorf("map entry %d: %w", i, err)
+		}
+		b = b[n:]
+		if len(b) < 5 {
+			return Reply{}, errors.New("incomplete map value")
+		}
+		op, n := b[0], int(binary.BigEndian.Uint32(b[1:]))
+		if len(b) < 5+n {
+			return Reply{}, errors.New("incomplete map value")
+		}
+		if _, dup := m[key]; dup {
+			return Reply{}, fmt.Errorf("duplicate map key %q", key)
+		}
+		v, err := decodeReply(op, b[5:5+n])
+		if err != nil {
+			return Reply{}, fmt.Errorf("map entry %q: %w", key, err)
+		}
+		m[key] = v
+		b = b[5+n:]
+	}
+	return Reply{typ: ReplyMap, m: m}, nil
+}
+
+// native converts the reply to the dynamic types returned by
+// decodeResponse: string, int64, *big.Int, float64, bool, nil, []interface{},
+// map[string]interface{} or, for error elements, *ServerError
+func (r Reply) native() interface{} {
+	switch r.typ {
+	case ReplyOK:
+		return "OK"
+	case ReplyValue:
+		return string(r.bytes)
+	case ReplyInteger:
+		return r.i
+	case ReplyFloat:
+		return r.f
+	case ReplyBool:
+		return r.b
+	case ReplyBigInt:
+		return r.big
+	case ReplyArray:
+		out := make([]interface{}, len(r.array))
+		for i, el := range r.array {
+			out[i] = el.native()
+		}
+		return out
+	case ReplyMap:
+		out := make(map[string]interface{}, len(r.m))
+		for k, v := range r.m {
+			out[k] = v.native()
+		}
+		return out
+	case ReplyError:
+		return r.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,317 @@
+package celrix
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Retry policy defaults, used for zero RetryPolicy fields
+const (
+	DefaultRetryAttempts   = 3
+	DefaultRetryBackoff    = 50 * time.Millisecond
+	DefaultMaxRetryBackoff = 5 * time.Second
+)
+
+// RetryPolicy governs reconnection after the connection drops. Zero fields
+// take the defaults above, and a zero Multiplier doubles each backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries a command gets, the first
+	// included, and the number of dials a reconnect may take
+	MaxAttempts int
+	// InitialBackoff is the wait before the first reconnect; each further
+	// one waits Multiplier times longer, up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter spreads each wait by up to this fraction either way, so
+	// clients dropped together do not reconnect in lockstep. Zero waits
+	// exactly.
+	Jitter float64
+}
+
+func (p *RetryPolicy) attempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultRetryAttempts
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the wait before retry n, counted from 1
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	base, limit, mult := p.InitialBackoff, p.MaxBackoff, p.Multiplier
+	if base <= 0 {
+		base = DefaultRetryBackoff
+	}
+	if limit <= 0 {
+		limit = DefaultMaxRetryBackoff
+	}
+	if mult < 1 {
+		mult = 2
+	}
+	d := math.Min(float64(base)*math.Pow(mult, float64(n-1)), float64(limit))
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// WithRetry makes the client survive a dropped connection. The initial
+// connection is retried, a command that fails in transit on an idempotent
+// opcode is resent on a new connection, and after any other transport
+// failure the next command reconnects before it is sent. Waits between
+// dials follow policy; each emits EventRetry, and the try number is in the
+// final error's CommandError.Attempt.
+//
+// Idempotent commands are the reads plus writes that leave the same state
+// when applied twice: SET without a condition, the VADD family, CONFIGSET
+// and ACLSETUSER. Commands whose reply would change on a second
+// application, such as DEL, INCRBY or the conditional writes, are never
+// resent, nor are streams and pipelined batches. A command bounded by
+// RunContext is not retried past its context.
+//
+// The database chosen with Select is restored on the new connection.
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = &policy
+	}
+}
+
+// WithOnReconnect calls fn after each successful reconnection made under
+// WithRetry, with the try number and the failure that caused it. It runs on
+// the goroutine issuing the command and must not use the client.
+func WithOnReconnect(fn func(attempt int, cause error)) Option {
+	return func(o *options) {
+		o.onReconnect = fn
+	}
+}
+
+// ConnectOptions configures ConnectWithOptions
+type ConnectOptions struct {
+	// Retry governs reconnection, as with WithRetry
+	Retry RetryPolicy
+	// OnReconnect, if set, is called as with WithOnReconnect
+	OnReconnect func(attempt int, cause error)
+	// TLSConfig, if set, encrypts the connections as with WithTLS
+	TLSConfig *tls.Config
+	// Options are any further client options
+	Options []Option
+}
+
+// ConnectWithOptions connects with automatic reconnection: the initial
+// connection is retried under co.Retry, and so is a dropped one for the
+// life of the client. It is shorthand for Connect with WithRetry,
+// WithOnReconnect and WithTLS.
+func ConnectWithOptions(addr string, co ConnectOptions) (*Client, error) {
+	opts := append(append([]Option(nil), co.Options...), WithRetry(co.Retry))
+	if co.OnReconnect != nil {
+		opts = append(opts, WithOnReconnect(co.OnReconnect))
+	}
+	if co.TLSConfig != nil {
+		opts = append(opts, WithTLS(co.TLSConfig))
+	}
+	return Connect(addr, opts...)
+}
+
+// idempotentOps are the opcodes WithRetry resends after a transport
+// failure
+var idempotentOps = map[uint8]bool{
+	OpPing: true, OpGet: true, OpExists: true, OpScan: true,
+	OpSet: true, OpSetCompressed: true,
+	OpVAdd: true, OpVAddMeta: true, OpVAddTTL: true, OpVAddBatch: true,
+	OpVGet: true, OpVSearch: true, OpVSearchFilter: true, OpVSearchMeta: true,
+	OpVSearchExplain: true, OpVSearchBatch: true,
+	OpDescribeCollection: true, OpCVAdd: true, OpCVSearch: true,
+	OpListCollections: true, OpSearchByField: true, OpAggregate: true,
+	OpHealth: true, OpACLList: true, OpACLSetUser: true, OpConfigGet: true,
+	OpConfigSet: true, OpKeyspaceSample: true, OpHotKeys: true,
+	OpMemoryUsage: true, OpClientList: true,
+	OpZScore: true, OpZCard: true, OpZRangeByScore: true,
+	OpBFExists: true, OpBFMExists: true, OpCMSQuery: true, OpTopKList: true,
+	OpTSRange: true, OpMGet: true, OpMVersion: true, OpHash: true,
+	OpMHash: true, OpTTL: true, OpGetStream: true,
+}
+
+// sentRequest is the last request sent, kept for resending until its reply
+// starts to arrive
+type sentRequest struct {
+	op      uint8
+	flags   uint16
+	payload []byte
+}
+
+// keepForRetry remembers a request just queued if WithRetry may resend it
+func (c *Client) keepForRetry(opcode uint8, flags uint16, payload []byte) {
+	if c.opts.retry != nil && idempotentOps[opcode] {
+		c.lastReq = &sentRequest{op: opcode, flags: flags, payload: append([]byte(nil), payload...)}
+	}
+}
+
+// transportFailed handles a connection failure of the command in flight.
+// Under WithRetry the connection is marked for replacement and an
+// idempotent command is resent, in which case nil is returned once it is
+// back on the wire.
+func (c *Client) transportFailed(err error) error {
+	if c.opts.retry == nil || c.closed || c.replaying {
+		return err
+	}
+	c.broken = unwrapCommand(err)
+	if c.lastReq == nil {
+		return err
+	}
+	req := c.lastReq
+	for c.pendingAttempt < c.opts.retry.attempts() && c.retryable(err) {
+		n := c.pendingAttempt
+		c.pendingAttempt++
+		rerr := c.reconnectOnce(n, err)
+		if rerr == nil {
+			rerr = c.resend(req)
+			if rerr == nil {
+				return nil
+			}
+		}
+		err = c.cmdErr(unwrapCommand(rerr))
+	}
+	return err
+}
+
+// reconnect replaces a broken connection before a command is sent, dialing
+// up to the policy's attempts
+func (c *Client) reconnect(cause error) error {
+	if c.opts.retry == nil {
+		// Without WithRetry only a quarantined or timed-out connection
+		// is replaced, with a single dial
+		if err := c.redial(); err != nil {
+			return err
+		}
+		c.logReconnect("replaced quarantined connection")
+		return nil
+	}
+	err := cause
+	for n := 1; n <= c.opts.retry.attempts(); n++ {
+		if !c.retryable(err) {
+			break
+		}
+		if err = c.reconnectOnce(n, cause); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// reconnectOnce backs off and dials a replacement connection for retry n
+func (c *Client) reconnectOnce(n int, cause error) error {
+	c.emit(EventRetry, n, cause)
+	if !c.backoff(c.opts.retry.backoff(n)) {
+		return cause
+	}
+	if err := c.redial(); err != nil {
+		c.logReconnect("reconnect failed", "attempt", n, "error", err)
+		return err
+	}
+	c.logReconnect("reconnected", "attempt", n)
+	if fn := c.opts.onReconnect; fn != nil {
+		fn(n, cause)
+	}
+	return nil
+}
+
+// backoff waits d, reporting false if the context of the command in flight
+// would end first
+func (c *Client) backoff(d time.Duration) bool {
+	if !c.ctxDeadline.IsZero() && time.Now().Add(d).After(c.ctxDeadline) {
+		return false
+	}
+	t := c.opts.timer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return true
+	case <-c.ctxDone:
+		return false
+	}
+}
+
+// retryable reports whether a command that failed with err may be tried
+// again on a new connection
+func (c *Client) retryable(err error) bool {
+	if c.closed || isServerError(err) || c.failedErr() != nil {
+		return false
+	}
+	if !c.ctxDeadline.IsZero() && !time.Now().Before(c.ctxDeadline) {
+		return false
+	}
+	select {
+	case <-c.ctxDone:
+		return false
+	default:
+		return true
+	}
+}
+
+// redial opens and negotiates a replacement connection, restores the
+// selected database and replays the write journal, leaving the state of the command in flight as it was
+func (c *Client) redial() error {
+	saved := c.pendingState
+	c.conn.Close()
+	cause := c.broken
+	c.broken = nil
+	ctx := context.Background()
+	if !c.ctxDeadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.ctxDeadline)
+		defer cancel()
+	}
+	err := c.dial(ctx)
+	if err == nil && c.db != 0 {
+		if err = c.sendFrame(OpSelect, binary.BigEndian.AppendUint32(nil, uint32(c.db))); err == nil {
+			err = c.expectOK()
+		}
+	}
+	if err == nil {
+		err = c.replayPending()
+	}
+	if err == nil && !c.ctxDeadline.IsZero() {
+		err = c.conn.SetDeadline(c.ctxDeadline)
+	}
+	c.pendingState = saved
+	if err != nil {
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.broken = cause
+	}
+	return err
+}
+
+// resend puts req on the new connection as the command in flight
+func (c *Client) resend(req *sentRequest) error {
+	c.pendingReqID = c.nextReqID
+	if !c.pending {
+		c.gauge(&varInflight, 1)
+	}
+	c.pending, c.pendingStart = true, c.opts.now()
+	if err := c.armDeadline(req.op); err != nil {
+		return err
+	}
+	_, err := c.queueFlagged(req.op, req.flags, req.payload)
+	if err == nil {
+		err = c.rw.Flush()
+	}
+	c.lastReq = req
+	return c.timeoutErr(err)
+}
+
+// unwrapCommand strips the CommandError of a command issued while
+// reconnecting, so the error can be reported against the one retried
+func unwrapCommand(err error) error {
+	var ce *CommandError
+	if errors.As(err, &ce) {
+		return ce.Err
+	}
+	return err
+}
@@ -0,0 +1,151 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+var testRetry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+// flakyServer serves a kv store with SELECT, but drops the connection
+// instead of answering requests for which drop reports true. It logs every
+// request it receives.
+type flakyServer struct {
+	testServer
+	drop func(conn int, f wire.Frame) bool
+
+	mu   sync.Mutex
+	seen []wire.Frame
+	dbs  map[int]uint32
+}
+
+func newFlakyServer(drop func(conn int, f wire.Frame) bool) *flakyServer {
+	s := &flakyServer{drop: drop, dbs: make(map[int]uint32)}
+	var store kv
+	s.handle = func(conn int, f wire.Frame) []wire.Frame {
+		s.mu.Lock()
+		s.seen = append(s.seen, f)
+		s.mu.Unlock()
+		if s.drop(conn, f) {
+			s.testServer.drop(conn)
+			return nil
+		}
+		if f.Opcode == OpSelect {
+			s.mu.Lock()
+			s.dbs[conn] = binary.BigEndian.Uint32(f.Payload)
+			s.mu.Unlock()
+			return reply(f, OpOk, nil)
+		}
+		return store.handle(f)
+	}
+	return s
+}
+
+// count returns how many requests with opcode the server received
+func (s *flakyServer) count(opcode uint8) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, f := range s.seen {
+		if f.Opcode == opcode {
+			n++
+		}
+	}
+	return n
+}
+
+// onFirstConn drops requests with opcode on the first connection only
+func onFirstConn(opcode uint8) func(int, wire.Frame) bool {
+	return func(conn int, f wire.Frame) bool { return conn == 1 && f.Opcode == opcode }
+}
+
+func TestRetryResendsIdempotentCommand(t *testing.T) {
+	s := newFlakyServer(onFirstConn(OpGet))
+	var reconnects []int
+	c := s.connect(t, WithRetry(testRetry), WithOnReconnect(func(attempt int, _ error) {
+		reconnects = append(reconnects, attempt)
+	}))
+	if err := c.Set("k", "v"); err != nil {
+		t.Fatal(err)
+	}
+
+	v, ok, err := c.Get("k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || v != "v" {
+		t.Errorf("got %q %v, want %q", v, ok, "v")
+	}
+	if got := s.count(OpGet); got != 2 {
+		t.Errorf("server saw %d GETs, want 2", got)
+	}
+	if s.dialCount() != 2 || len(reconnects) != 1 || reconnects[0] != 1 {
+		t.Errorf("dials %d, reconnects %v; want 2 dials and one reconnect on attempt 1", s.dialCount(), reconnects)
+	}
+}
+
+func TestRetryDoesNotResendNonIdempotentCommand(t *testing.T) {
+	s := newFlakyServer(onFirstConn(OpDel))
+	c := s.connect(t, WithRetry(testRetry))
+
+	if _, err := c.Del("k"); err == nil {
+		t.Fatal("DEL on a dropped connection succeeded")
+	}
+	if got := s.count(OpDel); got != 1 {
+		t.Errorf("server saw %d DELs, want 1", got)
+	}
+	// The next command reconnects before it is sent
+	if err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if s.dialCount() != 2 {
+		t.Errorf("dials %d, want 2", s.dialCount())
+	}
+}
+
+func TestRetryRestoresSelectedDatabase(t *testing.T) {
+	s := newFlakyServer(onFirstConn(OpGet))
+	c := s.connect(t, WithRetry(testRetry))
+	if err := c.Select(3); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Get("k"); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dbs[2] != 3 {
+		t.Errorf("database on the new connection is %d, want 3", s.dbs[2])
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	s := newFlakyServer(func(_ int, f wire.Frame) bool { return f.Opcode == OpGet })
+	c := s.connect(t, WithRetry(testRetry))
+
+	_, _, err := c.Get("k")
+	var ce *CommandError
+	if !errors.As(err, &ce) {
+		t.Fatalf("got %v, want a CommandError", err)
+	}
+	if ce.Attempt != testRetry.MaxAttempts {
+		t.Errorf("failed on attempt %d, want %d", ce.Attempt, testRetry.MaxAttempts)
+	}
+	if got := s.count(OpGet); got != testRetry.MaxAttempts {
+		t.Errorf("server saw %d GETs, want %d", got, testRetry.MaxAttempts)
+	}
+	var retries []int
+	for len(c.Events()) > 0 {
+		if ev := <-c.Events(); ev.Kind == EventRetry {
+			retries = append(retries, ev.Attempt)
+		}
+	}
+	if len(retries) != testRetry.MaxAttempts-1 {
+		t.Errorf("retry events for attempts %v, want %d", retries, testRetry.MaxAttempts-1)
+	}
+}
@@ -0,0 +1,58 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Scan returns a batch of keys matching a glob pattern (empty matches all)
+// and the cursor to pass to the next call. Iteration starts at cursor 0 and
+// is complete when the returned cursor is 0. count is a hint for the batch
+// size.
+func (c *Client) Scan(cursor uint64, match string, count int) (uint64, []string, error) {
+	// Payload: [cursor u64][count u32][pattern?]
+	payload := binary.BigEndian.AppendUint64(nil, cursor)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(count))
+	if match != "" {
+		payload = appendString(payload, match)
+	}
+	if err := c.sendFrame(OpScan, payload); err != nil {
+		return 0, nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Response: array whose first element is the next cursor (u64), followed
+	// by the keys
+	items, err := toKeys(resp)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(items) == 0 || len(items[0]) != 8 {
+		return 0, nil, errors.New("scan reply has no cursor")
+	}
+	return binary.BigEndian.Uint64([]byte(items[0])), items[1:], nil
+}
+
+// MemoryUsage returns the server's estimate of the bytes held by key,
+// including its value and bookkeeping. The boolean is false if the key does
+// not exist.
+func (c *Client) MemoryUsage(key string) (int64, bool, error) {
+	if err := c.sendKeyed(OpMemoryUsage, key, appendString(nil, key)); err != nil {
+		return 0, false, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return 0, false, err
+	}
+	switch v := resp.(type) {
+	case nil:
+		return 0, false, nil
+	case int64:
+		return v, true, nil
+	default:
+		return 0, false, c.unexpectedReply()
+	}
+}
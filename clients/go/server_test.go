@@ -0,0 +1,133 @@
+package celrix
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// testServer gives the client a fresh net.Pipe on every dial and answers
+// each request with the frames handle returns for it, in the request's
+// layout; conn counts the dials from 1. Returning no frames leaves a
+// request unanswered.
+type testServer struct {
+	handle func(conn int, f wire.Frame) []wire.Frame
+
+	mu    sync.Mutex
+	dials int
+	conns []net.Conn
+}
+
+func (s *testServer) dial(context.Context, string, string) (net.Conn, error) {
+	client, server := net.Pipe()
+	s.mu.Lock()
+	s.dials++
+	n := s.dials
+	s.conns = append(s.conns, server)
+	s.mu.Unlock()
+	go s.serve(n, server)
+	return client, nil
+}
+
+func (s *testServer) serve(n int, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		f, err := wire.ReadAnyFrame(r)
+		if err != nil {
+			return
+		}
+		l, err := wire.LayoutFor(f.Version)
+		if err != nil {
+			return
+		}
+		var out []byte
+		for _, reply := range s.handle(n, f) {
+			out = l.AppendFrame(out, reply)
+		}
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+// drop closes the server side of connection n
+func (s *testServer) drop(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns[n-1].Close()
+}
+
+func (s *testServer) dialCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dials
+}
+
+// connect connects a client to s
+func (s *testServer) connect(t testing.TB, opts ...Option) *Client {
+	t.Helper()
+	c, err := Connect("test", append([]Option{WithDialer(s.dial), WithLogger(testLogger{t})}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// testLogger sends the client's diagnostics to the test log
+type testLogger struct{ t testing.TB }
+
+func (l testLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	l.t.Helper()
+	l.t.Logf("%s %s %v", level, msg, keyvals)
+}
+
+// reply answers f with opcode and payload
+func reply(f wire.Frame, opcode uint8, payload []byte) []wire.Frame {
+	return []wire.Frame{{Opcode: opcode, RequestID: f.RequestID, Payload: payload}}
+}
+
+// kv is a minimal GET/SET/DEL store for testServer handlers
+type kv struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (s *kv) handle(f wire.Frame) []wire.Frame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		s.data = make(map[string]string)
+	}
+	switch f.Opcode {
+	case OpPing:
+		return reply(f, OpPong, nil)
+	case OpGet:
+		key, _, _ := readString(f.Payload)
+		if v, ok := s.data[key]; ok {
+			return reply(f, OpValue, []byte(v))
+		}
+		return reply(f, OpNil, nil)
+	case OpSet:
+		key, n, _ := readString(f.Payload)
+		val, _, _ := readString(f.Payload[n:])
+		s.data[key] = val
+		return reply(f, OpOk, nil)
+	case OpDel:
+		key, _, _ := readString(f.Payload)
+		_, ok := s.data[key]
+		delete(s.data, key)
+		var existed uint64
+		if ok {
+			existed = 1
+		}
+		return reply(f, OpInteger, binary.BigEndian.AppendUint64(nil, existed))
+	}
+	return reply(f, OpError, []byte("unknown command"))
+}
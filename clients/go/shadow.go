@@ -0,0 +1,194 @@
+package celrix
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ShadowOptions configures a ShadowClient
+type ShadowOptions struct {
+	// SampleRate is the fraction of reads, between 0 and 1, that are also
+	// sent to the shadow instance
+	SampleRate float64
+	// ScoreEpsilon is how far the shadow's VSearch scores may be from the
+	// primary's before the results count as a mismatch; zero requires
+	// equal scores
+	ScoreEpsilon float32
+	// OnMismatch is called from a background goroutine for every sampled
+	// read whose shadow result differs from the primary
+	OnMismatch func(ShadowMismatch)
+}
+
+// ShadowMismatch describes a sampled read that disagreed
+type ShadowMismatch struct {
+	Op        string
+	Key       string
+	Primary   interface{}
+	Secondary interface{}
+	// Err is set when the shadow read failed
+	Err error
+}
+
+// ShadowStats counts sampled reads
+type ShadowStats struct {
+	Sampled    uint64
+	Skipped    uint64
+	Mismatches uint64
+}
+
+// ShadowClient serves all traffic from the primary and replays a sample of
+// reads and searches against a shadow instance in the background, to
+// validate a new server version before cutover. Shadow reads never add
+// latency: a sample is skipped while the previous one is still running.
+type ShadowClient struct {
+	primary *Client
+	shadow  *Client
+	opts    ShadowOptions
+
+	shadowMu   sync.Mutex
+	wg         sync.WaitGroup
+	sampled    atomic.Uint64
+	skipped    atomic.Uint64
+	mismatches atomic.Uint64
+}
+
+// NewShadowClient wraps a primary and a shadow connection
+func NewShadowClient(primary, shadow *Client, opts ShadowOptions) *ShadowClient {
+	return &ShadowClient{primary: primary, shadow: shadow, opts: opts}
+}
+
+// Stats returns a snapshot of the shadow counters
+func (s *ShadowClient) Stats() ShadowStats {
+	return ShadowStats{
+		Sampled:    s.sampled.Load(),
+		Skipped:    s.skipped.Load(),
+		Mismatches: s.mismatches.Load(),
+	}
+}
+
+// Close waits for in-flight shadow reads and closes both clients
+func (s *ShadowClient) Close() error {
+	s.wg.Wait()
+	err := s.primary.Close()
+	if serr := s.shadow.Close(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Ping checks the primary
+func (s *ShadowClient) Ping() error { return s.primary.Ping() }
+
+// Set writes to the primary only
+func (s *ShadowClient) Set(key, value string) error { return s.primary.Set(key, value) }
+
+// Del deletes from the primary only
+func (s *ShadowClient) Del(key string) (bool, error) { return s.primary.Del(key) }
+
+// VAdd adds the vector to the primary only
+func (s *ShadowClient) VAdd(key string, vector []float32) error {
+	return s.primary.VAdd(key, vector)
+}
+
+// Get reads from the primary and samples the read against the shadow
+func (s *ShadowClient) Get(key string) (string, bool, error) {
+	val, found, err := s.primary.Get(key)
+	if err != nil {
+		return val, found, err
+	}
+	s.sample("GET", key, func(c *Client) (bool, interface{}, error) {
+		sval, sfound, serr := c.Get(key)
+		return sval == val && sfound == found, shadowGet{sval, sfound}, serr
+	}, shadowGet{val, found})
+	return val, found, nil
+}
+
+// VSearch searches the primary and samples the search against the shadow.
+// The instances agree if they return the same keys in the same order, with
+// scores within ScoreEpsilon; a mismatch reports both lists of matches.
+func (s *ShadowClient) VSearch(vector []float32, k int, opts *VSearchOptions) ([]VectorMatch, error) {
+	matches, err := s.primary.VSearch(vector, k, opts)
+	if err != nil {
+		return matches, err
+	}
+	primary := slices.Clone(matches)
+	s.sample("VSEARCH", "", func(c *Client) (bool, interface{}, error) {
+		smatches, serr := c.VSearch(vector, k, opts)
+		return equalMatches(primary, smatches, s.opts.ScoreEpsilon), smatches, serr
+	}, primary)
+	return matches, nil
+}
+
+// VSearchFilter searches the primary and samples the search against the shadow
+func (s *ShadowClient) VSearchFilter(vector []float32, k int, filter Filter) ([]string, error) {
+	keys, err := s.primary.VSearchFilter(vector, k, filter)
+	if err != nil {
+		return keys, err
+	}
+	s.sample("VSEARCH", filter.String(), func(c *Client) (bool, interface{}, error) {
+		skeys, serr := c.VSearchFilter(vector, k, filter)
+		return equalKeys(keys, skeys), skeys, serr
+	}, keys)
+	return keys, nil
+}
+
+type shadowGet struct {
+	Value string
+	Found bool
+}
+
+// sample runs read against the shadow in the background when selected by
+// the sample rate and the shadow connection is idle
+func (s *ShadowClient) sample(op, key string, read func(*Client) (bool, interface{}, error), primary interface{}) {
+	if s.opts.SampleRate <= 0 || rand.Float64() >= s.opts.SampleRate {
+		return
+	}
+	if !s.shadowMu.TryLock() {
+		s.skipped.Add(1)
+		return
+	}
+	s.sampled.Add(1)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.shadowMu.Unlock()
+		s.shadow.supervised("shadow read", func() error {
+			equal, secondary, err := read(s.shadow)
+			if err == nil && equal {
+				return nil
+			}
+			s.mismatches.Add(1)
+			if s.opts.OnMismatch != nil {
+				s.opts.OnMismatch(ShadowMismatch{Op: op, Key: key, Primary: primary, Secondary: secondary, Err: err})
+			}
+			return nil
+		})
+	}()
+}
+
+func equalMatches(a, b []VectorMatch, epsilon float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key != b[i].Key || math.Abs(float64(a[i].Score-b[i].Score)) > float64(epsilon) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
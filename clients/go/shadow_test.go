@@ -0,0 +1,43 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/YASSERRMD/celrix/clients/go/wire"
+)
+
+// vsearchServer answers every VSEARCH with one scored match
+func vsearchServer(key string, score float32) *testServer {
+	return &testServer{handle: func(_ int, f wire.Frame) []wire.Frame {
+		rec := binary.BigEndian.AppendUint32(appendString(nil, key), math.Float32bits(score))
+		return reply(f, OpArray, appendString(binary.BigEndian.AppendUint32(nil, 1), string(rec)))
+	}}
+}
+
+func TestShadowComparesScores(t *testing.T) {
+	for _, tc := range []struct {
+		epsilon float32
+		want    uint64
+	}{{0.1, 0}, {0.01, 1}} {
+		var got []ShadowMismatch
+		s := NewShadowClient(vsearchServer("a", 0.9).connect(t), vsearchServer("a", 0.85).connect(t), ShadowOptions{
+			SampleRate:   1,
+			ScoreEpsilon: tc.epsilon,
+			OnMismatch:   func(m ShadowMismatch) { got = append(got, m) },
+		})
+		if _, err := s.VSearch([]float32{1}, 1, nil); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+		if n := s.Stats().Mismatches; n != tc.want {
+			t.Errorf("epsilon %v: %d mismatches, want %d", tc.epsilon, n, tc.want)
+		}
+		if len(got) > 0 {
+			if m, ok := got[0].Secondary.([]VectorMatch); !ok || m[0].Score != 0.85 {
+				t.Errorf("mismatch reports shadow result %v", got[0].Secondary)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// CMSInit creates an empty count-min sketch at key with the given width
+// (counters per row) and depth (rows). Estimates overcount by at most
+// about 2/width of the total count, with probability 1 - 1/2^depth.
+// Incrementing a missing key creates a sketch with the server's default
+// dimensions instead.
+func (c *Client) CMSInit(key string, width, depth int) error {
+	if width <= 0 || depth <= 0 {
+		return fmt.Errorf("sketch dimensions must be positive, got %dx%d", width, depth)
+	}
+	// Payload: [key][width u32][depth u32]
+	payload := appendString(nil, key)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(width))
+	payload = binary.BigEndian.AppendUint32(payload, uint32(depth))
+	return c.write(OpCMSInit, key, payload)
+}
+
+// CMSIncr adds by to the count of item in the sketch at key and returns
+// its new estimate
+func (c *Client) CMSIncr(key, item string, by int64) (int64, error) {
+	if by <= 0 {
+		return 0, fmt.Errorf("increment must be positive, got %d", by)
+	}
+	// Payload: [key][item][by u64]
+	payload := appendString(appendString(nil, key), item)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(by))
+	if err := c.sendKeyed(OpCMSIncr, key, payload); err != nil {
+		return 0, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := resp.(int64)
+	if !ok {
+		return 0, c.unexpectedReply()
+	}
+	return n, nil
+}
+
+// CMSQuery returns the estimated count of each item in the sketch at key.
+// Estimates never undercount.
+func (c *Client) CMSQuery(key string, items ...string) ([]int64, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if err := c.sendKeyed(OpCMSQuery, key, appendStrings(appendString(nil, key), items)); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := resp.([]interface{})
+	if !ok || len(arr) != len(items) {
+		return nil, fmt.Errorf("expected %d counts, got %v", len(items), resp)
+	}
+	counts := make([]int64, len(arr))
+	for i, v := range arr {
+		n, ok := v.(int64)
+		if !ok {
+			return nil, fmt.Errorf("count %d: unexpected type %T", i, v)
+		}
+		counts[i] = n
+	}
+	return counts, nil
+}
+
+// TopKItem is an entry of a Top-K list
+type TopKItem struct {
+	Item  string
+	Count int64
+}
+
+// TopKReserve creates an empty Top-K structure at key tracking the k most
+// frequent items
+func (c *Client) TopKReserve(key string, k int) error {
+	if k <= 0 {
+		return fmt.Errorf("k must be positive, got %d", k)
+	}
+	payload := binary.BigEndian.AppendUint32(appendString(nil, key), uint32(k))
+	return c.write(OpTopKReserve, key, payload)
+}
+
+// TopKAdd counts one occurrence of each item in the Top-K structure at key
+// and returns the items they pushed out of the top k, if any
+func (c *Client) TopKAdd(key string, items ...string) ([]string, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if err := c.sendKeyed(OpTopKAdd, key, appendStrings(appendString(nil, key), items)); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	return toKeys(resp)
+}
+
+// TopKList returns the current top items at key, most frequent first.
+// Counts are estimates from the structure's sketch.
+func (c *Client) TopKList(key string) ([]TopKItem, error) {
+	if err := c.sendKeyed(OpTopKList, key, appendString(nil, key)); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	// Response: array of [count u64][item]
+	entries, err := toKeys(resp)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]TopKItem, len(entries))
+	for i, e := range entries {
+		if len(e) < 8 {
+			return nil, fmt.Errorf("top-k entry %d is truncated", i)
+		}
+		items[i] = TopKItem{Item: e[8:], Count: int64(binary.BigEndian.Uint64([]byte(e[:8])))}
+	}
+	return items, nil
+}
@@ -0,0 +1,95 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrSnapshotClosed is returned by searches on a closed SnapshotSearch
+var ErrSnapshotClosed = errors.New("celrix: search snapshot closed")
+
+// SnapshotSearch is a point-in-time view of a collection's index. Every
+// search through it sees the index as it was when the snapshot was taken,
+// however much is ingested meanwhile, so results paged across several
+// requests stay consistent: no duplicates or gaps from vectors added
+// between pages.
+//
+// A snapshot pins index memory on the server until it is closed. The
+// server also releases snapshots left idle past its snapshot timeout, after
+// which searches fail.
+type SnapshotSearch struct {
+	col    *Collection
+	id     uint64
+	closed bool
+}
+
+// SnapshotSearchHandle takes a snapshot of the collection's index for
+// stable searches. Close it when done.
+func (col *Collection) SnapshotSearchHandle() (*SnapshotSearch, error) {
+	col.client.nextCollection = col.name
+	if err := col.client.sendFrame(OpSnapshotSearch, appendString(nil, col.name)); err != nil {
+		return nil, err
+	}
+	resp, err := col.client.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	id, ok := resp.(int64)
+	if !ok {
+		return nil, col.client.unexpectedReply()
+	}
+	return &SnapshotSearch{col: col, id: uint64(id)}, nil
+}
+
+// ID returns the server's identifier for the snapshot
+func (s *SnapshotSearch) ID() uint64 { return s.id }
+
+// VSearch searches the snapshot like Collection.VSearch
+func (s *SnapshotSearch) VSearch(vector []float32, k int, filter *Filter) ([]string, error) {
+	if s.closed {
+		return nil, ErrSnapshotClosed
+	}
+	// Payload: [snapshot_id u64] followed by a CVSEARCH payload
+	payload, err := s.col.searchPayload(binary.BigEndian.AppendUint64(nil, s.id), vector, k, filter)
+	if err != nil {
+		return nil, err
+	}
+	c := s.col.client
+	c.nextCollection = s.col.name
+	if err := c.sendFrame(OpSnapshotVSearch, payload); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	return toKeys(resp)
+}
+
+// Page returns results offset through offset+limit of a search, for
+// paginating through a snapshot
+func (s *SnapshotSearch) Page(vector []float32, offset, limit int, filter *Filter) ([]string, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("invalid page offset %d, limit %d", offset, limit)
+	}
+	keys, err := s.VSearch(vector, offset+limit, filter)
+	if err != nil {
+		return nil, err
+	}
+	return page(keys, offset), nil
+}
+
+// Close releases the snapshot. Closing twice is a no-op.
+func (s *SnapshotSearch) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	c := s.col.client
+	c.nextCollection = s.col.name
+	if err := c.sendFrame(OpSnapshotRelease, binary.BigEndian.AppendUint64(nil, s.id)); err != nil {
+		return err
+	}
+	return c.expectOK()
+}
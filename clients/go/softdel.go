@@ -0,0 +1,48 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// DefaultTrashRetention is how long SoftDel keeps keys recoverable unless
+// WithTrashRetention says otherwise
+const DefaultTrashRetention = 24 * time.Hour
+
+// WithTrashRetention sets how long soft-deleted keys stay recoverable with
+// Undelete before the server purges them
+func WithTrashRetention(d time.Duration) Option {
+	return func(o *options) {
+		o.trashRetention = d
+	}
+}
+
+// SoftDel deletes key recoverably: the server moves it, value and TTL
+// included, to a trash namespace invisible to reads, and purges it after
+// the trash retention. It reports whether the key existed. Soft-deleting a
+// key again replaces the earlier trashed copy.
+func (c *Client) SoftDel(key string) (bool, error) {
+	retention := c.opts.trashRetention
+	if retention <= 0 {
+		retention = DefaultTrashRetention
+	}
+	// Payload: [key][retention u64 seconds]
+	payload := binary.BigEndian.AppendUint64(appendString(nil, key), ttlSeconds(retention))
+	c.vectors.forget(key)
+	if err := c.sendKeyed(OpSoftDel, key, payload); err != nil {
+		return false, c.journalFailure(OpSoftDel, payload, err)
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return false, c.journalFailure(OpSoftDel, payload, err)
+	}
+	return c.toBool(resp)
+}
+
+// Undelete restores a soft-deleted key with the value and remaining TTL it
+// had, and reports whether a trashed copy was found. The server refuses
+// with an error if the key has since been recreated, rather than
+// overwriting it.
+func (c *Client) Undelete(key string) (bool, error) {
+	return c.writeBool(OpUndelete, key, appendString(nil, key))
+}
@@ -0,0 +1,144 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ZAddFlag makes ZAdd conditional
+type ZAddFlag uint8
+
+// ZAdd flags
+const (
+	// ZAddNX only adds new members
+	ZAddNX ZAddFlag = 1 << iota
+	// ZAddXX only updates existing members
+	ZAddXX
+	// ZAddGT only raises scores
+	ZAddGT
+	// ZAddLT only lowers scores
+	ZAddLT
+)
+
+// ZMember is a sorted set member and its score
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// ZAdd sets the score of member in the sorted set at key, subject to flags
+// (zero for none), and reports whether the member was added or its score
+// changed. Only unconditional adds are journaled.
+func (c *Client) ZAdd(key, member string, score float64, flags ZAddFlag) (bool, error) {
+	if flags&ZAddNX != 0 && flags&(ZAddXX|ZAddGT|ZAddLT) != 0 {
+		return false, errors.New("ZAddNX cannot be combined with other flags")
+	}
+	if flags&ZAddGT != 0 && flags&ZAddLT != 0 {
+		return false, errors.New("ZAddGT and ZAddLT are mutually exclusive")
+	}
+	// Payload: [key][flags u8][score f64][member]
+	payload := appendString(nil, key)
+	payload = append(payload, uint8(flags))
+	payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(score))
+	payload = appendString(payload, member)
+	if flags != 0 {
+		return c.writeBool(OpZAdd, key, payload)
+	}
+	if err := c.sendKeyed(OpZAdd, key, payload); err != nil {
+		return false, c.journalFailure(OpZAdd, payload, err)
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return false, c.journalFailure(OpZAdd, payload, err)
+	}
+	return c.toBool(resp)
+}
+
+// ZRem removes member from the sorted set at key and reports whether it
+// was present
+func (c *Client) ZRem(key, member string) (bool, error) {
+	payload := appendString(appendString(nil, key), member)
+	if err := c.sendKeyed(OpZRem, key, payload); err != nil {
+		return false, c.journalFailure(OpZRem, payload, err)
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return false, c.journalFailure(OpZRem, payload, err)
+	}
+	return c.toBool(resp)
+}
+
+// ZScore returns the score of member in the sorted set at key
+func (c *Client) ZScore(key, member string) (float64, bool, error) {
+	if err := c.sendKeyed(OpZScore, key, appendString(appendString(nil, key), member)); err != nil {
+		return 0, false, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return 0, false, err
+	}
+	switch v := resp.(type) {
+	case nil:
+		return 0, false, nil
+	case float64:
+		return v, true, nil
+	default:
+		return 0, false, c.unexpectedReply()
+	}
+}
+
+// ZCard returns the number of members in the sorted set at key
+func (c *Client) ZCard(key string) (int64, error) {
+	if err := c.sendKeyed(OpZCard, key, appendString(nil, key)); err != nil {
+		return 0, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := resp.(int64)
+	if !ok {
+		return 0, c.unexpectedReply()
+	}
+	return n, nil
+}
+
+// ZRangeByScore returns up to limit members of the sorted set at key with
+// scores between min and max inclusive, lowest first. A limit of zero
+// returns them all; math.Inf bounds are allowed.
+func (c *Client) ZRangeByScore(key string, min, max float64, limit int) ([]ZMember, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative, got %d", limit)
+	}
+	// Payload: [key][min f64][max f64][limit u32]
+	payload := appendString(nil, key)
+	payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(min))
+	payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(max))
+	payload = binary.BigEndian.AppendUint32(payload, uint32(limit))
+	if err := c.sendKeyed(OpZRangeByScore, key, payload); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	// Response: array of [score f64][member]
+	items, err := toKeys(resp)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ZMember, len(items))
+	for i, item := range items {
+		if len(item) < 8 {
+			return nil, fmt.Errorf("sorted set entry %d is truncated", i)
+		}
+		members[i] = ZMember{
+			Member: item[8:],
+			Score:  math.Float64frombits(binary.BigEndian.Uint64([]byte(item[:8]))),
+		}
+	}
+	return members, nil
+}
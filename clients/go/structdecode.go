@@ -0,0 +1,306 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SearchHit is a search result with its score and metadata
+type SearchHit struct {
+	Key      string
+	Score    float32
+	Metadata Metadata
+}
+
+// VSearchInto runs a search and decodes each hit into an element of dest,
+// which must point to a slice of structs or struct pointers. Metadata fields
+// are matched to struct fields by their `celrix` tag, or by field name
+// (case-insensitively) when untagged:
+//
+//	type Doc struct {
+//		ID      string    `celrix:",key"`
+//		Score   float32   `celrix:",score"`
+//		Title   string    `celrix:"title"`
+//		Updated time.Time `celrix:"updated_at"`
+//		Draft   bool      `celrix:"-"`
+//	}
+//	var docs []Doc
+//	err := client.VSearchInto(query, 10, &docs)
+//
+// The ",key" and ",score" options receive the hit's key and similarity
+// score. Fields with no matching metadata are left at their zero value.
+func (c *Client) VSearchInto(vector []float32, k int, dest interface{}) error {
+	if err := checkSliceDest(dest); err != nil {
+		return err
+	}
+	hits, err := c.vsearchHits(vector, k, nil)
+	if err != nil {
+		return err
+	}
+	return decodeHits(hits, dest)
+}
+
+func checkSliceDest(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("celrix: search destination must be a pointer to a slice, got %T", dest)
+	}
+	return nil
+}
+
+// decodeHits stores hits into dest, a pointer to a slice checked by
+// checkSliceDest
+func decodeHits(hits []SearchHit, dest interface{}) error {
+	slice := reflect.ValueOf(dest).Elem()
+	elemType := slice.Type().Elem()
+	out := reflect.MakeSlice(slice.Type(), 0, len(hits))
+	for _, hit := range hits {
+		elem := reflect.New(elemType).Elem()
+		target := elem
+		if elemType.Kind() == reflect.Pointer {
+			elem.Set(reflect.New(elemType.Elem()))
+			target = elem.Elem()
+		}
+		if err := decodeHit(hit, target); err != nil {
+			return fmt.Errorf("celrix: decode hit %q: %w", hit.Key, err)
+		}
+		out = reflect.Append(out, elem)
+	}
+	slice.Set(out)
+	return nil
+}
+
+// UnmarshalMetadata decodes meta into the struct pointed to by dest using
+// the same field mapping as VSearchInto
+func UnmarshalMetadata(meta Metadata, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("celrix: UnmarshalMetadata destination must be a non-nil pointer, got %T", dest)
+	}
+	return decodeHit(SearchHit{Metadata: meta}, v.Elem())
+}
+
+func (c *Client) vsearchHits(vector []float32, k int, filter *Filter) ([]SearchHit, error) {
+	if err := c.checkVector(OpVSearchMeta, "", vector); err != nil {
+		return nil, err
+	}
+	// Payload: [count][f32...][k][filter?]
+	payload := appendVector(make([]byte, 0, 4+len(vector)*4+4), vector)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(k))
+	if filter != nil {
+		if err := filter.validate(); err != nil {
+			return nil, err
+		}
+		var err error
+		if payload, err = filter.appendTo(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.sendFrame(OpVSearchMeta, payload); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	records, err := toKeys(resp)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSearchHits(records)
+}
+
+// decodeSearchHits decodes VSEARCHMETA records, each
+// [key_len][key][score f32][metadata]
+func decodeSearchHits(records []string) ([]SearchHit, error) {
+	hits := make([]SearchHit, len(records))
+	for i, rec := range records {
+		b := []byte(rec)
+		key, n, err := readString(b)
+		if err != nil {
+			return nil, err
+		}
+		b = b[n:]
+		if len(b) < 4 {
+			return nil, errors.New("incomplete search hit score")
+		}
+		hits[i] = SearchHit{Key: key, Score: math.Float32frombits(binary.BigEndian.Uint32(b))}
+		if b = b[4:]; len(b) > 0 {
+			meta, _, err := decodeMetadata(b)
+			if err != nil {
+				return nil, err
+			}
+			hits[i].Metadata = meta
+		}
+	}
+	return hits, nil
+}
+
+// structField maps one metadata field, or the hit key or score, to a
+// struct field
+type structField struct {
+	index []int
+	name  string
+	key   bool
+	score bool
+}
+
+var structFieldCache sync.Map // reflect.Type -> []structField
+
+func structFields(t reflect.Type) []structField {
+	if cached, ok := structFieldCache.Load(t); ok {
+		return cached.([]structField)
+	}
+	var fields []structField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("celrix")
+		if tag == "-" {
+			continue
+		}
+		name, opt, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, structField{index: f.Index, name: name, key: opt == "key", score: opt == "score"})
+	}
+	structFieldCache.Store(t, fields)
+	return fields
+}
+
+func decodeHit(hit SearchHit, v reflect.Value) error {
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("destination element must be a struct, got %s", v.Type())
+	}
+	for _, f := range structFields(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		switch {
+		case f.key:
+			if fv.Kind() != reflect.String {
+				return fmt.Errorf("key field %s must be a string", f.name)
+			}
+			fv.SetString(hit.Key)
+		case f.score:
+			if !fv.CanFloat() {
+				return fmt.Errorf("score field %s must be a float", f.name)
+			}
+			fv.SetFloat(float64(hit.Score))
+		default:
+			mv, ok := lookupField(hit.Metadata, f.name)
+			if !ok {
+				continue
+			}
+			if err := setMetaField(fv, mv); err != nil {
+				return fmt.Errorf("field %s: %w", f.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// lookupField finds name exactly, then case-insensitively
+func lookupField(meta Metadata, name string) (MetaValue, bool) {
+	if v, ok := meta[name]; ok {
+		return v, true
+	}
+	for k, v := range meta {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return MetaValue{}, false
+}
+
+var (
+	metaValueType = reflect.TypeOf(MetaValue{})
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+func setMetaField(fv reflect.Value, mv MetaValue) error {
+	if fv.Kind() == reflect.Pointer {
+		p := reflect.New(fv.Type().Elem())
+		if err := setMetaField(p.Elem(), mv); err != nil {
+			return err
+		}
+		fv.Set(p)
+		return nil
+	}
+
+	switch {
+	case fv.Type() == metaValueType:
+		fv.Set(reflect.ValueOf(mv))
+		return nil
+	case fv.Type() == timeType:
+		if t, ok := mv.Time(); ok {
+			fv.Set(reflect.ValueOf(t))
+			return nil
+		}
+	case fv.Kind() == reflect.Interface && fv.NumMethod() == 0:
+		fv.Set(reflect.ValueOf(mv.native()))
+		return nil
+	case fv.Kind() == reflect.String:
+		if s, ok := mv.Str(); ok {
+			fv.SetString(s)
+			return nil
+		}
+	case fv.Kind() == reflect.Bool:
+		if b, ok := mv.Bool(); ok {
+			fv.SetBool(b)
+			return nil
+		}
+	case fv.CanInt():
+		if i, ok := mv.Int(); ok {
+			if fv.OverflowInt(i) {
+				return fmt.Errorf("value %d overflows %s", i, fv.Type())
+			}
+			fv.SetInt(i)
+			return nil
+		}
+	case fv.CanUint():
+		if i, ok := mv.Int(); ok {
+			if i < 0 || fv.OverflowUint(uint64(i)) {
+				return fmt.Errorf("value %d overflows %s", i, fv.Type())
+			}
+			fv.SetUint(uint64(i))
+			return nil
+		}
+	case fv.CanFloat():
+		if f, ok := mv.Float(); ok {
+			fv.SetFloat(f)
+			return nil
+		}
+		if i, ok := mv.Int(); ok {
+			fv.SetFloat(float64(i))
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot decode %s metadata into %s", mv.Type(), fv.Type())
+}
+
+// native returns the value as a plain Go value
+func (v MetaValue) native() interface{} {
+	switch v.typ {
+	case MetaString:
+		return v.s
+	case MetaInt:
+		return v.i
+	case MetaFloat:
+		return v.f
+	case MetaBool:
+		return v.b
+	case MetaTime:
+		t, _ := v.Time()
+		return t
+	default:
+		return nil
+	}
+}
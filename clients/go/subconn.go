@@ -0,0 +1,53 @@
+package celrix
+
+import (
+	"context"
+	"net"
+)
+
+// dialDedicated opens a separate connection to the same server for
+// long-running streams (CDC, export, restore) that would otherwise
+// monopolise the client's connection
+func (c *Client) dialDedicated(ctx context.Context) (*Client, error) {
+	// Streams share the parent's transport and reporting settings, but
+	// are not journaled, recorded, retried, or bound by command deadlines
+	opts := c.opts
+	opts.journalDir = ""
+	opts.recorder = nil
+	opts.adaptive = nil
+	opts.retry = nil
+	// Streams take their concurrency slot for their whole duration
+	opts.opLimits = nil
+	sub := &Client{addr: c.addr, opts: opts}
+	if err := sub.dial(ctx); err != nil {
+		return nil, err
+	}
+	sub.countOpen()
+	return sub, nil
+}
+
+// closeDedicated closes a connection opened by dialDedicated, taking it out
+// of the expvar gauges
+func (c *Client) closeDedicated() error {
+	if c.pending {
+		c.pending = false
+		c.gauge(&varInflight, -1)
+	}
+	c.gauge(&varOpen, -1)
+	c.counted = false
+	return c.conn.Close()
+}
+
+// closeOnDone closes conn when ctx is cancelled, unblocking any pending I/O.
+// The returned function stops the watcher.
+func closeOnDone(ctx context.Context, conn net.Conn) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,86 @@
+package celrix
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrClientFailed is wrapped by errors from commands on a client that has
+// entered the failed state after a background goroutine panicked. The
+// client must be replaced; Err returns the cause.
+var ErrClientFailed = errors.New("celrix: client failed")
+
+// PanicError is a panic recovered in one of the client's background
+// goroutines
+type PanicError struct {
+	// Goroutine names the task that panicked, e.g. "cdc reader"
+	Goroutine string
+	Value     interface{}
+	Stack     []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("celrix: panic in %s: %v", e.Goroutine, e.Value)
+}
+
+// WithOnAsyncError registers fn to receive failures from the client's
+// background goroutines, which have no caller to return an error to. It is
+// called from the failing goroutine and must not block.
+func WithOnAsyncError(fn func(error)) Option {
+	return func(o *options) {
+		o.onAsyncError = fn
+	}
+}
+
+// Err returns the failure that put the client in the failed state, or nil
+// while it is healthy
+func (c *Client) Err() error {
+	if f := c.failure.Load(); f != nil {
+		return f.err
+	}
+	return nil
+}
+
+type clientFailure struct {
+	err error
+}
+
+// supervised runs fn, the body of a background goroutine, converting a panic
+// into a *PanicError. A panic fails the client: its connection is closed and
+// later commands return ErrClientFailed. The panic, or fn's own error, is
+// returned for the goroutine to report through its own channel if it has
+// one.
+func (c *Client) supervised(name string, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Goroutine: name, Value: v, Stack: debug.Stack()}
+			c.fail(err)
+		}
+	}()
+	return fn()
+}
+
+// fail moves the client to the failed state and reports err through
+// OnAsyncError. Only the first failure is kept.
+func (c *Client) fail(err error) {
+	if !c.failure.CompareAndSwap(nil, &clientFailure{err: err}) {
+		return
+	}
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.emit(EventDisconnected, 0, err)
+	if c.opts.onAsyncError != nil {
+		c.opts.onAsyncError(err)
+	}
+}
+
+// failedErr returns the error for a command issued on a failed client, or
+// nil if the client is healthy
+func (c *Client) failedErr() error {
+	if f := c.failure.Load(); f != nil {
+		return fmt.Errorf("%w: %v", ErrClientFailed, f.err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Sample is a time-series data point. Timestamps have millisecond
+// resolution on the wire.
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Aggregation combines the samples in a downsampling bucket
+type Aggregation uint8
+
+// Aggregations
+const (
+	AggAvg Aggregation = iota + 1
+	AggSum
+	AggMin
+	AggMax
+	AggCount
+	AggFirst
+	AggLast
+)
+
+// String returns the aggregation name
+func (a Aggregation) String() string {
+	switch a {
+	case AggAvg:
+		return "avg"
+	case AggSum:
+		return "sum"
+	case AggMin:
+		return "min"
+	case AggMax:
+		return "max"
+	case AggCount:
+		return "count"
+	case AggFirst:
+		return "first"
+	case AggLast:
+		return "last"
+	default:
+		return fmt.Sprintf("Aggregation(%d)", uint8(a))
+	}
+}
+
+// TSCreate creates an empty time series at key that drops samples older
+// than retention (zero keeps them forever). TSAdd creates missing series
+// with unlimited retention, so TSCreate is only needed to bound one.
+func (c *Client) TSCreate(key string, retention time.Duration) error {
+	if retention < 0 {
+		return fmt.Errorf("retention must not be negative, got %v", retention)
+	}
+	// Payload: [key][retention_ms u64]
+	payload := binary.BigEndian.AppendUint64(appendString(nil, key), uint64(retention.Milliseconds()))
+	return c.write(OpTSCreate, key, payload)
+}
+
+// TSAdd appends a sample to the time series at key. A sample at an
+// existing timestamp replaces it.
+func (c *Client) TSAdd(key string, ts time.Time, value float64) error {
+	// Payload: [key][ts_ms i64][value f64]
+	payload := appendString(nil, key)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(ts.UnixMilli()))
+	payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(value))
+	return c.write(OpTSAdd, key, payload)
+}
+
+// TSRange returns the samples at key with timestamps in [from, to], oldest
+// first
+func (c *Client) TSRange(key string, from, to time.Time) ([]Sample, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("range ends before it starts: %v < %v", to, from)
+	}
+	// Payload: [key][from_ms i64][to_ms i64]
+	payload := appendString(nil, key)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(from.UnixMilli()))
+	payload = binary.BigEndian.AppendUint64(payload, uint64(to.UnixMilli()))
+	if err := c.sendKeyed(OpTSRange, key, payload); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	switch v := resp.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return decodeSamples([]byte(v))
+	default:
+		return nil, c.unexpectedReply()
+	}
+}
+
+// decodeSamples decodes [count u32]([ts_ms i64][value f64])...
+func decodeSamples(b []byte) ([]Sample, error) {
+	if len(b) < 4 {
+		return nil, errors.New("incomplete sample list")
+	}
+	count := int(binary.BigEndian.Uint32(b))
+	b = b[4:]
+	if len(b) != count*16 {
+		return nil, fmt.Errorf("sample list holds %d bytes, want %d", len(b), count*16)
+	}
+	samples := make([]Sample, count)
+	for i := range samples {
+		samples[i] = Sample{
+			Time:  time.UnixMilli(int64(binary.BigEndian.Uint64(b))).UTC(),
+			Value: math.Float64frombits(binary.BigEndian.Uint64(b[8:])),
+		}
+		b = b[16:]
+	}
+	return samples, nil
+}
+
+// TSCreateRule downsamples the series at src into dst: each bucket of
+// samples added to src is combined with agg into one sample in dst, stamped
+// with the bucket's start. dst must already exist, which lets it carry its
+// own retention.
+func (c *Client) TSCreateRule(src, dst string, agg Aggregation, bucket time.Duration) error {
+	if agg < AggAvg || agg > AggLast {
+		return fmt.Errorf("unknown aggregation %v", agg)
+	}
+	if bucket < time.Millisecond {
+		return fmt.Errorf("bucket must be at least 1ms, got %v", bucket)
+	}
+	// Payload: [src][dst][agg u8][bucket_ms u64]
+	payload := appendString(appendString(nil, src), dst)
+	payload = append(payload, uint8(agg))
+	payload = binary.BigEndian.AppendUint64(payload, uint64(bucket.Milliseconds()))
+	return c.write(OpTSCreateRule, src, payload)
+}
+
+// TSDeleteRule removes the downsampling rule from src to dst. Samples
+// already written to dst are kept.
+func (c *Client) TSDeleteRule(src, dst string) error {
+	return c.write(OpTSDeleteRule, src, appendString(appendString(nil, src), dst))
+}
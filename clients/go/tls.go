@@ -0,0 +1,55 @@
+package celrix
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// WithTLS encrypts every connection with TLS, including the dedicated
+// connections used for streams, for servers that terminate TLS themselves
+// or sit behind a terminator. The handshake runs on the connection opened
+// by the dialer, so WithDialer still chooses the transport underneath.
+//
+// cfg is cloned per connection. Its ServerName, sent as SNI and checked
+// against the server certificate, defaults to the host part of the
+// address. Set Certificates or GetClientCertificate for mutual TLS, and
+// RootCAs for a private CA.
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tls = cfg
+	}
+}
+
+// ConnectTLS connects to a CELRIX server over TLS. It is shorthand for
+// Connect with WithTLS(cfg).
+func ConnectTLS(addr string, cfg *tls.Config, opts ...Option) (*Client, error) {
+	return Connect(addr, append(append([]Option(nil), opts...), WithTLS(cfg))...)
+}
+
+// tlsDialer wraps dial to run a TLS client handshake on each connection
+func tlsDialer(dial DialFunc, cfg *tls.Config) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		c := cfg.Clone()
+		if c == nil {
+			c = &tls.Config{}
+		}
+		if c.ServerName == "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				host = addr
+			}
+			c.ServerName = host
+		}
+		tc := tls.Client(conn, c)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tc, nil
+	}
+}
@@ -0,0 +1,125 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StreamChunkSize is the most value bytes SetReader and GetWriter put in
+// one frame
+const StreamChunkSize = 256 << 10
+
+// SetReader sets key to the size bytes read from r, sent in frames of up to
+// StreamChunkSize so the value is never held in memory whole. The server
+// assembles the chunks and makes the value visible only once all have
+// arrived; until then reads see the previous value.
+//
+// If r fails or ends before size bytes, the upload is abandoned and key is
+// left as it was. The value is not compressed, and a failed upload is not
+// journaled. WithMaxValueSize applies to size.
+func (c *Client) SetReader(key string, r io.Reader, size int64) error {
+	if size < 0 {
+		return errors.New("size must not be negative")
+	}
+	if err := c.checkValue(OpSetStream, key, int(size)); err != nil {
+		return err
+	}
+	// Payload: [key][size u64]
+	payload := binary.BigEndian.AppendUint64(appendString(nil, key), uint64(size))
+	if err := c.sendKeyed(OpSetStream, key, payload); err != nil {
+		return err
+	}
+	if err := c.expectOK(); err != nil {
+		return err
+	}
+
+	buf := make([]byte, min(size, StreamChunkSize))
+	for sent := int64(0); sent < size; {
+		n := min(size-sent, StreamChunkSize)
+		if _, err := io.ReadFull(r, buf[:n]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = fmt.Errorf("reader ended after %d of %d bytes", sent, size)
+			}
+			return c.abortSetStream(key, err)
+		}
+		if err := c.sendKeyed(OpSetChunk, key, buf[:n]); err != nil {
+			return err
+		}
+		if err := c.expectOK(); err != nil {
+			return err
+		}
+		sent += n
+	}
+
+	// End payload: [commit u8]
+	if err := c.sendKeyed(OpSetStreamEnd, key, []byte{1}); err != nil {
+		return err
+	}
+	return c.expectOK()
+}
+
+// abortSetStream tells the server to discard an upload that failed on the
+// reader's side, and returns cause
+func (c *Client) abortSetStream(key string, cause error) error {
+	if err := c.sendKeyed(OpSetStreamEnd, key, []byte{0}); err != nil {
+		return err
+	}
+	if err := c.expectOK(); err != nil {
+		return err
+	}
+	return fmt.Errorf("celrix: SETSTREAM key=%q: %w", c.redacted(key), cause)
+}
+
+// GetWriter copies the value of key to w as it arrives, in frames of up to
+// StreamChunkSize, and returns its length. The boolean is false if the key
+// does not exist. If w fails, the rest of the value is still read, to keep
+// the connection in step, and w's error is returned.
+func (c *Client) GetWriter(key string, w io.Writer) (int64, bool, error) {
+	// Payload: [key][chunk size u32]
+	payload := binary.BigEndian.AppendUint32(appendString(nil, key), StreamChunkSize)
+	if err := c.sendKeyed(OpGetStream, key, payload); err != nil {
+		return 0, false, err
+	}
+
+	// Reply: nil, or the value's length followed by VALUECHUNK frames
+	// carrying exactly that many bytes
+	var buf []byte
+	f, err := c.recvFrameInto(&buf)
+	if err != nil {
+		return 0, false, err
+	}
+	switch {
+	case f.opcode == OpNil:
+		return 0, false, nil
+	case f.opcode != OpInteger:
+		return 0, false, c.fastReplyErr(f)
+	case len(f.payload) != 8:
+		return 0, false, c.cmdErr(fmt.Errorf("value length of %d bytes, want 8", len(f.payload)))
+	}
+	size := int64(binary.BigEndian.Uint64(f.payload))
+
+	var written int64
+	var werr error
+	for received := int64(0); received < size; {
+		f, err := c.recvFrameInto(&buf)
+		if err != nil {
+			return written, true, err
+		}
+		if f.opcode != OpValueChunk {
+			// The rest of the value is still on the connection
+			return written, true, c.desync(&f, "unexpected frame in value stream", nil)
+		}
+		received += int64(len(f.payload))
+		if received > size {
+			return written, true, c.cmdErr(fmt.Errorf("chunks exceed the value length of %d bytes", size))
+		}
+		if werr == nil {
+			var n int
+			n, werr = w.Write(f.payload)
+			written += int64(n)
+		}
+	}
+	return written, true, werr
+}
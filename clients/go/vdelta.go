@@ -0,0 +1,194 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"strings"
+)
+
+// Capability is a protocol feature negotiated with the server at connect
+type Capability uint32
+
+// Capabilities
+const (
+	// CapVectorDelta lets VAdd send only the changed dimensions of a vector
+	CapVectorDelta Capability = 1 << 0
+	// CapServerTiming asks the server to report each command's execution
+	// time in reply attributes
+	CapServerTiming Capability = 1 << 1
+)
+
+// negotiateCapabilities offers the capabilities enabled by options and
+// records those the server accepts. Servers that do not know CAPABILITIES
+// reply with an error, which leaves every capability off.
+//
+// Request:  [offered: u32]
+// Response: OpInteger accepted
+func (c *Client) negotiateCapabilities() error {
+	c.caps = 0
+	var offered Capability
+	if c.opts.vectorDeltas > 0 {
+		offered |= CapVectorDelta
+	}
+	if c.opts.serverTiming {
+		offered |= CapServerTiming
+	}
+	if offered == 0 {
+		return nil
+	}
+	if err := c.sendFrame(OpCapabilities, binary.BigEndian.AppendUint32(nil, uint32(offered))); err != nil {
+		return err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		if isServerError(err) {
+			c.logReconnect("capabilities refused", "error", err)
+			return nil
+		}
+		return err
+	}
+	n, ok := resp.(int64)
+	if !ok {
+		return fmt.Errorf("unexpected CAPABILITIES response: %v", resp)
+	}
+	c.caps = Capability(n) & offered
+	return nil
+}
+
+// HasCapability reports whether the server accepted capability cp on this
+// connection
+func (c *Client) HasCapability(cp Capability) bool {
+	return c.caps&cp == cp
+}
+
+// WithVectorDeltas makes VAdd send a sparse delta, the changed dimensions
+// only, when it overwrites a vector this client recently wrote, cutting
+// bandwidth for frequently refreshed embeddings. The last vector written
+// is remembered for up to capacity keys. Deltas are used only if the
+// server accepts CapVectorDelta; the server checks each delta against a
+// checksum of the vector it was computed from, and on a mismatch the full
+// vector is sent instead.
+func WithVectorDeltas(capacity int) Option {
+	return func(o *options) {
+		o.vectorDeltas = capacity
+	}
+}
+
+// vectorCache remembers the last vector written per key. Eviction is
+// arbitrary; a miss just costs a full write.
+type vectorCache struct {
+	capacity int
+	vectors  map[string][]float32
+}
+
+func (vc *vectorCache) get(key string) []float32 {
+	if vc == nil {
+		return nil
+	}
+	return vc.vectors[key]
+}
+
+func (vc *vectorCache) put(key string, vector []float32) {
+	if vc == nil {
+		return
+	}
+	if _, ok := vc.vectors[key]; !ok && len(vc.vectors) >= vc.capacity {
+		for k := range vc.vectors {
+			delete(vc.vectors, k)
+			break
+		}
+	}
+	vc.vectors[key] = append([]float32(nil), vector...)
+}
+
+func (vc *vectorCache) forget(key string) {
+	if vc != nil {
+		delete(vc.vectors, key)
+	}
+}
+
+func (vc *vectorCache) forgetPrefix(prefix string) {
+	if vc == nil {
+		return
+	}
+	for k := range vc.vectors {
+		if strings.HasPrefix(k, prefix) {
+			delete(vc.vectors, k)
+		}
+	}
+}
+
+// vectorCRC is the checksum a delta's base is identified by: CRC-32 (IEEE)
+// of the vector's big-endian float32 encoding
+func vectorCRC(vector []float32) uint32 {
+	var b [4]byte
+	h := crc32.NewIEEE()
+	for _, f := range vector {
+		binary.BigEndian.PutUint32(b[:], math.Float32bits(f))
+		h.Write(b[:])
+	}
+	return h.Sum32()
+}
+
+// encodeVectorDelta builds a VDELTA payload if it is smaller than the full
+// vector:
+//
+//	[key_len][key][dims: u32][base_crc: u32][count: u32]([index: u32][f32])...
+func encodeVectorDelta(key string, base, vector []float32) ([]byte, bool) {
+	if len(base) != len(vector) {
+		return nil, false
+	}
+	var changed []int
+	for i := range vector {
+		if math.Float32bits(base[i]) != math.Float32bits(vector[i]) {
+			changed = append(changed, i)
+			// Each changed dimension costs 8 bytes against 4 for the
+			// full vector's
+			if 2*len(changed) >= len(vector) {
+				return nil, false
+			}
+		}
+	}
+	payload := make([]byte, 0, 4+len(key)+12+8*len(changed))
+	payload = appendString(payload, key)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(vector)))
+	payload = binary.BigEndian.AppendUint32(payload, vectorCRC(base))
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(changed)))
+	for _, i := range changed {
+		payload = binary.BigEndian.AppendUint32(payload, uint32(i))
+		payload = binary.BigEndian.AppendUint32(payload, math.Float32bits(vector[i]))
+	}
+	return payload, true
+}
+
+// vaddDelta tries to write vector as a delta against the cached base. It
+// reports false when no delta was applied and the full vector must be sent.
+// Transport failures journal the full write, since a journaled delta could
+// be replayed against a different base.
+func (c *Client) vaddDelta(key string, vector []float32, full []byte) (bool, error) {
+	if !c.HasCapability(CapVectorDelta) {
+		return false, nil
+	}
+	payload, ok := encodeVectorDelta(key, c.vectors.get(key), vector)
+	if !ok {
+		return false, nil
+	}
+	if err := c.sendKeyed(OpVDelta, key, payload); err != nil {
+		return true, c.journalFailure(OpVAdd, full, err)
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		if isServerError(err) {
+			// The server's vector is not the base the delta was built on
+			c.vectors.forget(key)
+			return false, nil
+		}
+		return true, c.journalFailure(OpVAdd, full, err)
+	}
+	if s, ok := resp.(string); !ok || s != "OK" {
+		return true, c.cmdErr(fmt.Errorf("expected OK, got %v", resp))
+	}
+	return true, nil
+}
@@ -0,0 +1,131 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// VectorMatch is a search result: the key of a matching vector and its
+// similarity to the query
+type VectorMatch struct {
+	Key string
+	// Score is the similarity under the search's metric: the cosine
+	// similarity, the dot product, or the negated L2 distance, so that for
+	// every metric a higher score is nearer. Zero when the server does not
+	// report scores.
+	Score float32
+}
+
+// VSearchOptions tunes a VSearch
+type VSearchOptions struct {
+	// Metric ranks and scores the matches, in place of the metric the
+	// index was built with. The zero value, MetricDefault, keeps the
+	// index's.
+	Metric Metric
+}
+
+// VSEARCH request flags. With vsearchFlagScores the reply holds
+// [key][score f32] records instead of bare keys; with vsearchFlagMetric the
+// metric in bits 8-9 overrides the index's.
+const (
+	vsearchFlagScores  uint16 = 1 << 0
+	vsearchFlagMetric  uint16 = 1 << 1
+	vsearchMetricShift        = 8
+)
+
+// VSearch searches for the k vectors most similar to vector and returns
+// them nearest first, with their scores. opts may be nil, to use the
+// index's metric. Servers that ignore the request flags return bare keys:
+// their matches carry zero scores, ranked by the index's metric.
+func (c *Client) VSearch(vector []float32, k int, opts *VSearchOptions) ([]VectorMatch, error) {
+	if err := c.checkVector(OpVSearch, "", vector); err != nil {
+		return nil, err
+	}
+	flags, err := vsearchFlags(opts)
+	if err != nil {
+		return nil, err
+	}
+	c.nextFlags = flags
+	if err := c.sendFrame(OpVSearch, vsearchPayload(vector, k)); err != nil {
+		return nil, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	records, err := toKeys(resp)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMatches(records), nil
+}
+
+// vsearchFlags returns the frame flags of a scored VSEARCH under opts
+func vsearchFlags(opts *VSearchOptions) (uint16, error) {
+	flags := vsearchFlagScores
+	if opts != nil && opts.Metric != MetricDefault {
+		if opts.Metric > MetricDot {
+			return 0, fmt.Errorf("unknown metric %v", opts.Metric)
+		}
+		flags |= vsearchFlagMetric | uint16(opts.Metric.wire())<<vsearchMetricShift
+	}
+	return flags, nil
+}
+
+// vsearchPayload builds a VSEARCH payload: [count][f32...][k]
+func vsearchPayload(vector []float32, k int) []byte {
+	payload := appendVector(make([]byte, 0, 4+len(vector)*4+4), vector)
+	return binary.BigEndian.AppendUint32(payload, uint32(k))
+}
+
+// decodeMatches decodes the [key][score f32] records of a scored VSEARCH,
+// or, if any element is not exactly such a record, takes the elements as
+// the bare keys of a server that ignored vsearchFlagScores. A bare key
+// reads as a record only if its first four bytes give the length of the
+// rest less four, which no realistic key does.
+func decodeMatches(records []string) []VectorMatch {
+	matches := make([]VectorMatch, len(records))
+	for i, rec := range records {
+		b := []byte(rec)
+		key, n, err := readString(b)
+		if err != nil || len(b) != n+4 {
+			return bareMatches(records)
+		}
+		matches[i] = VectorMatch{Key: key, Score: math.Float32frombits(binary.BigEndian.Uint32(b[n:]))}
+	}
+	return matches
+}
+
+func bareMatches(keys []string) []VectorMatch {
+	matches := make([]VectorMatch, len(keys))
+	for i, key := range keys {
+		matches[i] = VectorMatch{Key: key}
+	}
+	return matches
+}
+
+// Matches decodes the reply to a VSEARCH queued on a Pipeline into its
+// scored matches, nearest first
+func (r Reply) Matches() ([]VectorMatch, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.typ != ReplyArray {
+		return nil, fmt.Errorf("expected array reply, got %s", r.typ)
+	}
+	records := make([]string, len(r.array))
+	for i, item := range r.array {
+		records[i] = string(item.bytes)
+	}
+	return decodeMatches(records), nil
+}
+
+// MatchKeys returns the keys of matches, in order
+func MatchKeys(matches []VectorMatch) []string {
+	keys := make([]string, len(matches))
+	for i, m := range matches {
+		keys[i] = m.Key
+	}
+	return keys
+}
@@ -0,0 +1,27 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestDecodeMatches(t *testing.T) {
+	scored := func(key string, score float32) string {
+		return string(binary.BigEndian.AppendUint32(appendString(nil, key), math.Float32bits(score)))
+	}
+	got := decodeMatches([]string{scored("a", 0.9), scored("b", 0.5)})
+	if len(got) != 2 || got[0] != (VectorMatch{"a", 0.9}) || got[1] != (VectorMatch{"b", 0.5}) {
+		t.Errorf("scored reply: got %v", got)
+	}
+
+	// A server that ignores the flags answers with bare keys
+	got = decodeMatches([]string{"doc:1", "doc:2"})
+	if len(got) != 2 || got[0] != (VectorMatch{Key: "doc:1"}) || got[1] != (VectorMatch{Key: "doc:2"}) {
+		t.Errorf("bare keys: got %v", got)
+	}
+	got = decodeMatches([]string{scored("a", 1), "doc:2"})
+	if len(got) != 2 || got[0].Key != scored("a", 1) || got[1].Key != "doc:2" {
+		t.Errorf("mixed reply: got %v", got)
+	}
+}
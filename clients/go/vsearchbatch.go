@@ -0,0 +1,142 @@
+package celrix
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// parallelDecodeMin is the reply size from which VSearchBatch decodes
+// result blocks in parallel; below it goroutine handoff costs more than
+// it saves
+const parallelDecodeMin = 256 << 10
+
+// VSearchBatch runs several searches in one request and returns the keys
+// found for each query, in query order. Large replies, such as many
+// queries with k in the thousands, are decoded in parallel by up to
+// GOMAXPROCS goroutines. An error for any one query fails the call.
+func (c *Client) VSearchBatch(queries [][]float32, k int) ([][]string, error) {
+	// Payload: [count]([count][f32...])...[k]
+	size := 4 + 4
+	for _, q := range queries {
+		if err := c.checkVector(OpVSearchBatch, "", q); err != nil {
+			return nil, err
+		}
+		size += 4 + len(q)*4
+	}
+	payload := make([]byte, 0, size)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(queries)))
+	for _, q := range queries {
+		payload = appendVector(payload, q)
+	}
+	payload = binary.BigEndian.AppendUint32(payload, uint32(k))
+
+	if err := c.sendFrame(OpVSearchBatch, payload); err != nil {
+		return nil, err
+	}
+	f, err := c.recvFrame()
+	if err != nil {
+		return nil, err
+	}
+	if f.opcode != OpTypedArray {
+		_, err := decodeResponse(f.opcode, f.payload)
+		if err == nil {
+			err = fmt.Errorf("unexpected response opcode %s", Op(f.opcode))
+		}
+		return nil, c.cmdErr(err)
+	}
+	results, err := decodeSearchBlocks(f.payload)
+	if err != nil {
+		return nil, c.cmdErr(err)
+	}
+	if len(results) != len(queries) {
+		return nil, c.cmdErr(fmt.Errorf("expected %d results, got %d", len(queries), len(results)))
+	}
+	return results, nil
+}
+
+// searchBlock is one query's element of a VSearchBatch reply
+type searchBlock struct {
+	op      uint8
+	payload []byte
+}
+
+// decodeSearchBlocks decodes a typed array holding one key array, or
+// error, per query. Splitting the array is cheap; decoding the blocks is
+// what dominates for large k, so that part is spread across workers.
+func decodeSearchBlocks(payload []byte) ([][]string, error) {
+	if len(payload) < 4 {
+		return nil, errors.New("incomplete typed array")
+	}
+	count := int(binary.BigEndian.Uint32(payload))
+	b := payload[4:]
+	if count > len(b)/5 {
+		return nil, errors.New("typed array count exceeds payload")
+	}
+	blocks := make([]searchBlock, count)
+	for i := range blocks {
+		if len(b) < 5 {
+			return nil, errors.New("incomplete typed array element")
+		}
+		op, n := b[0], int(binary.BigEndian.Uint32(b[1:]))
+		if len(b) < 5+n {
+			return nil, errors.New("incomplete typed array element")
+		}
+		blocks[i] = searchBlock{op: op, payload: b[5 : 5+n]}
+		b = b[5+n:]
+	}
+
+	results := make([][]string, count)
+	errs := make([]error, count)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > count {
+		workers = count
+	}
+	if len(payload) < parallelDecodeMin || workers < 2 {
+		for i, blk := range blocks {
+			results[i], errs[i] = blk.decode()
+		}
+	} else {
+		var wg sync.WaitGroup
+		// Workers take interleaved blocks, which balances uneven k
+		// better than contiguous ranges would
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := w; i < count; i += workers {
+					results[i], errs[i] = blocks[i].decode()
+				}
+			}(w)
+		}
+		wg.Wait()
+	}
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("query %d: %w", i, err)
+		}
+	}
+	return results, nil
+}
+
+// decode converts a block to the keys it holds
+func (blk searchBlock) decode() ([]string, error) {
+	r, err := decodeReply(blk.op, blk.payload)
+	if err != nil {
+		return nil, err
+	}
+	if r.typ == ReplyError {
+		return nil, r.err
+	}
+	arr, ok := r.Array()
+	if !ok {
+		return nil, fmt.Errorf("expected array, got %s", r.typ)
+	}
+	keys := make([]string, len(arr))
+	for i, el := range arr {
+		keys[i] = el.String()
+	}
+	return keys, nil
+}
@@ -0,0 +1,118 @@
+package wire
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+)
+
+// Golden is a reference frame with its exact encoding
+type Golden struct {
+	Name  string
+	Frame Frame
+	// Hex is the lowercase hex encoding of the whole frame
+	Hex string
+}
+
+// GoldenFrames returns the reference fixtures covering each frame shape the
+// Go client sends or expects, in both protocol versions. Implementations
+// must encode Frame to exactly Hex and decode Hex back to Frame. The
+// encodings are spelled out rather than produced by this package's
+// encoder, so a change to the encoder cannot change them too.
+func GoldenFrames() []Golden {
+	return []Golden{
+		{"ping", Frame{Version: 1, Opcode: 0x01, RequestID: 1},
+			"43454c58010100000000000000000000000000010000"},
+		{"pong", Frame{Version: 1, Opcode: 0x02, RequestID: 1},
+			"43454c58010200000000000000000000000000010000"},
+		{"get", Frame{Version: 1, Opcode: 0x03, RequestID: 2, Payload: str("user:1")},
+			"43454c58010300000000000a0000000000000002000000000006757365723a31"},
+		{"set", Frame{Version: 1, Opcode: 0x04, RequestID: 3, Payload: cat(str("user:1"), str("Jane"), u64(0))},
+			"43454c58010400000000001a0000000000000003000000000006757365723a31000000044a616e650000000000000000"},
+		{"set_ttl", Frame{Version: 1, Opcode: 0x04, RequestID: 4, Payload: cat(str("session"), str("token"), u64(3600))},
+			"43454c58010400000000001c000000000000000400000000000773657373696f6e00000005746f6b656e0000000000000e10"},
+		{"del", Frame{Version: 1, Opcode: 0x05, RequestID: 5, Payload: str("user:1")},
+			"43454c58010500000000000a0000000000000005000000000006757365723a31"},
+		{"ok", Frame{Version: 1, Opcode: 0x10, RequestID: 3},
+			"43454c58011000000000000000000000000000030000"},
+		{"error", Frame{Version: 1, Opcode: 0x11, RequestID: 6, Payload: []byte("ERR unknown command")},
+			"43454c5801110000000000130000000000000006000045525220756e6b6e6f776e20636f6d6d616e64"},
+		{"value", Frame{Version: 1, Opcode: 0x12, RequestID: 2, Payload: []byte("Jane")},
+			"43454c580112000000000004000000000000000200004a616e65"},
+		{"nil", Frame{Version: 1, Opcode: 0x13, RequestID: 2},
+			"43454c58011300000000000000000000000000020000"},
+		{"integer", Frame{Version: 1, Opcode: 0x14, RequestID: 5, Payload: u64(1)},
+			"43454c580114000000000008000000000000000500000000000000000001"},
+		{"integer_negative", Frame{Version: 1, Opcode: 0x14, RequestID: 7, Payload: u64(math.MaxUint64)},
+			"43454c58011400000000000800000000000000070000ffffffffffffffff"},
+		{"array", Frame{Version: 1, Opcode: 0x15, RequestID: 8, Payload: cat(u32(2), str("a"), str("bc"))},
+			"43454c58011500000000000f00000000000000080000000000020000000161000000026263"},
+		{"array_empty", Frame{Version: 1, Opcode: 0x15, RequestID: 9, Payload: u32(0)},
+			"43454c5801150000000000040000000000000009000000000000"},
+		{"vadd", Frame{Version: 1, Opcode: 0x20, RequestID: 10, Payload: cat(str("v"), vec(0.5, -1, 0))},
+			"43454c580120000000000015000000000000000a00000000000176000000033f000000bf80000000000000"},
+		{"vsearch", Frame{Version: 1, Opcode: 0x21, RequestID: 11, Payload: cat(vec(0.5, -1, 0), u32(5))},
+			"43454c580121000000000014000000000000000b0000000000033f000000bf8000000000000000000005"},
+		{"flags", Frame{Version: 1, Opcode: 0x21, Flags: 0x0102, RequestID: 12, Payload: cat(vec(1), u32(1))},
+			"43454c58012101020000000c000000000000000c0000000000013f80000000000001"},
+		{"max_request_id", Frame{Version: 1, Opcode: 0x01, RequestID: math.MaxUint64},
+			"43454c580101000000000000ffffffffffffffff0000"},
+
+		// Version 2 adds the stream ID and the payload's CRC-32
+		{"v2_ping", Frame{Version: 2, Opcode: 0x01, RequestID: 1},
+			"43454c5802010000000000000000000000000001000000000000000000000000"},
+		{"v2_get", Frame{Version: 2, Opcode: 0x03, RequestID: 2, StreamID: 7, Payload: str("user:1")},
+			"43454c58020300000000000a0000000000000002000000074a3d3e6d0000000000000006757365723a31"},
+		{"v2_value", Frame{Version: 2, Opcode: 0x12, RequestID: 2, StreamID: 7, Payload: []byte("Jane")},
+			"43454c5802120000000000040000000000000002000000075a5e15ac000000004a616e65"},
+		{"v2_flags", Frame{Version: 2, Opcode: 0x21, Flags: 0x0102, RequestID: 12, StreamID: math.MaxUint32, Payload: cat(vec(1), u32(1))},
+			"43454c58022101020000000c000000000000000cffffffffcf52499900000000000000013f80000000000001"},
+	}
+}
+
+// WriteFixtures writes the golden frames to w as JSON lines, for consumption
+// by test suites in other languages:
+//
+//	{"name":"ping","version":1,"opcode":1,"flags":0,"request_id":1,"stream_id":0,"payload":"","frame":"43454c58..."}
+func WriteFixtures(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, g := range GoldenFrames() {
+		if err := enc.Encode(struct {
+			Name      string `json:"name"`
+			Version   uint8  `json:"version"`
+			Opcode    uint8  `json:"opcode"`
+			Flags     uint16 `json:"flags"`
+			RequestID uint64 `json:"request_id"`
+			StreamID  uint32 `json:"stream_id"`
+			Payload   string `json:"payload"`
+			Frame     string `json:"frame"`
+		}{g.Name, g.Frame.Version, g.Frame.Opcode, g.Frame.Flags, g.Frame.RequestID, g.Frame.StreamID, hex.EncodeToString(g.Frame.Payload), g.Hex}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func str(s string) []byte { return append(u32(uint32(len(s))), s...) }
+
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func u64(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+func vec(fs ...float32) []byte {
+	b := u32(uint32(len(fs)))
+	for _, f := range fs {
+		b = binary.BigEndian.AppendUint32(b, math.Float32bits(f))
+	}
+	return b
+}
+
+func cat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
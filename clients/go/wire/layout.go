@@ -0,0 +1,187 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// V2HeaderSize is the size of a version 2 frame header
+const V2HeaderSize = 32
+
+// Layout is a frame header layout for one protocol version. Clients pick a
+// layout during the handshake and use it for the rest of the connection.
+type Layout interface {
+	// Version is the protocol version written into each header
+	Version() uint8
+	// HeaderSize is the fixed header length in bytes
+	HeaderSize() int
+	// AppendFrame appends the encoded frame to buf
+	AppendFrame(buf []byte, f Frame) []byte
+	// Decode decodes one frame from the start of b and returns the bytes
+	// consumed
+	Decode(b []byte) (Frame, int, error)
+	// ReadFrame reads one frame from r
+	ReadFrame(r io.Reader) (Frame, error)
+	// ReadFrameInto is ReadFrame reusing buf for the header and payload
+	// when it is large enough. The payload aliases buf, or a larger
+	// allocation returned in its place.
+	ReadFrameInto(r io.Reader, buf []byte) (Frame, []byte, error)
+}
+
+// Supported layouts
+var (
+	V1 Layout = v1Layout{}
+	V2 Layout = v2Layout{}
+)
+
+// LayoutFor returns the layout for a protocol version
+func LayoutFor(version uint8) (Layout, error) {
+	switch version {
+	case 1:
+		return V1, nil
+	case 2:
+		return V2, nil
+	default:
+		return nil, fmt.Errorf("wire: unsupported protocol version %d", version)
+	}
+}
+
+// ReadAnyFrame reads one frame of any supported version, selecting the
+// layout from the version byte that follows the magic
+func ReadAnyFrame(r io.Reader) (Frame, error) {
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return Frame{}, err
+	}
+	if string(prefix[0:4]) != Magic {
+		return Frame{}, fmt.Errorf("%w: %q", ErrInvalidMagic, prefix[0:4])
+	}
+	l, err := LayoutFor(prefix[4])
+	if err != nil {
+		return Frame{}, err
+	}
+	return l.ReadFrame(io.MultiReader(bytes.NewReader(prefix), r))
+}
+
+type v1Layout struct{}
+
+func (v1Layout) Version() uint8  { return 1 }
+func (v1Layout) HeaderSize() int { return HeaderSize }
+
+func (v1Layout) AppendFrame(buf []byte, f Frame) []byte {
+	f.Version = 1
+	return AppendFrame(buf, f)
+}
+
+func (v1Layout) Decode(b []byte) (Frame, int, error) { return Decode(b) }
+
+func (v1Layout) ReadFrame(r io.Reader) (Frame, error) { return ReadFrame(r) }
+
+func (v1Layout) ReadFrameInto(r io.Reader, buf []byte) (Frame, []byte, error) {
+	return ReadFrameInto(r, buf)
+}
+
+// v2Layout extends the header with a stream ID for multiplexed sessions and
+// a payload checksum:
+//
+//	[magic: "CELX"][version: u8 = 2][opcode: u8][flags: u16][length: u32]
+//	[request_id: u64][stream_id: u32][payload_crc32: u32][reserved: u32]
+type v2Layout struct{}
+
+func (v2Layout) Version() uint8  { return 2 }
+func (v2Layout) HeaderSize() int { return V2HeaderSize }
+
+func (v2Layout) AppendFrame(buf []byte, f Frame) []byte {
+	buf = append(buf, Magic...)
+	buf = append(buf, 2, f.Opcode)
+	buf = binary.BigEndian.AppendUint16(buf, f.Flags)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(f.Payload)))
+	buf = binary.BigEndian.AppendUint64(buf, f.RequestID)
+	buf = binary.BigEndian.AppendUint32(buf, f.StreamID)
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(f.Payload))
+	buf = binary.BigEndian.AppendUint32(buf, 0) // reserved
+	return append(buf, f.Payload...)
+}
+
+func (l v2Layout) parseHeader(h []byte) (Frame, uint32, uint32, error) {
+	if len(h) < V2HeaderSize {
+		return Frame{}, 0, 0, io.ErrUnexpectedEOF
+	}
+	if string(h[0:4]) != Magic {
+		return Frame{}, 0, 0, fmt.Errorf("%w: %q", ErrInvalidMagic, h[0:4])
+	}
+	if h[4] != 2 {
+		return Frame{}, 0, 0, fmt.Errorf("%w: expected version 2, got version %d", ErrVersion, h[4])
+	}
+	f := Frame{
+		Version:   2,
+		Opcode:    h[5],
+		Flags:     binary.BigEndian.Uint16(h[6:]),
+		RequestID: binary.BigEndian.Uint64(h[12:]),
+		StreamID:  binary.BigEndian.Uint32(h[20:]),
+	}
+	return f, binary.BigEndian.Uint32(h[8:]), binary.BigEndian.Uint32(h[24:]), nil
+}
+
+func (l v2Layout) Decode(b []byte) (Frame, int, error) {
+	f, n, sum, err := l.parseHeader(b)
+	if err != nil {
+		return Frame{}, 0, err
+	}
+	end := V2HeaderSize + int(n)
+	if len(b) < end {
+		return Frame{}, 0, io.ErrUnexpectedEOF
+	}
+	f.Payload = b[V2HeaderSize:end]
+	if crc32.ChecksumIEEE(f.Payload) != sum {
+		return Frame{}, 0, ErrChecksum
+	}
+	return f, end, nil
+}
+
+func (l v2Layout) ReadFrame(r io.Reader) (Frame, error) {
+	header := make([]byte, V2HeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return Frame{}, err
+	}
+	f, n, sum, err := l.parseHeader(header)
+	if err != nil {
+		return Frame{}, err
+	}
+	f.Payload = make([]byte, n)
+	if n > 0 {
+		if _, err := io.ReadFull(r, f.Payload); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return Frame{}, err
+		}
+	}
+	if crc32.ChecksumIEEE(f.Payload) != sum {
+		return Frame{}, ErrChecksum
+	}
+	return f, nil
+}
+
+func (l v2Layout) ReadFrameInto(r io.Reader, buf []byte) (Frame, []byte, error) {
+	buf = grow(buf, V2HeaderSize)
+	if _, err := io.ReadFull(r, buf[:V2HeaderSize]); err != nil {
+		return Frame{}, buf, err
+	}
+	f, n, sum, err := l.parseHeader(buf)
+	if err != nil {
+		return Frame{}, buf, err
+	}
+	buf = grow(buf, int(n))
+	f.Payload = buf[:n]
+	if err := readPayload(r, f.Payload); err != nil {
+		return Frame{}, buf, err
+	}
+	if crc32.ChecksumIEEE(f.Payload) != sum {
+		return Frame{}, buf, ErrChecksum
+	}
+	return f, buf, nil
+}
@@ -0,0 +1,158 @@
+// Package wire is the reference encoder and decoder for CELRIX frames.
+//
+// Every frame is a fixed 22-byte header followed by the payload:
+//
+//	[magic: "CELX"][version: u8][opcode: u8][flags: u16][length: u32][request_id: u64][reserved: u16]
+//
+// All integers are big-endian. The package also ships golden fixtures and
+// round-trip checks so other client implementations can verify they produce
+// and accept the same bytes.
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Frame constants
+const (
+	Magic      = "CELX"
+	Version    = 1
+	HeaderSize = 22
+)
+
+// Decoding errors
+var (
+	ErrInvalidMagic = errors.New("wire: invalid magic")
+	ErrChecksum     = errors.New("wire: payload checksum mismatch")
+	ErrVersion      = errors.New("wire: unexpected frame version")
+)
+
+// Frame is a decoded frame
+type Frame struct {
+	Version   uint8
+	Opcode    uint8
+	Flags     uint16
+	RequestID uint64
+	// StreamID identifies a logical session; carried by version 2 frames
+	// only
+	StreamID uint32
+	Payload  []byte
+}
+
+// AppendFrame appends the encoded frame to buf. A zero Version is encoded
+// as the current Version.
+func AppendFrame(buf []byte, f Frame) []byte {
+	v := f.Version
+	if v == 0 {
+		v = Version
+	}
+	buf = append(buf, Magic...)
+	buf = append(buf, v, f.Opcode)
+	buf = binary.BigEndian.AppendUint16(buf, f.Flags)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(f.Payload)))
+	buf = binary.BigEndian.AppendUint64(buf, f.RequestID)
+	buf = binary.BigEndian.AppendUint16(buf, 0) // reserved
+	return append(buf, f.Payload...)
+}
+
+// Encode returns the encoded frame
+func Encode(f Frame) []byte {
+	return AppendFrame(make([]byte, 0, HeaderSize+len(f.Payload)), f)
+}
+
+// ParseHeader decodes a frame header and returns the payload length
+func ParseHeader(h []byte) (Frame, uint32, error) {
+	if len(h) < HeaderSize {
+		return Frame{}, 0, io.ErrUnexpectedEOF
+	}
+	if string(h[0:4]) != Magic {
+		return Frame{}, 0, fmt.Errorf("%w: %q", ErrInvalidMagic, h[0:4])
+	}
+	f := Frame{
+		Version:   h[4],
+		Opcode:    h[5],
+		Flags:     binary.BigEndian.Uint16(h[6:]),
+		RequestID: binary.BigEndian.Uint64(h[12:]),
+	}
+	return f, binary.BigEndian.Uint32(h[8:]), nil
+}
+
+// Decode decodes one frame from the start of b and returns the number of
+// bytes consumed. The payload aliases b.
+func Decode(b []byte) (Frame, int, error) {
+	f, n, err := ParseHeader(b)
+	if err != nil {
+		return Frame{}, 0, err
+	}
+	end := HeaderSize + int(n)
+	if len(b) < end {
+		return Frame{}, 0, io.ErrUnexpectedEOF
+	}
+	f.Payload = b[HeaderSize:end]
+	return f, end, nil
+}
+
+// ReadFrame reads one frame from r
+func ReadFrame(r io.Reader) (Frame, error) {
+	header := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return Frame{}, err
+	}
+	f, n, err := ParseHeader(header)
+	if err != nil {
+		return Frame{}, err
+	}
+	f.Payload = make([]byte, n)
+	if n > 0 {
+		if _, err := io.ReadFull(r, f.Payload); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return Frame{}, err
+		}
+	}
+	return f, nil
+}
+
+// ReadFrameInto is ReadFrame reusing buf for the header and payload when it
+// is large enough. The payload aliases the returned buffer, which is buf or
+// a larger replacement for it.
+func ReadFrameInto(r io.Reader, buf []byte) (Frame, []byte, error) {
+	buf = grow(buf, HeaderSize)
+	if _, err := io.ReadFull(r, buf[:HeaderSize]); err != nil {
+		return Frame{}, buf, err
+	}
+	f, n, err := ParseHeader(buf)
+	if err != nil {
+		return Frame{}, buf, err
+	}
+	buf = grow(buf, int(n))
+	f.Payload = buf[:n]
+	if err := readPayload(r, f.Payload); err != nil {
+		return Frame{}, buf, err
+	}
+	return f, buf, nil
+}
+
+// grow returns buf resized to n bytes, reallocating only if its capacity
+// is too small
+func grow(buf []byte, n int) []byte {
+	if cap(buf) < n {
+		return make([]byte, n)
+	}
+	return buf[:n]
+}
+
+func readPayload(r io.Reader, p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	_, err := io.ReadFull(r, p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,38 @@
+package wire
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestReferenceRoundTrip(t *testing.T) {
+	if err := RoundTripCheck(Reference, 1, 500); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoundTripCheckCatchesEncodingRegression(t *testing.T) {
+	// Flags written little-endian, as a careless port might
+	broken := Reference
+	broken.Encode = func(f Frame) ([]byte, error) {
+		b, err := Reference.Encode(f)
+		if err == nil {
+			binary.LittleEndian.PutUint16(b[6:], f.Flags)
+		}
+		return b, err
+	}
+	err := RoundTripCheck(broken, 1, 0)
+	if err == nil || !strings.Contains(err.Error(), "golden flags:") || !strings.Contains(err.Error(), "golden v2_flags:") {
+		t.Errorf("got %v, want mismatches on the flags fixtures", err)
+	}
+}
+
+func TestRoundTripCheckV1Only(t *testing.T) {
+	v1 := Reference
+	v1.Versions = nil
+	v1.Encode = func(f Frame) ([]byte, error) { return Encode(f), nil }
+	if err := RoundTripCheck(v1, 2, 100); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,28 @@
+package celrix
+
+// WithZeroCopyReads lets APIs that hand back a Reply, such as ForEach,
+// decode it in place from a read buffer the client reuses, instead of
+// allocating a fresh buffer per reply. The bytes of such a reply, and of
+// any value inside it, are only valid until the next reply is read on the
+// connection: consumers must parse or copy what they need before returning
+// control to the client. Str and native conversions always copy, so only
+// Bytes and values reached through Array and Map alias the buffer.
+//
+// The default is safe copies, which is what any consumer that keeps
+// replies around needs.
+func WithZeroCopyReads() Option {
+	return func(o *options) {
+		o.zeroCopy = true
+	}
+}
+
+// recvReplyFrame reads the next frame of a reply that will be handed back
+// as a Reply, into the shared read buffer under WithZeroCopyReads
+func (c *Client) recvReplyFrame() (frame, error) {
+	if !c.opts.zeroCopy {
+		return c.recvFrame()
+	}
+	f, err := c.recvFrameInto(&c.rbuf)
+	c.rbuf = retain(c.rbuf)
+	return f, err
+}
@@ -15,6 +15,11 @@ pub const VERSION: u8 = 1;
 pub const HEADER_SIZE: usize = 22;
 
 /// Operation codes for VCP commands
+///
+/// The Go client (clients/go/client.go) adds commands in the codes this
+/// enum leaves free: replies in 0x16-0x1F, commands in 0x22-0xDF and
+/// private extensions in 0xE0-0xFF. New opcodes here must avoid the ones
+/// it already uses.
 #[derive(Debug, Clone, Copy, PartialEq, Eq)]
 #[repr(u8)]
 pub enum OpCode {